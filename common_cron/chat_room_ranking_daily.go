@@ -1,7 +1,6 @@
 package common_cron
 
 import (
-	"context"
 	"log"
 	"wechat-robot-client/service"
 	"wechat-robot-client/vars"
@@ -25,7 +24,7 @@ func (cron *ChatRoomRankingDailyCron) IsActive() bool {
 }
 
 func (cron *ChatRoomRankingDailyCron) Cron() error {
-	return service.NewChatRoomService(context.Background()).ChatRoomRankingDaily()
+	return service.NewChatRoomService(broadcastContext()).ChatRoomRankingDaily()
 }
 
 func (cron *ChatRoomRankingDailyCron) Register() {
@@ -1,7 +1,6 @@
 package common_cron
 
 import (
-	"context"
 	"log"
 	"wechat-robot-client/service"
 	"wechat-robot-client/vars"
@@ -27,7 +26,7 @@ func (cron *ChatRoomRankingMonthCron) IsActive() bool {
 }
 
 func (cron *ChatRoomRankingMonthCron) Cron() error {
-	return service.NewChatRoomService(context.Background()).ChatRoomRankingMonthly()
+	return service.NewChatRoomService(broadcastContext()).ChatRoomRankingMonthly()
 }
 
 func (cron *ChatRoomRankingMonthCron) Register() {
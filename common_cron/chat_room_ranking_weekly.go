@@ -1,7 +1,6 @@
 package common_cron
 
 import (
-	"context"
 	"log"
 	"wechat-robot-client/service"
 	"wechat-robot-client/vars"
@@ -27,7 +26,7 @@ func (cron *ChatRoomRankingWeeklyCron) IsActive() bool {
 }
 
 func (cron *ChatRoomRankingWeeklyCron) Cron() error {
-	return service.NewChatRoomService(context.Background()).ChatRoomRankingWeekly()
+	return service.NewChatRoomService(broadcastContext()).ChatRoomRankingWeekly()
 }
 
 func (cron *ChatRoomRankingWeeklyCron) Register() {
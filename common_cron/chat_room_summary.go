@@ -1,7 +1,6 @@
 package common_cron
 
 import (
-	"context"
 	"log"
 	"wechat-robot-client/service"
 	"wechat-robot-client/vars"
@@ -25,7 +24,7 @@ func (cron *ChatRoomSummaryCron) IsActive() bool {
 }
 
 func (cron *ChatRoomSummaryCron) Cron() error {
-	return service.NewChatRoomService(context.Background()).ChatRoomAISummary()
+	return service.NewChatRoomService(broadcastContext()).ChatRoomAISummary()
 }
 
 func (cron *ChatRoomSummaryCron) Register() {
@@ -61,7 +61,7 @@ func (cron *GoodMorningCron) Cron() error {
 	}
 
 	crService := service.NewChatRoomService(context.Background())
-	msgService := service.NewMessageService(context.Background())
+	msgService := service.NewMessageService(broadcastContext())
 	for _, setting := range chatRoomSettings {
 		summary, err := crService.GetChatRoomSummary(setting.ChatRoomID)
 		if err != nil {
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/sendqueue"
 	"wechat-robot-client/service"
 	"wechat-robot-client/vars"
 
@@ -23,6 +24,11 @@ type CronManager struct {
 	cancel         context.CancelFunc
 }
 
+// broadcastContext 定时任务群发的消息排在回复用户的消息之后发送
+func broadcastContext() context.Context {
+	return sendqueue.WithPriority(context.Background(), sendqueue.PriorityBroadcast)
+}
+
 type CronInstance interface {
 	IsActive() bool
 	Register()
@@ -100,6 +106,9 @@ func (m *CronManager) Start() {
 			// 每月群聊排行榜
 			chatRoomRankingMonthCron := NewChatRoomRankingMonthCron(m)
 			chatRoomRankingMonthCron.Register()
+			// 聊天记录语义搜索
+			messageEmbeddingCron := NewMessageEmbeddingCron(m)
+			messageEmbeddingCron.Register()
 		}
 	}
 }
@@ -0,0 +1,58 @@
+package common_cron
+
+import (
+	"context"
+	"log"
+	"sync"
+	"wechat-robot-client/service"
+	"wechat-robot-client/vars"
+)
+
+// 每分钟为新的文本消息生成向量
+const messageEmbeddingCronExpr = "* * * * *"
+
+type MessageEmbeddingCron struct {
+	CronManager *CronManager
+	// 上一次任务还没有完成时跳过本次任务
+	running sync.Mutex
+}
+
+func NewMessageEmbeddingCron(cronManager *CronManager) vars.CommonCronInstance {
+	return &MessageEmbeddingCron{
+		CronManager: cronManager,
+	}
+}
+
+// IsActive 群聊、好友可以单独开启语义搜索，所以任务总是注册，没有需要处理的聊天时直接返回
+func (cron *MessageEmbeddingCron) IsActive() bool {
+	return true
+}
+
+func (cron *MessageEmbeddingCron) Cron() error {
+	if !cron.running.TryLock() {
+		return nil
+	}
+	defer cron.running.Unlock()
+	count, err := service.NewChatHistoryService(context.Background()).EmbedPendingMessages()
+	if count > 0 {
+		log.Printf("聊天记录生成向量 %d 条", count)
+	}
+	return err
+}
+
+func (cron *MessageEmbeddingCron) Register() {
+	if !cron.IsActive() {
+		log.Println("聊天记录向量任务未启用")
+		return
+	}
+	err := cron.CronManager.AddJob(vars.MessageEmbeddingCron, messageEmbeddingCronExpr, func() {
+		if err := cron.Cron(); err != nil {
+			log.Printf("聊天记录生成向量失败: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("聊天记录向量任务注册失败: %v", err)
+		return
+	}
+	log.Println("聊天记录向量任务初始化成功")
+}
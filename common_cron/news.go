@@ -66,7 +66,7 @@ func (cron *NewsCron) Cron() error {
 		return fmt.Errorf("获取每日早报失败: %s", newsResp.Msg)
 	}
 
-	msgService := service.NewMessageService(context.Background())
+	msgService := service.NewMessageService(broadcastContext())
 	newsText := strings.Join(newsResp.News, "\n")
 
 	newsImage := newsResp.Image
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"errors"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AIPersona struct {
+}
+
+func NewAIPersonaController() *AIPersona {
+	return &AIPersona{}
+}
+
+func (ct *AIPersona) GetPersonas(c *gin.Context) {
+	resp := appx.NewResponse(c)
+	personas, err := service.NewAIPersonaService(c).GetPersonas()
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(personas)
+}
+
+func (ct *AIPersona) SavePersona(c *gin.Context) {
+	var req dto.AIPersonaRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewAIPersonaService(c).SavePersona(req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
+
+func (ct *AIPersona) DeletePersona(c *gin.Context) {
+	var req dto.AIPersonaDeleteRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewAIPersonaService(c).DeletePersona(req.ID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
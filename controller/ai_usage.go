@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"errors"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AIUsage struct {
+}
+
+func NewAIUsageController() *AIUsage {
+	return &AIUsage{}
+}
+
+func (ct *AIUsage) GetAIUsageStats(c *gin.Context) {
+	var req dto.AIUsageStatsRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	stats, err := service.NewAIUsageService(c).GetStats(req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(stats)
+}
+
+func (ct *AIUsage) GetAIQuotaStatus(c *gin.Context) {
+	var req dto.AIQuotaRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	status, err := service.NewAIUsageService(c).GetQuotaStatus(req.ContactID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(status)
+}
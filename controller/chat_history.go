@@ -31,3 +31,18 @@ func (ch *ChatHistory) GetChatHistory(c *gin.Context) {
 	}
 	resp.ToResponseList(list, total)
 }
+
+func (ch *ChatHistory) SemanticSearchChatHistory(c *gin.Context) {
+	var req dto.ChatHistorySemanticSearchRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	results, err := service.NewChatHistoryService(c).SemanticSearch(nil, req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(results)
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type KnowledgeBase struct {
+}
+
+func NewKnowledgeBaseController() *KnowledgeBase {
+	return &KnowledgeBase{}
+}
+
+func (ct *KnowledgeBase) GetKnowledgeBases(c *gin.Context) {
+	resp := appx.NewResponse(c)
+	knowledgeBases, err := service.NewKnowledgeBaseService(c).GetKnowledgeBases()
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(knowledgeBases)
+}
+
+func (ct *KnowledgeBase) SaveKnowledgeBase(c *gin.Context) {
+	var req dto.KnowledgeBaseRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewKnowledgeBaseService(c).SaveKnowledgeBase(req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
+
+func (ct *KnowledgeBase) DeleteKnowledgeBase(c *gin.Context) {
+	var req dto.KnowledgeBaseDeleteRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewKnowledgeBaseService(c).DeleteKnowledgeBase(req.ID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
+
+func (ct *KnowledgeBase) GetKnowledgeDocuments(c *gin.Context) {
+	var req dto.KnowledgeDocumentListRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	documents, err := service.NewKnowledgeBaseService(c).GetDocuments(req.KnowledgeBaseID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(documents)
+}
+
+func (ct *KnowledgeBase) UploadKnowledgeDocument(c *gin.Context) {
+	var req dto.KnowledgeDocumentUploadRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	file, fileHeader, err := c.Request.FormFile("file")
+	if err != nil {
+		resp.ToErrorResponse(errors.New("获取上传文件失败"))
+		return
+	}
+	defer file.Close()
+
+	filename := filepath.Base(fileHeader.Filename)
+	if !service.IsKnowledgeDocument(filename) {
+		resp.ToErrorResponse(errors.New("不支持的文档格式，仅支持 txt、md、pdf、docx"))
+		return
+	}
+	if fileHeader.Size > 20*1024*1024 { // 限制为20MB
+		resp.ToErrorResponse(errors.New("文档大小不能超过20MB"))
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		resp.ToErrorResponse(errors.New("读取上传文件失败"))
+		return
+	}
+	document, err := service.NewKnowledgeBaseService(c).AddDocument(req.KnowledgeBaseID, filename, data)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(document)
+}
+
+func (ct *KnowledgeBase) DeleteKnowledgeDocument(c *gin.Context) {
+	var req dto.KnowledgeDocumentDeleteRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewKnowledgeBaseService(c).DeleteDocument(req.ID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
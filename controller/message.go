@@ -32,6 +32,32 @@ func (m *Message) MessageRevoke(c *gin.Context) {
 	resp.ToResponse(nil)
 }
 
+func (m *Message) GetMessageDedupStats(c *gin.Context) {
+	resp := appx.NewResponse(c)
+	stats, err := service.NewMessageService(c).GetMessageDedupStats()
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(stats)
+}
+
+func (m *Message) GetMessageSendFailures(c *gin.Context) {
+	var req dto.MessageSendFailureRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	pager := appx.InitPager(c)
+	list, total, err := service.NewMessageService(c).GetMessageSendFailures(req, pager)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponseList(list, total)
+}
+
 func (m *Message) SendTextMessage(c *gin.Context) {
 	var req dto.SendTextMessageRequest
 	resp := appx.NewResponse(c)
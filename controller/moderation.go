@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"errors"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Moderation struct {
+}
+
+func NewModerationController() *Moderation {
+	return &Moderation{}
+}
+
+func (ct *Moderation) GetModerationLogs(c *gin.Context) {
+	var req dto.ModerationLogRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	pager := appx.InitPager(c)
+	list, total, err := service.NewModerationService(c).GetModerationLogs(req, pager)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponseList(list, total)
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"errors"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Plugin struct {
+}
+
+func NewPluginController() *Plugin {
+	return &Plugin{}
+}
+
+func (ct *Plugin) GetPlugins(c *gin.Context) {
+	var req dto.PluginListRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	plugins, err := service.NewPluginService(c).GetPlugins(req.ContactID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(plugins)
+}
+
+func (ct *Plugin) SavePluginEnabled(c *gin.Context) {
+	var req dto.PluginEnabledRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewPluginService(c).SavePluginEnabled(req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
+
+func (ct *Plugin) ResetPluginEnabled(c *gin.Context) {
+	var req dto.PluginResetRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewPluginService(c).ResetPluginEnabled(req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
+
+func (ct *Plugin) GetPluginConfig(c *gin.Context) {
+	var req dto.PluginConfigRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	config, err := service.NewPluginService(c).GetPluginConfig(req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(config)
+}
+
+func (ct *Plugin) SavePluginConfig(c *gin.Context) {
+	var req dto.PluginConfigSaveRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewPluginService(c).SavePluginConfig(req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"errors"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RateLimit struct {
+}
+
+func NewRateLimitController() *RateLimit {
+	return &RateLimit{}
+}
+
+func (ct *RateLimit) GetRateLimitCounters(c *gin.Context) {
+	var req dto.RateLimitRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	counters, err := service.NewRateLimitService(c).GetRateLimitCounters(req.ContactID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(counters)
+}
+
+func (ct *RateLimit) ResetRateLimitCounters(c *gin.Context) {
+	var req dto.RateLimitRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewRateLimitService(c).ResetRateLimitCounters(req.ContactID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
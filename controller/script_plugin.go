@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"errors"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScriptPlugin struct {
+}
+
+func NewScriptPluginController() *ScriptPlugin {
+	return &ScriptPlugin{}
+}
+
+func (ct *ScriptPlugin) GetScriptPlugins(c *gin.Context) {
+	resp := appx.NewResponse(c)
+	scriptPlugins, err := service.NewScriptPluginService(c).GetScriptPlugins()
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(scriptPlugins)
+}
+
+func (ct *ScriptPlugin) SaveScriptPlugin(c *gin.Context) {
+	var req dto.ScriptPluginRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewScriptPluginService(c).SaveScriptPlugin(req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
+
+func (ct *ScriptPlugin) DeleteScriptPlugin(c *gin.Context) {
+	var req dto.ScriptPluginDeleteRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewScriptPluginService(c).DeleteScriptPlugin(req.ID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
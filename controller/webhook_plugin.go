@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"errors"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookPlugin struct {
+}
+
+func NewWebhookPluginController() *WebhookPlugin {
+	return &WebhookPlugin{}
+}
+
+func (ct *WebhookPlugin) GetWebhookPlugins(c *gin.Context) {
+	resp := appx.NewResponse(c)
+	webhookPlugins, err := service.NewWebhookPluginService(c).GetWebhookPlugins()
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(webhookPlugins)
+}
+
+func (ct *WebhookPlugin) SaveWebhookPlugin(c *gin.Context) {
+	var req dto.WebhookPluginRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewWebhookPluginService(c).SaveWebhookPlugin(req)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
+
+func (ct *WebhookPlugin) DeleteWebhookPlugin(c *gin.Context) {
+	var req dto.WebhookPluginDeleteRequest
+	resp := appx.NewResponse(c)
+	if ok, err := appx.BindAndValid(c, &req); !ok || err != nil {
+		resp.ToErrorResponse(errors.New("参数错误"))
+		return
+	}
+	err := service.NewWebhookPluginService(c).DeleteWebhookPlugin(req.ID)
+	if err != nil {
+		resp.ToErrorResponse(err)
+		return
+	}
+	resp.ToResponse(nil)
+}
@@ -56,7 +56,12 @@ func (ct *WechatServerCallback) SyncMessageCallback(c *gin.Context) {
 	}
 	log.Printf("=== 消息回调详情结束 ===")
 
-	service.NewLoginService(c).SyncMessageCallback(wechatID, req.Data)
+	err := service.NewLoginService(c).SyncMessageCallback(wechatID, req.Data)
+	if err != nil {
+		log.Printf("消息写入消息队列失败: %v", err)
+		resp.ToErrorResponse(err)
+		return
+	}
 
 	resp.ToResponse(nil)
 }
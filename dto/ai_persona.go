@@ -0,0 +1,17 @@
+package dto
+
+type AIPersonaRequest struct {
+	ID                  int64    `form:"id" json:"id"`
+	Name                string   `form:"name" json:"name" binding:"required"`
+	Intro               string   `form:"intro" json:"intro"`
+	Avatar              string   `form:"avatar" json:"avatar"`
+	Prompt              string   `form:"prompt" json:"prompt" binding:"required"`
+	Model               string   `form:"model" json:"model"`
+	Temperature         *float32 `form:"temperature" json:"temperature"`
+	MaxCompletionTokens int      `form:"max_completion_tokens" json:"max_completion_tokens"`
+	TTSVoice            string   `form:"tts_voice" json:"tts_voice"`
+}
+
+type AIPersonaDeleteRequest struct {
+	ID int64 `form:"id" json:"id" binding:"required"`
+}
@@ -0,0 +1,43 @@
+package dto
+
+import "wechat-robot-client/model"
+
+type AIUsageGroupBy string
+
+const (
+	AIUsageGroupByContact AIUsageGroupBy = "contact"
+	AIUsageGroupBySender  AIUsageGroupBy = "sender"
+	AIUsageGroupByModel   AIUsageGroupBy = "model"
+	AIUsageGroupByFeature AIUsageGroupBy = "feature"
+	AIUsageGroupByDay     AIUsageGroupBy = "day"
+)
+
+type AIUsageStatsRequest struct {
+	ContactID  string               `form:"contact_id" json:"contact_id"`
+	SenderWxID string               `form:"sender_wxid" json:"sender_wxid"`
+	Feature    model.AIUsageFeature `form:"feature" json:"feature"`
+	StartTime  int64                `form:"start_time" json:"start_time"` // 秒，为空时不限制
+	EndTime    int64                `form:"end_time" json:"end_time"`     // 秒，为空时不限制
+	GroupBy    AIUsageGroupBy       `form:"group_by" json:"group_by" binding:"required,oneof=contact sender model feature day"`
+}
+
+type AIUsageStat struct {
+	Key              string  `gorm:"column:key" json:"key"` // 按 group_by 分组的值，例如群聊ID、模型名称、日期
+	Calls            int64   `gorm:"column:calls" json:"calls"`
+	PromptTokens     int64   `gorm:"column:prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int64   `gorm:"column:completion_tokens" json:"completion_tokens"`
+	TotalTokens      int64   `gorm:"column:total_tokens" json:"total_tokens"`
+	Cost             float64 `gorm:"column:cost" json:"cost"`
+}
+
+type AIQuotaRequest struct {
+	ContactID string `form:"contact_id" json:"contact_id" binding:"required"`
+}
+
+type AIQuotaStatus struct {
+	ContactID    string `json:"contact_id"`
+	DailyLimit   int64  `json:"daily_limit"` // 0表示不限制
+	DailyUsed    int64  `json:"daily_used"`
+	MonthlyLimit int64  `json:"monthly_limit"` // 0表示不限制
+	MonthlyUsed  int64  `json:"monthly_used"`
+}
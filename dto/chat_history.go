@@ -1,6 +1,20 @@
 package dto
 
+import "wechat-robot-client/model"
+
 type ChatHistoryRequest struct {
 	ContactID string `form:"contact_id" json:"contact_id" binding:"required"`
 	Keyword   string `form:"keyword" json:"keyword"`
 }
+
+type ChatHistorySemanticSearchRequest struct {
+	ContactID string `form:"contact_id" json:"contact_id" binding:"required"`
+	Query     string `form:"query" json:"query" binding:"required"`
+	Limit     int    `form:"limit" json:"limit"`           // 为空时返回5条，最多20条
+	StartTime int64  `form:"start_time" json:"start_time"` // 秒，为空时搜索最近一年的聊天记录
+}
+
+type ChatHistorySearchResult struct {
+	*model.Message
+	Score float64 `json:"score"` // 和搜索内容的相似度
+}
@@ -0,0 +1,23 @@
+package dto
+
+type KnowledgeBaseRequest struct {
+	ID          int64  `form:"id" json:"id"`
+	Name        string `form:"name" json:"name" binding:"required"`
+	Description string `form:"description" json:"description"`
+}
+
+type KnowledgeBaseDeleteRequest struct {
+	ID int64 `form:"id" json:"id" binding:"required"`
+}
+
+type KnowledgeDocumentListRequest struct {
+	KnowledgeBaseID int64 `form:"knowledge_base_id" json:"knowledge_base_id" binding:"required"`
+}
+
+type KnowledgeDocumentUploadRequest struct {
+	KnowledgeBaseID int64 `form:"knowledge_base_id" json:"knowledge_base_id" binding:"required"`
+}
+
+type KnowledgeDocumentDeleteRequest struct {
+	ID int64 `form:"id" json:"id" binding:"required"`
+}
@@ -34,3 +34,11 @@ type SendFileMessageRequest struct {
 	ChunkIndex      int64  `form:"chunk_index" json:"chunk_index"`
 	TotalChunks     int64  `form:"total_chunks" json:"total_chunks" binding:"required"`
 }
+
+type MessageDedupStats struct {
+	Dropped int64 `json:"dropped"` // 累计丢弃的重复消息数量
+}
+
+type MessageSendFailureRequest struct {
+	ToWxID string `form:"to_wxid" json:"to_wxid"`
+}
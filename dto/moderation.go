@@ -0,0 +1,6 @@
+package dto
+
+type ModerationLogRequest struct {
+	ChatRoomID string `form:"chat_room_id" json:"chat_room_id"`
+	MemberWxID string `form:"member_wxid" json:"member_wxid"`
+}
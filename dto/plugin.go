@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"wechat-robot-client/model"
+
+	"gorm.io/datatypes"
+)
+
+type PluginListRequest struct {
+	ContactID string `form:"contact_id" json:"contact_id"`
+}
+
+type PluginEnabledRequest struct {
+	PluginName string `form:"plugin_name" json:"plugin_name" binding:"required"`
+	ContactID  string `form:"contact_id" json:"contact_id"`
+	Enabled    *bool  `form:"enabled" json:"enabled" binding:"required"`
+}
+
+type PluginResetRequest struct {
+	PluginName string `form:"plugin_name" json:"plugin_name" binding:"required"`
+	ContactID  string `form:"contact_id" json:"contact_id" binding:"required"`
+}
+
+type PluginItem struct {
+	Name           string   `json:"name"`
+	Labels         []string `json:"labels"`
+	Priority       int      `json:"priority"`
+	Enabled        bool     `json:"enabled"`         // 最终生效的状态
+	GlobalEnabled  *bool    `json:"global_enabled"`  // 全局配置，为空表示默认启用
+	ContactEnabled *bool    `json:"contact_enabled"` // 群聊/好友配置，为空表示继承全局配置
+}
+
+type PluginConfigRequest struct {
+	PluginName string `form:"plugin_name" json:"plugin_name" binding:"required"`
+	ContactID  string `form:"contact_id" json:"contact_id"`
+}
+
+type PluginConfigSaveRequest struct {
+	PluginName string         `form:"plugin_name" json:"plugin_name" binding:"required"`
+	ContactID  string         `form:"contact_id" json:"contact_id"`
+	Config     datatypes.JSON `form:"config" json:"config" binding:"required"`
+}
+
+type PluginConfigResponse struct {
+	PluginName    string         `json:"plugin_name"`
+	ContactID     string         `json:"contact_id"`
+	Config        datatypes.JSON `json:"config"`         // 最终生效的配置
+	GlobalConfig  datatypes.JSON `json:"global_config"`  // 全局配置
+	ContactConfig datatypes.JSON `json:"contact_config"` // 群聊/好友配置，按字段覆盖全局配置
+}
+
+type WebhookPluginRequest struct {
+	ID          uint64                   `form:"id" json:"id"`
+	Name        string                   `form:"name" json:"name" binding:"required"`
+	URL         string                   `form:"url" json:"url" binding:"required"`
+	Secret      string                   `form:"secret" json:"secret"`
+	Labels      []string                 `form:"labels" json:"labels" binding:"required"`
+	TriggerType model.WebhookTriggerType `form:"trigger_type" json:"trigger_type" binding:"required"`
+	TriggerRule string                   `form:"trigger_rule" json:"trigger_rule"`
+	Priority    int                      `form:"priority" json:"priority"`
+	Timeout     int                      `form:"timeout" json:"timeout"`
+	MaxRetries  int                      `form:"max_retries" json:"max_retries"`
+	Enabled     *bool                    `form:"enabled" json:"enabled" binding:"required"`
+}
+
+type WebhookPluginDeleteRequest struct {
+	ID uint64 `form:"id" json:"id" binding:"required"`
+}
+
+type ScriptPluginRequest struct {
+	ID           uint64   `form:"id" json:"id"`
+	Name         string   `form:"name" json:"name" binding:"required"`
+	Description  string   `form:"description" json:"description"`
+	ContactID    string   `form:"contact_id" json:"contact_id"`
+	Labels       []string `form:"labels" json:"labels" binding:"required"`
+	Script       string   `form:"script" json:"script" binding:"required"`
+	AllowedHosts []string `form:"allowed_hosts" json:"allowed_hosts"`
+	Priority     int      `form:"priority" json:"priority"`
+	Timeout      int      `form:"timeout" json:"timeout"`
+	Enabled      *bool    `form:"enabled" json:"enabled" binding:"required"`
+}
+
+type ScriptPluginDeleteRequest struct {
+	ID uint64 `form:"id" json:"id" binding:"required"`
+}
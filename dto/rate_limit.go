@@ -0,0 +1,19 @@
+package dto
+
+import "wechat-robot-client/model"
+
+type RateLimitRequest struct {
+	ContactID string `form:"contact_id" json:"contact_id" binding:"required"`
+}
+
+type RateLimitCounter struct {
+	ContactID  string                 `json:"contact_id"`
+	Feature    model.RateLimitFeature `json:"feature"`
+	Scope      model.RateLimitScope   `json:"scope"`
+	SenderWxID string                 `json:"sender_wxid"` // 按发送者计数时才有
+	Limit      int                    `json:"limit"`
+	Period     int                    `json:"period"`    // 秒
+	Remaining  int                    `json:"remaining"` // 当前剩余次数
+	Allowed    int64                  `json:"allowed"`   // 计数周期内放行的次数
+	Rejected   int64                  `json:"rejected"`  // 计数周期内被限流的次数
+}
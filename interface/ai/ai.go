@@ -9,8 +9,6 @@ type AIService interface {
 	ExpireAISession(message *model.Message) error
 	ExpireAllAISessionByChatRoomID(chatRoomID string) error
 	IsInAISession(message *model.Message) (bool, error)
-	IsAISessionStart(message *model.Message) bool
 	GetAISessionStartTips() string
-	IsAISessionEnd(message *model.Message) bool
 	GetAISessionEndTips() string
 }
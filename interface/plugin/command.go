@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type CommandArgType string
+
+const (
+	CommandArgString CommandArgType = "string" // 单个参数，以空白字符分隔
+	CommandArgInt    CommandArgType = "int"    // 整数
+	CommandArgText   CommandArgType = "text"   // 剩余的全部内容，只能作为最后一个参数
+)
+
+type CommandScope string
+
+const (
+	CommandScopeAll      CommandScope = ""         // 群聊和私聊都可以使用
+	CommandScopeChatRoom CommandScope = "chatroom" // 只能在群聊中使用
+	CommandScopeFriend   CommandScope = "friend"   // 只能在私聊中使用
+)
+
+type CommandArg struct {
+	Name        string
+	Type        CommandArgType
+	Required    bool
+	Description string
+}
+
+// CommandArgs 解析后的命令参数，按参数名称取值
+type CommandArgs map[string]any
+
+func (args CommandArgs) String(name string) string {
+	value, _ := args[name].(string)
+	return value
+}
+
+func (args CommandArgs) Int(name string) int {
+	value, _ := args[name].(int)
+	return value
+}
+
+func (args CommandArgs) Has(name string) bool {
+	_, ok := args[name]
+	return ok
+}
+
+type Command struct {
+	// 命令前缀，例如 "#"，为空表示不需要前缀
+	Prefix      string
+	Name        string
+	Aliases     []string
+	Description string
+	Args        []CommandArg
+	Scope       CommandScope
+	// 使用命令需要的最低角色，默认所有人都可以使用
+	MinRole Role
+	// 额外的权限校验，为空表示不校验
+	Permission func(ctx *MessageContext) bool
+	// 同一个聊天中同一个人两次使用命令的最小间隔
+	Cooldown time.Duration
+	// 不在帮助信息中显示
+	Hidden  bool
+	Handler func(ctx *MessageContext, args CommandArgs)
+}
+
+// Triggers 返回命令和所有别名的完整触发词
+func (c *Command) Triggers() []string {
+	triggers := []string{c.Prefix + c.Name}
+	for _, alias := range c.Aliases {
+		triggers = append(triggers, c.Prefix+alias)
+	}
+	return triggers
+}
+
+// Usage 返回命令的用法，例如 "快递 <单号>"
+func (c *Command) Usage() string {
+	usage := c.Prefix + c.Name
+	for _, arg := range c.Args {
+		if arg.Required {
+			usage += fmt.Sprintf(" <%s>", arg.Name)
+		} else {
+			usage += fmt.Sprintf(" [%s]", arg.Name)
+		}
+	}
+	return usage
+}
+
+// InScope 判断命令能否在当前聊天中使用
+func (c *Command) InScope(ctx *MessageContext) bool {
+	switch c.Scope {
+	case CommandScopeChatRoom:
+		return ctx.Message.IsChatRoom
+	case CommandScopeFriend:
+		return !ctx.Message.IsChatRoom
+	default:
+		return true
+	}
+}
+
+// Allowed 判断当前消息的发送者是否有权限使用命令
+func (c *Command) Allowed(ctx *MessageContext) bool {
+	if c.MinRole > RoleMember && ctx.SenderRole() < c.MinRole {
+		return false
+	}
+	return c.Permission == nil || c.Permission(ctx)
+}
+
+// HelpLine 帮助信息中的一行
+func (c *Command) HelpLine() string {
+	line := c.Usage()
+	if len(c.Aliases) > 0 {
+		line += fmt.Sprintf(" (别名: %s)", strings.Join(c.Aliases, "、"))
+	}
+	if c.Description != "" {
+		line += " - " + c.Description
+	}
+	return line
+}
+
+// CommandProvider 插件实现该接口后，注册插件时会自动把命令注册到命令路由中
+type CommandProvider interface {
+	GetCommands() []*Command
+}
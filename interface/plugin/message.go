@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"wechat-robot-client/interface/settings"
 	"wechat-robot-client/model"
@@ -22,7 +23,12 @@ type MessageServiceIface interface {
 	SetMessageIsInContext(message *model.Message) error
 	XmlDecoder(content string) (robot.XmlMessage, error)
 	UpdateMessage(message *model.Message) error
-	ChatRoomAIDisabled(chatRoomID string) error
+	SetChatRoomAIEnabled(chatRoomID string, enabled bool) error
+	SetChatRoomAITrigger(chatRoomID, trigger string) error
+	SetChatRoomWelcomeEnabled(chatRoomID string, enabled bool) error
+	UpdatePluginConfig(pluginName, contactID string, fields any) error
+	GetSenderNickname(message *model.Message) string
+	GetSenderRole(message *model.Message) Role
 }
 
 type MessageContext struct {
@@ -33,6 +39,28 @@ type MessageContext struct {
 	Pat            bool
 	ReferMessage   *model.Message
 	MessageService MessageServiceIface
+	// 以下字段只在对应类型的消息中有值
+	Emoji     *robot.EmojiXml
+	Video     *robot.VideoSecretXml
+	Location  *robot.LocationXml
+	ShareCard *robot.ShareCardMessageXml
+	// 当前消息在插件链中的执行轨迹，由插件调度器维护
+	Trace *MessageTrace
+	// 发送者角色缓存，通过 SenderRole 获取
+	senderRole *Role
+}
+
+// GetPluginConfig 读取插件在当前聊天中生效的配置并解析到 config，config 需预先填充默认值，未配置的字段保持默认值
+// 插件实例被所有消息共享，配置随聊天不同，应在每条消息中读取后作为参数传递，不要保存到插件实例上
+func (ctx *MessageContext) GetPluginConfig(pluginName string, config any) error {
+	if ctx.Settings == nil {
+		return nil
+	}
+	data := ctx.Settings.GetPluginConfig(pluginName)
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, config)
 }
 
 type MessageHandler interface {
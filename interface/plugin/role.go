@@ -0,0 +1,45 @@
+package plugin
+
+// Role 消息发送者在当前聊天中的角色，数值越大权限越高
+type Role int
+
+const (
+	RoleMember        Role = iota // 普通成员
+	RoleChatRoomAdmin             // 群管理员
+	RoleChatRoomOwner             // 群主
+	RoleSuperAdmin                // 系统设置中配置的超级管理员
+	RoleOwner                     // 机器人自己的微信号
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleChatRoomAdmin:
+		return "群管理员"
+	case RoleChatRoomOwner:
+		return "群主"
+	case RoleSuperAdmin:
+		return "超级管理员"
+	case RoleOwner:
+		return "机器人主人"
+	default:
+		return "普通成员"
+	}
+}
+
+// RoleRestricted 插件实现该接口后，发送者的角色低于 GetMinRole 时调度器会跳过该插件
+type RoleRestricted interface {
+	GetMinRole() Role
+}
+
+// SenderRole 返回当前消息发送者的角色，同一条消息只查询一次
+func (ctx *MessageContext) SenderRole() Role {
+	if ctx.senderRole != nil {
+		return *ctx.senderRole
+	}
+	role := RoleMember
+	if ctx.Message != nil && ctx.MessageService != nil {
+		role = ctx.MessageService.GetSenderRole(ctx.Message)
+	}
+	ctx.senderRole = &role
+	return role
+}
@@ -0,0 +1,43 @@
+package plugin
+
+import (
+	"encoding/json"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// Tool AI聊天时可以调用的工具，AI根据工具的描述决定是否调用以及调用参数
+type Tool struct {
+	// 工具名称，只能包含字母、数字、下划线
+	Name        string
+	Description string
+	// 参数的 JSON Schema，没有参数时为空
+	Parameters *jsonschema.Definition
+	// 当前聊天中能否使用这个工具，为空表示总是可以使用
+	Available func(ctx *MessageContext) bool
+	// Handler 执行工具调用，args 为AI生成的 JSON 参数，返回值会交给AI继续生成回复
+	// 工具已经直接给用户发送了消息(例如图片、语音)时，返回简短的执行结果即可
+	Handler func(ctx *MessageContext, args json.RawMessage) (string, error)
+}
+
+// Definition 转换成 OpenAI 接口的工具定义
+func (t *Tool) Definition() openai.Tool {
+	parameters := t.Parameters
+	if parameters == nil {
+		parameters = &jsonschema.Definition{Type: jsonschema.Object, Properties: map[string]jsonschema.Definition{}}
+	}
+	return openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  parameters,
+		},
+	}
+}
+
+// ToolProvider 插件实现该接口后，注册插件时会自动把工具注册到工具列表中
+type ToolProvider interface {
+	GetTools() []*Tool
+}
@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type TraceStatus string
+
+const (
+	TraceStatusDisabled TraceStatus = "disabled" // 插件在当前聊天中被禁用
+	TraceStatusSkipped  TraceStatus = "skipped"  // PreAction 返回 false，插件被跳过
+	TraceStatusPassed   TraceStatus = "passed"   // Run 返回 false，继续执行后续插件
+	TraceStatusAborted  TraceStatus = "aborted"  // Run 返回 true，中止插件链
+	TraceStatusPanic    TraceStatus = "panic"    // 插件执行过程中发生 panic，中止插件链
+)
+
+// TraceEntry 单个插件的执行记录
+type TraceEntry struct {
+	Plugin   string        `json:"plugin"`
+	Priority int           `json:"priority"`
+	Status   TraceStatus   `json:"status"`
+	Reason   string        `json:"reason,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// MessageTrace 一条消息在插件链中的执行轨迹
+type MessageTrace struct {
+	MsgID     int64         `json:"msg_id"`
+	Label     string        `json:"label"`
+	Entries   []TraceEntry  `json:"entries"`
+	StoppedBy string        `json:"stopped_by,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+func (t *MessageTrace) String() string {
+	var parts []string
+	for _, entry := range t.Entries {
+		part := fmt.Sprintf("%s:%s", entry.Plugin, entry.Status)
+		if entry.Reason != "" {
+			part += fmt.Sprintf("(%s)", entry.Reason)
+		}
+		parts = append(parts, part)
+	}
+	stoppedBy := t.StoppedBy
+	if stoppedBy == "" {
+		stoppedBy = "-"
+	}
+	return fmt.Sprintf("[插件链] 消息ID: %d, 标签: %s, 中止者: %s, 耗时: %s, 轨迹: %s",
+		t.MsgID, t.Label, stoppedBy, t.Duration, strings.Join(parts, " -> "))
+}
@@ -7,6 +7,7 @@ import (
 )
 
 type AIConfig struct {
+	Provider              model.AIProviderType
 	BaseURL               string
 	APIKey                string
 	Model                 string
@@ -18,6 +19,20 @@ type AIConfig struct {
 	ImageAISettings       datatypes.JSON
 	TTSSettings           datatypes.JSON
 	LTTSSettings          datatypes.JSON
+	ASRSettings           datatypes.JSON
+	// 备用AI服务，主AI服务返回 429/5xx 或者网络错误时按顺序使用
+	FallbackProviders []model.AIProviderConfig
+	// 采样参数，为空时使用模型默认值
+	Temperature     *float32
+	TopP            *float32
+	PresencePenalty *float32
+	// 推理模型的思考强度
+	ReasoningEffort string
+	ResponseFormat  model.AIResponseFormat
+	// 推理模型思考过程的处理方式，为空时去掉思考过程
+	ReasoningOutput model.AIReasoningOutput
+	// 文本转语音的音色，为空时使用 TTSSettings 中的音色
+	TTSVoice string
 }
 
 type PatConfig struct {
@@ -29,11 +44,26 @@ type PatConfig struct {
 
 type Settings interface {
 	InitByMessage(message *model.Message) error
+	GetMessage() *model.Message
 	GetAIConfig() AIConfig
 	IsAIChatEnabled() bool
 	IsAIDrawingEnabled() bool
 	IsTTSEnabled() bool
+	IsASREnabled() bool
+	IsAIStreamEnabled() bool
+	IsAIMemoryEnabled() bool
+	IsSemanticSearchEnabled() bool
 	IsAITrigger() bool
 	GetAITriggerWord() string
 	GetPatConfig() PatConfig
+	IsPluginEnabled(pluginName string) bool
+	GetPluginConfig(pluginName string) datatypes.JSON
+	GetRateLimitRules(feature model.RateLimitFeature) []model.RateLimitRule
+	GetMCPServers() []model.MCPServer
+	GetAITokenQuota() model.AITokenQuota
+	GetKnowledgeBaseIDs() []int64
+	GetPersona() *model.AIPersona
+	IsPersonaSwitchEnabled() bool
+	IsModerationEnabled() bool
+	GetModerationSettings() model.ModerationSettings
 }
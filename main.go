@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"time"
 	"wechat-robot-client/common_cron"
+	"wechat-robot-client/pkg/messagequeue"
 	"wechat-robot-client/pkg/shutdown"
 	"wechat-robot-client/router"
+	"wechat-robot-client/service"
 	"wechat-robot-client/startup"
 	"wechat-robot-client/vars"
 
@@ -27,6 +30,10 @@ func main() {
 		log.Fatalf("初始化失败: %v", err)
 	}
 	shutdownManager := shutdown.NewShutdownManager(30 * time.Second)
+	// 上次退出时没有处理完的知识库文档标记为失败
+	if err := service.NewKnowledgeBaseService(context.Background()).FailInterruptedDocuments(); err != nil {
+		log.Printf("重置处理中断的知识库文档失败: %v", err)
+	}
 	// 注册消息处理插件
 	startup.RegisterMessagePlugin()
 	// 初始化微信机器人
@@ -36,6 +43,12 @@ func main() {
 	}
 	log.Println("微信机器人初始化完成")
 
+	// 启动消息队列消费者
+	messageConsumer := messagequeue.NewConsumer("消息队列消费者", vars.MessageQueue, func(msg *messagequeue.Message) {
+		service.NewMessageService(context.Background()).ConsumeSyncMessage(msg)
+	})
+	messageConsumer.Start()
+
 	// 初始化定时任务
 	log.Println("开始初始化定时任务...")
 	vars.CronManager = common_cron.NewCronManager()
@@ -59,6 +72,13 @@ func main() {
 	redisConn := &shutdown.RedisConnection{
 		Client: vars.RedisClient,
 	}
+	// 先等待处理中的消息(例如正在生成的AI回复)完成，再关闭数据库等连接
+	// 消息队列中没有拉取的消息保留在队列中，下次启动后继续处理
+	shutdownManager.RegisterDrainer(messageConsumer)
+	// 处理中的消息还会提交后台任务，消息处理完成后再等待后台任务完成
+	shutdownManager.RegisterDrainer(shutdown.Sequence(vars.MessageWorkerPool, vars.BackgroundWorkerPool))
+	shutdownManager.Register(vars.MessageSendQueue)
+	shutdownManager.Register(vars.MCPManager)
 	shutdownManager.Register(dbConn)
 	shutdownManager.Register(redisConn)
 	shutdownManager.Register(vars.RobotRuntime)
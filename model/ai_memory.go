@@ -0,0 +1,16 @@
+package model
+
+// AIMemory AI从聊天中记住的关于用户的信息，例如偏好、称呼、正在进行的话题
+// 按聊天和用户保存，群聊中记住的信息不会在其他群聊或者私聊中使用
+type AIMemory struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ContactID string `gorm:"type:varchar(64);index:idx_contact_id_wechat_id,priority:1;not null;column:contact_id;comment:群聊ID或者好友ID" json:"contact_id"`
+	WechatID  string `gorm:"type:varchar(64);index:idx_contact_id_wechat_id,priority:2;not null;column:wechat_id;comment:用户微信ID" json:"wechat_id"`
+	Content   string `gorm:"type:varchar(500);not null;column:content;comment:记忆内容" json:"content"`
+	CreatedAt int64  `gorm:"not null;column:created_at" json:"created_at"`
+	UpdatedAt int64  `gorm:"not null;column:updated_at" json:"updated_at"`
+}
+
+func (AIMemory) TableName() string {
+	return "ai_memories"
+}
@@ -0,0 +1,20 @@
+package model
+
+// AIPersona AI角色，群聊、好友选择角色后，AI聊天使用角色的提示词、模型和音色
+type AIPersona struct {
+	ID                  int64    `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Name                string   `gorm:"type:varchar(32);not null;uniqueIndex:uk_name;column:name;comment:角色名称" json:"name"`
+	Intro               string   `gorm:"type:varchar(500);not null;default:'';column:intro;comment:角色的自我介绍，切换角色和拍一拍时发送" json:"intro"`
+	Avatar              string   `gorm:"type:varchar(500);not null;default:'';column:avatar;comment:角色头像，发送聊天记录形式的长文本时使用" json:"avatar"`
+	Prompt              string   `gorm:"type:text;not null;column:prompt;comment:角色的系统提示词" json:"prompt"`
+	Model               string   `gorm:"type:varchar(100);not null;default:'';column:model;comment:角色使用的模型，为空时使用聊天配置的模型" json:"model"`
+	Temperature         *float32 `gorm:"column:temperature;comment:采样温度，为空时使用模型默认值" json:"temperature"`
+	MaxCompletionTokens int      `gorm:"not null;default:0;column:max_completion_tokens;comment:最大回复字数，0表示使用聊天配置" json:"max_completion_tokens"`
+	TTSVoice            string   `gorm:"type:varchar(100);not null;default:'';column:tts_voice;comment:文本转语音的音色，为空时使用语音配置的音色" json:"tts_voice"`
+	CreatedAt           int64    `gorm:"autoCreateTime;not null;column:created_at" json:"created_at"`
+	UpdatedAt           int64    `gorm:"autoUpdateTime;not null;column:updated_at" json:"updated_at"`
+}
+
+func (AIPersona) TableName() string {
+	return "ai_personas"
+}
@@ -0,0 +1,36 @@
+package model
+
+type AIProviderType string
+
+const (
+	AIProviderOpenAI    AIProviderType = "openai"    // OpenAI 兼容接口
+	AIProviderGemini    AIProviderType = "gemini"    // Gemini 原生接口
+	AIProviderAnthropic AIProviderType = "anthropic" // Anthropic 原生接口
+	AIProviderOllama    AIProviderType = "ollama"    // Ollama 原生接口
+)
+
+// AIProviderConfig 备用AI服务配置，保存在全局配置、群聊配置、好友配置的 ai_fallback_providers 字段中
+// 主AI服务返回 429/5xx 或者网络错误时按顺序使用备用AI服务，群聊/好友配置了备用AI服务时不再使用全局配置的备用AI服务
+type AIProviderConfig struct {
+	Type    AIProviderType `json:"type"`
+	BaseURL string         `json:"base_url"`
+	APIKey  string         `json:"api_key"`
+	// 模型名称，为空时使用和主AI服务相同的模型名称
+	ChatModel             string `json:"chat_model"`
+	WorkflowModel         string `json:"workflow_model"`
+	ImageRecognitionModel string `json:"image_recognition_model"`
+}
+
+type AIResponseFormat string
+
+const (
+	AIResponseFormatText AIResponseFormat = "text"        // 普通文本
+	AIResponseFormatJSON AIResponseFormat = "json_object" // JSON 对象
+)
+
+type AIReasoningOutput string
+
+const (
+	AIReasoningOutputStrip   AIReasoningOutput = "strip"   // 去掉思考过程，只发送回答
+	AIReasoningOutputSummary AIReasoningOutput = "summary" // 在回答前面附上思考过程的摘要
+)
@@ -0,0 +1,46 @@
+package model
+
+type AIUsageFeature string
+
+const (
+	AIUsageFeatureChat             AIUsageFeature = "chat"              // AI聊天，包括工具调用的每一轮
+	AIUsageFeatureWorkflow         AIUsageFeature = "workflow"          // 文本转语音提取文本等工作流
+	AIUsageFeatureImageRecognition AIUsageFeature = "image_recognition" // 图片识别
+	AIUsageFeatureSummary          AIUsageFeature = "summary"           // 群聊总结
+	AIUsageFeatureMoment           AIUsageFeature = "moment"            // 朋友圈点赞判断和评论
+	AIUsageFeatureKnowledge        AIUsageFeature = "knowledge"         // 知识库检索生成问题向量
+	AIUsageFeatureSemanticSearch   AIUsageFeature = "semantic_search"   // 聊天记录生成向量和语义搜索
+	AIUsageFeatureModeration       AIUsageFeature = "moderation"        // 群聊内容审核
+)
+
+// AIUsage 每次调用AI消耗的token
+type AIUsage struct {
+	ID               int64          `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ContactID        string         `gorm:"type:varchar(64);index:idx_contact_id_created_at,priority:1;not null;default:'';column:contact_id;comment:群聊ID或者好友ID" json:"contact_id"`
+	SenderWxID       string         `gorm:"type:varchar(64);not null;default:'';column:sender_wxid;comment:发送者微信ID，定时任务等没有发送者时为空" json:"sender_wxid"`
+	Feature          AIUsageFeature `gorm:"type:varchar(32);not null;default:'';column:feature;comment:功能" json:"feature"`
+	Model            string         `gorm:"type:varchar(100);not null;default:'';column:model;comment:模型名称" json:"model"`
+	PromptTokens     int            `gorm:"not null;default:0;column:prompt_tokens;comment:输入token数量" json:"prompt_tokens"`
+	CompletionTokens int            `gorm:"not null;default:0;column:completion_tokens;comment:输出token数量" json:"completion_tokens"`
+	TotalTokens      int            `gorm:"not null;default:0;column:total_tokens;comment:总token数量" json:"total_tokens"`
+	Cost             float64        `gorm:"not null;default:0;column:cost;comment:按全局配置的模型价格计算的费用" json:"cost"`
+	CreatedAt        int64          `gorm:"index:idx_contact_id_created_at,priority:2;index:idx_created_at;not null;column:created_at" json:"created_at"`
+}
+
+func (AIUsage) TableName() string {
+	return "ai_usages"
+}
+
+// AIModelPrice 模型价格，保存在全局配置的 ai_model_prices 字段中，单位为每百万token的价格
+type AIModelPrice struct {
+	Model           string  `json:"model"`
+	PromptPrice     float64 `json:"prompt_price"`
+	CompletionPrice float64 `json:"completion_price"`
+}
+
+// AITokenQuota 群聊/好友每天、每月最多使用的token数量，0表示不限制
+// 群聊/好友配置了额度时使用群聊/好友的额度，否则使用全局配置的额度
+type AITokenQuota struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
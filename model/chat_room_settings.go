@@ -5,42 +5,64 @@ import (
 )
 
 type ChatRoomSettings struct {
-	ID                        uint64         `gorm:"column:id;primaryKey;autoIncrement;comment:公共配置表主键ID" json:"id"`
-	ChatRoomID                string         `gorm:"column:chat_room_id;type:varchar(64);default:'';comment:群聊ID" json:"chat_room_id"`
-	ChatAIEnabled             *bool          `gorm:"column:chat_ai_enabled;default:false;comment:是否启用AI聊天功能" json:"chat_ai_enabled"`
-	ChatAITrigger             *string        `gorm:"column:chat_ai_trigger;type:varchar(20);default:'';comment:触发聊天AI的关键词" json:"chat_ai_trigger"`
-	ChatBaseURL               *string        `gorm:"column:chat_base_url;type:varchar(255);default:'';comment:聊天AI的基础URL地址" json:"chat_base_url"`
-	ChatAPIKey                *string        `gorm:"column:chat_api_key;type:varchar(255);default:'';comment:聊天AI的API密钥" json:"chat_api_key"`
-	WorkflowModel             *string        `gorm:"column:workflow_model;type:varchar(100);default:'';comment:聊天AI使用的模型名称" json:"workflow_model"`
-	ChatModel                 *string        `gorm:"column:chat_model;type:varchar(100);default:'';comment:聊天AI使用的模型名称" json:"chat_model"`
-	ImageRecognitionModel     *string        `gorm:"column:image_recognition_model;type:varchar(100);default:'';comment:图像识别AI使用的模型名称" json:"image_recognition_model"`
-	ChatPrompt                *string        `gorm:"column:chat_prompt;type:text;comment:聊天AI系统提示词" json:"chat_prompt"`
-	MaxCompletionTokens       *int           `gorm:"column:max_completion_tokens;default:0;comment:最大回复" json:"max_completion_tokens"`
-	ImageAIEnabled            *bool          `gorm:"column:image_ai_enabled;default:false;comment:是否启用AI绘图功能" json:"image_ai_enabled"`
-	ImageModel                *ImageModel    `gorm:"column:image_model;type:varchar(255);default:'';comment:绘图AI模型" json:"image_model"`
-	ImageAISettings           datatypes.JSON `gorm:"column:image_ai_settings;type:json;comment:绘图AI配置项" json:"image_ai_settings"`
-	TTSEnabled                *bool          `gorm:"column:tts_enabled;default:false;comment:是否启用AI文本转语音功能" json:"tts_enabled"`
-	TTSSettings               datatypes.JSON `gorm:"column:tts_settings;type:json;comment:文本转语音配置项" json:"tts_settings"`
-	LTTSSettings              datatypes.JSON `gorm:"column:ltts_settings;type:json;comment:长文本转语音配置项" json:"ltts_settings"`
-	PatEnabled                *bool          `gorm:"column:pat_enabled;default:false;comment:是否启用拍一拍功能" json:"pat_enabled"`
-	PatType                   PatType        `gorm:"column:pat_type;type:enum('text','voice');default:'text';comment:拍一拍方式：text-文本，voice-语音" json:"pat_type"`
-	PatText                   string         `gorm:"column:pat_text;type:varchar(255);default:'';comment:拍一拍的文本" json:"pat_text"`
-	PatVoiceTimbre            string         `gorm:"column:pat_voice_timbre;type:varchar(255);default:'';comment:拍一拍的音色" json:"pat_voice_timbre"`
-	WelcomeEnabled            *bool          `gorm:"column:welcome_enabled;default:false;comment:是否启用新成员加群欢迎功能" json:"welcome_enabled"`
-	WelcomeType               WelcomeType    `gorm:"column:welcome_type;type:enum('text','emoji','image','url');default:'text';comment:欢迎方式：text-文本，emoji-表情，image-图片，url-链接" json:"welcome_type"`
-	WelcomeText               string         `gorm:"column:welcome_text;type:varchar(255);default:'';comment:欢迎新成员的文本" json:"welcome_text"`
-	WelcomeEmojiMD5           string         `gorm:"column:welcome_emoji_md5;type:varchar(64);default:'';comment:欢迎新成员的表情MD5" json:"welcome_emoji_md5"`
-	WelcomeEmojiLen           int64          `gorm:"column:welcome_emoji_len;default:0;comment:欢迎新成员的表情MD5长度" json:"welcome_emoji_len"`
-	WelcomeImageURL           string         `gorm:"column:welcome_image_url;type:varchar(255);default:'';comment:欢迎新成员的图片URL" json:"welcome_image_url"`
-	WelcomeURL                string         `gorm:"column:welcome_url;type:varchar(255);default:'';comment:欢迎新成员的URL" json:"welcome_url"`
-	LeaveChatRoomAlertEnabled *bool          `gorm:"column:leave_chat_room_alert_enabled;default:false;comment:是否启用离开群聊提醒功能" json:"leave_chat_room_alert_enabled"`
-	LeaveChatRoomAlertText    string         `gorm:"column:leave_chat_room_alert_text;type:varchar(255);default:'';comment:离开群聊提醒文本" json:"leave_chat_room_alert_text"`
-	ChatRoomRankingEnabled    *bool          `gorm:"column:chat_room_ranking_enabled;default:false;comment:是否启用群聊排行榜功能" json:"chat_room_ranking_enabled"`
-	ChatRoomSummaryEnabled    *bool          `gorm:"column:chat_room_summary_enabled;default:false;comment:是否启用聊天记录总结功能" json:"chat_room_summary_enabled"`
-	ChatRoomSummaryModel      *string        `gorm:"column:chat_room_summary_model;type:varchar(100);default:'';comment:聊天总结使用的AI模型名称" json:"chat_room_summary_model"`
-	NewsEnabled               *bool          `gorm:"column:news_enabled;default:false;comment:是否启用每日早报功能" json:"news_enabled"`
-	NewsType                  *NewsType      `gorm:"column:news_type;type:enum('text','image');default:'text';comment:是否启用每日早报功能" json:"news_type"`
-	MorningEnabled            *bool          `gorm:"column:morning_enabled;default:false;comment:是否启用早安问候功能" json:"morning_enabled"`
+	ID                        uint64          `gorm:"column:id;primaryKey;autoIncrement;comment:公共配置表主键ID" json:"id"`
+	ChatRoomID                string          `gorm:"column:chat_room_id;type:varchar(64);default:'';comment:群聊ID" json:"chat_room_id"`
+	ChatAIEnabled             *bool           `gorm:"column:chat_ai_enabled;default:false;comment:是否启用AI聊天功能" json:"chat_ai_enabled"`
+	ChatAITrigger             *string         `gorm:"column:chat_ai_trigger;type:varchar(20);default:'';comment:触发聊天AI的关键词" json:"chat_ai_trigger"`
+	ChatBaseURL               *string         `gorm:"column:chat_base_url;type:varchar(255);default:'';comment:聊天AI的基础URL地址" json:"chat_base_url"`
+	ChatAPIKey                *string         `gorm:"column:chat_api_key;type:varchar(255);default:'';comment:聊天AI的API密钥" json:"chat_api_key"`
+	ChatProvider              *AIProviderType `gorm:"column:chat_provider;type:varchar(20);default:'';comment:聊天AI的接口类型，openai、gemini、anthropic、ollama，为空时使用 OpenAI 兼容接口" json:"chat_provider"`
+	AIFallbackProviders       datatypes.JSON  `gorm:"column:ai_fallback_providers;type:json;comment:备用AI服务，主AI服务限流或者出错时按顺序使用" json:"ai_fallback_providers"`
+	WorkflowModel             *string         `gorm:"column:workflow_model;type:varchar(100);default:'';comment:聊天AI使用的模型名称" json:"workflow_model"`
+	ChatModel                 *string         `gorm:"column:chat_model;type:varchar(100);default:'';comment:聊天AI使用的模型名称" json:"chat_model"`
+	ImageRecognitionModel     *string         `gorm:"column:image_recognition_model;type:varchar(100);default:'';comment:图像识别AI使用的模型名称" json:"image_recognition_model"`
+	ChatPrompt                *string         `gorm:"column:chat_prompt;type:text;comment:聊天AI系统提示词" json:"chat_prompt"`
+	MaxCompletionTokens       *int            `gorm:"column:max_completion_tokens;default:0;comment:最大回复" json:"max_completion_tokens"`
+	Temperature               *float32        `gorm:"column:temperature;comment:采样温度，取值 0~2，为空时使用模型默认值" json:"temperature"`
+	TopP                      *float32        `gorm:"column:top_p;comment:核采样概率，取值 0~1，为空时使用模型默认值" json:"top_p"`
+	PresencePenalty           *float32        `gorm:"column:presence_penalty;comment:话题新鲜度，取值 -2~2，为空时使用模型默认值" json:"presence_penalty"`
+	ReasoningEffort           *string         `gorm:"column:reasoning_effort;type:varchar(20);default:'';comment:推理模型的思考强度：low、medium、high，为空时使用模型默认值" json:"reasoning_effort"`
+	ResponseFormat            *string         `gorm:"column:response_format;type:varchar(20);default:'';comment:回复格式：text-文本，json_object-JSON，为空时使用文本" json:"response_format"`
+	ReasoningOutput           *string         `gorm:"column:reasoning_output;type:varchar(20);default:'';comment:推理模型思考过程的处理方式：strip-去掉，summary-附上摘要，为空时去掉" json:"reasoning_output"`
+	ImageAIEnabled            *bool           `gorm:"column:image_ai_enabled;default:false;comment:是否启用AI绘图功能" json:"image_ai_enabled"`
+	ImageModel                *ImageModel     `gorm:"column:image_model;type:varchar(255);default:'';comment:绘图AI模型" json:"image_model"`
+	ImageAISettings           datatypes.JSON  `gorm:"column:image_ai_settings;type:json;comment:绘图AI配置项" json:"image_ai_settings"`
+	TTSEnabled                *bool           `gorm:"column:tts_enabled;default:false;comment:是否启用AI文本转语音功能" json:"tts_enabled"`
+	TTSSettings               datatypes.JSON  `gorm:"column:tts_settings;type:json;comment:文本转语音配置项" json:"tts_settings"`
+	LTTSSettings              datatypes.JSON  `gorm:"column:ltts_settings;type:json;comment:长文本转语音配置项" json:"ltts_settings"`
+	ASREnabled                *bool           `gorm:"column:asr_enabled;default:false;comment:是否启用语音转文字功能" json:"asr_enabled"`
+	ASRSettings               datatypes.JSON  `gorm:"column:asr_settings;type:json;comment:语音转文字配置项" json:"asr_settings"`
+	AIStreamEnabled           *bool           `gorm:"column:ai_stream_enabled;default:false;comment:是否启用AI流式回复，长回复按段落分多条消息发送" json:"ai_stream_enabled"`
+	AIMemoryEnabled           *bool           `gorm:"column:ai_memory_enabled;default:false;comment:是否启用AI长期记忆，AI记住每个用户的偏好、称呼等信息" json:"ai_memory_enabled"`
+	SemanticSearchEnabled     *bool           `gorm:"column:semantic_search_enabled;default:false;comment:是否启用聊天记录语义搜索，开启后在后台为文本消息生成向量" json:"semantic_search_enabled"`
+	PatEnabled                *bool           `gorm:"column:pat_enabled;default:false;comment:是否启用拍一拍功能" json:"pat_enabled"`
+	PatType                   PatType         `gorm:"column:pat_type;type:enum('text','voice');default:'text';comment:拍一拍方式：text-文本，voice-语音" json:"pat_type"`
+	PatText                   string          `gorm:"column:pat_text;type:varchar(255);default:'';comment:拍一拍的文本" json:"pat_text"`
+	PatVoiceTimbre            string          `gorm:"column:pat_voice_timbre;type:varchar(255);default:'';comment:拍一拍的音色" json:"pat_voice_timbre"`
+	WelcomeEnabled            *bool           `gorm:"column:welcome_enabled;default:false;comment:是否启用新成员加群欢迎功能" json:"welcome_enabled"`
+	WelcomeType               WelcomeType     `gorm:"column:welcome_type;type:enum('text','emoji','image','url');default:'text';comment:欢迎方式：text-文本，emoji-表情，image-图片，url-链接" json:"welcome_type"`
+	WelcomeText               string          `gorm:"column:welcome_text;type:varchar(255);default:'';comment:欢迎新成员的文本" json:"welcome_text"`
+	WelcomeEmojiMD5           string          `gorm:"column:welcome_emoji_md5;type:varchar(64);default:'';comment:欢迎新成员的表情MD5" json:"welcome_emoji_md5"`
+	WelcomeEmojiLen           int64           `gorm:"column:welcome_emoji_len;default:0;comment:欢迎新成员的表情MD5长度" json:"welcome_emoji_len"`
+	WelcomeImageURL           string          `gorm:"column:welcome_image_url;type:varchar(255);default:'';comment:欢迎新成员的图片URL" json:"welcome_image_url"`
+	WelcomeURL                string          `gorm:"column:welcome_url;type:varchar(255);default:'';comment:欢迎新成员的URL" json:"welcome_url"`
+	LeaveChatRoomAlertEnabled *bool           `gorm:"column:leave_chat_room_alert_enabled;default:false;comment:是否启用离开群聊提醒功能" json:"leave_chat_room_alert_enabled"`
+	LeaveChatRoomAlertText    string          `gorm:"column:leave_chat_room_alert_text;type:varchar(255);default:'';comment:离开群聊提醒文本" json:"leave_chat_room_alert_text"`
+	ChatRoomRankingEnabled    *bool           `gorm:"column:chat_room_ranking_enabled;default:false;comment:是否启用群聊排行榜功能" json:"chat_room_ranking_enabled"`
+	ChatRoomSummaryEnabled    *bool           `gorm:"column:chat_room_summary_enabled;default:false;comment:是否启用聊天记录总结功能" json:"chat_room_summary_enabled"`
+	ChatRoomSummaryModel      *string         `gorm:"column:chat_room_summary_model;type:varchar(100);default:'';comment:聊天总结使用的AI模型名称" json:"chat_room_summary_model"`
+	NewsEnabled               *bool           `gorm:"column:news_enabled;default:false;comment:是否启用每日早报功能" json:"news_enabled"`
+	NewsType                  *NewsType       `gorm:"column:news_type;type:enum('text','image');default:'text';comment:是否启用每日早报功能" json:"news_type"`
+	MorningEnabled            *bool           `gorm:"column:morning_enabled;default:false;comment:是否启用早安问候功能" json:"morning_enabled"`
+	RateLimits                datatypes.JSON  `gorm:"column:rate_limits;type:json;comment:AI功能限流规则" json:"rate_limits"`
+	AIDailyTokenQuota         *int64          `gorm:"column:ai_daily_token_quota;default:0;comment:每天最多使用的AI token数量，0表示不限制" json:"ai_daily_token_quota"`
+	AIMonthlyTokenQuota       *int64          `gorm:"column:ai_monthly_token_quota;default:0;comment:每月最多使用的AI token数量，0表示不限制" json:"ai_monthly_token_quota"`
+	MCPServers                datatypes.JSON  `gorm:"column:mcp_servers;type:json;comment:MCP服务器配置，服务器提供的工具在AI聊天时交给AI调用" json:"mcp_servers"`
+	KnowledgeBaseIDs          datatypes.JSON  `gorm:"column:knowledge_base_ids;type:json;comment:关联的知识库ID列表，AI聊天时检索相关文档片段" json:"knowledge_base_ids"`
+	AIPersonaID               *int64          `gorm:"column:ai_persona_id;comment:当前使用的AI角色ID，为空时不使用角色" json:"ai_persona_id"`
+	PersonaSwitchEnabled      *bool           `gorm:"column:persona_switch_enabled;default:false;comment:是否允许群成员通过指令切换AI角色，管理员始终可以切换" json:"persona_switch_enabled"`
+	ModerationEnabled         *bool           `gorm:"column:moderation_enabled;default:false;comment:是否启用群聊内容审核" json:"moderation_enabled"`
+	ModerationSettings        datatypes.JSON  `gorm:"column:moderation_settings;type:json;comment:内容审核规则" json:"moderation_settings"`
 }
 
 // TableName 设置表名
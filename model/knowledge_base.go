@@ -0,0 +1,65 @@
+package model
+
+type KnowledgeDocumentStatus string
+
+const (
+	KnowledgeDocumentStatusProcessing KnowledgeDocumentStatus = "processing" // 正在生成向量
+	KnowledgeDocumentStatusReady      KnowledgeDocumentStatus = "ready"      // 可以检索
+	KnowledgeDocumentStatusFailed     KnowledgeDocumentStatus = "failed"     // 生成向量失败
+)
+
+// KnowledgeSettings 知识库配置，聊天记录语义搜索也使用这里的向量接口，接口地址和密钥为空时使用AI聊天的配置
+type KnowledgeSettings struct {
+	BaseURL        string `json:"base_url"`
+	APIKey         string `json:"api_key"`
+	EmbeddingModel string `json:"embedding_model"`
+	// 每次最多检索的片段数量
+	TopK int `json:"top_k"`
+	// 相似度低于该值的片段不加入上下文，取值 0~1
+	MinScore float64 `json:"min_score"`
+}
+
+// KnowledgeBase 知识库，群聊、好友关联知识库后，AI聊天时会检索相关的文档片段加入上下文
+type KnowledgeBase struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Name        string `gorm:"type:varchar(64);not null;uniqueIndex:uk_name;column:name;comment:知识库名称" json:"name"`
+	Description string `gorm:"type:varchar(255);not null;default:'';column:description;comment:知识库描述" json:"description"`
+	CreatedAt   int64  `gorm:"autoCreateTime;not null;column:created_at" json:"created_at"`
+	UpdatedAt   int64  `gorm:"autoUpdateTime;not null;column:updated_at" json:"updated_at"`
+}
+
+func (KnowledgeBase) TableName() string {
+	return "knowledge_bases"
+}
+
+// KnowledgeDocument 知识库中的文档，上传后切分成片段并生成向量
+type KnowledgeDocument struct {
+	ID              int64                   `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	KnowledgeBaseID int64                   `gorm:"not null;index:idx_knowledge_base_id;column:knowledge_base_id;comment:知识库ID" json:"knowledge_base_id"`
+	Name            string                  `gorm:"type:varchar(255);not null;column:name;comment:文档名称" json:"name"`
+	Size            int64                   `gorm:"not null;default:0;column:size;comment:文件大小，单位字节" json:"size"`
+	ChunkCount      int                     `gorm:"not null;default:0;column:chunk_count;comment:切分的片段数量" json:"chunk_count"`
+	Status          KnowledgeDocumentStatus `gorm:"type:varchar(20);not null;default:'processing';column:status;comment:状态：processing-处理中，ready-可用，failed-失败" json:"status"`
+	Error           string                  `gorm:"type:varchar(500);not null;default:'';column:error;comment:处理失败的原因" json:"error"`
+	CreatedAt       int64                   `gorm:"autoCreateTime;not null;column:created_at" json:"created_at"`
+	UpdatedAt       int64                   `gorm:"autoUpdateTime;not null;column:updated_at" json:"updated_at"`
+}
+
+func (KnowledgeDocument) TableName() string {
+	return "knowledge_documents"
+}
+
+// KnowledgeChunk 文档片段和向量，向量按 float32 小端序保存
+type KnowledgeChunk struct {
+	ID              int64  `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	KnowledgeBaseID int64  `gorm:"not null;index:idx_knowledge_base_id;column:knowledge_base_id;comment:知识库ID" json:"knowledge_base_id"`
+	DocumentID      int64  `gorm:"not null;index:idx_document_id;column:document_id;comment:文档ID" json:"document_id"`
+	ChunkIndex      int    `gorm:"not null;default:0;column:chunk_index;comment:片段在文档中的序号" json:"chunk_index"`
+	Content         string `gorm:"type:text;not null;column:content;comment:片段内容" json:"content"`
+	Embedding       []byte `gorm:"type:mediumblob;not null;column:embedding;comment:片段向量" json:"-"`
+	CreatedAt       int64  `gorm:"autoCreateTime;not null;column:created_at" json:"created_at"`
+}
+
+func (KnowledgeChunk) TableName() string {
+	return "knowledge_chunks"
+}
@@ -0,0 +1,26 @@
+package model
+
+type MCPTransport string
+
+const (
+	MCPTransportStdio MCPTransport = "stdio" // 启动本地进程，通过标准输入输出通信
+	MCPTransportSSE   MCPTransport = "sse"   // HTTP + SSE
+	MCPTransportHTTP  MCPTransport = "http"  // Streamable HTTP
+)
+
+// MCPServer MCP(Model Context Protocol)服务器配置，服务器提供的工具会在AI聊天时交给AI调用
+// 保存在全局配置、群聊配置的 mcp_servers 字段中，群聊配置的服务器和全局配置的服务器一起使用，名称相同时使用群聊的配置
+type MCPServer struct {
+	// 服务器名称，只能包含字母、数字、下划线、中划线，会作为工具名称的前缀
+	Name      string       `json:"name"`
+	Transport MCPTransport `json:"transport"`
+	// stdio 使用，启动服务器的命令、参数和额外的环境变量
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// sse、http 使用，服务器地址和请求头
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// 群聊配置中设置为 true 时，该群聊不使用全局配置中的同名服务器
+	Disabled bool `json:"disabled,omitempty"`
+}
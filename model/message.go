@@ -51,7 +51,7 @@ const (
 
 type Message struct {
 	ID                 int64          `gorm:"primarykey" json:"id"`
-	MsgId              int64          `gorm:"column:msg_id;index;" json:"msg_id"`               // 消息Id
+	MsgId              int64          `gorm:"column:msg_id;uniqueIndex;" json:"msg_id"`         // 消息Id
 	ClientMsgId        int64          `gorm:"column:client_msg_id;index;" json:"client_msg_id"` // 客户端消息Id
 	IsChatRoom         bool           `gorm:"column:is_chat_room;default:false;comment:'消息是否来自群聊'" json:"is_chat_room"`
 	IsAtMe             bool           `gorm:"column:is_at_me;default:false;comment:'消息是否艾特我'" json:"is_at_me"`              // @所有人 好的
@@ -67,6 +67,7 @@ type Message struct {
 	ReplyWxID          string         `gorm:"column:reply_wxid" json:"reply_wxid"`         // AI回复的人
 	ToWxID             string         `gorm:"column:to_wxid" json:"to_wxid"`               // 接收者
 	AttachmentUrl      string         `gorm:"column:attachment_url" json:"attachment_url"` // 文件地址
+	VoiceText          string         `gorm:"column:voice_text" json:"voice_text"`         // 语音消息识别出的文字
 	CreatedAt          int64          `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt          int64          `gorm:"column:updated_at" json:"updated_at"`
 	// 额外字段，通过联表查询填充，不参与建表
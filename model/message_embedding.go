@@ -0,0 +1,14 @@
+package model
+
+// MessageEmbedding 文本消息的向量，用于语义搜索聊天记录，向量按 float32 小端序保存
+type MessageEmbedding struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	MessageID int64  `gorm:"not null;uniqueIndex:uk_message_id;column:message_id;comment:消息表主键ID" json:"message_id"`
+	ContactID string `gorm:"type:varchar(64);not null;index:idx_contact_id_created_at,priority:1;column:contact_id;comment:群聊ID或者好友ID" json:"contact_id"`
+	Embedding []byte `gorm:"type:blob;not null;column:embedding;comment:消息向量" json:"-"`
+	CreatedAt int64  `gorm:"not null;index:idx_contact_id_created_at,priority:2;column:created_at;comment:消息的发送时间" json:"created_at"`
+}
+
+func (MessageEmbedding) TableName() string {
+	return "message_embeddings"
+}
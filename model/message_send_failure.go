@@ -0,0 +1,17 @@
+package model
+
+// MessageSendFailure 重试之后仍然发送失败的消息
+type MessageSendFailure struct {
+	ID        int64       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ToWxID    string      `gorm:"type:varchar(64);index:idx_to_wxid;not null;column:to_wxid;comment:接收者微信ID" json:"to_wxid"`
+	Type      MessageType `gorm:"not null;column:type;comment:消息类型" json:"type"`
+	Content   string      `gorm:"type:text;column:content;comment:文本消息的内容，图片等消息为空" json:"content"`
+	Priority  int         `gorm:"not null;default:0;column:priority;comment:发送优先级 0:回复 100:定时任务群发" json:"priority"`
+	Attempts  int         `gorm:"not null;default:0;column:attempts;comment:发送次数" json:"attempts"`
+	Error     string      `gorm:"type:varchar(1024);column:error;comment:最后一次发送的错误" json:"error"`
+	CreatedAt int64       `gorm:"index:idx_created_at;not null;column:created_at" json:"created_at"`
+}
+
+func (MessageSendFailure) TableName() string {
+	return "message_send_failures"
+}
@@ -0,0 +1,62 @@
+package model
+
+type ModerationCategory string
+
+const (
+	ModerationCategoryAd       ModerationCategory = "ad"        // 广告
+	ModerationCategoryScam     ModerationCategory = "scam"      // 诈骗、钓鱼链接
+	ModerationCategoryPorn     ModerationCategory = "porn"      // 色情
+	ModerationCategoryViolence ModerationCategory = "violence"  // 暴力、血腥
+	ModerationCategoryOffTopic ModerationCategory = "off_topic" // 和群聊主题无关的刷屏内容
+	ModerationCategoryFlood    ModerationCategory = "flood"     // 短时间内大量发言
+	ModerationCategoryKeyword  ModerationCategory = "keyword"   // 命中关键词或者正则
+)
+
+type ModerationAction string
+
+const (
+	ModerationActionWarn ModerationAction = "warn" // @违规成员警告
+	ModerationActionKick ModerationAction = "kick" // 踢出群聊
+)
+
+// ModerationSettings 群聊内容审核配置，群聊配置了审核规则时不再使用全局配置的审核规则
+type ModerationSettings struct {
+	// AI审核使用的模型，为空时只按关键词、正则和刷屏规则审核，接口地址和密钥使用AI聊天的配置
+	Model string `json:"model"`
+	// AI审核的类别，为空时审核广告、诈骗、色情、暴力
+	Categories []ModerationCategory `json:"categories"`
+	// 群聊主题，审核类别包含 off_topic 时AI按主题判断内容是否无关
+	Topic    string   `json:"topic"`
+	Keywords []string `json:"keywords"`
+	// 正则表达式，无法编译的正则会被忽略
+	Patterns []string `json:"patterns"`
+	// 刷屏：FloodWindow 秒内同一个人发送的消息超过 FloodMaxMessages 条，任意一个为0时不检查刷屏
+	FloodWindow      int `json:"flood_window"`
+	FloodMaxMessages int `json:"flood_max_messages"`
+	// 统计违规次数的天数，为0时统计最近30天
+	StrikeWindowDays int `json:"strike_window_days"`
+	// 违规次数达到该值时把成员踢出群聊，0表示不踢人
+	KickStrikes int `json:"kick_strikes"`
+	// 违规时撤回机器人两分钟内回复给违规成员的消息
+	RecallReplies bool `json:"recall_replies"`
+}
+
+// ModerationLog 内容审核记录，每次违规一条，用于统计违规次数和审计
+type ModerationLog struct {
+	ID         int64              `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ChatRoomID string             `gorm:"type:varchar(64);not null;index:idx_chat_room_member,priority:1;column:chat_room_id;comment:群聊ID" json:"chat_room_id"`
+	MemberWxID string             `gorm:"type:varchar(64);not null;index:idx_chat_room_member,priority:2;column:member_wxid;comment:违规成员微信ID" json:"member_wxid"`
+	MessageID  int64              `gorm:"not null;default:0;column:message_id;comment:违规消息ID" json:"message_id"`
+	Content    string             `gorm:"type:varchar(500);not null;default:'';column:content;comment:违规消息内容，超过500字时截断" json:"content"`
+	Category   ModerationCategory `gorm:"type:varchar(20);not null;default:'';column:category;comment:违规类别" json:"category"`
+	Source     string             `gorm:"type:varchar(20);not null;default:'';column:source;comment:审核方式：rule-关键词和正则，flood-刷屏，ai-AI审核" json:"source"`
+	Reason     string             `gorm:"type:varchar(255);not null;default:'';column:reason;comment:违规原因" json:"reason"`
+	Strikes    int                `gorm:"not null;default:0;column:strikes;comment:包括本次在内的违规次数" json:"strikes"`
+	Action     ModerationAction   `gorm:"type:varchar(20);not null;default:'';column:action;comment:处理方式：warn-警告，kick-踢出群聊" json:"action"`
+	Recalled   int                `gorm:"not null;default:0;column:recalled;comment:撤回的机器人消息数量" json:"recalled"`
+	CreatedAt  int64              `gorm:"autoCreateTime;not null;index:idx_chat_room_member,priority:3;column:created_at" json:"created_at"`
+}
+
+func (ModerationLog) TableName() string {
+	return "moderation_logs"
+}
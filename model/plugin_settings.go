@@ -0,0 +1,19 @@
+package model
+
+import "gorm.io/datatypes"
+
+// PluginSettings 插件配置，ContactID 为空表示全局默认配置，否则为群聊ID或者好友微信ID
+type PluginSettings struct {
+	ID         uint64         `gorm:"column:id;primaryKey;autoIncrement;comment:插件配置表主键ID" json:"id"`
+	PluginName string         `gorm:"column:plugin_name;type:varchar(64);not null;uniqueIndex:uk_plugin_contact;comment:插件名称" json:"plugin_name"`
+	ContactID  string         `gorm:"column:contact_id;type:varchar(64);not null;default:'';uniqueIndex:uk_plugin_contact;comment:群聊ID或者好友微信ID，为空表示全局配置" json:"contact_id"`
+	Enabled    *bool          `gorm:"column:enabled;comment:是否启用插件，为空表示继承全局配置" json:"enabled"`
+	Config     datatypes.JSON `gorm:"column:config;type:json;comment:插件配置，群聊/好友配置会按字段覆盖全局配置" json:"config"`
+	CreatedAt  int64          `gorm:"column:created_at;autoCreateTime;comment:创建时间" json:"created_at"`
+	UpdatedAt  int64          `gorm:"column:updated_at;autoUpdateTime;comment:更新时间" json:"updated_at"`
+}
+
+// TableName 设置表名
+func (PluginSettings) TableName() string {
+	return "plugin_settings"
+}
@@ -0,0 +1,25 @@
+package model
+
+type RateLimitFeature string
+
+const (
+	RateLimitFeatureChat    RateLimitFeature = "chat"    // AI聊天、图片识别
+	RateLimitFeatureDrawing RateLimitFeature = "drawing" // AI绘图、图片编辑
+	RateLimitFeatureTTS     RateLimitFeature = "tts"     // 文本转语音、长文本转语音
+)
+
+type RateLimitScope string
+
+const (
+	RateLimitScopeSender RateLimitScope = "sender" // 同一个聊天中的每个发送者单独计数
+	RateLimitScopeChat   RateLimitScope = "chat"   // 整个群聊/好友共用一个计数
+)
+
+// RateLimitRule 令牌桶限流规则，每 Period 秒最多使用 Limit 次，令牌按时间匀速恢复
+// 保存在全局配置、群聊配置、好友配置的 rate_limits 字段中，群聊/好友配置了某个功能的规则时，该功能不再使用全局规则
+type RateLimitRule struct {
+	Feature RateLimitFeature `json:"feature"`
+	Scope   RateLimitScope   `json:"scope"`
+	Limit   int              `json:"limit"`
+	Period  int              `json:"period"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import "gorm.io/datatypes"
+
+// ScriptPlugin Lua 脚本插件，脚本需要定义 run(msg) 函数，返回 true 表示中止插件链
+type ScriptPlugin struct {
+	ID           uint64         `gorm:"column:id;primaryKey;autoIncrement;comment:表主键ID" json:"id"`
+	Name         string         `gorm:"column:name;type:varchar(64);not null;uniqueIndex:uk_name;comment:插件名称，不能和其他插件重名" json:"name"`
+	Description  string         `gorm:"column:description;type:varchar(255);not null;default:'';comment:插件描述" json:"description"`
+	ContactID    string         `gorm:"column:contact_id;type:varchar(64);not null;default:'';comment:群聊ID或者好友微信ID，为空表示所有聊天都执行" json:"contact_id"`
+	Labels       datatypes.JSON `gorm:"column:labels;type:json;comment:插件标签，例如[\"text\"]" json:"labels"`
+	Script       string         `gorm:"column:script;type:text;not null;comment:Lua脚本" json:"script"`
+	AllowedHosts datatypes.JSON `gorm:"column:allowed_hosts;type:json;comment:脚本允许访问的域名白名单" json:"allowed_hosts"`
+	Priority     int            `gorm:"column:priority;default:300;not null;comment:插件优先级，数值越小越先执行" json:"priority"`
+	Timeout      int            `gorm:"column:timeout;default:3;not null;comment:单次执行超时时间，单位秒" json:"timeout"`
+	Enabled      *bool          `gorm:"column:enabled;default:true;comment:是否启用" json:"enabled"`
+	CreatedAt    int64          `gorm:"column:created_at;autoCreateTime;not null;comment:创建时间" json:"created_at"`
+	UpdatedAt    int64          `gorm:"column:updated_at;autoUpdateTime;not null;comment:更新时间" json:"updated_at"`
+}
+
+// TableName 设置表名
+func (ScriptPlugin) TableName() string {
+	return "script_plugins"
+}
+
+// GetID 返回主键ID
+func (p *ScriptPlugin) GetID() uint64 {
+	return p.ID
+}
+
+// GetName 返回插件名称
+func (p *ScriptPlugin) GetName() string {
+	return p.Name
+}
+
+// GetPriority 返回插件优先级
+func (p *ScriptPlugin) GetPriority() int {
+	return p.Priority
+}
+
+// IsEnabled 未设置时默认启用
+func (p *ScriptPlugin) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
@@ -1,5 +1,7 @@
 package model
 
+import "gorm.io/datatypes"
+
 type NotificationType string
 
 const (
@@ -17,6 +19,7 @@ type SystemSettings struct {
 	AutoVerifyUser             *bool            `gorm:"column:auto_verify_user;default:false;comment:自动通过好友验证" json:"auto_verify_user"`
 	VerifyUserDelay            *int             `gorm:"column:verify_user_delay;default:60;comment:自动通过好友验证延迟时间(秒)" json:"verify_user_delay"`
 	AutoChatroomInvite         *bool            `gorm:"column:auto_chatroom_invite;default:false;comment:自动邀请进群" json:"auto_chatroom_invite"`
+	SuperAdmins                datatypes.JSON   `gorm:"column:super_admins;type:json;comment:超级管理员微信ID列表" json:"super_admins"`
 	CreatedAt                  int64            `gorm:"column:created_at;autoCreateTime;comment:创建时间" json:"created_at"`
 	UpdatedAt                  int64            `gorm:"column:updated_at;autoUpdateTime;comment:更新时间" json:"updated_at"`
 }
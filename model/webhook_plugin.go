@@ -0,0 +1,53 @@
+package model
+
+import "gorm.io/datatypes"
+
+type WebhookTriggerType string
+
+const (
+	WebhookTriggerKeyword     WebhookTriggerType = "keyword"      // 消息内容包含任意一个关键词，多个关键词用英文逗号分隔
+	WebhookTriggerRegex       WebhookTriggerType = "regex"        // 消息内容匹配正则表达式
+	WebhookTriggerMessageType WebhookTriggerType = "message_type" // 消息类型，多个类型用英文逗号分隔
+)
+
+// WebhookPlugin 外部 HTTP 插件，消息命中触发条件后推送到外部服务，由外部服务返回需要执行的动作
+type WebhookPlugin struct {
+	ID          uint64             `gorm:"column:id;primaryKey;autoIncrement;comment:表主键ID" json:"id"`
+	Name        string             `gorm:"column:name;type:varchar(64);not null;uniqueIndex:uk_name;comment:插件名称，不能和内置插件重名" json:"name"`
+	URL         string             `gorm:"column:url;type:varchar(512);not null;comment:回调地址" json:"url"`
+	Secret      string             `gorm:"column:secret;type:varchar(128);not null;default:'';comment:签名密钥" json:"secret"`
+	Labels      datatypes.JSON     `gorm:"column:labels;type:json;comment:插件标签，例如[\"text\"]" json:"labels"`
+	TriggerType WebhookTriggerType `gorm:"column:trigger_type;type:enum('keyword','regex','message_type');default:'keyword';not null;comment:触发方式" json:"trigger_type"`
+	TriggerRule string             `gorm:"column:trigger_rule;type:varchar(1024);not null;default:'';comment:触发条件，为空表示匹配所有消息" json:"trigger_rule"`
+	Priority    int                `gorm:"column:priority;default:300;not null;comment:插件优先级，数值越小越先执行" json:"priority"`
+	Timeout     int                `gorm:"column:timeout;default:5;not null;comment:单次请求超时时间，单位秒" json:"timeout"`
+	MaxRetries  int                `gorm:"column:max_retries;default:2;not null;comment:请求失败后的最大重试次数" json:"max_retries"`
+	Enabled     *bool              `gorm:"column:enabled;default:true;comment:是否启用" json:"enabled"`
+	CreatedAt   int64              `gorm:"column:created_at;autoCreateTime;not null;comment:创建时间" json:"created_at"`
+	UpdatedAt   int64              `gorm:"column:updated_at;autoUpdateTime;not null;comment:更新时间" json:"updated_at"`
+}
+
+// TableName 设置表名
+func (WebhookPlugin) TableName() string {
+	return "webhook_plugins"
+}
+
+// GetID 返回主键ID
+func (p *WebhookPlugin) GetID() uint64 {
+	return p.ID
+}
+
+// GetName 返回插件名称
+func (p *WebhookPlugin) GetName() string {
+	return p.Name
+}
+
+// GetPriority 返回插件优先级
+func (p *WebhookPlugin) GetPriority() int {
+	return p.Priority
+}
+
+// IsEnabled 未设置时默认启用
+func (p *WebhookPlugin) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
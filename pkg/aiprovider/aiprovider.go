@@ -0,0 +1,145 @@
+package aiprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"wechat-robot-client/model"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Provider AI服务，请求和返回统一使用 OpenAI 接口的格式，由各个实现转换成对应服务的原生接口
+type Provider interface {
+	Name() string
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+	CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (Stream, error)
+}
+
+// Stream 流式返回，Recv 返回 io.EOF 表示结束
+type Stream interface {
+	Recv() (openai.ChatCompletionStreamResponse, error)
+	Close() error
+}
+
+// APIError AI服务返回的错误
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s 返回错误, 状态码: %d, 错误信息: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+var httpClient = &http.Client{Timeout: 5 * time.Minute}
+
+var versionSuffix = regexp.MustCompile(`/(v\d+(beta\d*)?|api)$`)
+
+// New 创建AI服务，baseURL 为空时使用官方地址
+func New(providerType model.AIProviderType, baseURL, apiKey string) (Provider, error) {
+	// 去掉地址末尾的版本号，原生接口的实现自己拼接完整的地址
+	nativeBaseURL := versionSuffix.ReplaceAllString(strings.TrimRight(baseURL, "/"), "")
+	switch providerType {
+	case model.AIProviderOpenAI, "":
+		return newOpenAIProvider(baseURL, apiKey), nil
+	case model.AIProviderGemini:
+		return newGeminiProvider(nativeBaseURL, apiKey), nil
+	case model.AIProviderAnthropic:
+		return newAnthropicProvider(nativeBaseURL, apiKey), nil
+	case model.AIProviderOllama:
+		return newOllamaProvider(nativeBaseURL, apiKey), nil
+	}
+	return nil, fmt.Errorf("不支持的AI服务类型: %s", providerType)
+}
+
+// IsRetryable 判断错误是否应该换一个AI服务重试：限流(429)、服务端错误(5xx)和网络错误
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.StatusCode)
+	}
+	var openaiErr *openai.APIError
+	if errors.As(err, &openaiErr) {
+		return retryableStatus(openaiErr.HTTPStatusCode)
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		return retryableStatus(requestErr.HTTPStatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// Target 备用链中的一个AI服务，Model 不为空时替换请求中的模型
+type Target struct {
+	Provider Provider
+	Model    string
+}
+
+type fallback struct {
+	targets []Target
+}
+
+// NewFallback 按顺序使用多个AI服务，前一个返回可以重试的错误时使用下一个
+// 流式请求只在建立连接失败时切换，已经开始返回内容后出错不再切换
+func NewFallback(targets ...Target) Provider {
+	return &fallback{targets: targets}
+}
+
+func (f *fallback) Name() string {
+	if len(f.targets) == 0 {
+		return ""
+	}
+	return f.targets[0].Provider.Name()
+}
+
+func (f *fallback) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (resp openai.ChatCompletionResponse, err error) {
+	err = f.each(req, func(target Target, req openai.ChatCompletionRequest) error {
+		resp, err = target.Provider.CreateChatCompletion(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (f *fallback) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (stream Stream, err error) {
+	err = f.each(req, func(target Target, req openai.ChatCompletionRequest) error {
+		stream, err = target.Provider.CreateChatCompletionStream(ctx, req)
+		return err
+	})
+	return stream, err
+}
+
+func (f *fallback) each(req openai.ChatCompletionRequest, call func(target Target, req openai.ChatCompletionRequest) error) error {
+	if len(f.targets) == 0 {
+		return errors.New("没有可用的AI服务")
+	}
+	var err error
+	for i, target := range f.targets {
+		targetReq := req
+		if target.Model != "" {
+			targetReq.Model = target.Model
+		}
+		err = call(target, targetReq)
+		if err == nil || !IsRetryable(err) || i == len(f.targets)-1 {
+			return err
+		}
+		next := f.targets[i+1]
+		log.Printf("AI服务 %s(%s) 调用失败，切换到备用AI服务 %s: %v", target.Provider.Name(), targetReq.Model, next.Provider.Name(), err)
+	}
+	return err
+}
@@ -0,0 +1,208 @@
+package aiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"wechat-robot-client/model"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+type fakeProvider struct {
+	name  string
+	err   error
+	model string
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	p.model = req.Model
+	if p.err != nil {
+		return openai.ChatCompletionResponse{}, p.err
+	}
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: p.name}}}}, nil
+}
+
+func (p *fakeProvider) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (Stream, error) {
+	return nil, p.err
+}
+
+func TestFallback(t *testing.T) {
+	limited := &fakeProvider{name: "a", err: &APIError{Provider: "a", StatusCode: http.StatusTooManyRequests}}
+	backup := &fakeProvider{name: "b"}
+	provider := NewFallback(Target{Provider: limited, Model: "model-a"}, Target{Provider: backup})
+	resp, err := provider.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Message.Content != "b" || limited.model != "model-a" || backup.model != "default" {
+		t.Fatalf("unexpected fallback: content=%s models=%s,%s", resp.Choices[0].Message.Content, limited.model, backup.model)
+	}
+
+	// 参数错误等不能重试的错误直接返回
+	invalid := &fakeProvider{name: "c", err: &APIError{Provider: "c", StatusCode: http.StatusBadRequest}}
+	backup.model = ""
+	_, err = NewFallback(Target{Provider: invalid}, Target{Provider: backup}).CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{})
+	if err == nil || backup.model != "" {
+		t.Fatalf("non-retryable error should not fall back, err=%v", err)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&APIError{StatusCode: 429}, true},
+		{&APIError{StatusCode: 503}, true},
+		{&APIError{StatusCode: 401}, false},
+		{&openai.APIError{HTTPStatusCode: 500}, true},
+		{&openai.RequestError{HTTPStatusCode: 404}, false},
+		{context.Canceled, false},
+		{errors.New("other"), false},
+	}
+	for _, c := range cases {
+		if got := IsRetryable(c.err); got != c.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+// collect 读取流式返回，合并文本和工具调用名称
+func collect(t *testing.T, stream Stream) (string, []string) {
+	t.Helper()
+	defer stream.Close()
+	var content strings.Builder
+	var tools []string
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return content.String(), tools
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, choice := range resp.Choices {
+			content.WriteString(choice.Delta.Content)
+			for _, call := range choice.Delta.ToolCalls {
+				if call.Function.Name != "" {
+					tools = append(tools, call.Function.Name)
+				}
+			}
+		}
+	}
+}
+
+var testMessages = []openai.ChatCompletionMessage{
+	{Role: openai.ChatMessageRoleSystem, Content: "你是机器人"},
+	{Role: openai.ChatMessageRoleUser, Content: "北京天气"},
+	{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{{ID: "1", Function: openai.FunctionCall{Name: "get_weather", Arguments: `{"city":"北京"}`}}}},
+	{Role: openai.ChatMessageRoleTool, ToolCallID: "1", Content: "晴"},
+}
+
+func TestNativeProviders(t *testing.T) {
+	cases := []struct {
+		providerType model.AIProviderType
+		path         string
+		// 检查请求体
+		check    func(body map[string]any) bool
+		response string
+		stream   string
+	}{
+		{
+			providerType: model.AIProviderAnthropic,
+			path:         "/v1/messages",
+			check: func(body map[string]any) bool {
+				return body["system"] == "你是机器人" && len(body["messages"].([]any)) == 3
+			},
+			response: `{"id":"msg","content":[{"type":"text","text":"晴天"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":2}}`,
+			stream: "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg\"}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"晴\"}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"天\"}}\n\n" +
+				"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"t\",\"name\":\"get_weather\"}}\n\n" +
+				"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+		},
+		{
+			providerType: model.AIProviderGemini,
+			path:         "/v1beta/models/test-model:",
+			check: func(body map[string]any) bool {
+				contents := body["contents"].([]any)
+				return body["systemInstruction"] != nil && len(contents) == 3 && contents[1].(map[string]any)["role"] == "model"
+			},
+			response: `{"candidates":[{"content":{"parts":[{"text":"思考","thought":true},{"text":"晴天"}]},"finishReason":"STOP"}]}`,
+			stream: "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"晴\"}]}}]}\n\n" +
+				"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"天\"},{\"functionCall\":{\"name\":\"get_weather\",\"args\":{}}}]},\"finishReason\":\"STOP\"}]}\n\n",
+		},
+		{
+			providerType: model.AIProviderOllama,
+			path:         "/api/chat",
+			check: func(body map[string]any) bool {
+				messages := body["messages"].([]any)
+				return len(messages) == 4 && messages[3].(map[string]any)["tool_name"] == "get_weather"
+			},
+			response: `{"message":{"role":"assistant","content":"晴天"},"done":true,"done_reason":"stop"}`,
+			stream: "{\"message\":{\"content\":\"晴\"},\"done\":false}\n" +
+				"{\"message\":{\"content\":\"天\",\"tool_calls\":[{\"function\":{\"name\":\"get_weather\",\"arguments\":{}}}]},\"done\":false}\n" +
+				"{\"message\":{\"content\":\"\"},\"done\":true}\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(string(c.providerType), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !strings.HasPrefix(r.URL.Path, c.path) || !c.check(body) {
+					http.Error(w, "bad request", http.StatusBadRequest)
+					return
+				}
+				if body["stream"] == true || strings.Contains(r.URL.Path, "stream") {
+					io.WriteString(w, c.stream)
+					return
+				}
+				io.WriteString(w, c.response)
+			}))
+			defer server.Close()
+
+			// 地址末尾带版本号时也能正确拼接
+			provider, err := New(c.providerType, server.URL+"/v1", "key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := openai.ChatCompletionRequest{Model: "test-model", Messages: testMessages}
+			resp, err := provider.CreateChatCompletion(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Choices[0].Message.Content != "晴天" {
+				t.Fatalf("content = %q", resp.Choices[0].Message.Content)
+			}
+			stream, err := provider.CreateChatCompletionStream(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, tools := collect(t, stream)
+			if content != "晴天" || len(tools) != 1 || tools[0] != "get_weather" {
+				t.Fatalf("stream content = %q, tools = %v", content, tools)
+			}
+		})
+	}
+
+	// 非 2xx 返回转换成 APIError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	provider, _ := New(model.AIProviderAnthropic, server.URL, "key")
+	_, err := provider.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Messages: testMessages})
+	if !IsRetryable(err) {
+		t.Fatalf("err = %v, want retryable", err)
+	}
+}
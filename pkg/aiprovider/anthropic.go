@@ -0,0 +1,354 @@
+package aiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	anthropicDefaultBaseURL = "https://api.anthropic.com"
+	anthropicVersion        = "2023-06-01"
+	// Anthropic 接口要求必须设置最大输出长度
+	anthropicDefaultMaxTokens = 4096
+)
+
+// anthropicProvider Anthropic 原生接口 /v1/messages
+type anthropicProvider struct {
+	baseURL string
+	apiKey  string
+}
+
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	MaxTokens     int                `json:"max_tokens"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+	Temperature   *float32           `json:"temperature,omitempty"`
+	TopP          *float32           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   string                `json:"content,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+func newAnthropicProvider(baseURL, apiKey string) *anthropicProvider {
+	if baseURL == "" {
+		baseURL = anthropicDefaultBaseURL
+	}
+	return &anthropicProvider{baseURL: baseURL, apiKey: apiKey}
+}
+
+func (p *anthropicProvider) Name() string {
+	return "anthropic"
+}
+
+func (p *anthropicProvider) post(ctx context.Context, req openai.ChatCompletionRequest, stream bool) (*http.Response, error) {
+	body, err := p.convertRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	body.Stream = stream
+	return postJSON(ctx, p.Name(), p.baseURL+"/v1/messages", map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}, body)
+}
+
+func (p *anthropicProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	resp, err := p.post(ctx, req, false)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	var result anthropicResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("解析 Anthropic 返回失败: %w", err)
+	}
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	var texts []string
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			texts = append(texts, block.Text)
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:       block.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+	message.Content = strings.Join(texts, "")
+	if _, ok := responseSchema(req); ok {
+		message.Content = trimJSONFence(message.Content)
+	}
+	return openai.ChatCompletionResponse{
+		ID:    result.ID,
+		Model: result.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      message,
+			FinishReason: anthropicFinishReason(result.StopReason),
+		}},
+		Usage: openai.Usage{
+			PromptTokens:     result.Usage.InputTokens,
+			CompletionTokens: result.Usage.OutputTokens,
+			TotalTokens:      result.Usage.InputTokens + result.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+func (p *anthropicProvider) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (Stream, error) {
+	resp, err := p.post(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	return &anthropicStream{body: resp.Body, reader: newSSEReader(resp.Body), toolIndex: make(map[int]int)}, nil
+}
+
+func (p *anthropicProvider) convertRequest(req openai.ChatCompletionRequest) (*anthropicRequest, error) {
+	body := &anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     maxTokens(req),
+		StopSequences: req.Stop,
+	}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = anthropicDefaultMaxTokens
+	}
+	if req.Temperature != 0 {
+		body.Temperature = &req.Temperature
+	}
+	if req.TopP != 0 {
+		body.TopP = &req.TopP
+	}
+	var systems []string
+	for _, msg := range req.Messages {
+		text, images := messageContent(msg)
+		var role string
+		var blocks []anthropicBlock
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			if text != "" {
+				systems = append(systems, text)
+			}
+			continue
+		case openai.ChatMessageRoleTool:
+			role = "user"
+			blocks = append(blocks, anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: text})
+		case openai.ChatMessageRoleAssistant:
+			role = "assistant"
+			if text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: text})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: toolArguments(call.Function.Arguments)})
+			}
+		default:
+			role = "user"
+			for _, image := range images {
+				blocks = append(blocks, anthropicBlock{Type: "image", Source: anthropicImage(image)})
+			}
+			if text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: text})
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		// 连续的同一角色的消息合并成一条，工具调用的多个结果也需要放在同一条消息中
+		if last := len(body.Messages) - 1; last >= 0 && body.Messages[last].Role == role {
+			body.Messages[last].Content = append(body.Messages[last].Content, blocks...)
+			continue
+		}
+		body.Messages = append(body.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	// Anthropic 接口不支持指定返回格式，通过系统提示词要求返回 JSON
+	if schema, ok := responseSchema(req); ok {
+		instruction := "请只返回 JSON，不要返回其他内容。"
+		if schema != nil {
+			instruction = fmt.Sprintf("请只返回符合以下 JSON Schema 的 JSON，不要返回其他内容：\n%s", schema)
+		}
+		systems = append(systems, instruction)
+	}
+	body.System = strings.Join(systems, "\n\n")
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		body.Tools = append(body.Tools, anthropicTool{Name: tool.Function.Name, Description: tool.Function.Description, InputSchema: schema})
+	}
+	return body, nil
+}
+
+func anthropicImage(url string) *anthropicImageSource {
+	if strings.HasPrefix(url, "data:") {
+		header, data, _ := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		return &anthropicImageSource{Type: "base64", MediaType: strings.TrimSuffix(header, ";base64"), Data: data}
+	}
+	return &anthropicImageSource{Type: "url", URL: url}
+}
+
+func anthropicFinishReason(stopReason string) openai.FinishReason {
+	switch stopReason {
+	case "tool_use":
+		return openai.FinishReasonToolCalls
+	case "max_tokens":
+		return openai.FinishReasonLength
+	case "refusal":
+		return openai.FinishReasonContentFilter
+	}
+	return openai.FinishReasonStop
+}
+
+// anthropicStream 把 Anthropic 的流式事件转换成 OpenAI 格式的片段
+type anthropicStream struct {
+	body   io.ReadCloser
+	reader *sseReader
+	id     string
+	model  string
+	usage  anthropicUsage
+	// 内容块序号对应的工具调用序号
+	toolIndex map[int]int
+}
+
+type anthropicEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		ID    string         `json:"id"`
+		Model string         `json:"model"`
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock anthropicBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (s *anthropicStream) Recv() (openai.ChatCompletionStreamResponse, error) {
+	for {
+		_, data, err := s.reader.next()
+		if err != nil {
+			return openai.ChatCompletionStreamResponse{}, err
+		}
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return openai.ChatCompletionStreamResponse{}, fmt.Errorf("解析 Anthropic 流式返回失败: %w", err)
+		}
+		var delta openai.ChatCompletionStreamChoiceDelta
+		var finishReason openai.FinishReason
+		var usage *openai.Usage
+		switch event.Type {
+		case "message_start":
+			s.id, s.model, s.usage = event.Message.ID, event.Message.Model, event.Message.Usage
+			continue
+		case "content_block_start":
+			if event.ContentBlock.Type != "tool_use" {
+				continue
+			}
+			index := len(s.toolIndex)
+			s.toolIndex[event.Index] = index
+			delta.ToolCalls = []openai.ToolCall{{
+				Index:    &index,
+				ID:       event.ContentBlock.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: event.ContentBlock.Name},
+			}}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				delta.Content = event.Delta.Text
+			case "input_json_delta":
+				index, ok := s.toolIndex[event.Index]
+				if !ok {
+					continue
+				}
+				delta.ToolCalls = []openai.ToolCall{{Index: &index, Function: openai.FunctionCall{Arguments: event.Delta.PartialJSON}}}
+			default:
+				continue
+			}
+		case "message_delta":
+			finishReason = anthropicFinishReason(event.Delta.StopReason)
+			s.usage.OutputTokens = event.Usage.OutputTokens
+			usage = &openai.Usage{
+				PromptTokens:     s.usage.InputTokens,
+				CompletionTokens: s.usage.OutputTokens,
+				TotalTokens:      s.usage.InputTokens + s.usage.OutputTokens,
+			}
+		case "message_stop":
+			return openai.ChatCompletionStreamResponse{}, io.EOF
+		case "error":
+			return openai.ChatCompletionStreamResponse{}, &APIError{Provider: "anthropic", StatusCode: http.StatusInternalServerError, Message: event.Error.Message}
+		default:
+			continue
+		}
+		delta.Role = openai.ChatMessageRoleAssistant
+		return openai.ChatCompletionStreamResponse{
+			ID:      s.id,
+			Model:   s.model,
+			Choices: []openai.ChatCompletionStreamChoice{{Delta: delta, FinishReason: finishReason}},
+			Usage:   usage,
+		}, nil
+	}
+}
+
+func (s *anthropicStream) Close() error {
+	return s.body.Close()
+}
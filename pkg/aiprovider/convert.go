@@ -0,0 +1,195 @@
+package aiprovider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 下载图片的大小限制
+const maxImageSize = 20 << 20
+
+// postJSON 发送 JSON 请求，非 2xx 的返回转换成 APIError
+func postJSON(ctx context.Context, provider, url string, headers map[string]string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &APIError{Provider: provider, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	return resp, nil
+}
+
+// decodeJSON 读取非流式返回
+func decodeJSON(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// messageContent 返回消息的文本和图片地址
+func messageContent(msg openai.ChatCompletionMessage) (text string, images []string) {
+	if len(msg.MultiContent) == 0 {
+		return msg.Content, nil
+	}
+	var texts []string
+	for _, part := range msg.MultiContent {
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL != nil && part.ImageURL.URL != "" {
+				images = append(images, part.ImageURL.URL)
+			}
+		}
+	}
+	return strings.Join(texts, "\n"), images
+}
+
+// loadImage 读取图片，返回 MIME 类型和 base64 编码的内容，支持 data URL 和 http 地址
+func loadImage(ctx context.Context, url string) (mimeType, data string, err error) {
+	if strings.HasPrefix(url, "data:") {
+		header, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return "", "", fmt.Errorf("不支持的图片地址: %.32s", url)
+		}
+		return strings.TrimSuffix(header, ";base64"), data, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("下载图片失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("下载图片失败, 状态码: %d", resp.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize))
+	if err != nil {
+		return "", "", fmt.Errorf("下载图片失败: %w", err)
+	}
+	mimeType = resp.Header.Get("Content-Type")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(content)
+	}
+	return mimeType, base64.StdEncoding.EncodeToString(content), nil
+}
+
+// maxTokens 返回请求中的最大输出长度，没有设置时返回 0
+func maxTokens(req openai.ChatCompletionRequest) int {
+	if req.MaxCompletionTokens > 0 {
+		return req.MaxCompletionTokens
+	}
+	return req.MaxTokens
+}
+
+// responseSchema 返回请求要求的 JSON Schema，没有要求返回 JSON 时 ok 为 false
+func responseSchema(req openai.ChatCompletionRequest) (schema json.RawMessage, ok bool) {
+	format := req.ResponseFormat
+	if format == nil {
+		return nil, false
+	}
+	switch format.Type {
+	case openai.ChatCompletionResponseFormatTypeJSONSchema:
+		if format.JSONSchema == nil || format.JSONSchema.Schema == nil {
+			return nil, true
+		}
+		data, err := json.Marshal(format.JSONSchema.Schema)
+		if err != nil {
+			return nil, true
+		}
+		return data, true
+	case openai.ChatCompletionResponseFormatTypeJSONObject:
+		return nil, true
+	}
+	return nil, false
+}
+
+// trimJSONFence 去掉模型在 JSON 外面包的 ```json 代码块
+func trimJSONFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimPrefix(content, "json")
+	content = strings.TrimSuffix(content, "```")
+	return strings.TrimSpace(content)
+}
+
+// toolArguments 工具调用参数，空参数转换成空对象
+func toolArguments(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" || !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// toolCallNames 工具调用 ID 对应的工具名称，工具结果消息没有带名称时使用
+func toolCallNames(messages []openai.ChatCompletionMessage) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			names[call.ID] = call.Function.Name
+		}
+	}
+	return names
+}
+
+// sseReader 读取 Server-Sent Events
+type sseReader struct {
+	reader *bufio.Reader
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{reader: bufio.NewReader(r)}
+}
+
+// next 返回下一个事件的名称和数据
+func (r *sseReader) next() (event string, data string, err error) {
+	var lines []string
+	for {
+		line, readErr := r.reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// 空行表示一个事件结束
+		if (line == "" || readErr != nil) && len(lines) > 0 {
+			return event, strings.Join(lines, "\n"), nil
+		}
+		if readErr != nil {
+			return "", "", readErr
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package aiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const geminiDefaultBaseURL = "https://generativelanguage.googleapis.com"
+
+// geminiProvider Gemini 原生接口 generateContent
+type geminiProvider struct {
+	baseURL string
+	apiKey  string
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name                 string `json:"name"`
+	Description          string `json:"description,omitempty"`
+	ParametersJSONSchema any    `json:"parametersJsonSchema,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens    int             `json:"maxOutputTokens,omitempty"`
+	Temperature        *float32        `json:"temperature,omitempty"`
+	TopP               *float32        `json:"topP,omitempty"`
+	PresencePenalty    *float32        `json:"presencePenalty,omitempty"`
+	StopSequences      []string        `json:"stopSequences,omitempty"`
+	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+	ResponseID   string `json:"responseId"`
+}
+
+func newGeminiProvider(baseURL, apiKey string) *geminiProvider {
+	if baseURL == "" {
+		baseURL = geminiDefaultBaseURL
+	}
+	return &geminiProvider{baseURL: baseURL, apiKey: apiKey}
+}
+
+func (p *geminiProvider) Name() string {
+	return "gemini"
+}
+
+func (p *geminiProvider) post(ctx context.Context, req openai.ChatCompletionRequest, method string) (*http.Response, error) {
+	body, err := p.convertRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:%s", p.baseURL, url.PathEscape(strings.TrimPrefix(req.Model, "models/")), method)
+	return postJSON(ctx, p.Name(), endpoint, map[string]string{"x-goog-api-key": p.apiKey}, body)
+}
+
+func (p *geminiProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	resp, err := p.post(ctx, req, "generateContent")
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	var result geminiResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("解析 Gemini 返回失败: %w", err)
+	}
+	counter := 0
+	response := openai.ChatCompletionResponse{ID: result.ResponseID, Model: result.ModelVersion}
+	for _, candidate := range result.Candidates {
+		text, toolCalls := geminiParts(candidate.Content.Parts, &counter)
+		response.Choices = append(response.Choices, openai.ChatCompletionChoice{
+			Index:        len(response.Choices),
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: text, ToolCalls: toolCalls},
+			FinishReason: geminiFinishReason(candidate.FinishReason, len(toolCalls) > 0),
+		})
+	}
+	if usage := geminiUsage(&result); usage != nil {
+		response.Usage = *usage
+	}
+	return response, nil
+}
+
+func (p *geminiProvider) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (Stream, error) {
+	resp, err := p.post(ctx, req, "streamGenerateContent?alt=sse")
+	if err != nil {
+		return nil, err
+	}
+	return &geminiStream{body: resp.Body, reader: newSSEReader(resp.Body)}, nil
+}
+
+func (p *geminiProvider) convertRequest(ctx context.Context, req openai.ChatCompletionRequest) (*geminiRequest, error) {
+	body := &geminiRequest{}
+	names := toolCallNames(req.Messages)
+	var systems []geminiPart
+	for _, msg := range req.Messages {
+		text, images := messageContent(msg)
+		var role string
+		var parts []geminiPart
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			if text != "" {
+				systems = append(systems, geminiPart{Text: text})
+			}
+			continue
+		case openai.ChatMessageRoleTool:
+			role = "user"
+			name := msg.Name
+			if name == "" {
+				name = names[msg.ToolCallID]
+			}
+			parts = append(parts, geminiPart{FunctionResponse: &geminiFunctionResponse{Name: name, Response: map[string]any{"result": text}}})
+		case openai.ChatMessageRoleAssistant:
+			role = "model"
+			if text != "" {
+				parts = append(parts, geminiPart{Text: text})
+			}
+			for _, call := range msg.ToolCalls {
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: call.Function.Name, Args: toolArguments(call.Function.Arguments)}})
+			}
+		default:
+			role = "user"
+			for _, image := range images {
+				mimeType, data, err := loadImage(ctx, image)
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, geminiPart{InlineData: &geminiInlineData{MimeType: mimeType, Data: data}})
+			}
+			if text != "" {
+				parts = append(parts, geminiPart{Text: text})
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		if last := len(body.Contents) - 1; last >= 0 && body.Contents[last].Role == role {
+			body.Contents[last].Parts = append(body.Contents[last].Parts, parts...)
+			continue
+		}
+		body.Contents = append(body.Contents, geminiContent{Role: role, Parts: parts})
+	}
+	if len(systems) > 0 {
+		body.SystemInstruction = &geminiContent{Parts: systems}
+	}
+	var declarations []geminiFunctionDeclaration
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		declarations = append(declarations, geminiFunctionDeclaration{
+			Name:                 tool.Function.Name,
+			Description:          tool.Function.Description,
+			ParametersJSONSchema: tool.Function.Parameters,
+		})
+	}
+	if len(declarations) > 0 {
+		body.Tools = []geminiTool{{FunctionDeclarations: declarations}}
+	}
+	config := &geminiGenerationConfig{MaxOutputTokens: maxTokens(req), StopSequences: req.Stop}
+	if req.Temperature != 0 {
+		config.Temperature = &req.Temperature
+	}
+	if req.TopP != 0 {
+		config.TopP = &req.TopP
+	}
+	if req.PresencePenalty != 0 {
+		config.PresencePenalty = &req.PresencePenalty
+	}
+	if schema, ok := responseSchema(req); ok {
+		config.ResponseMimeType = "application/json"
+		config.ResponseJSONSchema = schema
+	}
+	body.GenerationConfig = config
+	return body, nil
+}
+
+// geminiParts 转换返回的内容，跳过思考过程，Gemini 的函数调用没有 ID，按顺序生成
+func geminiParts(parts []geminiPart, counter *int) (string, []openai.ToolCall) {
+	var text strings.Builder
+	var toolCalls []openai.ToolCall
+	for _, part := range parts {
+		if part.Thought {
+			continue
+		}
+		if part.FunctionCall != nil {
+			index := len(toolCalls)
+			toolCalls = append(toolCalls, openai.ToolCall{
+				Index:    &index,
+				ID:       fmt.Sprintf("call_%d", *counter),
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: part.FunctionCall.Name, Arguments: string(toolArguments(string(part.FunctionCall.Args)))},
+			})
+			*counter++
+			continue
+		}
+		text.WriteString(part.Text)
+	}
+	return text.String(), toolCalls
+}
+
+func geminiFinishReason(reason string, hasToolCalls bool) openai.FinishReason {
+	switch reason {
+	case "":
+		return ""
+	case "STOP":
+		if hasToolCalls {
+			return openai.FinishReasonToolCalls
+		}
+		return openai.FinishReasonStop
+	case "MAX_TOKENS":
+		return openai.FinishReasonLength
+	}
+	return openai.FinishReasonContentFilter
+}
+
+func geminiUsage(result *geminiResponse) *openai.Usage {
+	if result.UsageMetadata == nil {
+		return nil
+	}
+	return &openai.Usage{
+		PromptTokens:     result.UsageMetadata.PromptTokenCount,
+		CompletionTokens: result.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      result.UsageMetadata.TotalTokenCount,
+	}
+}
+
+// geminiStream 流式返回的每个事件都是一个完整的 generateContent 返回
+type geminiStream struct {
+	body    io.ReadCloser
+	reader  *sseReader
+	counter int
+	// 已经返回的工具调用数量，多个事件中的工具调用需要使用不同的序号
+	toolCalls int
+}
+
+func (s *geminiStream) Recv() (openai.ChatCompletionStreamResponse, error) {
+	_, data, err := s.reader.next()
+	if err != nil {
+		return openai.ChatCompletionStreamResponse{}, err
+	}
+	var result geminiResponse
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return openai.ChatCompletionStreamResponse{}, fmt.Errorf("解析 Gemini 流式返回失败: %w", err)
+	}
+	response := openai.ChatCompletionStreamResponse{ID: result.ResponseID, Model: result.ModelVersion, Usage: geminiUsage(&result)}
+	for _, candidate := range result.Candidates {
+		text, toolCalls := geminiParts(candidate.Content.Parts, &s.counter)
+		for i := range toolCalls {
+			index := s.toolCalls
+			toolCalls[i].Index = &index
+			s.toolCalls++
+		}
+		response.Choices = append(response.Choices, openai.ChatCompletionStreamChoice{
+			Index: len(response.Choices),
+			Delta: openai.ChatCompletionStreamChoiceDelta{
+				Role:      openai.ChatMessageRoleAssistant,
+				Content:   text,
+				ToolCalls: toolCalls,
+			},
+			FinishReason: geminiFinishReason(candidate.FinishReason, s.toolCalls > 0),
+		})
+	}
+	return response, nil
+}
+
+func (s *geminiStream) Close() error {
+	return s.body.Close()
+}
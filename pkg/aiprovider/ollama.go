@@ -0,0 +1,271 @@
+package aiprovider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const ollamaDefaultBaseURL = "http://localhost:11434"
+
+// ollamaProvider Ollama 原生接口 /api/chat
+type ollamaProvider struct {
+	baseURL string
+	apiKey  string
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openai.Tool   `json:"tools,omitempty"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  map[string]any  `json:"options,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func newOllamaProvider(baseURL, apiKey string) *ollamaProvider {
+	if baseURL == "" {
+		baseURL = ollamaDefaultBaseURL
+	}
+	return &ollamaProvider{baseURL: baseURL, apiKey: apiKey}
+}
+
+func (p *ollamaProvider) Name() string {
+	return "ollama"
+}
+
+func (p *ollamaProvider) post(ctx context.Context, req openai.ChatCompletionRequest, stream bool) (*http.Response, error) {
+	body, err := p.convertRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	body.Stream = stream
+	headers := map[string]string{}
+	// 本地部署的 Ollama 不需要鉴权，通过反向代理部署时可以配置密钥
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	return postJSON(ctx, p.Name(), p.baseURL+"/api/chat", headers, body)
+}
+
+func (p *ollamaProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	resp, err := p.post(ctx, req, false)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	var result ollamaResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("解析 Ollama 返回失败: %w", err)
+	}
+	if result.Error != "" {
+		return openai.ChatCompletionResponse{}, &APIError{Provider: p.Name(), StatusCode: http.StatusInternalServerError, Message: result.Error}
+	}
+	toolCalls := ollamaToolCalls(result.Message.ToolCalls, 0)
+	return openai.ChatCompletionResponse{
+		Model: result.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{
+				Role:             openai.ChatMessageRoleAssistant,
+				Content:          result.Message.Content,
+				ReasoningContent: result.Message.Thinking,
+				ToolCalls:        toolCalls,
+			},
+			FinishReason: ollamaFinishReason(result.DoneReason, len(toolCalls) > 0),
+		}},
+		Usage: *ollamaUsage(&result),
+	}, nil
+}
+
+func (p *ollamaProvider) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (Stream, error) {
+	resp, err := p.post(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	return &ollamaStream{body: resp.Body, reader: bufio.NewReader(resp.Body)}, nil
+}
+
+func (p *ollamaProvider) convertRequest(ctx context.Context, req openai.ChatCompletionRequest) (*ollamaRequest, error) {
+	body := &ollamaRequest{Model: req.Model}
+	names := toolCallNames(req.Messages)
+	for _, msg := range req.Messages {
+		text, images := messageContent(msg)
+		message := ollamaMessage{Role: msg.Role, Content: text}
+		switch msg.Role {
+		case openai.ChatMessageRoleDeveloper:
+			message.Role = openai.ChatMessageRoleSystem
+		case openai.ChatMessageRoleTool:
+			message.ToolName = msg.Name
+			if message.ToolName == "" {
+				message.ToolName = names[msg.ToolCallID]
+			}
+		}
+		for _, image := range images {
+			_, data, err := loadImage(ctx, image)
+			if err != nil {
+				return nil, err
+			}
+			message.Images = append(message.Images, data)
+		}
+		for _, call := range msg.ToolCalls {
+			var toolCall ollamaToolCall
+			toolCall.Function.Name = call.Function.Name
+			toolCall.Function.Arguments = toolArguments(call.Function.Arguments)
+			message.ToolCalls = append(message.ToolCalls, toolCall)
+		}
+		body.Messages = append(body.Messages, message)
+	}
+	for _, tool := range req.Tools {
+		if tool.Function != nil {
+			body.Tools = append(body.Tools, tool)
+		}
+	}
+	if schema, ok := responseSchema(req); ok {
+		if schema == nil {
+			schema = json.RawMessage(`"json"`)
+		}
+		body.Format = schema
+	}
+	options := map[string]any{}
+	if n := maxTokens(req); n > 0 {
+		options["num_predict"] = n
+	}
+	if req.Temperature != 0 {
+		options["temperature"] = req.Temperature
+	}
+	if req.TopP != 0 {
+		options["top_p"] = req.TopP
+	}
+	if req.PresencePenalty != 0 {
+		options["presence_penalty"] = req.PresencePenalty
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+	if len(options) > 0 {
+		body.Options = options
+	}
+	return body, nil
+}
+
+// ollamaToolCalls 转换工具调用，Ollama 的工具调用没有 ID，按顺序生成
+func ollamaToolCalls(calls []ollamaToolCall, offset int) []openai.ToolCall {
+	var toolCalls []openai.ToolCall
+	for i, call := range calls {
+		index := offset + i
+		toolCalls = append(toolCalls, openai.ToolCall{
+			Index:    &index,
+			ID:       fmt.Sprintf("call_%d", index),
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: call.Function.Name, Arguments: string(toolArguments(string(call.Function.Arguments)))},
+		})
+	}
+	return toolCalls
+}
+
+func ollamaFinishReason(reason string, hasToolCalls bool) openai.FinishReason {
+	switch {
+	case hasToolCalls:
+		return openai.FinishReasonToolCalls
+	case reason == "length":
+		return openai.FinishReasonLength
+	}
+	return openai.FinishReasonStop
+}
+
+func ollamaUsage(result *ollamaResponse) *openai.Usage {
+	return &openai.Usage{
+		PromptTokens:     result.PromptEvalCount,
+		CompletionTokens: result.EvalCount,
+		TotalTokens:      result.PromptEvalCount + result.EvalCount,
+	}
+}
+
+// ollamaStream 流式返回每行一个 JSON，最后一行 done 为 true
+type ollamaStream struct {
+	body      io.ReadCloser
+	reader    *bufio.Reader
+	toolCalls int
+	done      bool
+}
+
+func (s *ollamaStream) Recv() (openai.ChatCompletionStreamResponse, error) {
+	for {
+		if s.done {
+			return openai.ChatCompletionStreamResponse{}, io.EOF
+		}
+		line, err := s.reader.ReadString('\n')
+		if errors.Is(err, io.EOF) && strings.TrimSpace(line) != "" {
+			err = nil
+		}
+		if err != nil {
+			return openai.ChatCompletionStreamResponse{}, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var result ollamaResponse
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			return openai.ChatCompletionStreamResponse{}, fmt.Errorf("解析 Ollama 流式返回失败: %w", err)
+		}
+		if result.Error != "" {
+			return openai.ChatCompletionStreamResponse{}, &APIError{Provider: "ollama", StatusCode: http.StatusInternalServerError, Message: result.Error}
+		}
+		toolCalls := ollamaToolCalls(result.Message.ToolCalls, s.toolCalls)
+		s.toolCalls += len(toolCalls)
+		response := openai.ChatCompletionStreamResponse{
+			Model: result.Model,
+			Choices: []openai.ChatCompletionStreamChoice{{
+				Delta: openai.ChatCompletionStreamChoiceDelta{
+					Role:             openai.ChatMessageRoleAssistant,
+					Content:          result.Message.Content,
+					ReasoningContent: result.Message.Thinking,
+					ToolCalls:        toolCalls,
+				},
+			}},
+		}
+		if result.Done {
+			s.done = true
+			response.Choices[0].FinishReason = ollamaFinishReason(result.DoneReason, s.toolCalls > 0)
+			response.Usage = ollamaUsage(&result)
+		}
+		return response, nil
+	}
+}
+
+func (s *ollamaStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,34 @@
+package aiprovider
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// openaiProvider OpenAI 兼容接口，Gemini、DeepSeek、通义千问等提供的兼容接口也使用这个实现
+type openaiProvider struct {
+	client *openai.Client
+}
+
+func newOpenAIProvider(baseURL, apiKey string) *openaiProvider {
+	config := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		config.BaseURL = baseURL
+	}
+	return &openaiProvider{client: openai.NewClientWithConfig(config)}
+}
+
+func (p *openaiProvider) Name() string {
+	return "openai"
+}
+
+func (p *openaiProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	req.Stream = false
+	return p.client.CreateChatCompletion(ctx, req)
+}
+
+func (p *openaiProvider) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (Stream, error) {
+	req.Stream = true
+	return p.client.CreateChatCompletionStream(ctx, req)
+}
@@ -0,0 +1,252 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"wechat-robot-client/model"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var ErrManagerClosed = errors.New("MCP客户端已关闭")
+
+type Config struct {
+	// 工具列表的缓存时间，过期后重新向服务器获取
+	ToolsTTL time.Duration
+	// 连接空闲超过这个时间后关闭，stdio 服务器的进程也会退出
+	IdleTimeout time.Duration
+}
+
+// Manager 管理到MCP服务器的连接，配置相同的服务器共用一个连接，连接出错后下次使用时重新连接
+type Manager struct {
+	name    string
+	config  Config
+	mu      sync.Mutex
+	servers map[string]*serverConn
+	closed  bool
+}
+
+type serverConn struct {
+	mu       sync.Mutex
+	config   model.MCPServer
+	client   *client.Client
+	tools    []mcp.Tool
+	toolsAt  time.Time
+	lastUsed time.Time
+}
+
+func New(name string, config Config) *Manager {
+	if config.ToolsTTL <= 0 {
+		config.ToolsTTL = 5 * time.Minute
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 30 * time.Minute
+	}
+	return &Manager{
+		name:    name,
+		config:  config,
+		servers: make(map[string]*serverConn),
+	}
+}
+
+func (m *Manager) Name() string {
+	return m.name
+}
+
+// ListTools 返回服务器提供的工具
+func (m *Manager) ListTools(ctx context.Context, config model.MCPServer) ([]mcp.Tool, error) {
+	s, err := m.server(config)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil && time.Since(s.toolsAt) < m.config.ToolsTTL {
+		return s.tools, nil
+	}
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+	result, err := s.client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("获取工具列表失败: %w", err)
+	}
+	s.tools = result.Tools
+	s.toolsAt = time.Now()
+	return s.tools, nil
+}
+
+// CallTool 调用服务器的工具，返回工具结果中的文本，工具返回错误时返回 error
+func (m *Manager) CallTool(ctx context.Context, config model.MCPServer, name string, arguments json.RawMessage) (string, error) {
+	s, err := m.server(config)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	if err := s.connect(ctx); err != nil {
+		s.mu.Unlock()
+		return "", err
+	}
+	c := s.client
+	s.mu.Unlock()
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = arguments
+	result, err := c.CallTool(ctx, request)
+	if err != nil {
+		if ctx.Err() == nil {
+			// 连接可能已经断开，下次使用时重新连接
+			s.mu.Lock()
+			if s.client == c {
+				s.close()
+			}
+			s.mu.Unlock()
+		}
+		return "", fmt.Errorf("调用工具失败: %w", err)
+	}
+	text := resultText(result)
+	if result.IsError {
+		return "", errors.New(text)
+	}
+	return text, nil
+}
+
+// server 返回配置对应的服务器，顺便关闭空闲太久的连接
+func (m *Manager) server(config model.MCPServer) (*serverConn, error) {
+	key, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+	now := time.Now()
+	for k, s := range m.servers {
+		if k != string(key) && now.Sub(s.lastUsed) > m.config.IdleTimeout {
+			delete(m.servers, k)
+			go func() {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				s.close()
+			}()
+		}
+	}
+	s, ok := m.servers[string(key)]
+	if !ok {
+		s = &serverConn{config: config}
+		m.servers[string(key)] = s
+	}
+	s.lastUsed = now
+	return s, nil
+}
+
+// Shutdown 关闭所有连接
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	servers := m.servers
+	m.servers = nil
+	m.mu.Unlock()
+	for _, s := range servers {
+		s.mu.Lock()
+		s.close()
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// connect 连接服务器并完成初始化，已经连接时直接返回，调用方需要持有 s.mu
+func (s *serverConn) connect(ctx context.Context) error {
+	if s.client != nil {
+		return nil
+	}
+	var c *client.Client
+	var err error
+	switch s.config.Transport {
+	case model.MCPTransportStdio:
+		var env []string
+		for k, v := range s.config.Env {
+			env = append(env, k+"="+v)
+		}
+		// stdio 客户端创建时就会启动进程
+		c, err = client.NewStdioMCPClient(s.config.Command, env, s.config.Args...)
+	case model.MCPTransportSSE:
+		c, err = client.NewSSEMCPClient(s.config.URL, transport.WithHeaders(s.config.Headers))
+		if err == nil {
+			err = c.Start(context.Background())
+		}
+	case model.MCPTransportHTTP:
+		c, err = client.NewStreamableHttpClient(s.config.URL, transport.WithHTTPHeaders(s.config.Headers))
+		if err == nil {
+			err = c.Start(context.Background())
+		}
+	default:
+		return fmt.Errorf("不支持的MCP传输方式: %s", s.config.Transport)
+	}
+	if err != nil {
+		if c != nil {
+			c.Close()
+		}
+		return fmt.Errorf("连接MCP服务器 %s 失败: %w", s.config.Name, err)
+	}
+
+	request := mcp.InitializeRequest{}
+	request.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	request.Params.ClientInfo = mcp.Implementation{Name: "wechat-robot-client", Version: "1.0.0"}
+	if _, err := c.Initialize(ctx, request); err != nil {
+		c.Close()
+		return fmt.Errorf("初始化MCP服务器 %s 失败: %w", s.config.Name, err)
+	}
+	log.Printf("已连接MCP服务器 %s (%s)", s.config.Name, s.config.Transport)
+	s.client = c
+	return nil
+}
+
+// close 关闭连接，调用方需要持有 s.mu
+func (s *serverConn) close() {
+	if s.client == nil {
+		return
+	}
+	if err := s.client.Close(); err != nil {
+		log.Printf("关闭MCP服务器 %s 的连接失败: %v", s.config.Name, err)
+	}
+	s.client = nil
+	s.tools = nil
+	s.toolsAt = time.Time{}
+}
+
+// resultText 提取工具结果中的文本，图片等其他类型的内容只保留类型说明
+func resultText(result *mcp.CallToolResult) string {
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			texts = append(texts, text.Text)
+			continue
+		}
+		if resource, ok := mcp.AsEmbeddedResource(content); ok {
+			if text, ok := mcp.AsTextResourceContents(resource.Resource); ok {
+				texts = append(texts, text.Text)
+				continue
+			}
+		}
+		texts = append(texts, fmt.Sprintf("[不支持的内容类型 %T]", content))
+	}
+	if len(texts) == 0 && result.StructuredContent != nil {
+		if data, err := json.Marshal(result.StructuredContent); err == nil {
+			texts = append(texts, string(data))
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,65 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	"wechat-robot-client/model"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func newTestMCPServer() *server.MCPServer {
+	s := server.NewMCPServer("test", "1.0.0")
+	s.AddTool(mcp.NewTool("echo", mcp.WithDescription("原样返回"), mcp.WithString("text", mcp.Required())),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			text := request.GetString("text", "")
+			if text == "" {
+				return mcp.NewToolResultError("text 不能为空"), nil
+			}
+			return mcp.NewToolResultText(text), nil
+		})
+	return s
+}
+
+func TestManager(t *testing.T) {
+	httpServer := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer httpServer.Close()
+	sseServer := server.NewTestServer(newTestMCPServer())
+	defer sseServer.Close()
+
+	m := New("MCP客户端", Config{})
+	servers := []model.MCPServer{
+		{Name: "http", Transport: model.MCPTransportHTTP, URL: httpServer.URL + "/mcp"},
+		{Name: "sse", Transport: model.MCPTransportSSE, URL: sseServer.URL + "/sse"},
+	}
+	for _, config := range servers {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		tools, err := m.ListTools(ctx, config)
+		if err != nil {
+			cancel()
+			t.Fatalf("%s: ListTools: %v", config.Name, err)
+		}
+		if len(tools) != 1 || tools[0].Name != "echo" {
+			t.Errorf("%s: tools = %+v, want echo", config.Name, tools)
+		}
+		result, err := m.CallTool(ctx, config, "echo", json.RawMessage(`{"text":"你好"}`))
+		if err != nil || result != "你好" {
+			t.Errorf("%s: CallTool = %q, %v; want 你好", config.Name, result, err)
+		}
+		if _, err := m.CallTool(ctx, config, "echo", json.RawMessage(`{}`)); err == nil || err.Error() != "text 不能为空" {
+			t.Errorf("%s: CallTool error = %v, want tool error", config.Name, err)
+		}
+		cancel()
+	}
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ListTools(context.Background(), servers[0]); !errors.Is(err, ErrManagerClosed) {
+		t.Fatalf("ListTools after Shutdown = %v, want ErrManagerClosed", err)
+	}
+}
@@ -0,0 +1,137 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNonRetryable 处理函数返回的错误包含该错误时，消息不再重试，直接进入死信队列
+var ErrNonRetryable = errors.New("消息无法处理")
+
+// Queue 持久化的消息队列，消息处理完成并确认之前不会丢失
+type Queue interface {
+	// Publish 发送消息，返回 nil 时消息已经持久化
+	Publish(ctx context.Context, payload []byte) error
+	// Consume 按发送顺序把消息交给 handler，阻塞直到 ctx 结束
+	// handler 可以在处理完成后异步调用 Message.Ack / Message.Nack，未确认的消息在客户端重启后会重新投递
+	Consume(ctx context.Context, handler func(msg *Message)) error
+	Close() error
+}
+
+// Message 队列中的一条消息
+type Message struct {
+	ID      string
+	Payload []byte
+	// 第几次投递，从 1 开始
+	Attempts int
+
+	once sync.Once
+	ack  func() error
+	nack func(reason error) error
+	done func()
+	// 由 Consumer 设置，记录 Go 启动的协程
+	workers *sync.WaitGroup
+}
+
+// Go 在后台协程中执行 fn，用于等待消息处理完成后再确认消息，Consumer 退出时会等待这些协程结束
+func (m *Message) Go(fn func()) {
+	if m.workers == nil {
+		go fn()
+		return
+	}
+	m.workers.Add(1)
+	go func() {
+		defer m.workers.Done()
+		fn()
+	}()
+}
+
+// Ack 确认消息处理成功，消息从队列中删除
+func (m *Message) Ack() (err error) {
+	m.once.Do(func() {
+		err = m.ack()
+		if m.done != nil {
+			m.done()
+		}
+	})
+	return
+}
+
+// Nack 消息处理失败，未超过最大投递次数时重新投递，否则进入死信队列
+func (m *Message) Nack(reason error) (err error) {
+	m.once.Do(func() {
+		err = m.nack(reason)
+		if m.done != nil {
+			m.done()
+		}
+	})
+	return
+}
+
+// Consumer 在后台消费队列，实现了优雅退出接口，退出时等待已经交给 handler 的消息确认
+type Consumer struct {
+	name    string
+	queue   Queue
+	handler func(msg *Message)
+	cancel  context.CancelFunc
+	stopped chan struct{}
+	pending sync.WaitGroup
+	// handler 通过 Message.Go 启动的确认协程
+	workers sync.WaitGroup
+}
+
+func NewConsumer(name string, queue Queue, handler func(msg *Message)) *Consumer {
+	return &Consumer{
+		name:    name,
+		queue:   queue,
+		handler: handler,
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start 启动消费协程
+func (c *Consumer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go func() {
+		defer close(c.stopped)
+		_ = c.queue.Consume(ctx, func(msg *Message) {
+			c.pending.Add(1)
+			done := msg.done
+			msg.done = func() {
+				if done != nil {
+					done()
+				}
+				c.pending.Done()
+			}
+			msg.workers = &c.workers
+			c.handler(msg)
+		})
+	}()
+}
+
+func (c *Consumer) Name() string {
+	return c.name
+}
+
+// Shutdown 停止拉取新消息，等待已经拉取的消息处理完成，并等待确认消息的协程结束，避免已经处理的消息在下次启动后重复投递
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	if c.cancel == nil {
+		return nil
+	}
+	c.cancel()
+	done := make(chan struct{})
+	go func() {
+		<-c.stopped
+		c.pending.Wait()
+		c.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return c.queue.Close()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
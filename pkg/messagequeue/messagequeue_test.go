@@ -0,0 +1,65 @@
+package messagequeue
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryQueue 把固定的消息交给 handler，然后阻塞直到 ctx 结束
+type memoryQueue struct {
+	payloads []string
+	acked    atomic.Int32
+}
+
+func (q *memoryQueue) Publish(ctx context.Context, payload []byte) error {
+	return nil
+}
+
+func (q *memoryQueue) Consume(ctx context.Context, handler func(msg *Message)) error {
+	for i, payload := range q.payloads {
+		msg := &Message{ID: strconv.Itoa(i), Payload: []byte(payload), Attempts: 1}
+		msg.ack = func() error {
+			q.acked.Add(1)
+			return nil
+		}
+		msg.nack = func(reason error) error {
+			return nil
+		}
+		handler(msg)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (q *memoryQueue) Close() error {
+	return nil
+}
+
+func TestConsumerWaitsForAckWorkers(t *testing.T) {
+	q := &memoryQueue{payloads: []string{"a", "b"}}
+	received := make(chan struct{}, len(q.payloads))
+	consumer := NewConsumer("test", q, func(msg *Message) {
+		// 模拟在后台等待消息处理完成后再确认
+		msg.Go(func() {
+			time.Sleep(100 * time.Millisecond)
+			msg.Ack()
+		})
+		received <- struct{}{}
+	})
+	consumer.Start()
+	for range q.payloads {
+		<-received
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := consumer.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := q.acked.Load(); n != 2 {
+		t.Fatalf("acked = %d, want 2", n)
+	}
+}
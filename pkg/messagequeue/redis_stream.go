@@ -0,0 +1,191 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisStreamConfig struct {
+	Stream   string // 消息流，死信队列为 {Stream}:dead
+	Group    string // 消费组
+	Consumer string // 消费者名称，多个客户端实例共用一个消费组时需要不同
+	// 最大投递次数，超过后进入死信队列
+	MaxAttempts int
+	// 消息被拉取后超过这个时间还没有确认，认为消费者已经崩溃，由其他消费者(或者重启后的自己)重新投递
+	// 处理失败的消息也留在待确认列表中，同样在这个时间之后重新投递，避免短暂的故障在很短时间内耗尽投递次数
+	ClaimIdle time.Duration
+}
+
+// RedisStreamQueue 基于 Redis Streams 的消息队列，确认后的消息会从流中删除
+type RedisStreamQueue struct {
+	client *redis.Client
+	config RedisStreamConfig
+}
+
+var _ Queue = (*RedisStreamQueue)(nil)
+
+func NewRedisStreamQueue(client *redis.Client, config RedisStreamConfig) *RedisStreamQueue {
+	if config.Group == "" {
+		config.Group = "wechat-robot-client"
+	}
+	if config.Consumer == "" {
+		config.Consumer = "consumer"
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.ClaimIdle <= 0 {
+		config.ClaimIdle = 5 * time.Minute
+	}
+	return &RedisStreamQueue{
+		client: client,
+		config: config,
+	}
+}
+
+func (q *RedisStreamQueue) deadStream() string {
+	return q.config.Stream + ":dead"
+}
+
+func (q *RedisStreamQueue) Publish(ctx context.Context, payload []byte) error {
+	return q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.config.Stream,
+		Values: map[string]any{"payload": payload, "attempts": 0},
+	}).Err()
+}
+
+func (q *RedisStreamQueue) Consume(ctx context.Context, handler func(msg *Message)) error {
+	err := q.client.XGroupCreateMkStream(ctx, q.config.Stream, q.config.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("创建消费组失败: %w", err)
+	}
+	nextClaim := time.Now()
+	for ctx.Err() == nil {
+		// 先接管其他消费者崩溃后没有确认的消息，启动时也会接管自己上次没有确认的消息
+		if time.Now().After(nextClaim) {
+			q.claim(ctx, handler)
+			nextClaim = time.Now().Add(q.config.ClaimIdle / 2)
+		}
+		streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    q.config.Group,
+			Consumer: q.config.Consumer,
+			Streams:  []string{q.config.Stream, ">"},
+			Count:    10,
+			Block:    5 * time.Second,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			log.Printf("拉取消息失败: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, stream := range streams {
+			for _, entry := range stream.Messages {
+				handler(q.newMessage(entry, 1))
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+func (q *RedisStreamQueue) claim(ctx context.Context, handler func(msg *Message)) {
+	start := "0-0"
+	for {
+		entries, next, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   q.config.Stream,
+			Group:    q.config.Group,
+			Consumer: q.config.Consumer,
+			MinIdle:  q.config.ClaimIdle,
+			Start:    start,
+			Count:    10,
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("接管未确认的消息失败: %v", err)
+			}
+			return
+		}
+		for _, entry := range entries {
+			deliveries := 1
+			pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+				Stream: q.config.Stream,
+				Group:  q.config.Group,
+				Start:  entry.ID,
+				End:    entry.ID,
+				Count:  1,
+			}).Result()
+			if err == nil && len(pending) > 0 {
+				deliveries = int(pending[0].RetryCount)
+			}
+			handler(q.newMessage(entry, deliveries))
+		}
+		if next == "0-0" || len(entries) == 0 {
+			return
+		}
+		start = next
+	}
+}
+
+// newMessage deliveries 为消息在消费组中的投递次数，attempts 字段是旧版本重新发布消息时记录的之前的投递次数
+func (q *RedisStreamQueue) newMessage(entry redis.XMessage, deliveries int) *Message {
+	previous, _ := strconv.Atoi(fmt.Sprint(entry.Values["attempts"]))
+	payload, _ := entry.Values["payload"].(string)
+	msg := &Message{
+		ID:       entry.ID,
+		Payload:  []byte(payload),
+		Attempts: previous + deliveries,
+	}
+	// 确认消息时使用独立的上下文，退出时已经拉取的消息仍然可以确认
+	ctx := context.Background()
+	msg.ack = func() error {
+		return q.remove(ctx, entry.ID)
+	}
+	msg.nack = func(reason error) error {
+		if msg.Attempts >= q.config.MaxAttempts || errors.Is(reason, ErrNonRetryable) {
+			log.Printf("消息 %s 第%d次处理失败，进入死信队列: %v", entry.ID, msg.Attempts, reason)
+			err := q.client.XAdd(ctx, &redis.XAddArgs{
+				Stream: q.deadStream(),
+				Values: map[string]any{
+					"payload":   payload,
+					"attempts":  msg.Attempts,
+					"error":     fmt.Sprint(reason),
+					"failed_at": time.Now().Unix(),
+				},
+			}).Err()
+			if err != nil {
+				return err
+			}
+			return q.remove(ctx, entry.ID)
+		}
+		// 不确认消息，消息留在待确认列表中原来的位置，空闲时间超过 ClaimIdle 后由 claim 重新投递
+		log.Printf("消息 %s 第%d次处理失败，%s后重新投递: %v", entry.ID, msg.Attempts, q.config.ClaimIdle, reason)
+		return nil
+	}
+	return msg
+}
+
+func (q *RedisStreamQueue) remove(ctx context.Context, id string) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.config.Stream, q.config.Group, id)
+		pipe.XDel(ctx, q.config.Stream, id)
+		return nil
+	})
+	return err
+}
+
+// Close Redis 连接由调用方管理，这里不需要关闭
+func (q *RedisStreamQueue) Close() error {
+	return nil
+}
@@ -0,0 +1,115 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestQueue(t *testing.T, consumer string) (*RedisStreamQueue, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return NewRedisStreamQueue(client, RedisStreamConfig{Stream: "sync_message", Consumer: consumer, MaxAttempts: 2, ClaimIdle: time.Minute}), client
+}
+
+// consumeN 消费 n 条消息后停止
+func consumeN(t *testing.T, q *RedisStreamQueue, n int, handle func(msg *Message)) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var count int
+	q.Consume(ctx, func(msg *Message) {
+		handle(msg)
+		count++
+		if count == n {
+			cancel()
+		}
+	})
+	if count != n {
+		t.Fatalf("consumed %d messages, want %d", count, n)
+	}
+}
+
+func TestRedisStreamQueueAckAndRetry(t *testing.T) {
+	q, client := newTestQueue(t, "c1")
+	ctx := context.Background()
+	for _, payload := range []string{"a", "b"} {
+		if err := q.Publish(ctx, []byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	consumeN(t, q, 2, func(msg *Message) {
+		got = append(got, string(msg.Payload))
+		if msg.Attempts != 1 {
+			t.Errorf("%s: attempts = %d, want 1", msg.Payload, msg.Attempts)
+		}
+		if string(msg.Payload) == "a" {
+			msg.Ack()
+		} else {
+			msg.Nack(errors.New("AI超时"))
+		}
+	})
+	if got[0] != "a" || got[1] != "b" {
+		t.Fatalf("unexpected order: %v", got)
+	}
+
+	// 失败的消息留在待确认列表中，空闲时间超过 ClaimIdle 后重新投递，超过最大投递次数后进入死信队列
+	if n := client.XLen(ctx, "sync_message").Val(); n != 1 {
+		t.Fatalf("stream length = %d, want 1", n)
+	}
+	q = NewRedisStreamQueue(client, RedisStreamConfig{Stream: "sync_message", Consumer: "c1", MaxAttempts: 2, ClaimIdle: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+	consumeN(t, q, 1, func(msg *Message) {
+		if string(msg.Payload) != "b" || msg.Attempts != 2 {
+			t.Errorf("retry: payload = %s, attempts = %d", msg.Payload, msg.Attempts)
+		}
+		msg.Nack(errors.New("AI超时"))
+	})
+	if n := client.XLen(ctx, "sync_message").Val(); n != 0 {
+		t.Fatalf("stream length = %d, want 0", n)
+	}
+	dead := client.XRange(ctx, "sync_message:dead", "-", "+").Val()
+	if len(dead) != 1 || dead[0].Values["payload"] != "b" || dead[0].Values["error"] != "AI超时" {
+		t.Fatalf("unexpected dead letters: %v", dead)
+	}
+}
+
+func TestRedisStreamQueueNonRetryable(t *testing.T) {
+	q, client := newTestQueue(t, "c1")
+	ctx := context.Background()
+	q.Publish(ctx, []byte("{"))
+	consumeN(t, q, 1, func(msg *Message) {
+		msg.Nack(errors.Join(ErrNonRetryable, errors.New("JSON格式错误")))
+	})
+	if n := client.XLen(ctx, "sync_message:dead").Val(); n != 1 {
+		t.Fatalf("dead letters = %d, want 1", n)
+	}
+}
+
+func TestRedisStreamQueueClaimsUnacked(t *testing.T) {
+	q, client := newTestQueue(t, "c1")
+	ctx := context.Background()
+	q.Publish(ctx, []byte("a"))
+	// 消费者拿到消息后崩溃，没有确认
+	consumeN(t, q, 1, func(msg *Message) {})
+
+	// 空闲时间超过 ClaimIdle 后由其他消费者接管
+	q2 := NewRedisStreamQueue(client, RedisStreamConfig{Stream: "sync_message", Consumer: "c2", MaxAttempts: 2, ClaimIdle: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+	consumeN(t, q2, 1, func(msg *Message) {
+		if string(msg.Payload) != "a" || msg.Attempts != 2 {
+			t.Errorf("claim: payload = %s, attempts = %d", msg.Payload, msg.Attempts)
+		}
+		msg.Ack()
+	})
+	if n := client.XLen(ctx, "sync_message").Val(); n != 0 {
+		t.Fatalf("stream length = %d, want 0", n)
+	}
+}
@@ -51,26 +51,26 @@ type VoiceMessageXml struct {
 }
 
 type VideoSecretXml struct {
-	AesKey            string `xml:"aeskey,attr"`
-	CdnVideoUrl       string `xml:"cdnvideourl,attr"`
-	CdnThumbAesKey    string `xml:"cdnthumbaeskey,attr"`
-	CdnThumbUrl       string `xml:"cdnthumburl,attr"`
-	Length            int64  `xml:"length,attr"`
-	PlayLength        int64  `xml:"playlength,attr"`
-	CdnThumbLength    int64  `xml:"cdnthumblength,attr"`
-	CdnThumbWidth     int64  `xml:"cdnthumbwidth,attr"`
-	CdnThumbHeight    int64  `xml:"cdnthumbheight,attr"`
-	FromUserName      string `xml:"fromusername,attr"`
-	Md5               string `xml:"md5,attr"`
-	NewMd5            string `xml:"newmd5,attr"`
-	IsPlaceholder     string `xml:"isplaceholder,attr"`
-	RawMd5            string `xml:"rawmd5,attr"`
-	RawLength         int64  `xml:"rawlength,attr"`
-	CdnRawVideoUrl    string `xml:"cdnrawvideourl,attr"`
-	CdnRawVideoAesKey string `xml:"cdnrawvideoaeskey,attr"`
-	OverwriteNewMsgId int64  `xml:"overwritenewmsgid,attr"`
-	OriginSourceMd5   string `xml:"originsourcemd5,attr"`
-	IsAd              string `xml:"isad,attr"`
+	AesKey            string `xml:"aeskey,attr" json:"aeskey"`
+	CdnVideoUrl       string `xml:"cdnvideourl,attr" json:"cdnvideourl"`
+	CdnThumbAesKey    string `xml:"cdnthumbaeskey,attr" json:"cdnthumbaeskey"`
+	CdnThumbUrl       string `xml:"cdnthumburl,attr" json:"cdnthumburl"`
+	Length            int64  `xml:"length,attr" json:"length"`
+	PlayLength        int64  `xml:"playlength,attr" json:"playlength"`
+	CdnThumbLength    int64  `xml:"cdnthumblength,attr" json:"cdnthumblength"`
+	CdnThumbWidth     int64  `xml:"cdnthumbwidth,attr" json:"cdnthumbwidth"`
+	CdnThumbHeight    int64  `xml:"cdnthumbheight,attr" json:"cdnthumbheight"`
+	FromUserName      string `xml:"fromusername,attr" json:"fromusername"`
+	Md5               string `xml:"md5,attr" json:"md5"`
+	NewMd5            string `xml:"newmd5,attr" json:"newmd5"`
+	IsPlaceholder     string `xml:"isplaceholder,attr" json:"isplaceholder"`
+	RawMd5            string `xml:"rawmd5,attr" json:"rawmd5"`
+	RawLength         int64  `xml:"rawlength,attr" json:"rawlength"`
+	CdnRawVideoUrl    string `xml:"cdnrawvideourl,attr" json:"cdnrawvideourl"`
+	CdnRawVideoAesKey string `xml:"cdnrawvideoaeskey,attr" json:"cdnrawvideoaeskey"`
+	OverwriteNewMsgId int64  `xml:"overwritenewmsgid,attr" json:"overwritenewmsgid"`
+	OriginSourceMd5   string `xml:"originsourcemd5,attr" json:"originsourcemd5"`
+	IsAd              string `xml:"isad,attr" json:"isad"`
 }
 
 type VideoMessageXml struct {
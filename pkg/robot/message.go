@@ -199,6 +199,58 @@ type Pat struct {
 	Template         string   `xml:"template"`
 }
 
+// EmojiMessageXml 表情消息
+type EmojiMessageXml struct {
+	XMLName xml.Name `xml:"msg"`
+	Emoji   EmojiXml `xml:"emoji"`
+}
+
+type EmojiXml struct {
+	FromUsername string `xml:"fromusername,attr" json:"from_username"`
+	ToUsername   string `xml:"tousername,attr" json:"to_username"`
+	Type         int    `xml:"type,attr" json:"type"`
+	Md5          string `xml:"md5,attr" json:"md5"`
+	Len          int32  `xml:"len,attr" json:"len"`
+	ProductID    string `xml:"productid,attr" json:"product_id"`
+	CdnURL       string `xml:"cdnurl,attr" json:"cdn_url"`
+	ThumbURL     string `xml:"thumburl,attr" json:"thumb_url"`
+	Width        int    `xml:"width,attr" json:"width"`
+	Height       int    `xml:"height,attr" json:"height"`
+	AesKey       string `xml:"aeskey,attr" json:"aes_key"`
+}
+
+// LocationMessageXml 地理位置消息
+type LocationMessageXml struct {
+	XMLName  xml.Name    `xml:"msg"`
+	Location LocationXml `xml:"location"`
+}
+
+type LocationXml struct {
+	Latitude  float64 `xml:"x,attr" json:"latitude"`
+	Longitude float64 `xml:"y,attr" json:"longitude"`
+	Scale     int     `xml:"scale,attr" json:"scale"`
+	Label     string  `xml:"label,attr" json:"label"`
+	PoiName   string  `xml:"poiname,attr" json:"poi_name"`
+	PoiID     string  `xml:"poiid,attr" json:"poi_id"`
+}
+
+// ShareCardMessageXml 名片消息，陌生人的 Username 是加密后的 v3 用户名，加好友时需要带上 AntispamTicket
+type ShareCardMessageXml struct {
+	XMLName         xml.Name `xml:"msg" json:"-"`
+	Username        string   `xml:"username,attr" json:"username"`
+	Nickname        string   `xml:"nickname,attr" json:"nickname"`
+	Alias           string   `xml:"alias,attr" json:"alias"`
+	Province        string   `xml:"province,attr" json:"province"`
+	City            string   `xml:"city,attr" json:"city"`
+	Sign            string   `xml:"sign,attr" json:"sign"`
+	Sex             int      `xml:"sex,attr" json:"sex"`
+	Scene           int      `xml:"scene,attr" json:"scene"`
+	CertFlag        int      `xml:"certflag,attr" json:"cert_flag"`
+	BigHeadImgURL   string   `xml:"bigheadimgurl,attr" json:"big_head_img_url"`
+	SmallHeadImgURL string   `xml:"smallheadimgurl,attr" json:"small_head_img_url"`
+	AntispamTicket  string   `xml:"antispamticket,attr" json:"antispam_ticket"`
+}
+
 type MemberList struct {
 	Members []Member `xml:"member"`
 }
@@ -17,6 +17,37 @@ func TestXmlDecoder(t *testing.T) {
 	}
 }
 
+func TestTypedMessageXmlDecoder(t *testing.T) {
+	var robot = &Robot{}
+	var emoji EmojiMessageXml
+	err := robot.XmlDecoder(`<msg><emoji fromusername="wxid_a" tousername="123@chatroom" type="2" md5="0a1b2c" len="20480" productid="" cdnurl="http://wxapp.tc.qq.com/x" width="240" height="240"></emoji></msg>`, &emoji)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if emoji.Emoji.Md5 != "0a1b2c" || emoji.Emoji.Len != 20480 || emoji.Emoji.Width != 240 {
+		t.Fatalf("unexpected emoji: %+v", emoji.Emoji)
+	}
+
+	var location LocationMessageXml
+	err = robot.XmlDecoder(`<msg><location x="22.543099" y="114.057868" scale="15" label="广东省深圳市福田区" maptype="roadmap" poiname="深圳市民中心" poiid="qqmap_123" /></msg>`, &location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Location.Latitude != 22.543099 || location.Location.Longitude != 114.057868 || location.Location.PoiName != "深圳市民中心" {
+		t.Fatalf("unexpected location: %+v", location.Location)
+	}
+
+	var card ShareCardMessageXml
+	err = robot.XmlDecoder(`<?xml version="1.0"?>
+<msg bigheadimgurl="http://wx.qlogo.cn/big" smallheadimgurl="http://wx.qlogo.cn/small" username="v3_abc@stranger" nickname="张三" alias="zhangsan" province="广东" city="深圳" sign="" sex="1" scene="17" certflag="0" antispamticket="v4_def@stranger" />`, &card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Username != "v3_abc@stranger" || card.Nickname != "张三" || card.Sex != 1 || card.AntispamTicket != "v4_def@stranger" {
+		t.Fatalf("unexpected share card: %+v", card)
+	}
+}
+
 func TestDownloadImage(t *testing.T) {
 	var robot = &Robot{
 		WxID: "wxid_7bpstqonj92212",
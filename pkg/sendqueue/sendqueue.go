@@ -0,0 +1,285 @@
+package sendqueue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+var ErrQueueClosed = errors.New("发送队列已关闭")
+
+// Priority 数值越小越先发送
+type Priority int
+
+const (
+	PriorityInteractive Priority = 0   // 回复用户的消息
+	PriorityBroadcast   Priority = 100 // 定时任务群发的消息
+)
+
+type priorityKey struct{}
+
+// WithPriority 设置通过这个上下文发送的消息的优先级，没有设置时为 PriorityInteractive
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityInteractive
+}
+
+type Config struct {
+	// 最大发送次数，网络错误等临时错误会重试
+	MaxAttempts int
+	// 第一次重试的等待时间，之后每次翻倍
+	RetryBackoff time.Duration
+	// 两条群发消息(PriorityBroadcast)之间的随机间隔，回复用户的消息不受这个间隔限制
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// 发给同一个联系人的两条消息之间的随机间隔
+	ContactMinInterval time.Duration
+	ContactMaxInterval time.Duration
+	// 判断错误是否可以重试，默认只重试网络错误
+	Retryable func(err error) bool
+}
+
+// Job 一次发送，Send 在发送队列的协程中执行
+type Job struct {
+	ToWxID   string
+	Priority Priority
+	Send     func() error
+
+	attempts  int
+	notBefore time.Time
+	seq       uint64
+	cancelled bool
+	done      chan error
+}
+
+// Attempts 已经发送的次数
+func (j *Job) Attempts() int {
+	return j.attempts
+}
+
+// Queue 所有消息由一个协程依次发送，优先级高的先发送，同优先级按提交顺序发送
+// 发给同一个联系人的消息之间随机间隔一段时间；群发消息之间还有全局的随机间隔，避免短时间内大量发送，
+// 回复不同联系人的消息不需要等待全局间隔，不会因为其他聊天的消息排队而变慢
+type Queue struct {
+	name           string
+	config         Config
+	mu             sync.Mutex
+	jobs           []*Job
+	ready          map[string]time.Time // 联系人下一次可以发送的时间
+	broadcastReady time.Time            // 下一条群发消息可以发送的时间
+	seq            uint64
+	closed         bool
+	notify         chan struct{}
+	stopped        chan struct{}
+}
+
+func New(name string, config Config) *Queue {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = 2 * time.Second
+	}
+	if config.Retryable == nil {
+		config.Retryable = IsTransient
+	}
+	q := &Queue{
+		name:    name,
+		config:  config,
+		ready:   make(map[string]time.Time),
+		notify:  make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// IsTransient 网络错误、连接被断开等临时错误
+func IsTransient(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Send 把消息放入队列并等待发送完成，返回最后一次发送的错误
+// ctx 结束时还没有发送的消息会被取消
+func (q *Queue) Send(ctx context.Context, job *Job) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrQueueClosed
+	}
+	q.seq++
+	job.seq = q.seq
+	job.done = make(chan error, 1)
+	q.jobs = append(q.jobs, job)
+	q.mu.Unlock()
+	q.wakeup()
+
+	select {
+	case err := <-job.done:
+		return err
+	case <-ctx.Done():
+		q.mu.Lock()
+		job.cancelled = true
+		q.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Pending 队列中等待发送的消息数量
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+func (q *Queue) wakeup() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) run() {
+	defer close(q.stopped)
+	for {
+		q.mu.Lock()
+		job, wait := q.next(time.Now())
+		if job == nil && q.closed && len(q.jobs) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+		if job == nil {
+			q.sleep(wait)
+			continue
+		}
+
+		job.attempts++
+		err := q.send(job)
+		now := time.Now()
+		q.mu.Lock()
+		q.ready[job.ToWxID] = now.Add(randDuration(q.config.ContactMinInterval, q.config.ContactMaxInterval))
+		if job.Priority >= PriorityBroadcast {
+			q.broadcastReady = now.Add(randDuration(q.config.MinInterval, q.config.MaxInterval))
+		}
+		if err != nil && job.attempts < q.config.MaxAttempts && q.config.Retryable(err) && !q.closed {
+			backoff := q.config.RetryBackoff << (job.attempts - 1)
+			log.Printf("[%s] 发送给 %s 的消息第%d次发送失败，%s后重试: %v", q.name, job.ToWxID, job.attempts, backoff, err)
+			job.notBefore = now.Add(backoff)
+			q.jobs = append(q.jobs, job)
+			q.mu.Unlock()
+		} else {
+			q.mu.Unlock()
+			job.done <- err
+		}
+	}
+}
+
+// send 执行发送，panic 按发送失败处理
+func (q *Queue) send(job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[%s] 发送消息 panic: %v", q.name, r)
+			err = errors.New("发送消息异常")
+		}
+	}()
+	return job.Send()
+}
+
+// next 取出可以发送的优先级最高的消息，没有可以发送的消息时返回需要等待的时间，0 表示一直等到有新消息
+func (q *Queue) next(now time.Time) (*Job, time.Duration) {
+	best := -1
+	var wait time.Duration
+	jobs := q.jobs[:0]
+	for _, job := range q.jobs {
+		if job.cancelled {
+			continue
+		}
+		jobs = append(jobs, job)
+		at := job.notBefore
+		if ready := q.ready[job.ToWxID]; ready.After(at) {
+			at = ready
+		}
+		if job.Priority >= PriorityBroadcast && q.broadcastReady.After(at) {
+			at = q.broadcastReady
+		}
+		if at.After(now) {
+			if d := at.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		if best < 0 || job.Priority < jobs[best].Priority || (job.Priority == jobs[best].Priority && job.seq < jobs[best].seq) {
+			best = len(jobs) - 1
+		}
+	}
+	clear(q.jobs[len(jobs):])
+	q.jobs = jobs
+	for wxID, ready := range q.ready {
+		if !ready.After(now) {
+			delete(q.ready, wxID)
+		}
+	}
+	if best < 0 {
+		return nil, wait
+	}
+	job := q.jobs[best]
+	q.jobs = append(q.jobs[:best], q.jobs[best+1:]...)
+	return job, 0
+}
+
+func (q *Queue) sleep(d time.Duration) {
+	if d <= 0 {
+		<-q.notify
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-q.notify:
+	case <-timer.C:
+	}
+}
+
+func randDuration(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + rand.N(max-min)
+}
+
+func (q *Queue) Name() string {
+	return q.name
+}
+
+// Shutdown 不再接收新消息，等待队列中的消息发送完成，超时后取消剩余的消息
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.wakeup()
+	select {
+	case <-q.stopped:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		for _, job := range q.jobs {
+			job.done <- ErrQueueClosed
+		}
+		q.jobs = nil
+		q.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,144 @@
+package sendqueue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	q := New("test", Config{})
+	defer q.Shutdown(context.Background())
+
+	// 第一条消息发送期间放入的消息按优先级排序
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func() error {
+		return func() error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.Send(context.Background(), &Job{ToWxID: "a", Send: func() error {
+			close(started)
+			<-release
+			return nil
+		}})
+	}()
+	<-started
+	jobs := []*Job{
+		{ToWxID: "b", Priority: PriorityBroadcast, Send: record("broadcast1")},
+		{ToWxID: "c", Priority: PriorityBroadcast, Send: record("broadcast2")},
+		{ToWxID: "d", Priority: PriorityInteractive, Send: record("reply")},
+	}
+	for i, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.Send(context.Background(), job)
+		}()
+		// 等待放入队列，保证提交顺序
+		for q.Pending() < i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	close(release)
+	wg.Wait()
+	if len(order) != 3 || order[0] != "reply" || order[1] != "broadcast1" || order[2] != "broadcast2" {
+		t.Fatalf("order = %v", order)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	q := New("test", Config{MaxAttempts: 3, RetryBackoff: time.Millisecond})
+	defer q.Shutdown(context.Background())
+
+	calls := 0
+	job := &Job{ToWxID: "a", Send: func() error {
+		calls++
+		if calls < 3 {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}}
+	if err := q.Send(context.Background(), job); err != nil || job.Attempts() != 3 {
+		t.Fatalf("transient: err = %v, attempts = %d", err, job.Attempts())
+	}
+
+	failed := errors.New("不是好友")
+	job = &Job{ToWxID: "a", Send: func() error { return failed }}
+	if err := q.Send(context.Background(), job); !errors.Is(err, failed) || job.Attempts() != 1 {
+		t.Fatalf("permanent: err = %v, attempts = %d", err, job.Attempts())
+	}
+}
+
+func TestContactInterval(t *testing.T) {
+	interval := 50 * time.Millisecond
+	q := New("test", Config{ContactMinInterval: interval, ContactMaxInterval: interval})
+	defer q.Shutdown(context.Background())
+
+	var sentAt []time.Time
+	for range 2 {
+		q.Send(context.Background(), &Job{ToWxID: "a", Send: func() error {
+			sentAt = append(sentAt, time.Now())
+			return nil
+		}})
+	}
+	if d := sentAt[1].Sub(sentAt[0]); d < interval {
+		t.Fatalf("interval = %s, want >= %s", d, interval)
+	}
+	start := time.Now()
+	q.Send(context.Background(), &Job{ToWxID: "b", Send: func() error { return nil }})
+	if d := time.Since(start); d >= interval {
+		t.Fatalf("other contact waited %s", d)
+	}
+}
+
+func TestBroadcastInterval(t *testing.T) {
+	interval := 200 * time.Millisecond
+	q := New("test", Config{MinInterval: interval, MaxInterval: interval})
+	defer q.Shutdown(context.Background())
+
+	// 回复不同联系人的消息不需要等待全局间隔
+	q.Send(context.Background(), &Job{ToWxID: "a", Send: func() error { return nil }})
+	start := time.Now()
+	q.Send(context.Background(), &Job{ToWxID: "b", Send: func() error { return nil }})
+	if d := time.Since(start); d >= interval {
+		t.Fatalf("interactive job for b waited %s after a send to a", d)
+	}
+
+	// 群发消息之间有全局间隔
+	q.Send(context.Background(), &Job{ToWxID: "c", Priority: PriorityBroadcast, Send: func() error { return nil }})
+	start = time.Now()
+	q.Send(context.Background(), &Job{ToWxID: "d", Priority: PriorityBroadcast, Send: func() error { return nil }})
+	if d := time.Since(start); d < interval {
+		t.Fatalf("broadcast interval = %s, want >= %s", d, interval)
+	}
+	// 群发间隔期间回复用户的消息直接发送
+	start = time.Now()
+	q.Send(context.Background(), &Job{ToWxID: "e", Send: func() error { return nil }})
+	if d := time.Since(start); d >= interval {
+		t.Fatalf("interactive job waited %s behind broadcast interval", d)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	q := New("test", Config{})
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Send(context.Background(), &Job{ToWxID: "a", Send: func() error { return nil }}); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("err = %v, want ErrQueueClosed", err)
+	}
+}
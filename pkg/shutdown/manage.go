@@ -2,9 +2,11 @@ package shutdown
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -16,8 +18,36 @@ type ShutdownHandler interface {
 	Name() string
 }
 
+// sequence 按顺序退出的一组组件
+type sequence []ShutdownHandler
+
+// Sequence 把多个组件合并成一个，按顺序退出，前一个组件退出后才退出下一个
+// 用于前一个组件在退出过程中还会往后一个组件提交任务的场景
+func Sequence(handlers ...ShutdownHandler) ShutdownHandler {
+	return sequence(handlers)
+}
+
+func (s sequence) Name() string {
+	names := make([]string, 0, len(s))
+	for _, handler := range s {
+		names = append(names, handler.Name())
+	}
+	return strings.Join(names, " -> ")
+}
+
+func (s sequence) Shutdown(ctx context.Context) error {
+	for _, handler := range s {
+		if err := handler.Shutdown(ctx); err != nil {
+			return fmt.Errorf("%s: %w", handler.Name(), err)
+		}
+	}
+	return nil
+}
+
 // ShutdownManager 优雅退出管理器
 type ShutdownManager struct {
+	// 最先退出的组件，例如处理中的消息，需要在关闭数据库等连接之前执行完成
+	drainers []ShutdownHandler
 	handlers []ShutdownHandler
 	timeout  time.Duration
 	mu       sync.RWMutex
@@ -39,6 +69,14 @@ func (m *ShutdownManager) Register(handler ShutdownHandler) {
 	log.Printf("注册优雅退出处理函数: %s", handler.Name())
 }
 
+// RegisterDrainer 注册需要最先退出的组件，所有 Drainer 退出后才会执行 Register 注册的组件的退出
+func (m *ShutdownManager) RegisterDrainer(handler ShutdownHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.drainers = append(m.drainers, handler)
+	log.Printf("注册优雅退出处理函数: %s", handler.Name())
+}
+
 // Start 开始监听程序终止信号
 func (m *ShutdownManager) Start() {
 	quit := make(chan os.Signal, 1)
@@ -55,6 +93,8 @@ func (m *ShutdownManager) Start() {
 // shutdown 执行所有组件的优雅退出
 func (m *ShutdownManager) shutdown() {
 	m.mu.RLock()
+	drainers := make([]ShutdownHandler, len(m.drainers))
+	copy(drainers, m.drainers)
 	handlers := make([]ShutdownHandler, len(m.handlers))
 	copy(handlers, m.handlers)
 	m.mu.RUnlock()
@@ -62,6 +102,16 @@ func (m *ShutdownManager) shutdown() {
 	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
 	defer cancel()
 
+	if !m.shutdownHandlers(ctx, drainers) {
+		return
+	}
+	if m.shutdownHandlers(ctx, handlers) {
+		log.Println("所有组件都已经优雅退出...")
+	}
+}
+
+// shutdownHandlers 并发执行组件的停止操作，超时返回 false
+func (m *ShutdownManager) shutdownHandlers(ctx context.Context, handlers []ShutdownHandler) bool {
 	// 并发执行所有组件的停止操作
 	var wg sync.WaitGroup
 	for _, handler := range handlers {
@@ -87,8 +137,9 @@ func (m *ShutdownManager) shutdown() {
 
 	select {
 	case <-done:
-		log.Println("所有组件都已经优雅退出...")
+		return true
 	case <-ctx.Done():
 		log.Println("程序优雅退出超时，强制退出...")
+		return false
 	}
 }
@@ -0,0 +1,127 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+var ErrPoolClosed = errors.New("协程池已关闭")
+
+var ErrPoolFull = errors.New("协程池队列已满")
+
+// Pool 按 key 把任务分配给固定的 worker，同一个 key 的任务按提交顺序串行执行，不同 key 的任务并行执行
+// 每个 worker 的队列长度有限，队列满了之后 Submit 会阻塞，直到队列有空位
+type Pool struct {
+	name   string
+	queues []chan func()
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New 创建协程池，workers 为 worker 数量，queueSize 为每个 worker 的队列长度
+func New(name string, workers, queueSize int) *Pool {
+	workers = max(1, workers)
+	queueSize = max(0, queueSize)
+	p := &Pool{
+		name:   name,
+		queues: make([]chan func(), workers),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan func(), queueSize)
+		p.wg.Add(1)
+		go p.work(p.queues[i])
+	}
+	return p
+}
+
+func (p *Pool) work(queue chan func()) {
+	defer p.wg.Done()
+	for task := range queue {
+		p.run(task)
+	}
+}
+
+// run 执行任务，任务 panic 时只记录日志，不影响 worker 继续处理后面的任务
+func (p *Pool) run(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("%s 任务发生 panic: %v\n%s", p.name, r, debug.Stack())
+		}
+	}()
+	task()
+}
+
+// Submit 提交任务，协程池关闭后返回 ErrPoolClosed
+func (p *Pool) Submit(key string, task func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	p.queues[p.index(key)] <- task
+	return nil
+}
+
+// TrySubmit 提交任务，队列满了时不等待，直接返回 ErrPoolFull，适合可以丢弃的后台任务
+func (p *Pool) TrySubmit(key string, task func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	select {
+	case p.queues[p.index(key)] <- task:
+		return nil
+	default:
+		return ErrPoolFull
+	}
+}
+
+func (p *Pool) index(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+// Pending 队列中等待执行的任务数量
+func (p *Pool) Pending() int {
+	var pending int
+	for _, queue := range p.queues {
+		pending += len(queue)
+	}
+	return pending
+}
+
+// 实现优雅退出接口
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Shutdown 不再接收新任务，等待队列中的任务全部执行完成
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,111 @@
+package workerpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoolKeepsOrderPerKey(t *testing.T) {
+	p := New("test", 4, 2)
+	var mu sync.Mutex
+	got := map[string][]int{}
+	for i := range 50 {
+		for _, key := range []string{"a@chatroom", "b@chatroom", "wxid_c"} {
+			err := p.Submit(key, func() {
+				mu.Lock()
+				got[key] = append(got[key], i)
+				mu.Unlock()
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for key, seq := range got {
+		if len(seq) != 50 {
+			t.Fatalf("%s: got %d tasks, want 50", key, len(seq))
+		}
+		for i, v := range seq {
+			if v != i {
+				t.Fatalf("%s: out of order: %v", key, seq)
+			}
+		}
+	}
+	if err := p.Submit("a@chatroom", func() {}); err != ErrPoolClosed {
+		t.Fatalf("Submit after shutdown: err = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestPoolRunsKeysInParallel(t *testing.T) {
+	p := New("test", 2, 0)
+	block := make(chan struct{})
+	started := make(chan string, 2)
+	// 找到分配到不同 worker 的两个 key
+	keys := []string{"key0"}
+	for i := 1; len(keys) < 2; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if p.index(key) != p.index(keys[0]) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		p.Submit(key, func() {
+			started <- key
+			<-block
+		})
+	}
+	for range keys {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("tasks of different keys should run in parallel")
+		}
+	}
+	close(block)
+	p.Shutdown(context.Background())
+}
+
+func TestPoolShutdownTimeoutAndPanic(t *testing.T) {
+	p := New("test", 1, 1)
+	p.Submit("a", func() { panic("boom") })
+	block := make(chan struct{})
+	p.Submit("a", func() { <-block })
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown: err = %v, want DeadlineExceeded", err)
+	}
+	close(block)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolTrySubmit(t *testing.T) {
+	p := New("test", 1, 1)
+	block := make(chan struct{})
+	started := make(chan struct{})
+	if err := p.TrySubmit("a", func() { close(started); <-block }); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := p.TrySubmit("a", func() {}); err != nil {
+		t.Fatalf("queue has room: err = %v", err)
+	}
+	if err := p.TrySubmit("a", func() {}); err != ErrPoolFull {
+		t.Fatalf("queue is full: err = %v, want ErrPoolFull", err)
+	}
+	close(block)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.TrySubmit("a", func() {}); err != ErrPoolClosed {
+		t.Fatalf("TrySubmit after shutdown: err = %v, want ErrPoolClosed", err)
+	}
+}
@@ -0,0 +1,264 @@
+package plugin
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wechat-robot-client/interface/plugin"
+)
+
+// CommandRouterName 命令路由本身也是一个插件，可以按聊天启用/禁用
+const CommandRouterName = "Command"
+
+// 过期的冷却记录每隔一段时间清理一次
+const cooldownSweepInterval = time.Minute
+
+type registeredCommand struct {
+	pluginName string
+	command    *plugin.Command
+}
+
+// CommandRouter 统一解析和分发命令，负责参数解析、权限校验、冷却时间以及自动生成帮助信息
+type CommandRouter struct {
+	mu        sync.RWMutex
+	commands  []registeredCommand
+	cooldowns sync.Map // 冷却记录，值为冷却结束时间
+	lastSweep atomic.Int64
+}
+
+func NewCommandRouter() *CommandRouter {
+	r := &CommandRouter{}
+	r.Register(CommandRouterName, &plugin.Command{
+		Prefix:      "#",
+		Name:        "帮助",
+		Aliases:     []string{"help"},
+		Description: "查看当前聊天中可以使用的命令",
+		Handler:     r.help,
+	})
+	return r
+}
+
+// Register 注册命令，pluginName 为命令所属的插件，插件在当前聊天中被禁用时命令也不可用
+func (r *CommandRouter) Register(pluginName string, commands ...*plugin.Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, command := range commands {
+		if trigger, existing := r.findConflict(command); existing != nil {
+			log.Printf("命令 %s 已被插件 %s 注册，插件 %s 的同名命令将被忽略", trigger, existing.pluginName, pluginName)
+			continue
+		}
+		r.commands = append(r.commands, registeredCommand{pluginName: pluginName, command: command})
+	}
+}
+
+// Unregister 注销插件注册的所有命令
+func (r *CommandRouter) Unregister(pluginName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	commands := r.commands[:0]
+	for _, c := range r.commands {
+		if c.pluginName != pluginName {
+			commands = append(commands, c)
+		}
+	}
+	r.commands = commands
+}
+
+// findConflict 查找与命令的任意一个触发词冲突的已注册命令
+func (r *CommandRouter) findConflict(command *plugin.Command) (string, *registeredCommand) {
+	for _, trigger := range command.Triggers() {
+		if existing := r.find(trigger, command.Scope); existing != nil {
+			return trigger, existing
+		}
+	}
+	return "", nil
+}
+
+// find 查找与触发词冲突的命令，只能在群聊和只能在私聊的同名命令互不冲突
+func (r *CommandRouter) find(trigger string, scope plugin.CommandScope) *registeredCommand {
+	for i, c := range r.commands {
+		if scope != plugin.CommandScopeAll && c.command.Scope != plugin.CommandScopeAll && scope != c.command.Scope {
+			continue
+		}
+		for _, t := range c.command.Triggers() {
+			if t == trigger {
+				return &r.commands[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Match 找到消息对应的、在当前聊天中可用的命令，有多个命令匹配时取触发词最长的，同样长度的取先注册的
+func (r *CommandRouter) Match(ctx *plugin.MessageContext, content string) (pluginName string, command *plugin.Command, rest string, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	matchedLen := 0
+	for _, c := range r.commands {
+		if !r.usable(ctx, c.pluginName, c.command) {
+			continue
+		}
+		for _, trigger := range c.command.Triggers() {
+			if len(trigger) <= matchedLen || !strings.HasPrefix(content, trigger) {
+				continue
+			}
+			remain := strings.TrimSpace(content[len(trigger):])
+			// 没有参数的命令必须完全匹配，避免 "摸鱼" 匹配到 "摸鱼视频"
+			if remain != "" && len(c.command.Args) == 0 {
+				continue
+			}
+			pluginName, command, rest, ok = c.pluginName, c.command, remain, true
+			matchedLen = len(trigger)
+		}
+	}
+	return
+}
+
+// ParseArgs 按命令声明的参数解析
+func ParseArgs(command *plugin.Command, rest string) (plugin.CommandArgs, error) {
+	args := plugin.CommandArgs{}
+	fields := strings.Fields(rest)
+	for i, arg := range command.Args {
+		if arg.Type == plugin.CommandArgText {
+			if i < len(fields) {
+				args[arg.Name] = strings.Join(fields[i:], " ")
+			} else if arg.Required {
+				return nil, fmt.Errorf("缺少参数 %s", arg.Name)
+			}
+			break
+		}
+		if i >= len(fields) {
+			if arg.Required {
+				return nil, fmt.Errorf("缺少参数 %s", arg.Name)
+			}
+			continue
+		}
+		switch arg.Type {
+		case plugin.CommandArgInt:
+			value, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return nil, fmt.Errorf("参数 %s 必须是整数", arg.Name)
+			}
+			args[arg.Name] = value
+		default:
+			args[arg.Name] = fields[i]
+		}
+	}
+	return args, nil
+}
+
+// Available 当前聊天中可以使用的命令，用于生成帮助信息
+func (r *CommandRouter) Available(ctx *plugin.MessageContext) []*plugin.Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	commands := make([]*plugin.Command, 0, len(r.commands))
+	for _, c := range r.commands {
+		if c.command.Hidden || !r.usable(ctx, c.pluginName, c.command) || !c.command.Allowed(ctx) {
+			continue
+		}
+		commands = append(commands, c.command)
+	}
+	return commands
+}
+
+func (r *CommandRouter) usable(ctx *plugin.MessageContext, pluginName string, command *plugin.Command) bool {
+	if !command.InScope(ctx) {
+		return false
+	}
+	return ctx.Settings == nil || ctx.Settings.IsPluginEnabled(pluginName)
+}
+
+// inCooldown 判断命令是否在冷却中，不在冷却中时记录本次使用时间
+func (r *CommandRouter) inCooldown(ctx *plugin.MessageContext, pluginName string, command *plugin.Command) bool {
+	if command.Cooldown <= 0 {
+		return false
+	}
+	key := fmt.Sprintf("%s:%s:%s:%s", pluginName, command.Name, ctx.Message.FromWxID, ctx.Message.SenderWxID)
+	now := time.Now()
+	r.sweepCooldowns(now)
+	if expiry, ok := r.cooldowns.Load(key); ok && now.Before(expiry.(time.Time)) {
+		return true
+	}
+	r.cooldowns.Store(key, now.Add(command.Cooldown))
+	return false
+}
+
+// sweepCooldowns 删除已经过了冷却时间的记录
+func (r *CommandRouter) sweepCooldowns(now time.Time) {
+	last := r.lastSweep.Load()
+	if now.UnixNano()-last < int64(cooldownSweepInterval) || !r.lastSweep.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	r.cooldowns.Range(func(key, expiry any) bool {
+		if !now.Before(expiry.(time.Time)) {
+			r.cooldowns.Delete(key)
+		}
+		return true
+	})
+}
+
+func (r *CommandRouter) reply(ctx *plugin.MessageContext, content string) {
+	if ctx.Message.IsChatRoom {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, content, ctx.Message.SenderWxID)
+	} else {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, content)
+	}
+}
+
+func (r *CommandRouter) help(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	commands := r.Available(ctx)
+	lines := make([]string, 0, len(commands)+1)
+	lines = append(lines, "可用命令：")
+	for _, command := range commands {
+		lines = append(lines, command.HelpLine())
+	}
+	r.reply(ctx, strings.Join(lines, "\n"))
+}
+
+func (r *CommandRouter) GetName() string {
+	return CommandRouterName
+}
+
+func (r *CommandRouter) GetLabels() []string {
+	return []string{"text"}
+}
+
+func (r *CommandRouter) PreAction(ctx *plugin.MessageContext) bool {
+	return ctx.Message != nil
+}
+
+func (r *CommandRouter) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+// Run 匹配到命令时执行命令并中止插件链，命令不可用时交给后续插件处理
+func (r *CommandRouter) Run(ctx *plugin.MessageContext) bool {
+	content := strings.TrimSpace(ctx.MessageContent)
+	pluginName, command, rest, ok := r.Match(ctx, content)
+	if !ok {
+		return false
+	}
+	if !command.Allowed(ctx) {
+		if command.MinRole > plugin.RoleMember {
+			r.reply(ctx, fmt.Sprintf("你没有权限使用该命令，需要%s及以上权限", command.MinRole))
+		} else {
+			r.reply(ctx, "你没有权限使用该命令")
+		}
+		return true
+	}
+	if r.inCooldown(ctx, pluginName, command) {
+		log.Printf("命令 %s 冷却中，忽略来自 %s 的消息", command.Name, ctx.Message.SenderWxID)
+		return true
+	}
+	args, err := ParseArgs(command, rest)
+	if err != nil {
+		r.reply(ctx, fmt.Sprintf("%s，用法：%s", err.Error(), command.Usage()))
+		return true
+	}
+	command.Handler(ctx, args)
+	return true
+}
@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+)
+
+type replyRecorder struct {
+	plugin.MessageServiceIface
+	texts []string
+	role  plugin.Role
+}
+
+func (s *replyRecorder) GetSenderRole(message *model.Message) plugin.Role {
+	return s.role
+}
+
+func (s *replyRecorder) SendTextMessage(toWxID, content string, at ...string) error {
+	s.texts = append(s.texts, content)
+	return nil
+}
+
+func newCommandContext(content string, isChatRoom bool, svc *replyRecorder) *plugin.MessageContext {
+	return &plugin.MessageContext{
+		Message:        &model.Message{FromWxID: "123@chatroom", SenderWxID: "wxid_sender", IsChatRoom: isChatRoom},
+		MessageContent: content,
+		MessageService: svc,
+	}
+}
+
+func TestCommandMatch(t *testing.T) {
+	r := NewCommandRouter()
+	r.Register("moyu", &plugin.Command{Name: "摸鱼"}, &plugin.Command{Name: "摸鱼视频"})
+	r.Register("express", &plugin.Command{Name: "快递", Args: []plugin.CommandArg{{Name: "单号", Type: plugin.CommandArgText, Required: true}}})
+	r.Register("friend", &plugin.Command{Prefix: "#", Name: "进入AI绘图", Scope: plugin.CommandScopeFriend})
+	r.Register("chatroom", &plugin.Command{Prefix: "#", Name: "进入AI绘图", Scope: plugin.CommandScopeChatRoom})
+	// 和已注册命令冲突的命令整个被忽略
+	r.Register("duplicate", &plugin.Command{Name: "打卡", Aliases: []string{"摸鱼"}})
+
+	cases := []struct {
+		content    string
+		isChatRoom bool
+		plugin     string
+		rest       string
+	}{
+		{"摸鱼", true, "moyu", ""},
+		{"摸鱼视频", true, "moyu", ""},
+		{"摸鱼 一下", true, "", ""},
+		{"快递 SF123", true, "express", "SF123"},
+		{"#进入AI绘图", false, "friend", ""},
+		{"#进入AI绘图", true, "chatroom", ""},
+		{"#help", true, CommandRouterName, ""},
+		{"打卡", true, "", ""},
+	}
+	for _, c := range cases {
+		pluginName, _, rest, ok := r.Match(newCommandContext(c.content, c.isChatRoom, nil), c.content)
+		if pluginName != c.plugin || rest != c.rest || ok != (c.plugin != "") {
+			t.Errorf("Match(%q) = %q, %q, %v; want %q, %q", c.content, pluginName, rest, ok, c.plugin, c.rest)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	command := &plugin.Command{
+		Name: "举牌",
+		Args: []plugin.CommandArg{
+			{Name: "次数", Type: plugin.CommandArgInt, Required: true},
+			{Name: "内容", Type: plugin.CommandArgText},
+		},
+	}
+	args, err := ParseArgs(command, "3 摸鱼 快乐")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Int("次数") != 3 || args.String("内容") != "摸鱼 快乐" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+	if _, err := ParseArgs(command, "abc"); err == nil {
+		t.Fatal("expected int parse error")
+	}
+	if _, err := ParseArgs(command, ""); err == nil {
+		t.Fatal("expected missing arg error")
+	}
+	if args, err := ParseArgs(command, "1"); err != nil || args.Has("内容") {
+		t.Fatalf("optional arg: args = %v, err = %v", args, err)
+	}
+}
+
+func TestCommandRouterRun(t *testing.T) {
+	r := NewCommandRouter()
+	var calls int
+	r.Register("demo",
+		&plugin.Command{Name: "签到", Description: "每日签到", Cooldown: time.Minute, Handler: func(ctx *plugin.MessageContext, args plugin.CommandArgs) { calls++ }},
+		&plugin.Command{Name: "管理", Permission: func(ctx *plugin.MessageContext) bool { return false }, Handler: func(ctx *plugin.MessageContext, args plugin.CommandArgs) { calls++ }},
+		&plugin.Command{Name: "隐藏", Hidden: true, Handler: func(ctx *plugin.MessageContext, args plugin.CommandArgs) {}},
+	)
+	svc := &replyRecorder{}
+
+	if !r.Run(newCommandContext("签到", true, svc)) || !r.Run(newCommandContext("签到", true, svc)) || calls != 1 {
+		t.Fatalf("cooldown: calls = %d, want 1", calls)
+	}
+	if !r.Run(newCommandContext("管理", true, svc)) || calls != 1 || svc.texts[len(svc.texts)-1] != "你没有权限使用该命令" {
+		t.Fatalf("permission: calls = %d, texts = %v", calls, svc.texts)
+	}
+	if r.Run(newCommandContext("你好", true, svc)) {
+		t.Fatal("non-command message should not abort")
+	}
+
+	r.Run(newCommandContext("#帮助", true, svc))
+	help := svc.texts[len(svc.texts)-1]
+	if !strings.Contains(help, "签到 - 每日签到") || strings.Contains(help, "管理") || strings.Contains(help, "隐藏") {
+		t.Fatalf("unexpected help: %s", help)
+	}
+}
+
+func TestCommandMinRole(t *testing.T) {
+	r := NewCommandRouter()
+	var calls int
+	r.Register("admin", &plugin.Command{Name: "关闭AI", MinRole: plugin.RoleChatRoomAdmin, Handler: func(ctx *plugin.MessageContext, args plugin.CommandArgs) { calls++ }})
+
+	member := &replyRecorder{role: plugin.RoleMember}
+	if !r.Run(newCommandContext("关闭AI", true, member)) || calls != 0 {
+		t.Fatalf("member: calls = %d, want 0", calls)
+	}
+	if got, want := member.texts[0], "你没有权限使用该命令，需要群管理员及以上权限"; got != want {
+		t.Fatalf("reply = %q, want %q", got, want)
+	}
+	for _, role := range []plugin.Role{plugin.RoleChatRoomAdmin, plugin.RoleChatRoomOwner, plugin.RoleSuperAdmin, plugin.RoleOwner} {
+		r.Run(newCommandContext("关闭AI", true, &replyRecorder{role: role}))
+	}
+	if calls != 4 {
+		t.Fatalf("admins: calls = %d, want 4", calls)
+	}
+}
+
+func TestCommandCooldownSweep(t *testing.T) {
+	r := NewCommandRouter()
+	command := &plugin.Command{Name: "签到", Cooldown: time.Millisecond}
+	ctx := newCommandContext("签到", true, nil)
+	if r.inCooldown(ctx, "checkin", command) {
+		t.Fatal("first use should not be in cooldown")
+	}
+	time.Sleep(2 * time.Millisecond)
+	r.sweepCooldowns(time.Now().Add(cooldownSweepInterval))
+	if _, ok := r.cooldowns.Load("checkin:签到:123@chatroom:wxid_sender"); ok {
+		t.Fatal("expired cooldown should be deleted")
+	}
+}
@@ -0,0 +1,150 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+type ASRProvider string
+
+const (
+	ASRProviderOpenAI  ASRProvider = "openai"  // OpenAI 兼容的 /audio/transcriptions 接口
+	ASRProviderWhisper ASRProvider = "whisper" // 本地部署的 whisper.cpp server，使用 /inference 接口
+)
+
+type ASRConfig struct {
+	Provider ASRProvider `json:"provider"`
+	BaseURL  string      `json:"base_url"`
+	APIKey   string      `json:"api_key"`
+	Model    string      `json:"model"`
+	Language string      `json:"language"`
+	// 单次识别超时时间(秒)
+	Timeout int `json:"timeout"`
+}
+
+// ASR 语音转文字
+type ASR interface {
+	Transcribe(ctx context.Context, audio io.Reader, filename string) (string, error)
+}
+
+const (
+	defaultASRModel   = "whisper-1"
+	defaultASRTimeout = 60 * time.Second
+)
+
+func NewASR(config ASRConfig) (ASR, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("语音转文字接口地址不能为空")
+	}
+	switch config.Provider {
+	case "", ASRProviderOpenAI:
+		return &openAIASR{config: config}, nil
+	case ASRProviderWhisper:
+		return &whisperServerASR{config: config}, nil
+	default:
+		return nil, fmt.Errorf("不支持的语音转文字服务: %s", config.Provider)
+	}
+}
+
+func (c ASRConfig) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Second
+	}
+	return defaultASRTimeout
+}
+
+type openAIASR struct {
+	config ASRConfig
+}
+
+func (a *openAIASR) Transcribe(ctx context.Context, audio io.Reader, filename string) (string, error) {
+	openaiConfig := openai.DefaultConfig(a.config.APIKey)
+	openaiConfig.BaseURL = a.config.BaseURL
+	client := openai.NewClientWithConfig(openaiConfig)
+
+	model := a.config.Model
+	if model == "" {
+		model = defaultASRModel
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.config.timeout())
+	defer cancel()
+	resp, err := client.CreateTranscription(ctx, openai.AudioRequest{
+		Model:    model,
+		FilePath: filename,
+		Reader:   audio,
+		Language: a.config.Language,
+		Format:   openai.AudioResponseFormatJSON,
+	})
+	if err != nil {
+		return "", fmt.Errorf("语音转文字请求失败: %w", err)
+	}
+	return strings.TrimSpace(resp.Text), nil
+}
+
+type whisperServerASR struct {
+	config ASRConfig
+}
+
+type whisperServerResponse struct {
+	Text  string `json:"text"`
+	Error string `json:"error"`
+}
+
+func (a *whisperServerASR) Transcribe(ctx context.Context, audio io.Reader, filename string) (string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(part, audio); err != nil {
+		return "", err
+	}
+	_ = writer.WriteField("response_format", "json")
+	if a.config.Language != "" {
+		_ = writer.WriteField("language", a.config.Language)
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.config.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(a.config.BaseURL, "/")+"/inference", body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if a.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.config.APIKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("语音转文字请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("语音转文字请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
+	}
+	var result whisperServerResponse
+	if err = json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("解析语音转文字结果失败: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("语音转文字失败: %s", result.Error)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
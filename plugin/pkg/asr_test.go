@@ -0,0 +1,67 @@
+package pkg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestASRProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart form: %v", err)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("form file: %v", err)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "voice.wav" || string(data) != "RIFF" {
+			t.Errorf("unexpected upload %q: %q", header.Filename, data)
+		}
+		switch r.URL.Path {
+		case "/v1/audio/transcriptions":
+			if r.FormValue("model") != "whisper-1" || r.Header.Get("Authorization") != "Bearer sk-test" {
+				t.Errorf("unexpected openai request: model=%q auth=%q", r.FormValue("model"), r.Header.Get("Authorization"))
+			}
+			w.Write([]byte(`{"text":" 你好 "}`))
+		case "/inference":
+			if r.FormValue("language") != "zh" {
+				t.Errorf("language = %q, want zh", r.FormValue("language"))
+			}
+			w.Write([]byte(`{"text":"今天天气怎么样"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		config ASRConfig
+		want   string
+	}{
+		{ASRConfig{BaseURL: server.URL + "/v1", APIKey: "sk-test"}, "你好"},
+		{ASRConfig{Provider: ASRProviderWhisper, BaseURL: server.URL, Language: "zh"}, "今天天气怎么样"},
+	}
+	for _, c := range cases {
+		asr, err := NewASR(c.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := asr.Transcribe(context.Background(), strings.NewReader("RIFF"), "voice.wav")
+		if err != nil {
+			t.Fatalf("%s: %v", c.config.Provider, err)
+		}
+		if text != c.want {
+			t.Fatalf("%s: text = %q, want %q", c.config.Provider, text, c.want)
+		}
+	}
+
+	if _, err := NewASR(ASRConfig{Provider: "unknown", BaseURL: server.URL}); err == nil {
+		t.Fatal("expected unknown provider error")
+	}
+}
@@ -1,15 +1,269 @@
 package plugin
 
-import "wechat-robot-client/interface/plugin"
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"wechat-robot-client/interface/plugin"
+)
+
+// 插件优先级，数值越小越先执行
+const (
+	PriorityHighest = 0
+	PriorityHigh    = 100
+	PriorityKeyword = 300 // 关键词触发类插件
+	PriorityNormal  = 500
+	PriorityLow     = 900
+)
+
+type registeredPlugin struct {
+	handler  plugin.MessageHandler
+	priority int
+}
+
+// Registration 待注册的插件及其优先级
+type Registration struct {
+	Handler  plugin.MessageHandler
+	Priority int
+}
 
 type MessagePlugin struct {
-	Plugins []plugin.MessageHandler
+	mu       sync.RWMutex
+	plugins  []registeredPlugin
+	commands *CommandRouter
+	tools    *ToolRegistry
 }
 
 func NewMessagePlugin() *MessagePlugin {
-	return &MessagePlugin{}
+	return &MessagePlugin{
+		commands: NewCommandRouter(),
+		tools:    NewToolRegistry(),
+	}
+}
+
+// Commands 返回命令路由，命令路由需要作为插件注册后才会生效
+func (mp *MessagePlugin) Commands() *CommandRouter {
+	return mp.commands
+}
+
+// Tools 返回AI聊天时可以调用的工具
+func (mp *MessagePlugin) Tools() *ToolRegistry {
+	return mp.tools
+}
+
+// Register 注册插件，priority 数值越小越先执行，相同优先级按注册顺序执行
+// 插件实现了 CommandProvider、ToolProvider 时，插件声明的命令、工具会一起注册
+func (mp *MessagePlugin) Register(handler plugin.MessageHandler, priority int) {
+	if provider, ok := handler.(plugin.CommandProvider); ok {
+		mp.commands.Register(handler.GetName(), provider.GetCommands()...)
+	}
+	if provider, ok := handler.(plugin.ToolProvider); ok {
+		mp.tools.Register(handler.GetName(), provider.GetTools()...)
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.plugins = append(mp.plugins, registeredPlugin{handler: handler, priority: priority})
+	sort.SliceStable(mp.plugins, func(i, j int) bool {
+		return mp.plugins[i].priority < mp.plugins[j].priority
+	})
+}
+
+// RegisterTools 只注册插件声明的工具，用于不作为消息插件注册、只在AI聊天时被调用的插件
+func (mp *MessagePlugin) RegisterTools(handler plugin.MessageHandler) {
+	if provider, ok := handler.(plugin.ToolProvider); ok {
+		mp.tools.Register(handler.GetName(), provider.GetTools()...)
+	}
+}
+
+// Unregister 按名称注销插件，插件不存在时返回 false
+func (mp *MessagePlugin) Unregister(name string) bool {
+	mp.commands.Unregister(name)
+	mp.tools.Unregister(name)
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	for i, p := range mp.plugins {
+		if p.handler.GetName() == name {
+			mp.plugins = slices.Delete(mp.plugins, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceAll 注销 match 匹配的所有插件并注册 registrations，插件列表在同一次加锁中完成替换，
+// 替换期间分发的消息要么看到全部旧插件，要么看到全部新插件。和其他插件重名的插件不会注册，返回这些插件的名称
+func (mp *MessagePlugin) ReplaceAll(match func(handler plugin.MessageHandler) bool, registrations []Registration) (conflicts []string) {
+	var removed, added []plugin.MessageHandler
+	mp.mu.Lock()
+	plugins := make([]registeredPlugin, 0, len(mp.plugins)+len(registrations))
+	names := make(map[string]struct{}, len(mp.plugins)+len(registrations))
+	for _, p := range mp.plugins {
+		if match(p.handler) {
+			removed = append(removed, p.handler)
+			continue
+		}
+		plugins = append(plugins, p)
+		names[p.handler.GetName()] = struct{}{}
+	}
+	for _, r := range registrations {
+		name := r.Handler.GetName()
+		if _, ok := names[name]; ok {
+			conflicts = append(conflicts, name)
+			continue
+		}
+		names[name] = struct{}{}
+		plugins = append(plugins, registeredPlugin{handler: r.Handler, priority: r.Priority})
+		added = append(added, r.Handler)
+	}
+	sort.SliceStable(plugins, func(i, j int) bool {
+		return plugins[i].priority < plugins[j].priority
+	})
+	mp.plugins = plugins
+	mp.mu.Unlock()
+
+	for _, handler := range removed {
+		mp.commands.Unregister(handler.GetName())
+		mp.tools.Unregister(handler.GetName())
+	}
+	for _, handler := range added {
+		if provider, ok := handler.(plugin.CommandProvider); ok {
+			mp.commands.Register(handler.GetName(), provider.GetCommands()...)
+		}
+		if provider, ok := handler.(plugin.ToolProvider); ok {
+			mp.tools.Register(handler.GetName(), provider.GetTools()...)
+		}
+	}
+	return conflicts
+}
+
+// Plugins 返回按优先级排序后的插件列表
+func (mp *MessagePlugin) Plugins() []plugin.MessageHandler {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	handlers := make([]plugin.MessageHandler, 0, len(mp.plugins))
+	for _, p := range mp.plugins {
+		handlers = append(handlers, p.handler)
+	}
+	return handlers
+}
+
+// GetPriority 获取插件的优先级
+func (mp *MessagePlugin) GetPriority(name string) (int, bool) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	for _, p := range mp.plugins {
+		if p.handler.GetName() == name {
+			return p.priority, true
+		}
+	}
+	return 0, false
+}
+
+// Dispatch 按优先级将消息分发给带有指定标签的插件
+// 在当前聊天中被禁用的插件、发送者角色低于插件要求的插件不会执行；PreAction 返回 false 的插件会被跳过；Run 返回 true 或者发生 panic 时中止插件链；
+// 只要 PreAction 通过，PostAction 一定会被执行
+func (mp *MessagePlugin) Dispatch(ctx *plugin.MessageContext, label string) *plugin.MessageTrace {
+	mp.mu.RLock()
+	plugins := make([]registeredPlugin, len(mp.plugins))
+	copy(plugins, mp.plugins)
+	mp.mu.RUnlock()
+
+	trace := &plugin.MessageTrace{
+		Label:     label,
+		StartedAt: time.Now(),
+	}
+	if ctx.Message != nil {
+		trace.MsgID = ctx.Message.MsgId
+	}
+	ctx.Trace = trace
+
+	for _, p := range plugins {
+		if !slices.Contains(p.handler.GetLabels(), label) {
+			continue
+		}
+		entry := mp.runPlugin(ctx, p)
+		trace.Entries = append(trace.Entries, entry)
+		if entry.Status == plugin.TraceStatusAborted || entry.Status == plugin.TraceStatusPanic {
+			trace.StoppedBy = entry.Plugin
+			break
+		}
+	}
+	trace.Duration = time.Since(trace.StartedAt)
+	if len(trace.Entries) > 0 {
+		log.Println(trace.String())
+	}
+	return trace
+}
+
+func (mp *MessagePlugin) runPlugin(ctx *plugin.MessageContext, p registeredPlugin) (entry plugin.TraceEntry) {
+	name := p.handler.GetName()
+	entry = plugin.TraceEntry{
+		Plugin:   name,
+		Priority: p.priority,
+	}
+	startedAt := time.Now()
+	defer func() {
+		entry.Duration = time.Since(startedAt)
+	}()
+
+	if ctx.Settings != nil && !ctx.Settings.IsPluginEnabled(name) {
+		entry.Status = plugin.TraceStatusDisabled
+		entry.Reason = "当前聊天已禁用该插件"
+		return
+	}
+	if restricted, ok := p.handler.(plugin.RoleRestricted); ok && ctx.SenderRole() < restricted.GetMinRole() {
+		entry.Status = plugin.TraceStatusSkipped
+		entry.Reason = fmt.Sprintf("需要%s及以上权限", restricted.GetMinRole())
+		return
+	}
+
+	var pass bool
+	if err := safeCall(name, "PreAction", func() { pass = p.handler.PreAction(ctx) }); err != nil {
+		entry.Status = plugin.TraceStatusPanic
+		entry.Reason = err.Error()
+		return
+	}
+	if !pass {
+		entry.Status = plugin.TraceStatusSkipped
+		entry.Reason = "PreAction 未通过"
+		return
+	}
+
+	// PreAction 通过后，无论 Run 是否中止或者 panic，都要执行 PostAction
+	defer func() {
+		if err := safeCall(name, "PostAction", func() { p.handler.PostAction(ctx) }); err != nil && entry.Status != plugin.TraceStatusPanic {
+			entry.Status = plugin.TraceStatusPanic
+			entry.Reason = err.Error()
+		}
+	}()
+
+	var abort bool
+	if err := safeCall(name, "Run", func() { abort = p.handler.Run(ctx) }); err != nil {
+		entry.Status = plugin.TraceStatusPanic
+		entry.Reason = err.Error()
+		return
+	}
+	if abort {
+		entry.Status = plugin.TraceStatusAborted
+		entry.Reason = "Run 返回 true"
+		return
+	}
+	entry.Status = plugin.TraceStatusPassed
+	return
 }
 
-func (mp *MessagePlugin) Register(handler plugin.MessageHandler) {
-	mp.Plugins = append(mp.Plugins, handler)
+// safeCall 执行插件方法，将 panic 转换为 error，避免单个插件导致整个客户端崩溃
+func safeCall(pluginName, method string, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("插件 %s 执行 %s 发生 panic: %v\n%s", pluginName, method, r, debug.Stack())
+			err = fmt.Errorf("%s panic: %v", method, r)
+		}
+	}()
+	fn()
+	return nil
 }
@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"slices"
+	"testing"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+)
+
+type testPlugin struct {
+	name      string
+	labels    []string
+	pre       bool
+	abort     bool
+	panicRun  bool
+	calls     *[]string
+	postCalls *[]string
+}
+
+func (p *testPlugin) GetName() string     { return p.name }
+func (p *testPlugin) GetLabels() []string { return p.labels }
+func (p *testPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	return p.pre
+}
+func (p *testPlugin) PostAction(ctx *plugin.MessageContext) {
+	*p.postCalls = append(*p.postCalls, p.name)
+}
+func (p *testPlugin) Run(ctx *plugin.MessageContext) bool {
+	*p.calls = append(*p.calls, p.name)
+	if p.panicRun {
+		panic("boom")
+	}
+	return p.abort
+}
+
+func TestDispatchPriorityAndAbort(t *testing.T) {
+	var calls, postCalls []string
+	mp := NewMessagePlugin()
+	mp.Register(&testPlugin{name: "low", labels: []string{"text"}, pre: true, calls: &calls, postCalls: &postCalls}, PriorityLow)
+	mp.Register(&testPlugin{name: "high", labels: []string{"text"}, pre: true, calls: &calls, postCalls: &postCalls}, PriorityHigh)
+	mp.Register(&testPlugin{name: "skipped", labels: []string{"text"}, pre: false, calls: &calls, postCalls: &postCalls}, PriorityHigh)
+	mp.Register(&testPlugin{name: "image", labels: []string{"image"}, pre: true, calls: &calls, postCalls: &postCalls}, PriorityHighest)
+	mp.Register(&testPlugin{name: "normal", labels: []string{"text"}, pre: true, abort: true, calls: &calls, postCalls: &postCalls}, PriorityNormal)
+
+	ctx := &plugin.MessageContext{Message: &model.Message{MsgId: 1}}
+	trace := mp.Dispatch(ctx, "text")
+
+	if got, want := calls, []string{"high", "normal"}; !slices.Equal(got, want) {
+		t.Fatalf("Run calls = %v, want %v", got, want)
+	}
+	if got, want := postCalls, []string{"high", "normal"}; !slices.Equal(got, want) {
+		t.Fatalf("PostAction calls = %v, want %v", got, want)
+	}
+	if trace.StoppedBy != "normal" {
+		t.Fatalf("StoppedBy = %q, want %q", trace.StoppedBy, "normal")
+	}
+	if len(trace.Entries) != 3 || trace.Entries[1].Status != plugin.TraceStatusSkipped {
+		t.Fatalf("unexpected trace entries: %+v", trace.Entries)
+	}
+	if ctx.Trace != trace {
+		t.Fatalf("trace not attached to message context")
+	}
+}
+
+func TestDispatchRecoversPanic(t *testing.T) {
+	var calls, postCalls []string
+	mp := NewMessagePlugin()
+	mp.Register(&testPlugin{name: "panic", labels: []string{"text"}, pre: true, panicRun: true, calls: &calls, postCalls: &postCalls}, PriorityHigh)
+	mp.Register(&testPlugin{name: "next", labels: []string{"text"}, pre: true, calls: &calls, postCalls: &postCalls}, PriorityNormal)
+
+	trace := mp.Dispatch(&plugin.MessageContext{}, "text")
+
+	if trace.StoppedBy != "panic" || trace.Entries[0].Status != plugin.TraceStatusPanic {
+		t.Fatalf("unexpected trace: %+v", trace)
+	}
+	if got, want := postCalls, []string{"panic"}; !slices.Equal(got, want) {
+		t.Fatalf("PostAction calls = %v, want %v", got, want)
+	}
+}
+
+type restrictedPlugin struct {
+	testPlugin
+	minRole plugin.Role
+}
+
+func (p *restrictedPlugin) GetMinRole() plugin.Role { return p.minRole }
+
+func TestDispatchSkipsRestrictedPlugin(t *testing.T) {
+	var calls, postCalls []string
+	mp := NewMessagePlugin()
+	mp.Register(&restrictedPlugin{testPlugin: testPlugin{name: "admin", labels: []string{"text"}, pre: true, calls: &calls, postCalls: &postCalls}, minRole: plugin.RoleSuperAdmin}, PriorityHigh)
+	mp.Register(&testPlugin{name: "normal", labels: []string{"text"}, pre: true, calls: &calls, postCalls: &postCalls}, PriorityNormal)
+
+	trace := mp.Dispatch(&plugin.MessageContext{Message: &model.Message{MsgId: 1}, MessageService: &replyRecorder{role: plugin.RoleChatRoomOwner}}, "text")
+	if got, want := calls, []string{"normal"}; !slices.Equal(got, want) {
+		t.Fatalf("Run calls = %v, want %v", got, want)
+	}
+	if trace.Entries[0].Status != plugin.TraceStatusSkipped {
+		t.Fatalf("unexpected trace entries: %+v", trace.Entries)
+	}
+
+	calls = nil
+	mp.Dispatch(&plugin.MessageContext{Message: &model.Message{MsgId: 2}, MessageService: &replyRecorder{role: plugin.RoleOwner}}, "text")
+	if got, want := calls, []string{"admin", "normal"}; !slices.Equal(got, want) {
+		t.Fatalf("Run calls = %v, want %v", got, want)
+	}
+}
+
+// dynamicPlugin 模拟从数据库加载的插件
+type dynamicPlugin struct {
+	testPlugin
+}
+
+func TestReplaceAll(t *testing.T) {
+	var calls, postCalls []string
+	mp := NewMessagePlugin()
+	mp.Register(&testPlugin{name: "builtin", labels: []string{"text"}, pre: true, calls: &calls, postCalls: &postCalls}, PriorityNormal)
+	mp.Register(&dynamicPlugin{testPlugin: testPlugin{name: "old", labels: []string{"text"}, pre: true, calls: &calls, postCalls: &postCalls}}, PriorityHigh)
+
+	isDynamic := func(handler plugin.MessageHandler) bool {
+		_, ok := handler.(*dynamicPlugin)
+		return ok
+	}
+	conflicts := mp.ReplaceAll(isDynamic, []Registration{
+		{Handler: &dynamicPlugin{testPlugin: testPlugin{name: "new", labels: []string{"text"}, pre: true, calls: &calls, postCalls: &postCalls}}, Priority: PriorityLow},
+		{Handler: &dynamicPlugin{testPlugin: testPlugin{name: "builtin", labels: []string{"text"}, pre: true, calls: &calls, postCalls: &postCalls}}, Priority: PriorityHighest},
+	})
+
+	if want := []string{"builtin"}; !slices.Equal(conflicts, want) {
+		t.Fatalf("conflicts = %v, want %v", conflicts, want)
+	}
+	var names []string
+	for _, handler := range mp.Plugins() {
+		names = append(names, handler.GetName())
+	}
+	if want := []string{"builtin", "new"}; !slices.Equal(names, want) {
+		t.Fatalf("plugins = %v, want %v", names, want)
+	}
+}
@@ -1,9 +1,20 @@
 package plugins
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"wechat-robot-client/dto"
 	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/appx"
 	"wechat-robot-client/service"
 	"wechat-robot-client/utils"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 type AIChatPlugin struct{}
@@ -57,8 +68,19 @@ func (p *AIChatPlugin) Run(ctx *plugin.MessageContext) bool {
 			return true
 		}
 	}
+	if !checkRateLimit(ctx, model.RateLimitFeatureChat) {
+		return true
+	}
+	if !checkAIQuota(ctx) {
+		return true
+	}
 	aiChatService := service.NewAIChatService(ctx.Context, ctx.Settings)
-	aiReply, err := aiChatService.Chat(aiContext)
+	tools, toolCalled := newChatTools(ctx)
+	if ctx.Settings.IsAIStreamEnabled() {
+		p.chatStream(ctx, aiChatService, aiContext, tools)
+		return true
+	}
+	aiReply, err := aiChatService.ChatWithTools(withKnowledge(ctx, withMemory(ctx, aiContext)), tools)
 	if err != nil {
 		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, err.Error())
 		return true
@@ -70,6 +92,10 @@ func (p *AIChatPlugin) Run(ctx *plugin.MessageContext) bool {
 		aiReplyText = aiReply.MultiContent[0].Text
 	}
 	if aiReplyText == "" {
+		if *toolCalled > 0 {
+			// 工具已经把结果发送给用户了
+			return true
+		}
 		aiReplyText = "AI返回了空内容。"
 	}
 	// 待处理，AI返回了图片
@@ -79,5 +105,150 @@ func (p *AIChatPlugin) Run(ctx *plugin.MessageContext) bool {
 	} else {
 		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiReplyText)
 	}
+	rememberConversation(ctx, aiContext, aiReplyText)
 	return true
 }
+
+// chatStream 流式回复，AI每生成一段内容就发送一条消息，群聊中只在第一条消息@发送者
+func (p *AIChatPlugin) chatStream(ctx *plugin.MessageContext, aiChatService *service.AIChatService, aiContext []openai.ChatCompletionMessage, tools *service.ChatTools) {
+	sent := 0
+	aiReply, err := aiChatService.ChatStream(withKnowledge(ctx, withMemory(ctx, aiContext)), tools, func(chunk string) error {
+		var err error
+		if ctx.Message.IsChatRoom && sent == 0 {
+			err = ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, chunk, ctx.Message.SenderWxID)
+		} else {
+			err = ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, chunk)
+		}
+		sent++
+		return err
+	})
+	if err != nil {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, err.Error())
+		return
+	}
+	rememberConversation(ctx, aiContext, aiReply.Content)
+}
+
+func (p *AIChatPlugin) GetTools() []*plugin.Tool {
+	return []*plugin.Tool{
+		{
+			Name:        "request_song",
+			Description: "点歌，给用户发送一首歌曲",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"song_title": {Type: jsonschema.String, Description: "歌名，可以带上歌手名"},
+				},
+				Required: []string{"song_title"},
+			},
+			Handler: p.requestSong,
+		},
+		{
+			Name:        "search_chat_history",
+			Description: "按关键词搜索当前聊天的历史消息，返回最近的20条",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"keyword": {Type: jsonschema.String, Description: "消息内容包含的关键词"},
+				},
+				Required: []string{"keyword"},
+			},
+			Handler: p.searchChatHistory,
+		},
+		{
+			Name:        "lookup_chat_room_member",
+			Description: "按昵称或备注查找当前群聊的成员，返回成员的昵称、是否管理员、入群时间和最近活跃时间",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"keyword": {Type: jsonschema.String, Description: "成员昵称或备注包含的关键词"},
+				},
+				Required: []string{"keyword"},
+			},
+			Available: func(ctx *plugin.MessageContext) bool {
+				return ctx.Message.IsChatRoom
+			},
+			Handler: p.lookupChatRoomMember,
+		},
+	}
+}
+
+func (p *AIChatPlugin) requestSong(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+	var params struct {
+		SongTitle string `json:"song_title"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil || params.SongTitle == "" {
+		return "", errors.New("歌名不能为空")
+	}
+	if err := ctx.MessageService.SendMusicMessage(ctx.Message.FromWxID, params.SongTitle); err != nil {
+		return "", err
+	}
+	return "歌曲已经发送给用户", nil
+}
+
+func (p *AIChatPlugin) searchChatHistory(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+	var params struct {
+		Keyword string `json:"keyword"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil || params.Keyword == "" {
+		return "", errors.New("关键词不能为空")
+	}
+	messages, total, err := service.NewChatHistoryService(ctx.Context).GetChatHistory(dto.ChatHistoryRequest{
+		ContactID: ctx.Message.FromWxID,
+		Keyword:   params.Keyword,
+	}, appx.Pager{PageIndex: 1, PageSize: 20})
+	if err != nil {
+		return "", err
+	}
+	if total == 0 {
+		return "没有找到相关的聊天记录", nil
+	}
+	var lines []string
+	for _, message := range messages {
+		if message.Type != model.MsgTypeText {
+			continue
+		}
+		lines = append(lines, formatChatHistoryLine(message))
+	}
+	return fmt.Sprintf("共找到 %d 条聊天记录，最近的 %d 条：\n%s", total, len(lines), strings.Join(lines, "\n")), nil
+}
+
+func (p *AIChatPlugin) lookupChatRoomMember(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+	var params struct {
+		Keyword string `json:"keyword"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil || params.Keyword == "" {
+		return "", errors.New("关键词不能为空")
+	}
+	members, total, err := service.NewChatRoomService(ctx.Context).GetChatRoomMembers(dto.ChatRoomMemberRequest{
+		ChatRoomID: ctx.Message.FromWxID,
+		Keyword:    params.Keyword,
+	}, appx.Pager{PageIndex: 1, PageSize: 20})
+	if err != nil {
+		return "", err
+	}
+	if total == 0 {
+		return "没有找到相关的群成员", nil
+	}
+	var lines []string
+	for _, member := range members {
+		line := fmt.Sprintf("昵称: %s", member.Nickname)
+		if member.Remark != "" {
+			line += fmt.Sprintf("，群备注: %s", member.Remark)
+		}
+		if member.IsAdmin {
+			line += "，群管理员"
+		}
+		if member.IsLeaved != nil && *member.IsLeaved {
+			line += "，已退群"
+		}
+		if member.JoinedAt > 0 {
+			line += fmt.Sprintf("，入群时间: %s", time.Unix(member.JoinedAt, 0).Format("2006-01-02"))
+		}
+		if member.LastActiveAt > 0 {
+			line += fmt.Sprintf("，最近活跃: %s", time.Unix(member.LastActiveAt, 0).Format("2006-01-02 15:04"))
+		}
+		lines = append(lines, line)
+	}
+	return fmt.Sprintf("共找到 %d 个群成员：\n%s", total, strings.Join(lines, "\n")), nil
+}
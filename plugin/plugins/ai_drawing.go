@@ -2,11 +2,14 @@ package plugins
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"strings"
 	"wechat-robot-client/interface/plugin"
 	"wechat-robot-client/model"
 	"wechat-robot-client/plugin/pkg"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 type AIDrawingPlugin struct{}
@@ -32,6 +35,12 @@ func (p *AIDrawingPlugin) PostAction(ctx *plugin.MessageContext) {
 }
 
 func (p *AIDrawingPlugin) Run(ctx *plugin.MessageContext) bool {
+	if !checkRateLimit(ctx, model.RateLimitFeatureDrawing) {
+		return true
+	}
+	if !checkAIQuota(ctx) {
+		return true
+	}
 	aiConfig := ctx.Settings.GetAIConfig()
 	switch aiConfig.ImageModel {
 	case model.ImageModelDoubao:
@@ -123,3 +132,32 @@ func (p *AIDrawingPlugin) Run(ctx *plugin.MessageContext) bool {
 	}
 	return true
 }
+
+func (p *AIDrawingPlugin) GetTools() []*plugin.Tool {
+	return []*plugin.Tool{
+		{
+			Name:        "draw_image",
+			Description: "根据提示词画一张图片，图片会直接发送给用户",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"prompt": {Type: jsonschema.String, Description: "画图的提示词，描述画面内容和风格"},
+				},
+				Required: []string{"prompt"},
+			},
+			Available: func(ctx *plugin.MessageContext) bool {
+				return ctx.Settings.IsAIDrawingEnabled()
+			},
+			Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+				var params struct {
+					Prompt string `json:"prompt"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil || params.Prompt == "" {
+					return "", errors.New("提示词不能为空")
+				}
+				p.Run(withMessageContent(ctx, params.Prompt))
+				return "已经开始画图，图片会直接发送给用户", nil
+			},
+		},
+	}
+}
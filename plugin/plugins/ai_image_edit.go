@@ -3,6 +3,7 @@ package plugins
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"wechat-robot-client/interface/plugin"
@@ -10,6 +11,7 @@ import (
 	"wechat-robot-client/plugin/pkg"
 	"wechat-robot-client/service"
 
+	"github.com/sashabaranov/go-openai/jsonschema"
 	doubaoModel "github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
 )
 
@@ -56,6 +58,12 @@ func (p *AImageEditPlugin) GetOSSFileURL(ctx *plugin.MessageContext) (string, er
 }
 
 func (p *AImageEditPlugin) Run(ctx *plugin.MessageContext) bool {
+	if !checkRateLimit(ctx, model.RateLimitFeatureDrawing) {
+		return true
+	}
+	if !checkAIQuota(ctx) {
+		return true
+	}
 	aiConfig := ctx.Settings.GetAIConfig()
 	switch aiConfig.ImageModel {
 	case model.ImageModelDoubao:
@@ -124,3 +132,32 @@ func (p *AImageEditPlugin) Run(ctx *plugin.MessageContext) bool {
 	}
 	return true
 }
+
+func (p *AImageEditPlugin) GetTools() []*plugin.Tool {
+	return []*plugin.Tool{
+		{
+			Name:        "edit_image",
+			Description: "按要求修改用户引用的图片，修改后的图片会直接发送给用户",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"prompt": {Type: jsonschema.String, Description: "修改图片的要求"},
+				},
+				Required: []string{"prompt"},
+			},
+			Available: func(ctx *plugin.MessageContext) bool {
+				return ctx.ReferMessage != nil && ctx.ReferMessage.Type == model.MsgTypeImage && ctx.Settings.IsAIDrawingEnabled()
+			},
+			Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+				var params struct {
+					Prompt string `json:"prompt"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil || params.Prompt == "" {
+					return "", errors.New("修改要求不能为空")
+				}
+				p.Run(withMessageContent(ctx, params.Prompt))
+				return "已经开始修改图片，图片会直接发送给用户", nil
+			},
+		},
+	}
+}
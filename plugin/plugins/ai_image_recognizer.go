@@ -2,12 +2,16 @@ package plugins
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
 	"wechat-robot-client/service"
 
 	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 type AImageRecognizerPlugin struct{}
@@ -57,6 +61,12 @@ func (p *AImageRecognizerPlugin) Run(ctx *plugin.MessageContext) bool {
 		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, "你需要引用一条图片消息。")
 		return true
 	}
+	if !checkRateLimit(ctx, model.RateLimitFeatureChat) {
+		return true
+	}
+	if !checkAIQuota(ctx) {
+		return true
+	}
 	var dataURL string
 	if ctx.ReferMessage.AttachmentUrl != "" {
 		dataURL = ctx.ReferMessage.AttachmentUrl
@@ -119,3 +129,32 @@ func (p *AImageRecognizerPlugin) Run(ctx *plugin.MessageContext) bool {
 	}
 	return true
 }
+
+func (p *AImageRecognizerPlugin) GetTools() []*plugin.Tool {
+	return []*plugin.Tool{
+		{
+			Name:        "recognize_image",
+			Description: "识别用户引用的图片，回答关于图片内容的问题，识别结果会直接发送给用户",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"question": {Type: jsonschema.String, Description: "用户关于图片的问题"},
+				},
+				Required: []string{"question"},
+			},
+			Available: func(ctx *plugin.MessageContext) bool {
+				return ctx.ReferMessage != nil && ctx.ReferMessage.Type == model.MsgTypeImage
+			},
+			Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+				var params struct {
+					Question string `json:"question"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil || params.Question == "" {
+					return "", errors.New("问题不能为空")
+				}
+				p.Run(withMessageContent(ctx, params.Question))
+				return "识别结果已经发送给用户", nil
+			},
+		},
+	}
+}
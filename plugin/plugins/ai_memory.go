@@ -0,0 +1,153 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/service"
+	"wechat-robot-client/vars"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// AIMemoryCommandPlugin 查看、清除AI记住的关于自己的信息
+type AIMemoryCommandPlugin struct{}
+
+func NewAIMemoryCommandPlugin() plugin.MessageHandler {
+	return &AIMemoryCommandPlugin{}
+}
+
+func (p *AIMemoryCommandPlugin) GetName() string {
+	return "AIMemoryCommand"
+}
+
+func (p *AIMemoryCommandPlugin) GetLabels() []string {
+	return []string{"command"}
+}
+
+func (p *AIMemoryCommandPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	return true
+}
+
+func (p *AIMemoryCommandPlugin) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+// Run 命令由命令路由分发，插件本身不处理消息
+func (p *AIMemoryCommandPlugin) Run(ctx *plugin.MessageContext) bool {
+	return false
+}
+
+func (p *AIMemoryCommandPlugin) GetCommands() []*plugin.Command {
+	memoryEnabled := func(ctx *plugin.MessageContext) bool {
+		return ctx.Settings.IsAIMemoryEnabled()
+	}
+	return []*plugin.Command{
+		{
+			Prefix:      "#",
+			Name:        "我的记忆",
+			Aliases:     []string{"查看记忆"},
+			Description: "查看AI记住的关于你的信息",
+			Permission:  memoryEnabled,
+			Handler:     p.onShowMemory,
+		},
+		{
+			Prefix:      "#",
+			Name:        "清除记忆",
+			Aliases:     []string{"忘记我"},
+			Description: "清除AI记住的关于你的所有信息",
+			Permission:  memoryEnabled,
+			Handler:     p.onClearMemory,
+		},
+	}
+}
+
+func (p *AIMemoryCommandPlugin) onShowMemory(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	memories, err := service.NewAIMemoryService(ctx.Context, ctx.Settings).GetMemories(ctx.Message)
+	if err != nil {
+		log.Printf("获取记忆失败: %v", err)
+		replyText(ctx, "获取记忆失败，请稍后再试。")
+		return
+	}
+	if len(memories) == 0 {
+		replyText(ctx, "我还没有记住关于你的任何信息。")
+		return
+	}
+	var builder strings.Builder
+	builder.WriteString("我记住了关于你的这些信息：")
+	for i, memory := range memories {
+		builder.WriteString(fmt.Sprintf("\n%d. %s", i+1, memory.Content))
+	}
+	builder.WriteString("\n\n发送 #清除记忆 可以让我忘记这些信息。")
+	replyText(ctx, builder.String())
+}
+
+func (p *AIMemoryCommandPlugin) onClearMemory(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	if err := service.NewAIMemoryService(ctx.Context, ctx.Settings).ClearMemories(ctx.Message); err != nil {
+		log.Printf("清除记忆失败: %v", err)
+		replyText(ctx, "清除记忆失败，请稍后再试。")
+		return
+	}
+	replyText(ctx, "好的，我已经忘记了关于你的所有信息。")
+}
+
+// replyText 回复当前消息，群聊中@发送者
+func replyText(ctx *plugin.MessageContext, content string) {
+	if ctx.Message.IsChatRoom {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, content, ctx.Message.SenderWxID)
+	} else {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, content)
+	}
+}
+
+// withMemory 开启AI记忆时，把记住的关于发送者的信息作为系统提示词加到对话前面
+func withMemory(ctx *plugin.MessageContext, aiContext []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if !ctx.Settings.IsAIMemoryEnabled() {
+		return aiContext
+	}
+	prompt, err := service.NewAIMemoryService(ctx.Context, ctx.Settings).MemoryPrompt(ctx.Message)
+	if err != nil {
+		log.Printf("获取记忆失败: %v", err)
+		return aiContext
+	}
+	if prompt == "" {
+		return aiContext
+	}
+	memory := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: prompt}
+	return append([]openai.ChatCompletionMessage{memory}, aiContext...)
+}
+
+// rememberConversation 开启AI记忆时，从最新的一轮对话中提取需要记住的信息
+// 提取需要再调用一次AI，放到后台执行，不阻塞同一个聊天后面的消息，同一个发送者的提取按顺序执行
+// 提取同样消耗AI额度，额度用完后不再提取，也不再提示用户
+func rememberConversation(ctx *plugin.MessageContext, aiContext []openai.ChatCompletionMessage, reply string) {
+	if !ctx.Settings.IsAIMemoryEnabled() || len(aiContext) == 0 {
+		return
+	}
+	if exceededAIQuota(ctx) != "" {
+		return
+	}
+	userText := messageText(aiContext[len(aiContext)-1])
+	message, settings := ctx.Message, ctx.Settings
+	err := vars.BackgroundWorkerPool.TrySubmit(message.FromWxID+":"+message.SenderWxID, func() {
+		if err := service.NewAIMemoryService(context.Background(), settings).Remember(message, userText, reply); err != nil {
+			log.Printf("更新记忆失败: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("提交记忆提取任务失败: %v", err)
+	}
+}
+
+// messageText 返回消息中的文字内容，不包括图片
+func messageText(message openai.ChatCompletionMessage) string {
+	text := message.Content
+	for _, part := range message.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			text += part.Text
+		}
+	}
+	return text
+}
@@ -0,0 +1,122 @@
+package plugins
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/service"
+)
+
+// AIPersonaCommandPlugin 在聊天中查看、切换AI角色
+type AIPersonaCommandPlugin struct{}
+
+func NewAIPersonaCommandPlugin() plugin.MessageHandler {
+	return &AIPersonaCommandPlugin{}
+}
+
+func (p *AIPersonaCommandPlugin) GetName() string {
+	return "AIPersonaCommand"
+}
+
+func (p *AIPersonaCommandPlugin) GetLabels() []string {
+	return []string{"command"}
+}
+
+func (p *AIPersonaCommandPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	return true
+}
+
+func (p *AIPersonaCommandPlugin) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+// Run 命令由命令路由分发，插件本身不处理消息
+func (p *AIPersonaCommandPlugin) Run(ctx *plugin.MessageContext) bool {
+	return false
+}
+
+func (p *AIPersonaCommandPlugin) GetCommands() []*plugin.Command {
+	return []*plugin.Command{
+		{
+			Prefix:      "#",
+			Name:        "角色列表",
+			Description: "查看可以切换的AI角色",
+			Permission:  canSwitchPersona,
+			Cooldown:    5 * time.Second,
+			Handler:     p.onListPersonas,
+		},
+		{
+			Prefix:      "#",
+			Name:        "切换角色",
+			Description: "切换当前聊天中AI使用的角色",
+			Args:        []plugin.CommandArg{{Name: "角色名称", Type: plugin.CommandArgText, Required: true}},
+			Permission:  canSwitchPersona,
+			Cooldown:    5 * time.Second,
+			Handler:     p.onSwitchPersona,
+		},
+		{
+			Prefix:      "#",
+			Name:        "取消角色",
+			Description: "当前聊天中AI不再使用角色，恢复默认的提示词",
+			Permission:  canSwitchPersona,
+			Cooldown:    5 * time.Second,
+			Handler:     p.onResetPersona,
+		},
+	}
+}
+
+// canSwitchPersona 群管理员始终可以切换角色，其他人需要开启角色切换
+func canSwitchPersona(ctx *plugin.MessageContext) bool {
+	return ctx.Settings.IsPersonaSwitchEnabled() || ctx.SenderRole() >= plugin.RoleChatRoomAdmin
+}
+
+func (p *AIPersonaCommandPlugin) onListPersonas(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	personas, err := service.NewAIPersonaService(ctx.Context).GetPersonas()
+	if err != nil {
+		log.Printf("获取AI角色列表失败: %v", err)
+		replyText(ctx, "获取角色列表失败，请稍后再试。")
+		return
+	}
+	if len(personas) == 0 {
+		replyText(ctx, "还没有可以切换的角色，请先在管理后台添加角色。")
+		return
+	}
+	var current string
+	if persona := ctx.Settings.GetPersona(); persona != nil {
+		current = persona.Name
+	}
+	lines := []string{"可以切换的角色："}
+	for _, persona := range personas {
+		line := "· " + persona.Name
+		if persona.Name == current {
+			line += " (当前)"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "发送 #切换角色 角色名称 即可切换。")
+	replyText(ctx, strings.Join(lines, "\n"))
+}
+
+func (p *AIPersonaCommandPlugin) onSwitchPersona(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	name := strings.TrimSpace(args.String("角色名称"))
+	persona, err := service.NewAIPersonaService(ctx.Context).SwitchPersona(ctx.Message.FromWxID, name)
+	if err != nil {
+		replyText(ctx, fmt.Sprintf("切换角色失败: %v", err))
+		return
+	}
+	if persona.Intro != "" {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, persona.Intro)
+		return
+	}
+	replyText(ctx, fmt.Sprintf("已切换为「%s」。", persona.Name))
+}
+
+func (p *AIPersonaCommandPlugin) onResetPersona(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	if _, err := service.NewAIPersonaService(ctx.Context).SwitchPersona(ctx.Message.FromWxID, ""); err != nil {
+		replyText(ctx, fmt.Sprintf("取消角色失败: %v", err))
+		return
+	}
+	replyText(ctx, "已取消角色，AI恢复默认设定。")
+}
@@ -0,0 +1,46 @@
+package plugins
+
+import (
+	"log"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/service"
+)
+
+// exceededAIQuota 返回群聊/好友的AI额度已经用完的周期，没有用完或者没有配置额度时返回空字符串
+func exceededAIQuota(ctx *plugin.MessageContext) service.AIQuotaPeriod {
+	quota := ctx.Settings.GetAITokenQuota()
+	if quota.Daily <= 0 && quota.Monthly <= 0 {
+		return ""
+	}
+	period, err := service.NewAIUsageService(ctx.Context).CheckQuota(ctx.Message.FromWxID, quota)
+	if err != nil {
+		// 统计出错时不影响正常使用
+		log.Printf("检查AI额度失败: %v", err)
+		return ""
+	}
+	return period
+}
+
+// checkAIQuota 检查群聊/好友的AI token额度，额度用完时通知超级管理员并返回 false，同一个发送者每个周期只提示一次
+// AI聊天、绘图、文本转语音等所有消耗AI额度的功能调用前都需要检查
+func checkAIQuota(ctx *plugin.MessageContext) bool {
+	period := exceededAIQuota(ctx)
+	if period == "" {
+		return true
+	}
+	usageService := service.NewAIUsageService(ctx.Context)
+	usageService.NotifyQuotaExceeded(ctx.Message.FromWxID, period, ctx.Settings.GetAITokenQuota())
+	if !usageService.ShouldReplyQuotaExceeded(ctx.Message, period) {
+		return false
+	}
+	reply := "今天的AI额度已经用完了，明天再来吧。"
+	if period == service.AIQuotaPeriodMonthly {
+		reply = "本月的AI额度已经用完了，下个月再来吧。"
+	}
+	if ctx.Message.IsChatRoom {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, reply, ctx.Message.SenderWxID)
+	} else {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, reply)
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package plugins
+
+import (
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/service"
+	"wechat-robot-client/vars"
+)
+
+// newChatTools 返回当前聊天中AI可以调用的工具，包括插件注册的工具和当前聊天配置的MCP服务器提供的工具，没有可用的工具时返回 nil
+// called 记录AI调用插件工具的次数，插件工具可能已经直接给用户发送了图片、语音，AI最终回复为空时不需要再提示
+func newChatTools(ctx *plugin.MessageContext) (tools *service.ChatTools, called *int) {
+	called = new(int)
+	registry := vars.MessagePlugin.Tools()
+	var pluginTools *service.ChatTools
+	if available := registry.Available(ctx); len(available) > 0 {
+		pluginTools = &service.ChatTools{
+			Call: func(name, arguments string) string {
+				*called++
+				return registry.Call(ctx, name, arguments)
+			},
+		}
+		for _, tool := range available {
+			pluginTools.Tools = append(pluginTools.Tools, tool.Definition())
+		}
+	}
+	mcpTools := service.NewMCPService(ctx.Context, ctx.Settings).ChatTools()
+	return service.MergeChatTools(pluginTools, mcpTools), called
+}
+
+// withMessageContent 复制消息上下文并替换消息内容，工具调用插件的 Run 时使用，不影响原消息上下文
+func withMessageContent(ctx *plugin.MessageContext, content string) *plugin.MessageContext {
+	toolCtx := *ctx
+	toolCtx.MessageContent = content
+	return &toolCtx
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -16,6 +17,8 @@ import (
 	"wechat-robot-client/plugin/pkg"
 	"wechat-robot-client/service"
 	"wechat-robot-client/vars"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 type AITTSPlugin struct{}
@@ -29,7 +32,7 @@ func (p *AITTSPlugin) GetName() string {
 }
 
 func (p *AITTSPlugin) GetLabels() []string {
-	return []string{"text", "internal", "voice"}
+	return []string{"text", "internal", "tts"}
 }
 
 func (p *AITTSPlugin) PreAction(ctx *plugin.MessageContext) bool {
@@ -57,28 +60,82 @@ func (p *AITTSPlugin) Run(ctx *plugin.MessageContext) bool {
 		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, "你要说的也太多了，要不你还是说点别的吧。", ctx.Message.SenderWxID)
 		return true
 	}
+	if !checkRateLimit(ctx, model.RateLimitFeatureTTS) {
+		return true
+	}
+	if !checkAIQuota(ctx) {
+		return true
+	}
+	if err := sendTTSVoice(ctx, ttsContent); err != nil {
+		log.Printf("文本转语音失败: %v", err)
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, err.Error(), ctx.Message.SenderWxID)
+	}
+	return true
+}
+
+// sendTTSVoice 使用豆包文本转语音将文本合成语音，并发送到当前聊天
+func sendTTSVoice(ctx *plugin.MessageContext, text string) error {
 	aiConfig := ctx.Settings.GetAIConfig()
 	var doubaoConfig pkg.DoubaoTTSConfig
 	if err := json.Unmarshal(aiConfig.TTSSettings, &doubaoConfig); err != nil {
-		log.Printf("反序列化豆包文本转语音配置失败: %v", err)
-		return true
+		return fmt.Errorf("反序列化豆包文本转语音配置失败: %w", err)
 	}
-	doubaoConfig.Request.Text = ttsContent
+	if aiConfig.TTSVoice != "" {
+		doubaoConfig.Audio.VoiceType = aiConfig.TTSVoice
+	}
+	doubaoConfig.Request.Text = text
 
 	audioBase64, err := pkg.DoubaoTTSSubmit(&doubaoConfig)
 	if err != nil {
-		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, fmt.Sprintf("豆包文本转语音请求失败: %v", err), ctx.Message.SenderWxID)
-		return true
+		return fmt.Errorf("豆包文本转语音请求失败: %w", err)
 	}
 	audioData, err := base64.StdEncoding.DecodeString(audioBase64)
 	if err != nil {
-		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, fmt.Sprintf("音频数据解码失败: %v", err), ctx.Message.SenderWxID)
-		return true
+		return fmt.Errorf("音频数据解码失败: %w", err)
 	}
 	audioReader := bytes.NewReader(audioData)
-	ctx.MessageService.MsgSendVoice(ctx.Message.FromWxID, audioReader, fmt.Sprintf(".%s", doubaoConfig.Audio.Encoding))
+	return ctx.MessageService.MsgSendVoice(ctx.Message.FromWxID, audioReader, fmt.Sprintf(".%s", doubaoConfig.Audio.Encoding))
+}
 
-	return true
+func (p *AITTSPlugin) GetTools() []*plugin.Tool {
+	return []*plugin.Tool{
+		{
+			Name:        "text_to_speech",
+			Description: "把一段文字转换成语音发送给用户，文字不能超过260个字",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"text": {Type: jsonschema.String, Description: "要转换成语音的文字"},
+				},
+				Required: []string{"text"},
+			},
+			Available: func(ctx *plugin.MessageContext) bool {
+				return ctx.Settings.IsTTSEnabled()
+			},
+			Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+				var params struct {
+					Text string `json:"text"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil || params.Text == "" {
+					return "", errors.New("文字不能为空")
+				}
+				if utf8.RuneCountInString(params.Text) > 260 {
+					return "", errors.New("文字超过260个字，请缩短后再转换")
+				}
+				if !checkRateLimit(ctx, model.RateLimitFeatureTTS) {
+					return "用户触发了频率限制，已经提示用户", nil
+				}
+				if !checkAIQuota(ctx) {
+					return "AI额度已经用完，已经提示用户", nil
+				}
+				if err := sendTTSVoice(ctx, params.Text); err != nil {
+					log.Printf("文本转语音失败: %v", err)
+					return "", err
+				}
+				return "语音已经发送给用户", nil
+			},
+		},
+	}
 }
 
 type AILTTSPlugin struct{}
@@ -92,7 +149,7 @@ func (p *AILTTSPlugin) GetName() string {
 }
 
 func (p *AILTTSPlugin) GetLabels() []string {
-	return []string{"internal", "voice"}
+	return []string{"internal", "tts"}
 }
 
 func (p *AILTTSPlugin) PreAction(ctx *plugin.MessageContext) bool {
@@ -117,6 +174,12 @@ func (p *AILTTSPlugin) Run(ctx *plugin.MessageContext) bool {
 		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, "请使用TXT文本文件进行长文本转语音", ctx.Message.SenderWxID)
 		return true
 	}
+	if !checkRateLimit(ctx, model.RateLimitFeatureTTS) {
+		return true
+	}
+	if !checkAIQuota(ctx) {
+		return true
+	}
 
 	aiConfig := ctx.Settings.GetAIConfig()
 	var doubaoConfig pkg.DoubaoLTTSConfig
@@ -230,3 +293,19 @@ func (p *AILTTSPlugin) Run(ctx *plugin.MessageContext) bool {
 
 	return true
 }
+
+func (p *AILTTSPlugin) GetTools() []*plugin.Tool {
+	return []*plugin.Tool{
+		{
+			Name:        "long_text_to_speech",
+			Description: "把用户引用的TXT文本文件转换成语音，转换需要一段时间，完成后语音会发送给用户",
+			Available: func(ctx *plugin.MessageContext) bool {
+				return ctx.ReferMessage != nil && ctx.ReferMessage.Type == model.MsgTypeApp && ctx.ReferMessage.AppMsgType == model.AppMsgTypeAttach && ctx.Settings.IsTTSEnabled()
+			},
+			Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+				p.Run(ctx)
+				return "任务处理结果已经发送给用户", nil
+			},
+		},
+	}
+}
@@ -2,21 +2,23 @@ package plugins
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 	"wechat-robot-client/interface/plugin"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 // ApilotPlugin Apilot多功能插件
-type ApilotPlugin struct {
-	config Config
-}
+type ApilotPlugin struct{}
 
 // Config 插件配置
 type Config struct {
@@ -61,9 +63,7 @@ var HotTrendTypes = map[string]string{
 
 // NewApilotPlugin 创建Apilot插件实例
 func NewApilotPlugin() plugin.MessageHandler {
-	plugin := &ApilotPlugin{}
-	plugin.loadConfig()
-	return plugin
+	return &ApilotPlugin{}
 }
 
 // GetName 获取插件名称
@@ -86,94 +86,159 @@ func (p *ApilotPlugin) PostAction(ctx *plugin.MessageContext) {
 	// 可以在这里添加清理逻辑
 }
 
-// Run 主要逻辑
-func (p *ApilotPlugin) Run(ctx *plugin.MessageContext) bool {
-	content := strings.TrimSpace(ctx.MessageContent)
-
-	// 早报
-	if content == "早报" {
-		news := p.getMorningNews()
-		replyType := "text"
-		if p.isValidURL(news) {
-			replyType = "image_url"
-		}
-		p.sendReply(ctx, replyType, news)
-		return true
+// GetCommands 固定关键词的功能注册为命令，由命令路由统一解析参数，星座、热榜、天气等自然语言查询仍然在 Run 中处理
+func (p *ApilotPlugin) GetCommands() []*plugin.Command {
+	return []*plugin.Command{
+		{Name: "早报", Description: "每日早报", Cooldown: 10 * time.Second, Handler: p.onMorningNews},
+		{Name: "摸鱼", Description: "摸鱼日历", Cooldown: 10 * time.Second, Handler: p.onMoyuCalendar},
+		{Name: "摸鱼视频", Description: "摸鱼日历视频", Cooldown: 10 * time.Second, Handler: p.onMoyuCalendarVideo},
+		{Name: "八卦", Description: "明星八卦", Cooldown: 10 * time.Second, Handler: p.onMxBagua},
+		{
+			Name:        "举牌",
+			Description: "生成举牌小人图片",
+			Args:        []plugin.CommandArg{{Name: "内容", Type: plugin.CommandArgText, Required: true}},
+			Cooldown:    10 * time.Second,
+			Handler:     p.onJupai,
+		},
+		{
+			Name:        "快递",
+			Description: "查询快递，顺丰快递需要补充寄/收件人手机号后四位，例如 SF12345:0000",
+			Args:        []plugin.CommandArg{{Name: "单号", Type: plugin.CommandArgText, Required: true}},
+			Handler:     p.onExpress,
+		},
 	}
+}
 
-	// 摸鱼
-	if content == "摸鱼" {
-		moyu := p.getMoyuCalendar()
-		replyType := "text"
-		if p.isValidURL(moyu) {
-			replyType = "image_url"
-		}
-		p.sendReply(ctx, replyType, moyu)
-		return true
+// GetTools 天气、热榜查询提供给AI调用，查询结果交给AI整理后回复
+func (p *ApilotPlugin) GetTools() []*plugin.Tool {
+	var hotTrendTypes []string
+	for k := range HotTrendTypes {
+		hotTrendTypes = append(hotTrendTypes, k)
+	}
+	sort.Strings(hotTrendTypes)
+	return []*plugin.Tool{
+		{
+			Name:        "get_weather",
+			Description: "查询国内城市的天气",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"city": {Type: jsonschema.String, Description: "城市名称，不带市、县、区，例如 广州"},
+					"date": {Type: jsonschema.String, Enum: []string{"今天", "明天", "后天", "七天"}, Description: "查询哪天的天气"},
+				},
+				Required: []string{"city"},
+			},
+			Available: func(ctx *plugin.MessageContext) bool {
+				return p.loadConfig(ctx).AlapiToken != ""
+			},
+			Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+				var params struct {
+					City string `json:"city"`
+					Date string `json:"date"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil || params.City == "" {
+					return "", errors.New("城市不能为空")
+				}
+				return p.getWeather(p.loadConfig(ctx), params.City, params.Date, params.City), nil
+			},
+		},
+		{
+			Name:        "get_hot_trends",
+			Description: "查询各平台的热榜",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"platform": {Type: jsonschema.String, Enum: hotTrendTypes, Description: "热榜平台"},
+				},
+				Required: []string{"platform"},
+			},
+			Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+				var params struct {
+					Platform string `json:"platform"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil || params.Platform == "" {
+					return "", errors.New("热榜平台不能为空")
+				}
+				return p.getHotTrends(p.loadConfig(ctx), params.Platform), nil
+			},
+		},
 	}
+}
 
-	// 摸鱼视频
-	if content == "摸鱼视频" {
-		moyu := p.getMoyuCalendarVideo()
-		replyType := "text"
-		if p.isValidURL(moyu) {
-			replyType = "video_url"
-		}
-		p.sendReply(ctx, replyType, moyu)
-		return true
+func (p *ApilotPlugin) onMorningNews(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	cfg := p.loadConfig(ctx)
+	news := p.getMorningNews(cfg)
+	replyType := "text"
+	if p.isValidURL(news) {
+		replyType = "image_url"
 	}
+	p.sendReply(ctx, replyType, news)
+}
 
-	// 八卦
-	if content == "八卦" {
-		bagua := p.getMxBagua()
-		replyType := "text"
-		if p.isValidURL(bagua) {
-			replyType = "image_url"
-		}
-		p.sendReply(ctx, replyType, bagua)
-		return true
+func (p *ApilotPlugin) onMoyuCalendar(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	cfg := p.loadConfig(ctx)
+	moyu := p.getMoyuCalendar(cfg)
+	replyType := "text"
+	if p.isValidURL(moyu) {
+		replyType = "image_url"
 	}
+	p.sendReply(ctx, replyType, moyu)
+}
 
-	// 举牌
-	if strings.Contains(content, "举牌") {
-		parts := strings.Split(content, " ")
-		if len(parts) > 1 {
-			jupai := p.getJupaiPic(parts[1])
-			if jupai != "" {
-				replyType := "text"
-				if p.isValidURL(jupai) {
-					replyType = "image_url"
-				}
-				p.sendReply(ctx, replyType, jupai)
-			}
-		}
-		return true
+func (p *ApilotPlugin) onMoyuCalendarVideo(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	moyu := p.getMoyuCalendarVideo()
+	replyType := "text"
+	if p.isValidURL(moyu) {
+		replyType = "video_url"
 	}
+	p.sendReply(ctx, replyType, moyu)
+}
 
-	// 快递查询
-	if strings.HasPrefix(content, "快递") {
-		trackingNumber := strings.TrimSpace(content[2:])
-		trackingNumber = strings.ReplaceAll(trackingNumber, "：", ":")
-
-		if p.config.AlapiToken == "" {
-			p.sendReply(ctx, "text", "请先配置alapi的token")
-			return true
-		}
+func (p *ApilotPlugin) onMxBagua(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	bagua := p.getMxBagua()
+	replyType := "text"
+	if p.isValidURL(bagua) {
+		replyType = "image_url"
+	}
+	p.sendReply(ctx, replyType, bagua)
+}
 
-		// 检查顺丰快递格式
-		if strings.HasPrefix(trackingNumber, "SF") && !strings.Contains(trackingNumber, ":") {
-			p.sendReply(ctx, "text", "顺丰快递需要补充寄/收件人手机号后四位，格式：SF12345:0000")
-			return true
-		}
+func (p *ApilotPlugin) onJupai(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	jupai := p.getJupaiPic(args.String("内容"))
+	if jupai == "" {
+		return
+	}
+	replyType := "text"
+	if p.isValidURL(jupai) {
+		replyType = "image_url"
+	}
+	p.sendReply(ctx, replyType, jupai)
+}
 
-		result := p.queryExpressInfo(trackingNumber)
-		p.sendReply(ctx, "text", result)
-		return true
+func (p *ApilotPlugin) onExpress(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	cfg := p.loadConfig(ctx)
+	trackingNumber := strings.ReplaceAll(args.String("单号"), "：", ":")
+	if cfg.AlapiToken == "" {
+		p.sendReply(ctx, "text", "请先配置alapi的token")
+		return
+	}
+	// 检查顺丰快递格式
+	if strings.HasPrefix(trackingNumber, "SF") && !strings.Contains(trackingNumber, ":") {
+		p.sendReply(ctx, "text", "顺丰快递需要补充寄/收件人手机号后四位，格式：SF12345:0000")
+		return
 	}
+	result := p.queryExpressInfo(cfg, trackingNumber)
+	p.sendReply(ctx, "text", result)
+}
+
+// Run 主要逻辑
+func (p *ApilotPlugin) Run(ctx *plugin.MessageContext) bool {
+	cfg := p.loadConfig(ctx)
+	content := strings.TrimSpace(ctx.MessageContent)
 
 	// 星座查询
 	if zodiacEnglish, exists := ZodiacMapping[content]; exists {
-		result := p.getHoroscope(zodiacEnglish)
+		result := p.getHoroscope(cfg, zodiacEnglish)
 		p.sendReply(ctx, "text", result)
 		return true
 	}
@@ -182,7 +247,7 @@ func (p *ApilotPlugin) Run(ctx *plugin.MessageContext) bool {
 	hotTrendMatch := regexp.MustCompile(`(.{1,6})热榜$`)
 	if matches := hotTrendMatch.FindStringSubmatch(content); len(matches) > 1 {
 		hotTrendsType := strings.TrimSpace(matches[1])
-		result := p.getHotTrends(hotTrendsType)
+		result := p.getHotTrends(cfg, hotTrendsType)
 		p.sendReply(ctx, "text", result)
 		return true
 	}
@@ -196,12 +261,12 @@ func (p *ApilotPlugin) Run(ctx *plugin.MessageContext) bool {
 		}
 		date := matches[3]
 
-		if p.config.AlapiToken == "" {
+		if cfg.AlapiToken == "" {
 			p.sendReply(ctx, "text", "请先配置alapi的token")
 			return true
 		}
 
-		result := p.getWeather(cityOrID, date, content)
+		result := p.getWeather(cfg, cityOrID, date, content)
 		p.sendReply(ctx, "text", result)
 		return true
 	}
@@ -209,22 +274,24 @@ func (p *ApilotPlugin) Run(ctx *plugin.MessageContext) bool {
 	return false
 }
 
-// loadConfig 加载配置
-func (p *ApilotPlugin) loadConfig() {
-	configPath := "plugin/plugins/apilot_config.json"
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		// 使用默认配置
-		p.config = Config{
-			AlapiToken:             "",
-			MorningNewsTextEnabled: false,
-			BaseURLVVHan:           "https://api.vvhan.com/api/",
-			BaseURLAlapi:           "https://v2.alapi.cn/api/",
-		}
-		return
+// defaultApilotConfig 默认配置
+func defaultApilotConfig() Config {
+	return Config{
+		AlapiToken:             "",
+		MorningNewsTextEnabled: false,
+		BaseURLVVHan:           "https://api.vvhan.com/api/",
+		BaseURLAlapi:           "https://v2.alapi.cn/api/",
 	}
+}
 
-	json.Unmarshal(data, &p.config)
+// loadConfig 从插件配置中加载配置，未配置的字段使用默认值
+func (p *ApilotPlugin) loadConfig(ctx *plugin.MessageContext) Config {
+	config := defaultApilotConfig()
+	if err := ctx.GetPluginConfig(p.GetName(), &config); err != nil {
+		log.Printf("解析Apilot插件配置失败: %v", err)
+		return defaultApilotConfig()
+	}
+	return config
 }
 
 // sendReply 发送回复
@@ -284,10 +351,10 @@ func (p *ApilotPlugin) isValidURL(urlStr string) bool {
 }
 
 // getMorningNews 获取早报
-func (p *ApilotPlugin) getMorningNews() string {
-	if p.config.AlapiToken == "" {
+func (p *ApilotPlugin) getMorningNews(cfg Config) string {
+	if cfg.AlapiToken == "" {
 		// 使用免费API
-		apiURL := p.config.BaseURLAlapi + "zaobao"
+		apiURL := cfg.BaseURLAlapi + "zaobao"
 		headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
 		data := make(url.Values)
 		data.Set("format", "json")
@@ -298,7 +365,7 @@ func (p *ApilotPlugin) getMorningNews() string {
 		}
 
 		if success, ok := result["success"].(bool); ok && success {
-			if p.config.MorningNewsTextEnabled {
+			if cfg.MorningNewsTextEnabled {
 				if data, ok := result["data"].([]interface{}); ok && len(data) > 0 {
 					var newsList []string
 					for i, news := range data[:len(data)-1] {
@@ -319,10 +386,10 @@ func (p *ApilotPlugin) getMorningNews() string {
 		return "早报信息获取失败，可配置\"alapi token\"切换至 Alapi 服务，或者稍后再试"
 	} else {
 		// 使用Alapi
-		apiURL := p.config.BaseURLAlapi + "zaobao"
+		apiURL := cfg.BaseURLAlapi + "zaobao"
 		headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
 		data := make(url.Values)
-		data.Set("token", p.config.AlapiToken)
+		data.Set("token", cfg.AlapiToken)
 		data.Set("format", "json")
 
 		result, err := p.makeRequest(apiURL, "POST", headers, data)
@@ -333,7 +400,7 @@ func (p *ApilotPlugin) getMorningNews() string {
 		if code, ok := result["code"].(float64); ok && code == 200 {
 			if data, ok := result["data"].(map[string]interface{}); ok {
 				if imgURL, ok := data["image"].(string); ok {
-					if p.config.MorningNewsTextEnabled {
+					if cfg.MorningNewsTextEnabled {
 						if news, ok := data["news"].([]interface{}); ok {
 							if weiyu, ok := data["weiyu"].(string); ok {
 								if date, ok := data["date"].(string); ok {
@@ -356,8 +423,8 @@ func (p *ApilotPlugin) getMorningNews() string {
 }
 
 // getMoyuCalendar 获取摸鱼日历
-func (p *ApilotPlugin) getMoyuCalendar() string {
-	apiURL := p.config.BaseURLVVHan + "moyu?type=json"
+func (p *ApilotPlugin) getMoyuCalendar(cfg Config) string {
+	apiURL := cfg.BaseURLVVHan + "moyu?type=json"
 	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
 	data := make(url.Values)
 	data.Set("format", "json")
@@ -457,10 +524,10 @@ func (p *ApilotPlugin) getJupaiPic(keyword string) string {
 }
 
 // getHoroscope 获取星座运势
-func (p *ApilotPlugin) getHoroscope(astroSign string) string {
-	if p.config.AlapiToken == "" {
+func (p *ApilotPlugin) getHoroscope(cfg Config, astroSign string) string {
+	if cfg.AlapiToken == "" {
 		// 使用免费API
-		apiURL := p.config.BaseURLVVHan + "horoscope"
+		apiURL := cfg.BaseURLVVHan + "horoscope"
 		headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
 		data := make(url.Values)
 		data.Set("type", astroSign)
@@ -479,10 +546,10 @@ func (p *ApilotPlugin) getHoroscope(astroSign string) string {
 		return "星座信息获取失败，可配置\"alapi token\"切换至 Alapi 服务，或者稍后再试"
 	} else {
 		// 使用Alapi
-		apiURL := p.config.BaseURLVVHan + "star"
+		apiURL := cfg.BaseURLVVHan + "star"
 		headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
 		data := make(url.Values)
-		data.Set("token", p.config.AlapiToken)
+		data.Set("token", cfg.AlapiToken)
 		data.Set("star", astroSign)
 
 		result, err := p.makeRequest(apiURL, "POST", headers, data)
@@ -656,7 +723,7 @@ func (p *ApilotPlugin) formatAlapiHoroscopeData(data map[string]interface{}) str
 }
 
 // getHotTrends 获取热榜
-func (p *ApilotPlugin) getHotTrends(hotTrendsType string) string {
+func (p *ApilotPlugin) getHotTrends(cfg Config, hotTrendsType string) string {
 	hotTrendsTypeEn, exists := HotTrendTypes[hotTrendsType]
 	if !exists {
 		var supportedTypes []string
@@ -666,7 +733,7 @@ func (p *ApilotPlugin) getHotTrends(hotTrendsType string) string {
 		return fmt.Sprintf("👉 已支持的类型有：\n\n    %s\n\n📝 请按照以下格式发送：\n    类型+热榜  例如：微博热榜", strings.Join(supportedTypes, "/"))
 	}
 
-	apiURL := p.config.BaseURLVVHan + "hotlist/" + hotTrendsTypeEn
+	apiURL := cfg.BaseURLVVHan + "hotlist/" + hotTrendsTypeEn
 	headers := map[string]string{
 		"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
 	}
@@ -714,11 +781,11 @@ func (p *ApilotPlugin) getHotTrends(hotTrendsType string) string {
 }
 
 // queryExpressInfo 查询快递信息
-func (p *ApilotPlugin) queryExpressInfo(trackingNumber string) string {
-	apiURL := p.config.BaseURLVVHan + "kd"
+func (p *ApilotPlugin) queryExpressInfo(cfg Config, trackingNumber string) string {
+	apiURL := cfg.BaseURLVVHan + "kd"
 	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
 	data := make(url.Values)
-	data.Set("token", p.config.AlapiToken)
+	data.Set("token", cfg.AlapiToken)
 	data.Set("number", trackingNumber)
 	data.Set("com", "")
 	data.Set("order", "asc")
@@ -777,11 +844,11 @@ func (p *ApilotPlugin) queryExpressInfo(trackingNumber string) string {
 }
 
 // getWeather 获取天气信息
-func (p *ApilotPlugin) getWeather(cityOrID, date, content string) string {
-	apiURL := p.config.BaseURLAlapi + "tianqi"
+func (p *ApilotPlugin) getWeather(cfg Config, cityOrID, date, content string) string {
+	apiURL := cfg.BaseURLAlapi + "tianqi"
 	isFuture := strings.Contains(date, "明天") || strings.Contains(date, "后天") || strings.Contains(date, "七天") || strings.Contains(date, "7天")
 	if isFuture {
-		apiURL = p.config.BaseURLVVHan + "tianqi/seven"
+		apiURL = cfg.BaseURLVVHan + "tianqi/seven"
 	}
 
 	var data url.Values
@@ -789,12 +856,12 @@ func (p *ApilotPlugin) getWeather(cityOrID, date, content string) string {
 		// 使用城市ID
 		data = make(url.Values)
 		data.Set("city_id", cityOrID)
-		data.Set("token", p.config.AlapiToken)
+		data.Set("token", cfg.AlapiToken)
 	} else {
 		// 使用城市名称
 		data = make(url.Values)
 		data.Set("city", cityOrID)
-		data.Set("token", p.config.AlapiToken)
+		data.Set("token", cfg.AlapiToken)
 	}
 
 	result, err := p.makeRequest(apiURL, "GET", nil, data)
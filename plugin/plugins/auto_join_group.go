@@ -2,14 +2,18 @@ package plugins
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"regexp"
 	"wechat-robot-client/interface/plugin"
 	"wechat-robot-client/service"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 type AutoJoinGroupPlugin struct{}
 
-func NewAutoJoinGroupPlugin() plugin.MessageHandler {
+func NewAutoJoinGroupPlugin() *AutoJoinGroupPlugin {
 	return &AutoJoinGroupPlugin{}
 }
 
@@ -25,6 +29,25 @@ func (p *AutoJoinGroupPlugin) PreAction(ctx *plugin.MessageContext) bool {
 	return true
 }
 
+func (p *AutoJoinGroupPlugin) GetCommands() []*plugin.Command {
+	return []*plugin.Command{
+		{
+			Name:        "申请进群",
+			Description: "申请加入机器人所在的群聊",
+			Args:        []plugin.CommandArg{{Name: "群名称", Type: plugin.CommandArgText, Required: true}},
+			Scope:       plugin.CommandScopeFriend,
+			Handler:     p.onApply,
+		},
+	}
+}
+
+func (p *AutoJoinGroupPlugin) onApply(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	err := service.NewChatRoomService(context.Background()).AutoInviteChatRoomMember(args.String("群名称"), []string{ctx.Message.FromWxID})
+	if err != nil {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, err.Error())
+	}
+}
+
 func (p *AutoJoinGroupPlugin) PostAction(ctx *plugin.MessageContext) {
 
 }
@@ -42,3 +65,35 @@ func (p *AutoJoinGroupPlugin) Run(ctx *plugin.MessageContext) bool {
 	}
 	return true
 }
+
+func (p *AutoJoinGroupPlugin) GetTools() []*plugin.Tool {
+	return []*plugin.Tool{
+		{
+			Name:        "apply_to_join_group",
+			Description: "用户申请加入机器人所在的某个群聊，机器人会发送入群邀请",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"chat_room_name": {Type: jsonschema.String, Description: "群聊名称"},
+				},
+				Required: []string{"chat_room_name"},
+			},
+			Available: func(ctx *plugin.MessageContext) bool {
+				return !ctx.Message.IsChatRoom
+			},
+			Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+				var params struct {
+					ChatRoomName string `json:"chat_room_name"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil || params.ChatRoomName == "" {
+					return "", errors.New("群聊名称不能为空")
+				}
+				err := service.NewChatRoomService(context.Background()).AutoInviteChatRoomMember(params.ChatRoomName, []string{ctx.Message.FromWxID})
+				if err != nil {
+					return "", err
+				}
+				return "已经发送入群邀请", nil
+			},
+		},
+	}
+}
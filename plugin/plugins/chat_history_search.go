@@ -0,0 +1,144 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/service"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// ChatHistorySearchPlugin 按语义搜索当前聊天的历史消息，帮助群成员找回之前的讨论和结论
+type ChatHistorySearchPlugin struct{}
+
+func NewChatHistorySearchPlugin() plugin.MessageHandler {
+	return &ChatHistorySearchPlugin{}
+}
+
+func (p *ChatHistorySearchPlugin) GetName() string {
+	return "ChatHistorySearch"
+}
+
+func (p *ChatHistorySearchPlugin) GetLabels() []string {
+	return []string{"command"}
+}
+
+func (p *ChatHistorySearchPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	return true
+}
+
+func (p *ChatHistorySearchPlugin) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+// Run 命令由命令路由分发，插件本身不处理消息
+func (p *ChatHistorySearchPlugin) Run(ctx *plugin.MessageContext) bool {
+	return false
+}
+
+func semanticSearchEnabled(ctx *plugin.MessageContext) bool {
+	return ctx.Settings.IsSemanticSearchEnabled()
+}
+
+func (p *ChatHistorySearchPlugin) GetCommands() []*plugin.Command {
+	return []*plugin.Command{
+		{
+			Prefix:      "#",
+			Name:        "搜",
+			Aliases:     []string{"搜索聊天记录"},
+			Description: "按意思搜索当前聊天的历史消息，例如：#搜 上次谁说过报销流程",
+			Args:        []plugin.CommandArg{{Name: "内容", Type: plugin.CommandArgText, Required: true}},
+			Permission:  semanticSearchEnabled,
+			Cooldown:    10 * time.Second,
+			Handler:     p.onSearch,
+		},
+	}
+}
+
+func (p *ChatHistorySearchPlugin) GetTools() []*plugin.Tool {
+	return []*plugin.Tool{
+		{
+			Name:        "semantic_search_chat_history",
+			Description: "按语义搜索当前聊天的历史消息，不需要和原文的字词完全一致，返回最相关的消息和发送者、发送时间。用户询问之前谁说过什么、之前讨论的结论时使用",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"query": {Type: jsonschema.String, Description: "要搜索的内容，用一句话描述"},
+				},
+				Required: []string{"query"},
+			},
+			Available: semanticSearchEnabled,
+			Handler:   p.searchTool,
+		},
+	}
+}
+
+func (p *ChatHistorySearchPlugin) search(ctx *plugin.MessageContext, query string, limit int) ([]*dto.ChatHistorySearchResult, error) {
+	return service.NewChatHistoryService(ctx.Context).SemanticSearch(ctx.Message, dto.ChatHistorySemanticSearchRequest{
+		ContactID: ctx.Message.FromWxID,
+		Query:     query,
+		Limit:     limit,
+	})
+}
+
+func (p *ChatHistorySearchPlugin) onSearch(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	// 生成搜索内容的向量同样消耗AI额度
+	if !checkAIQuota(ctx) {
+		return
+	}
+	results, err := p.search(ctx, args.String("内容"), 5)
+	if err != nil {
+		log.Printf("搜索聊天记录失败: %v", err)
+		replyText(ctx, "搜索聊天记录失败，请稍后再试。")
+		return
+	}
+	if len(results) == 0 {
+		replyText(ctx, "没有找到相关的聊天记录。")
+		return
+	}
+	lines := []string{"找到以下相关的聊天记录："}
+	for _, result := range results {
+		lines = append(lines, formatChatHistoryLine(result.Message))
+	}
+	replyText(ctx, strings.Join(lines, "\n"))
+}
+
+func (p *ChatHistorySearchPlugin) searchTool(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+	var params struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil || params.Query == "" {
+		return "", errors.New("搜索内容不能为空")
+	}
+	if !checkAIQuota(ctx) {
+		return "AI额度已经用完，已经提示用户", nil
+	}
+	results, err := p.search(ctx, params.Query, 10)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "没有找到相关的聊天记录", nil
+	}
+	lines := make([]string, 0, len(results))
+	for _, result := range results {
+		lines = append(lines, formatChatHistoryLine(result.Message))
+	}
+	return fmt.Sprintf("最相关的 %d 条聊天记录(按相关程度排序)：\n%s", len(lines), strings.Join(lines, "\n")), nil
+}
+
+// formatChatHistoryLine 把聊天记录格式化成“[时间] 发送者: 内容”
+func formatChatHistoryLine(message *model.Message) string {
+	sender := message.SenderNickname
+	if sender == "" {
+		sender = message.SenderWxID
+	}
+	return fmt.Sprintf("[%s] %s: %s", time.Unix(message.CreatedAt, 0).Format("2006-01-02 15:04"), sender, message.Content)
+}
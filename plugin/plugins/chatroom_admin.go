@@ -0,0 +1,109 @@
+package plugins
+
+import (
+	"log"
+	"wechat-robot-client/interface/plugin"
+)
+
+// ChatRoomAdminCommandPlugin 群聊管理命令，只有群管理员及以上角色可以使用
+type ChatRoomAdminCommandPlugin struct{}
+
+func NewChatRoomAdminCommandPlugin() plugin.MessageHandler {
+	return &ChatRoomAdminCommandPlugin{}
+}
+
+func (p *ChatRoomAdminCommandPlugin) GetName() string {
+	return "ChatRoomAdminCommand"
+}
+
+func (p *ChatRoomAdminCommandPlugin) GetLabels() []string {
+	return []string{"command"}
+}
+
+func (p *ChatRoomAdminCommandPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	return true
+}
+
+func (p *ChatRoomAdminCommandPlugin) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+// Run 命令由命令路由分发，插件本身不处理消息
+func (p *ChatRoomAdminCommandPlugin) Run(ctx *plugin.MessageContext) bool {
+	return false
+}
+
+func (p *ChatRoomAdminCommandPlugin) GetCommands() []*plugin.Command {
+	return []*plugin.Command{
+		{
+			Prefix:      "#",
+			Name:        "开启AI",
+			Description: "开启当前群聊的AI聊天",
+			Scope:       plugin.CommandScopeChatRoom,
+			MinRole:     plugin.RoleChatRoomAdmin,
+			Handler:     p.onAIEnabled,
+		},
+		{
+			Prefix:      "#",
+			Name:        "关闭AI",
+			Description: "关闭当前群聊的AI聊天",
+			Scope:       plugin.CommandScopeChatRoom,
+			MinRole:     plugin.RoleChatRoomAdmin,
+			Handler:     p.onAIDisabled,
+		},
+		{
+			Prefix:      "#",
+			Name:        "设置触发词",
+			Description: "修改当前群聊触发AI聊天的关键词",
+			Args:        []plugin.CommandArg{{Name: "触发词", Type: plugin.CommandArgString, Required: true}},
+			Scope:       plugin.CommandScopeChatRoom,
+			MinRole:     plugin.RoleChatRoomAdmin,
+			Handler:     p.onSetAITrigger,
+		},
+		{
+			Prefix:      "#",
+			Name:        "开启欢迎",
+			Description: "开启当前群聊的新成员欢迎",
+			Scope:       plugin.CommandScopeChatRoom,
+			MinRole:     plugin.RoleChatRoomAdmin,
+			Handler:     p.onWelcomeEnabled,
+		},
+		{
+			Prefix:      "#",
+			Name:        "关闭欢迎",
+			Description: "关闭当前群聊的新成员欢迎",
+			Scope:       plugin.CommandScopeChatRoom,
+			MinRole:     plugin.RoleChatRoomAdmin,
+			Handler:     p.onWelcomeDisabled,
+		},
+	}
+}
+
+func (p *ChatRoomAdminCommandPlugin) reply(ctx *plugin.MessageContext, action string, err error) {
+	if err != nil {
+		log.Printf("%s失败: %v", action, err)
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, action+"失败："+err.Error(), ctx.Message.SenderWxID)
+		return
+	}
+	ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, action+"成功", ctx.Message.SenderWxID)
+}
+
+func (p *ChatRoomAdminCommandPlugin) onAIEnabled(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	p.reply(ctx, "开启AI", ctx.MessageService.SetChatRoomAIEnabled(ctx.Message.FromWxID, true))
+}
+
+func (p *ChatRoomAdminCommandPlugin) onAIDisabled(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	p.reply(ctx, "关闭AI", ctx.MessageService.SetChatRoomAIEnabled(ctx.Message.FromWxID, false))
+}
+
+func (p *ChatRoomAdminCommandPlugin) onSetAITrigger(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	p.reply(ctx, "设置触发词", ctx.MessageService.SetChatRoomAITrigger(ctx.Message.FromWxID, args.String("触发词")))
+}
+
+func (p *ChatRoomAdminCommandPlugin) onWelcomeEnabled(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	p.reply(ctx, "开启欢迎", ctx.MessageService.SetChatRoomWelcomeEnabled(ctx.Message.FromWxID, true))
+}
+
+func (p *ChatRoomAdminCommandPlugin) onWelcomeDisabled(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	p.reply(ctx, "关闭欢迎", ctx.MessageService.SetChatRoomWelcomeEnabled(ctx.Message.FromWxID, false))
+}
@@ -6,83 +6,80 @@ import (
 	"wechat-robot-client/service"
 )
 
-type ChatRoomAIChatSessionStartPlugin struct{}
+// ChatRoomAIChatCommandPlugin 群聊AI会话的进入、退出命令
+type ChatRoomAIChatCommandPlugin struct{}
 
-func NewChatRoomAIChatSessionStartPlugin() plugin.MessageHandler {
-	return &ChatRoomAIChatSessionStartPlugin{}
+func NewChatRoomAIChatCommandPlugin() plugin.MessageHandler {
+	return &ChatRoomAIChatCommandPlugin{}
 }
 
-func (p *ChatRoomAIChatSessionStartPlugin) GetName() string {
-	return "ChatRoomAIChatSessionStart"
+func (p *ChatRoomAIChatCommandPlugin) GetName() string {
+	return "ChatRoomAIChatCommand"
 }
 
-func (p *ChatRoomAIChatSessionStartPlugin) GetLabels() []string {
-	return []string{"chat"}
+func (p *ChatRoomAIChatCommandPlugin) GetLabels() []string {
+	return []string{"command"}
 }
 
-func (p *ChatRoomAIChatSessionStartPlugin) PreAction(ctx *plugin.MessageContext) bool {
+func (p *ChatRoomAIChatCommandPlugin) PreAction(ctx *plugin.MessageContext) bool {
 	return true
 }
 
-func (p *ChatRoomAIChatSessionStartPlugin) PostAction(ctx *plugin.MessageContext) {
+func (p *ChatRoomAIChatCommandPlugin) PostAction(ctx *plugin.MessageContext) {
 
 }
 
-func (p *ChatRoomAIChatSessionStartPlugin) Run(ctx *plugin.MessageContext) bool {
-	if !ctx.Message.IsChatRoom {
-		return false
-	}
-	aiChatService := service.NewAIChatService(ctx.Context, ctx.Settings)
-	aiDrawingService := service.NewAIDrawingService(ctx.Context, ctx.Settings)
-	if aiChatService.IsAISessionStart(ctx.Message) {
-		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiChatService.GetAISessionStartTips(), ctx.Message.SenderWxID)
-		// 是闲聊，则结束绘画上下文，如果有的话
-		err := aiDrawingService.ExpireAISession(ctx.Message)
-		if err != nil {
-			log.Println("结束绘画会话失败:", err)
-		}
-		// 重置一下会话上下文
-		err = ctx.MessageService.ResetChatRoomAIMessageContext(ctx.Message)
-		if err != nil {
-			log.Println("重置会话上下文失败:", err)
-		}
-		return true
-	}
+// Run 命令由命令路由分发，插件本身不处理消息
+func (p *ChatRoomAIChatCommandPlugin) Run(ctx *plugin.MessageContext) bool {
 	return false
 }
 
-type ChatRoomAIChatSessionEndPlugin struct{}
-
-func NewChatRoomAIChatSessionEndPlugin() plugin.MessageHandler {
-	return &ChatRoomAIChatSessionEndPlugin{}
-}
-
-func (p *ChatRoomAIChatSessionEndPlugin) GetName() string {
-	return "ChatRoomAIChatSessionEnd"
-}
-
-func (p *ChatRoomAIChatSessionEndPlugin) GetLabels() []string {
-	return []string{"chat"}
-}
-
-func (p *ChatRoomAIChatSessionEndPlugin) PreAction(ctx *plugin.MessageContext) bool {
-	return true
+func (p *ChatRoomAIChatCommandPlugin) GetCommands() []*plugin.Command {
+	return []*plugin.Command{
+		{
+			Prefix:      "#",
+			Name:        "进入AI会话",
+			Description: "开始AI会话，会话中的消息不需要@机器人",
+			Scope:       plugin.CommandScopeChatRoom,
+			Handler:     p.onSessionStart,
+		},
+		{
+			Prefix:      "#",
+			Name:        "退出AI会话",
+			Description: "结束AI会话",
+			Scope:       plugin.CommandScopeChatRoom,
+			Handler:     p.onSessionEnd,
+		},
+	}
 }
 
-func (p *ChatRoomAIChatSessionEndPlugin) PostAction(ctx *plugin.MessageContext) {
-
+func (p *ChatRoomAIChatCommandPlugin) onSessionStart(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	aiChatService := service.NewAIChatService(ctx.Context, ctx.Settings)
+	aiDrawingService := service.NewAIDrawingService(ctx.Context, ctx.Settings)
+	if err := aiChatService.SetAISession(ctx.Message); err != nil {
+		log.Println("开始AI会话失败:", err)
+		return
+	}
+	ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiChatService.GetAISessionStartTips(), ctx.Message.SenderWxID)
+	// 是闲聊，则结束绘画上下文，如果有的话
+	err := aiDrawingService.ExpireAISession(ctx.Message)
+	if err != nil {
+		log.Println("结束绘画会话失败:", err)
+	}
+	// 重置一下会话上下文
+	err = ctx.MessageService.ResetChatRoomAIMessageContext(ctx.Message)
+	if err != nil {
+		log.Println("重置会话上下文失败:", err)
+	}
 }
 
-func (p *ChatRoomAIChatSessionEndPlugin) Run(ctx *plugin.MessageContext) bool {
-	if !ctx.Message.IsChatRoom {
-		return false
-	}
+func (p *ChatRoomAIChatCommandPlugin) onSessionEnd(ctx *plugin.MessageContext, args plugin.CommandArgs) {
 	aiChatService := service.NewAIChatService(ctx.Context, ctx.Settings)
-	if aiChatService.IsAISessionEnd(ctx.Message) {
-		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiChatService.GetAISessionEndTips(), ctx.Message.SenderWxID)
-		return true
+	if err := aiChatService.ExpireAISession(ctx.Message); err != nil {
+		log.Println("结束AI会话失败:", err)
+		return
 	}
-	return false
+	ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiChatService.GetAISessionEndTips(), ctx.Message.SenderWxID)
 }
 
 type ChatRoomAIChatPlugin struct{}
@@ -6,83 +6,80 @@ import (
 	"wechat-robot-client/service"
 )
 
-type ChatRoomAIDrawingSessionStartPlugin struct{}
+// ChatRoomAIDrawingCommandPlugin 群聊AI绘图的进入、退出命令
+type ChatRoomAIDrawingCommandPlugin struct{}
 
-func NewChatRoomAIDrawingSessionStartPlugin() plugin.MessageHandler {
-	return &ChatRoomAIDrawingSessionStartPlugin{}
+func NewChatRoomAIDrawingCommandPlugin() plugin.MessageHandler {
+	return &ChatRoomAIDrawingCommandPlugin{}
 }
 
-func (p *ChatRoomAIDrawingSessionStartPlugin) GetName() string {
-	return "ChatRoomAIDrawingSessionStart"
+func (p *ChatRoomAIDrawingCommandPlugin) GetName() string {
+	return "ChatRoomAIDrawingCommand"
 }
 
-func (p *ChatRoomAIDrawingSessionStartPlugin) GetLabels() []string {
-	return []string{"text", "drawing"}
+func (p *ChatRoomAIDrawingCommandPlugin) GetLabels() []string {
+	return []string{"command"}
 }
 
-func (p *ChatRoomAIDrawingSessionStartPlugin) PreAction(ctx *plugin.MessageContext) bool {
+func (p *ChatRoomAIDrawingCommandPlugin) PreAction(ctx *plugin.MessageContext) bool {
 	return true
 }
 
-func (p *ChatRoomAIDrawingSessionStartPlugin) PostAction(ctx *plugin.MessageContext) {
+func (p *ChatRoomAIDrawingCommandPlugin) PostAction(ctx *plugin.MessageContext) {
 
 }
 
-func (p *ChatRoomAIDrawingSessionStartPlugin) Run(ctx *plugin.MessageContext) bool {
-	if !ctx.Message.IsChatRoom {
-		return false
-	}
-	aiChatService := service.NewAIChatService(ctx.Context, ctx.Settings)
-	aiDrawingService := service.NewAIDrawingService(ctx.Context, ctx.Settings)
-	if aiDrawingService.IsAISessionStart(ctx.Message) {
-		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiDrawingService.GetAISessionStartTips(), ctx.Message.SenderWxID)
-		// 是绘画，则结束闲聊上下文，如果有的话
-		err := aiChatService.ExpireAISession(ctx.Message)
-		if err != nil {
-			log.Println("结束闲聊会话失败:", err)
-		}
-		// 重置一下会话上下文
-		err = ctx.MessageService.ResetChatRoomAIMessageContext(ctx.Message)
-		if err != nil {
-			log.Println("重置会话上下文失败:", err)
-		}
-		return true
-	}
+// Run 命令由命令路由分发，插件本身不处理消息
+func (p *ChatRoomAIDrawingCommandPlugin) Run(ctx *plugin.MessageContext) bool {
 	return false
 }
 
-type ChatRoomAIDrawingSessionEndPlugin struct{}
-
-func NewChatRoomAIDrawingSessionEndPlugin() plugin.MessageHandler {
-	return &ChatRoomAIDrawingSessionEndPlugin{}
-}
-
-func (p *ChatRoomAIDrawingSessionEndPlugin) GetName() string {
-	return "ChatRoomAIDrawingSessionEnd"
-}
-
-func (p *ChatRoomAIDrawingSessionEndPlugin) GetLabels() []string {
-	return []string{"drawing"}
-}
-
-func (p *ChatRoomAIDrawingSessionEndPlugin) PreAction(ctx *plugin.MessageContext) bool {
-	return true
+func (p *ChatRoomAIDrawingCommandPlugin) GetCommands() []*plugin.Command {
+	return []*plugin.Command{
+		{
+			Prefix:      "#",
+			Name:        "进入AI绘图",
+			Description: "开始AI绘图，会话中发送的消息会作为绘图提示词",
+			Scope:       plugin.CommandScopeChatRoom,
+			Handler:     p.onSessionStart,
+		},
+		{
+			Prefix:      "#",
+			Name:        "退出AI绘图",
+			Description: "结束AI绘图",
+			Scope:       plugin.CommandScopeChatRoom,
+			Handler:     p.onSessionEnd,
+		},
+	}
 }
 
-func (p *ChatRoomAIDrawingSessionEndPlugin) PostAction(ctx *plugin.MessageContext) {
-
+func (p *ChatRoomAIDrawingCommandPlugin) onSessionStart(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	aiChatService := service.NewAIChatService(ctx.Context, ctx.Settings)
+	aiDrawingService := service.NewAIDrawingService(ctx.Context, ctx.Settings)
+	if err := aiDrawingService.SetAISession(ctx.Message); err != nil {
+		log.Println("开始AI绘图失败:", err)
+		return
+	}
+	ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiDrawingService.GetAISessionStartTips(), ctx.Message.SenderWxID)
+	// 是绘画，则结束闲聊上下文，如果有的话
+	err := aiChatService.ExpireAISession(ctx.Message)
+	if err != nil {
+		log.Println("结束闲聊会话失败:", err)
+	}
+	// 重置一下会话上下文
+	err = ctx.MessageService.ResetChatRoomAIMessageContext(ctx.Message)
+	if err != nil {
+		log.Println("重置会话上下文失败:", err)
+	}
 }
 
-func (p *ChatRoomAIDrawingSessionEndPlugin) Run(ctx *plugin.MessageContext) bool {
-	if !ctx.Message.IsChatRoom {
-		return false
-	}
+func (p *ChatRoomAIDrawingCommandPlugin) onSessionEnd(ctx *plugin.MessageContext, args plugin.CommandArgs) {
 	aiDrawingService := service.NewAIDrawingService(ctx.Context, ctx.Settings)
-	if aiDrawingService.IsAISessionEnd(ctx.Message) {
-		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiDrawingService.GetAISessionEndTips(), ctx.Message.SenderWxID)
-		return true
+	if err := aiDrawingService.ExpireAISession(ctx.Message); err != nil {
+		log.Println("结束AI绘图失败:", err)
+		return
 	}
-	return false
+	ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiDrawingService.GetAISessionEndTips(), ctx.Message.SenderWxID)
 }
 
 type ChatRoomAIDrawingPlugin struct{}
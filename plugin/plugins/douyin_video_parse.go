@@ -1,11 +1,14 @@
 package plugins
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/sashabaranov/go-openai/jsonschema"
 
 	"wechat-robot-client/interface/plugin"
 	"wechat-robot-client/pkg/robot"
@@ -109,3 +112,35 @@ func (p *DouyinVideoParsePlugin) Run(ctx *plugin.MessageContext) bool {
 
 	return true
 }
+
+func (p *DouyinVideoParsePlugin) GetTools() []*plugin.Tool {
+	return []*plugin.Tool{
+		{
+			Name:        "parse_douyin_video",
+			Description: "解析抖音分享链接，下载无水印视频并发送给用户",
+			Parameters: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"url": {Type: jsonschema.String, Description: "抖音分享链接，例如 https://v.douyin.com/xxx"},
+				},
+				Required: []string{"url"},
+			},
+			Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+				var params struct {
+					URL string `json:"url"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil || params.URL == "" {
+					return "", errors.New("抖音链接不能为空")
+				}
+				// Run 从引用消息或者消息内容中提取链接，这里换成AI给出的链接
+				toolCtx := withMessageContent(ctx, params.URL)
+				message := *ctx.Message
+				message.Content = params.URL
+				toolCtx.Message = &message
+				toolCtx.ReferMessage = nil
+				p.Run(toolCtx)
+				return "视频已经发送给用户", nil
+			},
+		},
+	}
+}
@@ -6,77 +6,74 @@ import (
 	"wechat-robot-client/service"
 )
 
-type FriendAIDrawingSessionStartPlugin struct{}
+// FriendAIDrawingCommandPlugin 私聊AI绘图的进入、退出命令
+type FriendAIDrawingCommandPlugin struct{}
 
-func NewFriendAIDrawingSessionStartPlugin() plugin.MessageHandler {
-	return &FriendAIDrawingSessionStartPlugin{}
+func NewFriendAIDrawingCommandPlugin() plugin.MessageHandler {
+	return &FriendAIDrawingCommandPlugin{}
 }
 
-func (p *FriendAIDrawingSessionStartPlugin) GetName() string {
-	return "FriendAIDrawingSessionStart"
+func (p *FriendAIDrawingCommandPlugin) GetName() string {
+	return "FriendAIDrawingCommand"
 }
 
-func (p *FriendAIDrawingSessionStartPlugin) GetLabels() []string {
-	return []string{"text", "drawing"}
+func (p *FriendAIDrawingCommandPlugin) GetLabels() []string {
+	return []string{"command"}
 }
 
-func (p *FriendAIDrawingSessionStartPlugin) PreAction(ctx *plugin.MessageContext) bool {
+func (p *FriendAIDrawingCommandPlugin) PreAction(ctx *plugin.MessageContext) bool {
 	return true
 }
 
-func (p *FriendAIDrawingSessionStartPlugin) PostAction(ctx *plugin.MessageContext) {
+func (p *FriendAIDrawingCommandPlugin) PostAction(ctx *plugin.MessageContext) {
 
 }
 
-func (p *FriendAIDrawingSessionStartPlugin) Run(ctx *plugin.MessageContext) bool {
-	if ctx.Message.IsChatRoom {
-		return false
-	}
-	aiDrawingService := service.NewAIDrawingService(ctx.Context, ctx.Settings)
-	if aiDrawingService.IsAISessionStart(ctx.Message) {
-		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiDrawingService.GetAISessionStartTips(), ctx.Message.SenderWxID)
-		// 重置一下会话上下文
-		err := ctx.MessageService.ResetChatRoomAIMessageContext(ctx.Message)
-		if err != nil {
-			log.Println("重置会话上下文失败:", err)
-		}
-		return true
-	}
+// Run 命令由命令路由分发，插件本身不处理消息
+func (p *FriendAIDrawingCommandPlugin) Run(ctx *plugin.MessageContext) bool {
 	return false
 }
 
-type FriendAIDrawingSessionEndPlugin struct{}
-
-func NewFriendAIDrawingSessionEndPlugin() plugin.MessageHandler {
-	return &FriendAIDrawingSessionEndPlugin{}
-}
-
-func (p *FriendAIDrawingSessionEndPlugin) GetName() string {
-	return "FriendAIDrawingSessionEnd"
-}
-
-func (p *FriendAIDrawingSessionEndPlugin) GetLabels() []string {
-	return []string{"drawing"}
-}
-
-func (p *FriendAIDrawingSessionEndPlugin) PreAction(ctx *plugin.MessageContext) bool {
-	return true
+func (p *FriendAIDrawingCommandPlugin) GetCommands() []*plugin.Command {
+	return []*plugin.Command{
+		{
+			Prefix:      "#",
+			Name:        "进入AI绘图",
+			Description: "开始AI绘图，会话中发送的消息会作为绘图提示词",
+			Scope:       plugin.CommandScopeFriend,
+			Handler:     p.onSessionStart,
+		},
+		{
+			Prefix:      "#",
+			Name:        "退出AI绘图",
+			Description: "结束AI绘图",
+			Scope:       plugin.CommandScopeFriend,
+			Handler:     p.onSessionEnd,
+		},
+	}
 }
 
-func (p *FriendAIDrawingSessionEndPlugin) PostAction(ctx *plugin.MessageContext) {
-
+func (p *FriendAIDrawingCommandPlugin) onSessionStart(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	aiDrawingService := service.NewAIDrawingService(ctx.Context, ctx.Settings)
+	if err := aiDrawingService.SetAISession(ctx.Message); err != nil {
+		log.Println("开始AI绘图失败:", err)
+		return
+	}
+	ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiDrawingService.GetAISessionStartTips())
+	// 重置一下会话上下文
+	err := ctx.MessageService.ResetChatRoomAIMessageContext(ctx.Message)
+	if err != nil {
+		log.Println("重置会话上下文失败:", err)
+	}
 }
 
-func (p *FriendAIDrawingSessionEndPlugin) Run(ctx *plugin.MessageContext) bool {
-	if ctx.Message.IsChatRoom {
-		return false
-	}
+func (p *FriendAIDrawingCommandPlugin) onSessionEnd(ctx *plugin.MessageContext, args plugin.CommandArgs) {
 	aiDrawingService := service.NewAIDrawingService(ctx.Context, ctx.Settings)
-	if aiDrawingService.IsAISessionEnd(ctx.Message) {
-		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiDrawingService.GetAISessionEndTips(), ctx.Message.SenderWxID)
-		return true
+	if err := aiDrawingService.ExpireAISession(ctx.Message); err != nil {
+		log.Println("结束AI绘图失败:", err)
+		return
 	}
-	return false
+	ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiDrawingService.GetAISessionEndTips())
 }
 
 type FriendAIDrawingPlugin struct{}
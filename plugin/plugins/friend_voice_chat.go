@@ -0,0 +1,87 @@
+package plugins
+
+import (
+	"log"
+	"unicode/utf8"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/service"
+	"wechat-robot-client/vars"
+)
+
+// FriendAIVoiceChatPlugin 私聊语音聊天，语音识别出的文字交给AI回复，开启了文本转语音时用语音回复
+type FriendAIVoiceChatPlugin struct{}
+
+func NewFriendAIVoiceChatPlugin() plugin.MessageHandler {
+	return &FriendAIVoiceChatPlugin{}
+}
+
+func (p *FriendAIVoiceChatPlugin) GetName() string {
+	return "FriendAIVoiceChat"
+}
+
+func (p *FriendAIVoiceChatPlugin) GetLabels() []string {
+	return []string{"voice", "chat"}
+}
+
+func (p *FriendAIVoiceChatPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	return true
+}
+
+func (p *FriendAIVoiceChatPlugin) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+func (p *FriendAIVoiceChatPlugin) Run(ctx *plugin.MessageContext) bool {
+	if ctx.Message.IsChatRoom {
+		return false
+	}
+	if ctx.Message.SenderWxID == vars.RobotRuntime.WxID {
+		return false
+	}
+	if !ctx.Settings.IsAIChatEnabled() || ctx.MessageContent == "" {
+		return false
+	}
+	aiChatService := service.NewAIChatService(ctx.Context, ctx.Settings)
+	defer func() {
+		aiChatService.RenewAISession(ctx.Message)
+		err := ctx.MessageService.SetMessageIsInContext(ctx.Message)
+		if err != nil {
+			log.Printf("更新消息上下文失败: %v", err)
+		}
+	}()
+	if !checkRateLimit(ctx, model.RateLimitFeatureChat) {
+		return true
+	}
+	if !checkAIQuota(ctx) {
+		return true
+	}
+	aiContext, err := ctx.MessageService.GetAIMessageContext(ctx.Message)
+	if err != nil {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, err.Error())
+		return true
+	}
+	aiReply, err := aiChatService.Chat(withKnowledge(ctx, withMemory(ctx, aiContext)))
+	if err != nil {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, err.Error())
+		return true
+	}
+	aiReplyText := aiReply.Content
+	if aiReplyText == "" && len(aiReply.MultiContent) > 0 {
+		aiReplyText = aiReply.MultiContent[0].Text
+	}
+	if aiReplyText == "" {
+		aiReplyText = "AI返回了空内容。"
+	}
+	defer rememberConversation(ctx, aiContext, aiReplyText)
+	// 语音消息最长只能发送一分钟左右，回复太长时改用文字回复；AI聊天已经计过数，语音回复不再单独限流
+	if ctx.Settings.IsTTSEnabled() && utf8.RuneCountInString(aiReplyText) <= 260 {
+		err = sendTTSVoice(ctx, aiReplyText)
+		if err == nil {
+			return true
+		}
+		log.Printf("语音回复失败，改用文字回复: %v", err)
+	}
+	ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, aiReplyText)
+	return true
+}
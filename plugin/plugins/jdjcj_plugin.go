@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -13,9 +13,7 @@ import (
 )
 
 // JdjcjPlugin 京东积存金价格查询插件
-type JdjcjPlugin struct {
-	config JdjcjConfig
-}
+type JdjcjPlugin struct{}
 
 // JdjcjConfig 插件配置
 type JdjcjConfig struct {
@@ -47,9 +45,7 @@ type PriceResponse struct {
 
 // NewJdjcjPlugin 创建京东积存金插件实例
 func NewJdjcjPlugin() plugin.MessageHandler {
-	plugin := &JdjcjPlugin{}
-	plugin.loadConfig()
-	return plugin
+	return &JdjcjPlugin{}
 }
 
 // GetName 获取插件名称
@@ -81,24 +77,29 @@ func (p *JdjcjPlugin) Run(ctx *plugin.MessageContext) bool {
 		return false
 	}
 
-	// 处理语音开关命令
+	// 处理语音开关命令，开关只对当前群聊/好友生效
 	if content == "积存金语音开" || content == "积存金语音打开" {
-		p.config.VoiceReply = true
-		p.saveConfig()
+		if err := p.saveVoiceReply(ctx, true); err != nil {
+			p.sendReply(ctx, "text", "开启积存金语音回复功能失败")
+			return true
+		}
 		p.sendReply(ctx, "text", "已开启积存金语音回复功能")
 		return true
 	}
 
 	if content == "积存金语音关" || content == "积存金语音关闭" {
-		p.config.VoiceReply = false
-		p.saveConfig()
+		if err := p.saveVoiceReply(ctx, false); err != nil {
+			p.sendReply(ctx, "text", "关闭积存金语音回复功能失败")
+			return true
+		}
 		p.sendReply(ctx, "text", "已关闭积存金语音回复功能")
 		return true
 	}
 
 	// 处理查询命令
 	if content == "jcj" || content == "积存金" || content == "激存金" {
-		price, _, err := p.getJdjcjPrice()
+		cfg := p.loadConfig(ctx)
+		price, _, err := p.getJdjcjPrice(cfg)
 		if err != nil {
 			p.sendReply(ctx, "text", "获取失败,等待修复⌛️")
 			return true
@@ -110,7 +111,7 @@ func (p *JdjcjPlugin) Run(ctx *plugin.MessageContext) bool {
 			p.sendReply(ctx, "text", priceText)
 
 			// 根据配置决定是否使用语音回复
-			if p.config.VoiceReply {
+			if cfg.VoiceReply {
 				// 这里可以添加语音回复逻辑
 				// 暂时用文字表示
 				p.sendReply(ctx, "text", "🔊 语音回复: 京东积存金当前价格"+fmt.Sprintf("%.2f元每克", price))
@@ -141,31 +142,34 @@ func (p *JdjcjPlugin) containsJdjcjKeywords(content string) bool {
 	return false
 }
 
-// loadConfig 加载配置
-func (p *JdjcjPlugin) loadConfig() {
-	configPath := "plugin/plugins/jdjcj_config.json"
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		// 使用默认配置
-		p.config = JdjcjConfig{
-			VoiceReply: false,
-			APIBaseURL: "https://api.jdjygold.com/gw/generic/hj/h5/m/",
-		}
-		return
+// defaultJdjcjConfig 默认配置
+func defaultJdjcjConfig() JdjcjConfig {
+	return JdjcjConfig{
+		VoiceReply: false,
+		APIBaseURL: "https://api.jdjygold.com/gw/generic/hj/h5/m/",
 	}
+}
 
-	json.Unmarshal(data, &p.config)
+// loadConfig 从插件配置中加载配置，未配置的字段使用默认值
+func (p *JdjcjPlugin) loadConfig(ctx *plugin.MessageContext) JdjcjConfig {
+	config := defaultJdjcjConfig()
+	if err := ctx.GetPluginConfig(p.GetName(), &config); err != nil {
+		log.Printf("解析Jdjcj插件配置失败: %v", err)
+		return defaultJdjcjConfig()
+	}
+	return config
 }
 
-// saveConfig 保存配置
-func (p *JdjcjPlugin) saveConfig() {
-	configPath := "plugin/plugins/jdjcj_config.json"
-	data, err := json.MarshalIndent(p.config, "", "    ")
+// saveVoiceReply 保存当前群聊/好友的语音回复开关
+func (p *JdjcjPlugin) saveVoiceReply(ctx *plugin.MessageContext, voiceReply bool) error {
+	err := ctx.MessageService.UpdatePluginConfig(p.GetName(), ctx.Message.FromWxID, map[string]any{
+		"voice_reply": voiceReply,
+	})
 	if err != nil {
-		return
+		log.Printf("保存Jdjcj插件配置失败: %v", err)
+		return err
 	}
-
-	os.WriteFile(configPath, data, 0644)
+	return nil
 }
 
 // sendReply 发送回复
@@ -178,8 +182,8 @@ func (p *JdjcjPlugin) sendReply(ctx *plugin.MessageContext, replyType, content s
 }
 
 // getJdjcjPrice 获取京东积存金价格
-func (p *JdjcjPlugin) getJdjcjPrice() (float64, int64, error) {
-	url := p.config.APIBaseURL + "latestPrice"
+func (p *JdjcjPlugin) getJdjcjPrice(cfg JdjcjConfig) (float64, int64, error) {
+	url := cfg.APIBaseURL + "latestPrice"
 
 	// 创建请求
 	req, err := http.NewRequest("POST", url, nil)
@@ -3,18 +3,16 @@ package plugins
 import (
 	"encoding/json"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 	"wechat-robot-client/interface/plugin"
 )
 
 // KFCWenanPlugin KFC文案插件
-type KFCWenanPlugin struct {
-	config KFCWenanConfig
-}
+type KFCWenanPlugin struct{}
 
 // KFCWenanConfig 插件配置
 type KFCWenanConfig struct {
@@ -29,9 +27,7 @@ type APIResponse struct {
 
 // NewKFCWenanPlugin 创建KFC文案插件实例
 func NewKFCWenanPlugin() plugin.MessageHandler {
-	plugin := &KFCWenanPlugin{}
-	plugin.loadConfig()
-	return plugin
+	return &KFCWenanPlugin{}
 }
 
 // GetName 获取插件名称
@@ -56,11 +52,12 @@ func (p *KFCWenanPlugin) PostAction(ctx *plugin.MessageContext) {
 
 // Run 主要逻辑
 func (p *KFCWenanPlugin) Run(ctx *plugin.MessageContext) bool {
+	cfg := p.loadConfig(ctx)
 	content := strings.ToLower(strings.TrimSpace(ctx.MessageContent))
 
 	// 检查是否是KFC相关命令
 	if content == "kfc" || content == "疯狂星期四" {
-		result := p.getKFCWenan()
+		result := p.getKFCWenan(cfg)
 		if result != "" {
 			p.sendReply(ctx, "text", result)
 		} else {
@@ -71,7 +68,7 @@ func (p *KFCWenanPlugin) Run(ctx *plugin.MessageContext) bool {
 
 	// 检查是否是舔狗相关命令
 	if content == "舔狗" {
-		result := p.getDogWenan()
+		result := p.getDogWenan(cfg)
 		if result != "" {
 			p.sendReply(ctx, "text", result)
 		} else {
@@ -83,19 +80,21 @@ func (p *KFCWenanPlugin) Run(ctx *plugin.MessageContext) bool {
 	return false
 }
 
-// loadConfig 加载配置
-func (p *KFCWenanPlugin) loadConfig() {
-	configPath := "plugin/plugins/kfcwenan_config.json"
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		// 使用默认配置
-		p.config = KFCWenanConfig{
-			BaseURL: "https://api.pearktrue.cn/api/",
-		}
-		return
+// defaultKFCWenanConfig 默认配置
+func defaultKFCWenanConfig() KFCWenanConfig {
+	return KFCWenanConfig{
+		BaseURL: "https://api.pearktrue.cn/api/",
 	}
+}
 
-	json.Unmarshal(data, &p.config)
+// loadConfig 从插件配置中加载配置，未配置的字段使用默认值
+func (p *KFCWenanPlugin) loadConfig(ctx *plugin.MessageContext) KFCWenanConfig {
+	config := defaultKFCWenanConfig()
+	if err := ctx.GetPluginConfig(p.GetName(), &config); err != nil {
+		log.Printf("解析KFCWenan插件配置失败: %v", err)
+		return defaultKFCWenanConfig()
+	}
+	return config
 }
 
 // sendReply 发送回复
@@ -108,14 +107,14 @@ func (p *KFCWenanPlugin) sendReply(ctx *plugin.MessageContext, replyType, conten
 }
 
 // getKFCWenan 获取KFC文案
-func (p *KFCWenanPlugin) getKFCWenan() string {
-	apiURL := p.config.BaseURL + "kfc"
+func (p *KFCWenanPlugin) getKFCWenan(cfg KFCWenanConfig) string {
+	apiURL := cfg.BaseURL + "kfc"
 	return p.makeAPIRequest(apiURL)
 }
 
 // getDogWenan 获取舔狗文案
-func (p *KFCWenanPlugin) getDogWenan() string {
-	apiURL := p.config.BaseURL + "dog"
+func (p *KFCWenanPlugin) getDogWenan(cfg KFCWenanConfig) string {
+	apiURL := cfg.BaseURL + "dog"
 	return p.makeAPIRequest(apiURL)
 }
 
@@ -0,0 +1,148 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"slices"
+	"time"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/service"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 通过消息加入知识库的文档最大20MB
+const maxKnowledgeDocumentSize = 20 * 1024 * 1024
+
+// KnowledgeBaseCommandPlugin 引用一条文件消息，把文件加入当前聊天关联的知识库
+type KnowledgeBaseCommandPlugin struct{}
+
+func NewKnowledgeBaseCommandPlugin() plugin.MessageHandler {
+	return &KnowledgeBaseCommandPlugin{}
+}
+
+func (p *KnowledgeBaseCommandPlugin) GetName() string {
+	return "KnowledgeBaseCommand"
+}
+
+func (p *KnowledgeBaseCommandPlugin) GetLabels() []string {
+	return []string{"command"}
+}
+
+func (p *KnowledgeBaseCommandPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	return true
+}
+
+func (p *KnowledgeBaseCommandPlugin) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+// Run 命令由命令路由分发，插件本身不处理消息
+func (p *KnowledgeBaseCommandPlugin) Run(ctx *plugin.MessageContext) bool {
+	return false
+}
+
+func (p *KnowledgeBaseCommandPlugin) GetCommands() []*plugin.Command {
+	return []*plugin.Command{
+		{
+			Prefix:      "#",
+			Name:        "加入知识库",
+			Description: "引用一条文件消息(txt、md、pdf、docx)，把文件加入当前聊天关联的知识库，不指定知识库名称时加入第一个",
+			Args:        []plugin.CommandArg{{Name: "知识库名称", Type: plugin.CommandArgText}},
+			MinRole:     plugin.RoleChatRoomAdmin,
+			Permission: func(ctx *plugin.MessageContext) bool {
+				return len(ctx.Settings.GetKnowledgeBaseIDs()) > 0
+			},
+			Cooldown: 10 * time.Second,
+			Handler:  p.onAddDocument,
+		},
+	}
+}
+
+func (p *KnowledgeBaseCommandPlugin) onAddDocument(ctx *plugin.MessageContext, args plugin.CommandArgs) {
+	refer := ctx.ReferMessage
+	if refer == nil || refer.Type != model.MsgTypeApp || refer.AppMsgType != model.AppMsgTypeAttach {
+		replyText(ctx, "你需要引用一条文件消息。")
+		return
+	}
+	// 生成文档向量同样消耗AI额度
+	if !checkAIQuota(ctx) {
+		return
+	}
+	knowledgeBaseService := service.NewKnowledgeBaseService(ctx.Context)
+	knowledgeBase, err := p.findKnowledgeBase(ctx, knowledgeBaseService, args.String("知识库名称"))
+	if err != nil {
+		replyText(ctx, err.Error())
+		return
+	}
+	reader, filename, err := service.NewAttachDownloadService(ctx.Context).DownloadFile(refer.ID)
+	if err != nil {
+		log.Printf("下载文件失败: %v", err)
+		replyText(ctx, "下载文件失败，请稍后再试。")
+		return
+	}
+	defer reader.Close()
+	filename = filepath.Base(filename)
+	if !service.IsKnowledgeDocument(filename) {
+		replyText(ctx, "不支持的文档格式，仅支持 txt、md、pdf、docx。")
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, maxKnowledgeDocumentSize+1))
+	if err != nil {
+		log.Printf("读取文件失败: %v", err)
+		replyText(ctx, "读取文件失败，请稍后再试。")
+		return
+	}
+	if len(data) > maxKnowledgeDocumentSize {
+		replyText(ctx, "文档大小不能超过20MB。")
+		return
+	}
+	document, err := knowledgeBaseService.AddDocument(knowledgeBase.ID, filename, data)
+	if err != nil {
+		replyText(ctx, fmt.Sprintf("加入知识库失败: %v", err))
+		return
+	}
+	replyText(ctx, fmt.Sprintf("《%s》已加入知识库「%s」，共 %d 个片段，正在建立索引，稍后即可检索。", document.Name, knowledgeBase.Name, document.ChunkCount))
+}
+
+// findKnowledgeBase 在当前聊天关联的知识库中查找，名称为空时返回第一个
+func (p *KnowledgeBaseCommandPlugin) findKnowledgeBase(ctx *plugin.MessageContext, knowledgeBaseService *service.KnowledgeBaseService, name string) (*model.KnowledgeBase, error) {
+	ids := ctx.Settings.GetKnowledgeBaseIDs()
+	for _, id := range ids {
+		knowledgeBase, err := knowledgeBaseService.GetKnowledgeBase(id)
+		if err != nil {
+			log.Printf("获取知识库失败: %v", err)
+			return nil, errors.New("获取知识库失败，请稍后再试。")
+		}
+		if knowledgeBase != nil && (name == "" || knowledgeBase.Name == name) {
+			return knowledgeBase, nil
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("当前聊天没有关联知识库「%s」。", name)
+	}
+	return nil, errors.New("当前聊天关联的知识库不存在。")
+}
+
+// withKnowledge 当前聊天关联了知识库时，检索和用户问题相关的资料，作为系统提示词放在用户问题前面
+func withKnowledge(ctx *plugin.MessageContext, aiContext []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	ids := ctx.Settings.GetKnowledgeBaseIDs()
+	if len(ids) == 0 || len(aiContext) == 0 {
+		return aiContext
+	}
+	last := aiContext[len(aiContext)-1]
+	prompt, err := service.NewKnowledgeBaseService(ctx.Context).KnowledgePrompt(ctx.Message, ids, messageText(last))
+	if err != nil {
+		log.Printf("检索知识库失败: %v", err)
+		return aiContext
+	}
+	if prompt == "" {
+		return aiContext
+	}
+	knowledge := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: prompt}
+	return append(slices.Clone(aiContext[:len(aiContext)-1]), knowledge, last)
+}
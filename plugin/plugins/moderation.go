@@ -0,0 +1,96 @@
+package plugins
+
+import (
+	"fmt"
+	"log"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/service"
+)
+
+// 违规类别在提示消息中的名称
+var moderationCategoryNames = map[model.ModerationCategory]string{
+	model.ModerationCategoryAd:       "广告",
+	model.ModerationCategoryScam:     "诈骗",
+	model.ModerationCategoryPorn:     "色情低俗",
+	model.ModerationCategoryViolence: "暴力",
+	model.ModerationCategoryOffTopic: "无关刷屏",
+	model.ModerationCategoryFlood:    "刷屏",
+	model.ModerationCategoryKeyword:  "违禁内容",
+}
+
+// ModerationPlugin 群聊内容审核，先按关键词、正则和刷屏规则审核，没有命中并且配置了审核模型时再由AI审核，命中后中止插件链
+// 群管理员、群主和超级管理员的消息不审核
+type ModerationPlugin struct{}
+
+func NewModerationPlugin() plugin.MessageHandler {
+	return &ModerationPlugin{}
+}
+
+func (p *ModerationPlugin) GetName() string {
+	return "Moderation"
+}
+
+func (p *ModerationPlugin) GetLabels() []string {
+	return []string{"text", "share_card", "link"}
+}
+
+func (p *ModerationPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	if !ctx.Message.IsChatRoom || !ctx.Settings.IsModerationEnabled() {
+		return false
+	}
+	return ctx.SenderRole() < plugin.RoleChatRoomAdmin
+}
+
+func (p *ModerationPlugin) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+func (p *ModerationPlugin) Run(ctx *plugin.MessageContext) bool {
+	config := ctx.Settings.GetModerationSettings()
+	moderationService := service.NewModerationService(ctx.Context)
+	result, err := moderationService.CheckRules(config, ctx.Message, ctx.MessageContent)
+	if err != nil {
+		log.Printf("内容审核失败: %v", err)
+		return false
+	}
+	if result != nil {
+		p.punish(ctx, moderationService, config, result)
+		return true
+	}
+	if config.Model != "" {
+		// AI审核同步执行，命中后中止插件链，违规消息不会再触发AI回复等其他插件
+		result, err := moderationService.CheckAI(ctx.Settings.GetAIConfig(), config, ctx.Message, ctx.MessageContent)
+		if err != nil {
+			log.Printf("AI内容审核失败: %v", err)
+			return false
+		}
+		if result != nil {
+			p.punish(ctx, moderationService, config, result)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ModerationPlugin) punish(ctx *plugin.MessageContext, moderationService *service.ModerationService, config model.ModerationSettings, result *service.ModerationResult) {
+	moderationLog, err := moderationService.Punish(config, ctx.Message, result)
+	if err != nil {
+		log.Printf("处理违规消息失败: %v", err)
+		return
+	}
+	nickname := ctx.MessageService.GetSenderNickname(ctx.Message)
+	if moderationLog.Action == model.ModerationActionKick {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, fmt.Sprintf(service.ModerationNoticePrefix+"%s 多次发送%s内容，已被移出群聊。", nickname, moderationCategoryNames[result.Category]))
+		return
+	}
+	warning := fmt.Sprintf(service.ModerationNoticePrefix+"你的消息涉嫌%s", moderationCategoryNames[result.Category])
+	if result.Reason != "" {
+		warning += fmt.Sprintf("(%s)", result.Reason)
+	}
+	warning += fmt.Sprintf("，这是第 %d 次违规", moderationLog.Strikes)
+	if config.KickStrikes > 0 {
+		warning += fmt.Sprintf("，累计 %d 次将被移出群聊", config.KickStrikes)
+	}
+	replyText(ctx, warning+"。")
+}
@@ -1,9 +1,9 @@
 package plugins
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -14,9 +14,7 @@ import (
 )
 
 // NeteasyPlugin 网易视频下载插件
-type NeteasyPlugin struct {
-	config NeteasyConfig
-}
+type NeteasyPlugin struct{}
 
 // NeteasyConfig 插件配置
 type NeteasyConfig struct {
@@ -38,9 +36,7 @@ type VideoInfo struct {
 
 // NewNeteasyPlugin 创建网易视频插件实例
 func NewNeteasyPlugin() plugin.MessageHandler {
-	plugin := &NeteasyPlugin{}
-	plugin.loadConfig()
-	return plugin
+	return &NeteasyPlugin{}
 }
 
 // GetName 获取插件名称
@@ -65,10 +61,11 @@ func (p *NeteasyPlugin) PostAction(ctx *plugin.MessageContext) {
 
 // Run 主要逻辑
 func (p *NeteasyPlugin) Run(ctx *plugin.MessageContext) bool {
+	cfg := p.loadConfig(ctx)
 	content := ctx.MessageContent
 	
 	// 检查是否包含网易视频链接
-	if !strings.Contains(content, p.config.NeteasyAppID) {
+	if !strings.Contains(content, cfg.NeteasyAppID) {
 		return false
 	}
 	
@@ -79,7 +76,7 @@ func (p *NeteasyPlugin) Run(ctx *plugin.MessageContext) bool {
 	}
 	
 	// 下载视频
-	videoInfo, err := p.downloadVideo(videoURL)
+	videoInfo, err := p.downloadVideo(cfg, videoURL)
 	if err != nil {
 		p.sendReply(ctx, "text", fmt.Sprintf("视频下载失败: %v", err))
 		return true
@@ -106,23 +103,25 @@ func (p *NeteasyPlugin) Run(ctx *plugin.MessageContext) bool {
 	return true
 }
 
-// loadConfig 加载配置
-func (p *NeteasyPlugin) loadConfig() {
-	configPath := "plugin/plugins/neteasy_config.json"
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		// 使用默认配置
-		p.config = NeteasyConfig{
-			VideoFolder:    "video",
-			TempFolder:     "temp_segments",
-			UploadFolder:   "网易视频",
-			UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
-			NeteasyAppID:   "wx7be3c1bb46c68c63",
-		}
-		return
+// defaultNeteasyConfig 默认配置
+func defaultNeteasyConfig() NeteasyConfig {
+	return NeteasyConfig{
+		VideoFolder:    "video",
+		TempFolder:     "temp_segments",
+		UploadFolder:   "网易视频",
+		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+		NeteasyAppID:   "wx7be3c1bb46c68c63",
 	}
-	
-	json.Unmarshal(data, &p.config)
+}
+
+// loadConfig 从插件配置中加载配置，未配置的字段使用默认值
+func (p *NeteasyPlugin) loadConfig(ctx *plugin.MessageContext) NeteasyConfig {
+	config := defaultNeteasyConfig()
+	if err := ctx.GetPluginConfig(p.GetName(), &config); err != nil {
+		log.Printf("解析Neteasy插件配置失败: %v", err)
+		return defaultNeteasyConfig()
+	}
+	return config
 }
 
 // sendReply 发送回复
@@ -150,7 +149,7 @@ func (p *NeteasyPlugin) parseVideoURL(content string) string {
 }
 
 // downloadVideo 下载视频
-func (p *NeteasyPlugin) downloadVideo(url string) (*VideoInfo, error) {
+func (p *NeteasyPlugin) downloadVideo(cfg NeteasyConfig, url string) (*VideoInfo, error) {
 	// 创建HTTP客户端
 	client := &http.Client{
 		Timeout: 30 * time.Second,
@@ -166,7 +165,7 @@ func (p *NeteasyPlugin) downloadVideo(url string) (*VideoInfo, error) {
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7")
 	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
 	req.Header.Set("Host", "c.m.163.com")
-	req.Header.Set("User-Agent", p.config.UserAgent)
+	req.Header.Set("User-Agent", cfg.UserAgent)
 	
 	// 发送请求
 	resp, err := client.Do(req)
@@ -182,13 +181,13 @@ func (p *NeteasyPlugin) downloadVideo(url string) (*VideoInfo, error) {
 	}
 	
 	// 解析HTML内容
-	videoInfo := p.parseHTMLContent(string(body), url)
+	videoInfo := p.parseHTMLContent(cfg, string(body), url)
 	if videoInfo == nil {
 		return nil, fmt.Errorf("无法解析视频信息")
 	}
 	
 	// 下载M3U8视频
-	err = p.downloadM3U8Video(videoInfo)
+	err = p.downloadM3U8Video(cfg, videoInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -197,7 +196,7 @@ func (p *NeteasyPlugin) downloadVideo(url string) (*VideoInfo, error) {
 }
 
 // parseHTMLContent 解析HTML内容获取视频信息
-func (p *NeteasyPlugin) parseHTMLContent(htmlContent, originalURL string) *VideoInfo {
+func (p *NeteasyPlugin) parseHTMLContent(cfg NeteasyConfig, htmlContent, originalURL string) *VideoInfo {
 	// 查找包含data-m3u8属性的video标签
 	m3u8Pattern := regexp.MustCompile(`<video[^>]*data-m3u8="([^"]*)"[^>]*>`)
 	m3u8Matches := m3u8Pattern.FindStringSubmatch(htmlContent)
@@ -235,7 +234,7 @@ func (p *NeteasyPlugin) parseHTMLContent(htmlContent, originalURL string) *Video
 	title = p.cleanTitle(title)
 	
 	// 生成输出文件路径
-	outputFile := filepath.Join(p.config.VideoFolder, title+".mp4")
+	outputFile := filepath.Join(cfg.VideoFolder, title+".mp4")
 	
 	return &VideoInfo{
 		Title:       title,
@@ -261,15 +260,15 @@ func (p *NeteasyPlugin) cleanTitle(title string) string {
 }
 
 // downloadM3U8Video 下载M3U8视频
-func (p *NeteasyPlugin) downloadM3U8Video(videoInfo *VideoInfo) error {
+func (p *NeteasyPlugin) downloadM3U8Video(cfg NeteasyConfig, videoInfo *VideoInfo) error {
 	// 创建视频目录
-	err := os.MkdirAll(p.config.VideoFolder, 0755)
+	err := os.MkdirAll(cfg.VideoFolder, 0755)
 	if err != nil {
 		return err
 	}
 	
 	// 创建临时目录
-	err = os.MkdirAll(p.config.TempFolder, 0755)
+	err = os.MkdirAll(cfg.TempFolder, 0755)
 	if err != nil {
 		return err
 	}
@@ -295,7 +294,7 @@ func (p *NeteasyPlugin) downloadM3U8Video(videoInfo *VideoInfo) error {
 	
 	// 下载所有片段
 	for i, segmentURL := range segments {
-		segmentPath := filepath.Join(p.config.TempFolder, fmt.Sprintf("segment_%d.ts", i))
+		segmentPath := filepath.Join(cfg.TempFolder, fmt.Sprintf("segment_%d.ts", i))
 		err := p.downloadSegment(segmentURL, segmentPath)
 		if err != nil {
 			return fmt.Errorf("下载片段 %d 失败: %v", i, err)
@@ -303,13 +302,13 @@ func (p *NeteasyPlugin) downloadM3U8Video(videoInfo *VideoInfo) error {
 	}
 	
 	// 合并视频片段
-	err = p.mergeSegments(videoInfo.OutputFile, len(segments))
+	err = p.mergeSegments(cfg, videoInfo.OutputFile, len(segments))
 	if err != nil {
 		return fmt.Errorf("合并视频失败: %v", err)
 	}
 	
 	// 清理临时文件
-	p.cleanupTempSegments(len(segments))
+	p.cleanupTempSegments(cfg, len(segments))
 	
 	return nil
 }
@@ -355,9 +354,9 @@ func (p *NeteasyPlugin) downloadSegment(url, filepath string) error {
 }
 
 // mergeSegments 合并视频片段
-func (p *NeteasyPlugin) mergeSegments(outputFile string, segmentCount int) error {
+func (p *NeteasyPlugin) mergeSegments(cfg NeteasyConfig, outputFile string, segmentCount int) error {
 	// 创建文件列表
-	fileListPath := filepath.Join(p.config.TempFolder, "file_list.txt")
+	fileListPath := filepath.Join(cfg.TempFolder, "file_list.txt")
 	file, err := os.Create(fileListPath)
 	if err != nil {
 		return err
@@ -366,7 +365,7 @@ func (p *NeteasyPlugin) mergeSegments(outputFile string, segmentCount int) error
 	
 	// 写入文件列表
 	for i := 0; i < segmentCount; i++ {
-		segmentPath := filepath.Join(p.config.TempFolder, fmt.Sprintf("segment_%d.ts", i))
+		segmentPath := filepath.Join(cfg.TempFolder, fmt.Sprintf("segment_%d.ts", i))
 		_, err := file.WriteString(fmt.Sprintf("file '%s'\n", segmentPath))
 		if err != nil {
 			return err
@@ -387,7 +386,7 @@ func (p *NeteasyPlugin) mergeSegments(outputFile string, segmentCount int) error
 	
 	// 逐个复制片段到输出文件
 	for i := 0; i < segmentCount; i++ {
-		segmentPath := filepath.Join(p.config.TempFolder, fmt.Sprintf("segment_%d.ts", i))
+		segmentPath := filepath.Join(cfg.TempFolder, fmt.Sprintf("segment_%d.ts", i))
 		segmentFile, err := os.Open(segmentPath)
 		if err != nil {
 			return err
@@ -407,9 +406,9 @@ func (p *NeteasyPlugin) mergeSegments(outputFile string, segmentCount int) error
 }
 
 // cleanupTempSegments 清理临时片段文件
-func (p *NeteasyPlugin) cleanupTempSegments(count int) {
+func (p *NeteasyPlugin) cleanupTempSegments(cfg NeteasyConfig, count int) {
 	for i := 0; i < count; i++ {
-		segmentPath := filepath.Join(p.config.TempFolder, fmt.Sprintf("segment_%d.ts", i))
+		segmentPath := filepath.Join(cfg.TempFolder, fmt.Sprintf("segment_%d.ts", i))
 		os.Remove(segmentPath)
 	}
 }
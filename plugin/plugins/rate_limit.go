@@ -0,0 +1,50 @@
+package plugins
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/service"
+)
+
+// checkRateLimit 检查AI功能的限流规则，超过限制时返回 false，超级管理员及以上角色不受限制
+// 同一个发送者在等待时间内只提示一次，避免刷屏的用户每条消息都收到提示
+func checkRateLimit(ctx *plugin.MessageContext, feature model.RateLimitFeature) bool {
+	rules := ctx.Settings.GetRateLimitRules(feature)
+	if len(rules) == 0 || ctx.SenderRole() >= plugin.RoleSuperAdmin {
+		return true
+	}
+	rateLimitService := service.NewRateLimitService(ctx.Context)
+	allowed, wait, err := rateLimitService.Allow(ctx.Message, rules)
+	if err != nil {
+		// 限流出错时不影响正常使用
+		log.Printf("检查限流失败: %v", err)
+		return true
+	}
+	if allowed {
+		return true
+	}
+	if !rateLimitService.ShouldNotify(ctx.Message, feature, wait) {
+		return false
+	}
+	reply := fmt.Sprintf("你用得太频繁了，歇一会儿吧，%s后再试。", formatWait(wait))
+	if ctx.Message.IsChatRoom {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, reply, ctx.Message.SenderWxID)
+	} else {
+		ctx.MessageService.SendTextMessage(ctx.Message.FromWxID, reply)
+	}
+	return false
+}
+
+func formatWait(wait time.Duration) string {
+	switch {
+	case wait >= time.Hour:
+		return fmt.Sprintf("%d小时", int((wait+time.Hour-1)/time.Hour))
+	case wait >= time.Minute:
+		return fmt.Sprintf("%d分钟", int((wait+time.Minute-1)/time.Minute))
+	default:
+		return fmt.Sprintf("%d秒", max(1, int((wait+time.Second-1)/time.Second)))
+	}
+}
@@ -0,0 +1,416 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/robot"
+	"wechat-robot-client/plugin/pkg"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+const (
+	// 脚本入口函数，签名为 run(msg)，返回 true 表示中止插件链
+	entryFunction = "run"
+	// HTTP 响应体大小上限
+	maxHTTPBodySize = 1 << 20
+	// 默认单次执行超时时间，不包括脚本结束后发送消息的时间
+	defaultTimeout = 3 * time.Second
+	// MaxTimeout 单次执行超时时间上限，单位秒，脚本在处理消息的协程中同步执行
+	MaxTimeout = 10
+	// string.rep 生成的字符串、json_decode 解析的字符串长度上限
+	maxStringSize = 1 << 20
+	// 单次执行最多发送的消息数量
+	maxSendsPerRun = 5
+)
+
+// ScriptPlugin 运行 Lua 脚本的插件，脚本保存在数据库中，运行时加载
+type ScriptPlugin struct {
+	config       *model.ScriptPlugin
+	labels       []string
+	allowedHosts []string
+	proto        *lua.FunctionProto
+}
+
+var _ plugin.MessageHandler = (*ScriptPlugin)(nil)
+
+// NewScriptPlugin 编译脚本并创建插件，脚本语法错误时返回错误
+func NewScriptPlugin(config *model.ScriptPlugin) (*ScriptPlugin, error) {
+	p := &ScriptPlugin{config: config}
+	if len(config.Labels) > 0 {
+		if err := json.Unmarshal(config.Labels, &p.labels); err != nil {
+			return nil, fmt.Errorf("解析插件标签失败: %w", err)
+		}
+	}
+	if len(p.labels) == 0 {
+		p.labels = []string{"text"}
+	}
+	if len(config.AllowedHosts) > 0 {
+		if err := json.Unmarshal(config.AllowedHosts, &p.allowedHosts); err != nil {
+			return nil, fmt.Errorf("解析HTTP白名单失败: %w", err)
+		}
+	}
+	chunk, err := parse.Parse(strings.NewReader(config.Script), config.Name)
+	if err != nil {
+		return nil, fmt.Errorf("脚本语法错误: %w", err)
+	}
+	proto, err := lua.Compile(chunk, config.Name)
+	if err != nil {
+		return nil, fmt.Errorf("脚本编译失败: %w", err)
+	}
+	p.proto = proto
+	return p, nil
+}
+
+func (p *ScriptPlugin) GetName() string {
+	return p.config.Name
+}
+
+func (p *ScriptPlugin) GetLabels() []string {
+	return p.labels
+}
+
+// PreAction 限定了群聊/好友的脚本只在对应的聊天中执行
+func (p *ScriptPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	if ctx.Message == nil {
+		return false
+	}
+	return p.config.ContactID == "" || p.config.ContactID == ctx.Message.FromWxID
+}
+
+func (p *ScriptPlugin) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+func (p *ScriptPlugin) Run(ctx *plugin.MessageContext) bool {
+	abort, err := p.Execute(ctx)
+	if err != nil {
+		log.Printf("脚本插件[%s]执行失败: %v", p.config.Name, err)
+		return false
+	}
+	return abort
+}
+
+// Execute 在独立的 Lua 虚拟机中执行脚本，超时后虚拟机会被中断
+// 脚本发送的消息先暂存，脚本结束后再进入发送队列，发送队列的排队时间不计入脚本的超时时间
+func (p *ScriptPlugin) Execute(ctx *plugin.MessageContext) (bool, error) {
+	var outbox []func() error
+	abort, err := p.execute(ctx, &outbox)
+	for _, send := range outbox {
+		if err := send(); err != nil {
+			log.Printf("脚本插件[%s]发送消息失败: %v", p.config.Name, err)
+		}
+	}
+	return abort, err
+}
+
+func (p *ScriptPlugin) execute(ctx *plugin.MessageContext, outbox *[]func() error) (bool, error) {
+	timeout := time.Duration(min(p.config.Timeout, MaxTimeout)) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	// 虚拟机每执行一条指令都会检查上下文，超时后中断脚本
+	execCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	L := newSandbox()
+	defer L.Close()
+	L.SetContext(execCtx)
+	L.SetGlobal("robot", p.newRobotModule(L, ctx, execCtx, outbox))
+
+	L.Push(L.NewFunctionFromProto(p.proto))
+	if err := L.PCall(0, lua.MultRet, nil); err != nil {
+		return false, err
+	}
+	entry := L.GetGlobal(entryFunction)
+	if entry.Type() != lua.LTFunction {
+		return false, errors.New("脚本缺少 run(msg) 函数")
+	}
+	err := L.CallByParam(lua.P{Fn: entry, NRet: 1, Protect: true}, p.newMessageTable(L, ctx))
+	if err != nil {
+		return false, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	return lua.LVAsBool(ret), nil
+}
+
+// newSandbox 创建只包含基础库、字符串、表和数学库的虚拟机，不能访问文件系统和操作系统
+// 调用栈深度和寄存器数量有上限，超过后脚本报错退出
+func newSandbox() *lua.LState {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:        true,
+		CallStackSize:       256,
+		RegistrySize:        1024 * 4,
+		RegistryMaxSize:     1024 * 64,
+		MinimizeStackMemory: true,
+	})
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "module", "require"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	// string.rep 一次就能分配出超大字符串，执行前先检查结果长度
+	if strLib, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		strLib.RawSetString("rep", L.NewFunction(strRep))
+	}
+	return L
+}
+
+func strRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n <= 0 || str == "" {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	if len(str) > maxStringSize/n {
+		L.RaiseError("string.rep 生成的字符串超过 %d 字节", maxStringSize)
+		return 0
+	}
+	L.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+func (p *ScriptPlugin) newMessageTable(L *lua.LState, ctx *plugin.MessageContext) *lua.LTable {
+	msg := L.NewTable()
+	msg.RawSetString("msg_id", lua.LNumber(ctx.Message.MsgId))
+	msg.RawSetString("type", lua.LNumber(ctx.Message.Type))
+	msg.RawSetString("content", lua.LString(ctx.MessageContent))
+	msg.RawSetString("from_wxid", lua.LString(ctx.Message.FromWxID))
+	msg.RawSetString("sender_wxid", lua.LString(ctx.Message.SenderWxID))
+	msg.RawSetString("is_chat_room", lua.LBool(ctx.Message.IsChatRoom))
+	msg.RawSetString("is_at_me", lua.LBool(ctx.Message.IsAtMe))
+	msg.RawSetString("pat", lua.LBool(ctx.Pat))
+	if ctx.Trace != nil {
+		msg.RawSetString("label", lua.LString(ctx.Trace.Label))
+	}
+	if ctx.MessageService != nil {
+		msg.RawSetString("sender_nickname", lua.LString(ctx.MessageService.GetSenderNickname(ctx.Message)))
+	}
+	if ctx.ReferMessage != nil {
+		refer := L.NewTable()
+		refer.RawSetString("type", lua.LNumber(ctx.ReferMessage.Type))
+		refer.RawSetString("content", lua.LString(ctx.ReferMessage.Content))
+		refer.RawSetString("sender_wxid", lua.LString(ctx.ReferMessage.SenderWxID))
+		msg.RawSetString("refer", refer)
+	}
+	if ctx.Emoji != nil {
+		emoji := L.NewTable()
+		emoji.RawSetString("md5", lua.LString(ctx.Emoji.Md5))
+		emoji.RawSetString("len", lua.LNumber(ctx.Emoji.Len))
+		msg.RawSetString("emoji", emoji)
+	}
+	if ctx.Location != nil {
+		location := L.NewTable()
+		location.RawSetString("latitude", lua.LNumber(ctx.Location.Latitude))
+		location.RawSetString("longitude", lua.LNumber(ctx.Location.Longitude))
+		location.RawSetString("label", lua.LString(ctx.Location.Label))
+		location.RawSetString("poi_name", lua.LString(ctx.Location.PoiName))
+		msg.RawSetString("location", location)
+	}
+	if ctx.ShareCard != nil {
+		card := L.NewTable()
+		card.RawSetString("username", lua.LString(ctx.ShareCard.Username))
+		card.RawSetString("nickname", lua.LString(ctx.ShareCard.Nickname))
+		card.RawSetString("alias", lua.LString(ctx.ShareCard.Alias))
+		msg.RawSetString("share_card", card)
+	}
+	return msg
+}
+
+// newRobotModule 脚本可以调用的受限接口，消息统一回复到当前聊天
+// 发送消息的接口只校验参数并把消息放入 outbox，返回值只包含校验错误，发送失败记录在日志中
+func (p *ScriptPlugin) newRobotModule(L *lua.LState, ctx *plugin.MessageContext, execCtx context.Context, outbox *[]func() error) *lua.LTable {
+	toWxID := ctx.Message.FromWxID
+	sends := 0
+	// 限制单次执行发送的消息数量，防止脚本循环刷屏
+	checkSend := func() error {
+		if sends >= maxSendsPerRun {
+			return fmt.Errorf("单次执行最多发送 %d 条消息", maxSendsPerRun)
+		}
+		sends++
+		return nil
+	}
+	return L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		// robot.send_text(content, at_sender)
+		"send_text": func(L *lua.LState) int {
+			content := L.CheckString(1)
+			if err := checkSend(); err != nil {
+				return pushError(L, err)
+			}
+			var at []string
+			if L.OptBool(2, false) && ctx.Message.IsChatRoom {
+				at = append(at, ctx.Message.SenderWxID)
+			}
+			*outbox = append(*outbox, func() error {
+				return ctx.MessageService.SendTextMessage(toWxID, content, at...)
+			})
+			return pushError(L, nil)
+		},
+		// robot.send_image_url(url)
+		"send_image_url": func(L *lua.LState) int {
+			imageURL := L.CheckString(1)
+			if err := checkSend(); err != nil {
+				return pushError(L, err)
+			}
+			if err := p.checkURL(imageURL); err != nil {
+				return pushError(L, err)
+			}
+			*outbox = append(*outbox, func() error {
+				return pkg.SendImageByURL(ctx.MessageService, toWxID, imageURL)
+			})
+			return pushError(L, nil)
+		},
+		// robot.share_link({title=, description=, url=, thumb_url=})
+		"share_link": func(L *lua.LState) int {
+			link := L.CheckTable(1)
+			if err := checkSend(); err != nil {
+				return pushError(L, err)
+			}
+			message := robot.ShareLinkMessage{
+				Title:    lua.LVAsString(link.RawGetString("title")),
+				Des:      lua.LVAsString(link.RawGetString("description")),
+				Url:      lua.LVAsString(link.RawGetString("url")),
+				ThumbUrl: robot.CDATAString(lua.LVAsString(link.RawGetString("thumb_url"))),
+			}
+			*outbox = append(*outbox, func() error {
+				return ctx.MessageService.ShareLink(toWxID, message)
+			})
+			return pushError(L, nil)
+		},
+		// robot.http_get(url) 返回 body, status_code，失败时返回 nil, err
+		"http_get": func(L *lua.LState) int {
+			body, statusCode, err := p.httpGet(execCtx, L.CheckString(1))
+			if err != nil {
+				L.Push(lua.LNil)
+				L.Push(lua.LString(err.Error()))
+				return 2
+			}
+			L.Push(lua.LString(body))
+			L.Push(lua.LNumber(statusCode))
+			return 2
+		},
+		// robot.json_decode(str) 返回 table，失败时返回 nil, err
+		"json_decode": func(L *lua.LState) int {
+			str := L.CheckString(1)
+			if len(str) > maxStringSize {
+				L.Push(lua.LNil)
+				L.Push(lua.LString(fmt.Sprintf("JSON字符串超过 %d 字节", maxStringSize)))
+				return 2
+			}
+			var data any
+			if err := json.Unmarshal([]byte(str), &data); err != nil {
+				L.Push(lua.LNil)
+				L.Push(lua.LString(err.Error()))
+				return 2
+			}
+			L.Push(toLValue(L, data))
+			return 1
+		},
+		"log": func(L *lua.LState) int {
+			log.Printf("脚本插件[%s]: %s", p.config.Name, L.CheckString(1))
+			return 0
+		},
+	})
+}
+
+// checkURL 只允许访问白名单中的域名
+func (p *ScriptPlugin) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("不支持的协议: %s", u.Scheme)
+	}
+	if !slices.Contains(p.allowedHosts, u.Hostname()) {
+		return fmt.Errorf("域名 %s 不在白名单中", u.Hostname())
+	}
+	return nil
+}
+
+func (p *ScriptPlugin) httpGet(ctx context.Context, rawURL string) (string, int, error) {
+	if err := p.checkURL(rawURL); err != nil {
+		return "", 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	// 不跟随跳转到白名单以外的域名
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return p.checkURL(req.URL.String())
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBodySize))
+	if err != nil {
+		return "", 0, err
+	}
+	return string(body), resp.StatusCode, nil
+}
+
+func pushError(L *lua.LState, err error) int {
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+		return 1
+	}
+	L.Push(lua.LNil)
+	return 1
+}
+
+func toLValue(L *lua.LState, value any) lua.LValue {
+	switch v := value.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []any:
+		table := L.NewTable()
+		for _, item := range v {
+			table.Append(toLValue(L, item))
+		}
+		return table
+	case map[string]any:
+		table := L.NewTable()
+		for key, item := range v {
+			table.RawSetString(key, toLValue(L, item))
+		}
+		return table
+	default:
+		return lua.LString(fmt.Sprint(v))
+	}
+}
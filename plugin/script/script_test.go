@@ -0,0 +1,185 @@
+package script
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+)
+
+type fakeMessageService struct {
+	plugin.MessageServiceIface
+	texts     []string
+	at        [][]string
+	sendDelay time.Duration
+}
+
+func (s *fakeMessageService) SendTextMessage(toWxID, content string, at ...string) error {
+	time.Sleep(s.sendDelay)
+	s.texts = append(s.texts, content)
+	s.at = append(s.at, at)
+	return nil
+}
+
+func (s *fakeMessageService) GetSenderNickname(message *model.Message) string {
+	return "张三"
+}
+
+func newTestContext(content string, svc *fakeMessageService) *plugin.MessageContext {
+	return &plugin.MessageContext{
+		Message: &model.Message{
+			FromWxID:   "123@chatroom",
+			SenderWxID: "wxid_sender",
+			IsChatRoom: true,
+		},
+		MessageContent: content,
+		MessageService: svc,
+	}
+}
+
+func TestExecuteSendsText(t *testing.T) {
+	p, err := NewScriptPlugin(&model.ScriptPlugin{
+		Name: "hello",
+		Script: `
+function run(msg)
+  if msg.content ~= "你好" then
+    return false
+  end
+  robot.send_text("你好，" .. msg.sender_nickname, true)
+  return true
+end`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &fakeMessageService{}
+	abort, err := p.Execute(newTestContext("你好", svc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !abort || len(svc.texts) != 1 || svc.texts[0] != "你好，张三" {
+		t.Fatalf("abort = %v, texts = %v", abort, svc.texts)
+	}
+	if len(svc.at[0]) != 1 || svc.at[0][0] != "wxid_sender" {
+		t.Fatalf("at = %v, want [wxid_sender]", svc.at[0])
+	}
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	p, err := NewScriptPlugin(&model.ScriptPlugin{
+		Name:    "loop",
+		Timeout: 1,
+		Script:  `function run(msg) while true do end end`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = p.Execute(newTestContext("", &fakeMessageService{}))
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("script ran for %s, want about 1s", elapsed)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	p, err := NewScriptPlugin(&model.ScriptPlugin{
+		Name: "sandbox",
+		Script: `
+function run(msg)
+  local body, err = robot.http_get("https://example.com/")
+  robot.send_text(tostring(os) .. "|" .. tostring(io) .. "|" .. tostring(dofile) .. "|" .. err)
+  return false
+end`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &fakeMessageService{}
+	if _, err := p.Execute(newTestContext("", svc)); err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.texts) != 1 || !strings.HasPrefix(svc.texts[0], "nil|nil|nil|") || !strings.Contains(svc.texts[0], "白名单") {
+		t.Fatalf("unexpected sandbox output: %v", svc.texts)
+	}
+}
+
+func TestExecuteLimits(t *testing.T) {
+	p, err := NewScriptPlugin(&model.ScriptPlugin{
+		Name: "limits",
+		Script: `
+function run(msg)
+  local ok = pcall(string.rep, "a", 1024 * 1024 * 1024)
+  robot.send_text(tostring(ok))
+  for i = 1, 10 do
+    robot.send_text("刷屏")
+  end
+  return false
+end`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &fakeMessageService{}
+	if _, err := p.Execute(newTestContext("", svc)); err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.texts) != maxSendsPerRun || svc.texts[0] != "false" {
+		t.Fatalf("texts = %v, want %d texts starting with false", svc.texts, maxSendsPerRun)
+	}
+}
+
+func TestExecuteSendsAfterScriptReturns(t *testing.T) {
+	p, err := NewScriptPlugin(&model.ScriptPlugin{
+		Name:    "slow_send",
+		Timeout: 1,
+		Script: `
+function run(msg)
+  for i = 1, 3 do
+    robot.send_text("第" .. i .. "条")
+  end
+  return true
+end`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 发送队列的排队时间不计入脚本的超时时间
+	svc := &fakeMessageService{sendDelay: 500 * time.Millisecond}
+	abort, err := p.Execute(newTestContext("", svc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !abort || len(svc.texts) != 3 {
+		t.Fatalf("abort = %v, texts = %v", abort, svc.texts)
+	}
+}
+
+func TestExecuteCallStackLimit(t *testing.T) {
+	p, err := NewScriptPlugin(&model.ScriptPlugin{
+		Name:    "recursion",
+		Timeout: 1,
+		Script: `
+local function f(n) return f(n + 1) + 1 end
+function run(msg) return f(1) end`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := p.Execute(newTestContext("", &fakeMessageService{})); err == nil || !strings.Contains(err.Error(), "stack overflow") {
+		t.Fatalf("err = %v, want stack overflow", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("recursion ran for %s, want to stop at the call stack limit", elapsed)
+	}
+}
+
+func TestNewScriptPluginSyntaxError(t *testing.T) {
+	if _, err := NewScriptPlugin(&model.ScriptPlugin{Name: "bad", Script: "function run(msg"}); err == nil {
+		t.Fatal("expected syntax error")
+	}
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"wechat-robot-client/interface/plugin"
+)
+
+type registeredTool struct {
+	pluginName string
+	tool       *plugin.Tool
+}
+
+// ToolRegistry AI聊天时可以调用的工具，新增能力只需要注册工具，不需要修改意图识别
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools []registeredTool
+}
+
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{}
+}
+
+// Register 注册工具，pluginName 为工具所属的插件，插件在当前聊天中被禁用时工具也不可用
+func (r *ToolRegistry) Register(pluginName string, tools ...*plugin.Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tool := range tools {
+		if existing := r.find(tool.Name); existing != nil {
+			log.Printf("工具 %s 已被插件 %s 注册，插件 %s 的同名工具将被忽略", tool.Name, existing.pluginName, pluginName)
+			continue
+		}
+		r.tools = append(r.tools, registeredTool{pluginName: pluginName, tool: tool})
+	}
+}
+
+// Unregister 注销插件注册的所有工具
+func (r *ToolRegistry) Unregister(pluginName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tools := r.tools[:0]
+	for _, t := range r.tools {
+		if t.pluginName != pluginName {
+			tools = append(tools, t)
+		}
+	}
+	r.tools = tools
+}
+
+func (r *ToolRegistry) find(name string) *registeredTool {
+	for i, t := range r.tools {
+		if t.tool.Name == name {
+			return &r.tools[i]
+		}
+	}
+	return nil
+}
+
+func (r *ToolRegistry) available(ctx *plugin.MessageContext, t registeredTool) bool {
+	if ctx.Settings != nil && !ctx.Settings.IsPluginEnabled(t.pluginName) {
+		return false
+	}
+	return t.tool.Available == nil || t.tool.Available(ctx)
+}
+
+// Available 返回当前聊天中可以使用的工具
+func (r *ToolRegistry) Available(ctx *plugin.MessageContext) []*plugin.Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var tools []*plugin.Tool
+	for _, t := range r.tools {
+		if r.available(ctx, t) {
+			tools = append(tools, t.tool)
+		}
+	}
+	return tools
+}
+
+// Call 执行AI返回的工具调用，返回交给AI的结果，执行失败时把错误信息交给AI
+func (r *ToolRegistry) Call(ctx *plugin.MessageContext, name, arguments string) (result string) {
+	r.mu.RLock()
+	t := r.find(name)
+	var tool registeredTool
+	if t != nil {
+		tool = *t
+	}
+	r.mu.RUnlock()
+	if t == nil || !r.available(ctx, tool) {
+		return fmt.Sprintf("工具 %s 不存在或者当前不可用", name)
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("工具 %s 发生 panic: %v\n%s", name, rec, debug.Stack())
+			result = fmt.Sprintf("工具 %s 执行异常", name)
+		}
+	}()
+	if arguments == "" {
+		arguments = "{}"
+	}
+	if !json.Valid([]byte(arguments)) {
+		return fmt.Sprintf("工具 %s 的参数不是有效的 JSON", name)
+	}
+	log.Printf("[AI工具] 调用 %s，参数: %s", name, arguments)
+	result, err := tool.tool.Handler(ctx, json.RawMessage(arguments))
+	if err != nil {
+		log.Printf("[AI工具] %s 执行失败: %v", name, err)
+		return fmt.Sprintf("执行失败: %v", err)
+	}
+	return result
+}
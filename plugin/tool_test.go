@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"wechat-robot-client/interface/plugin"
+)
+
+func TestToolRegistry(t *testing.T) {
+	r := NewToolRegistry()
+	echo := &plugin.Tool{
+		Name: "echo",
+		Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+			return string(args), nil
+		},
+	}
+	r.Register("a", echo, &plugin.Tool{
+		Name: "chatroom_only",
+		Available: func(ctx *plugin.MessageContext) bool {
+			return ctx.Message.IsChatRoom
+		},
+		Handler: func(ctx *plugin.MessageContext, args json.RawMessage) (string, error) {
+			panic("boom")
+		},
+	})
+	// 同名工具被忽略
+	r.Register("b", &plugin.Tool{Name: "echo"})
+
+	friend := newCommandContext("", false, nil)
+	chatRoom := newCommandContext("", true, nil)
+	if tools := r.Available(friend); len(tools) != 1 || tools[0] != echo {
+		t.Fatalf("Available(friend) = %v, want only echo", tools)
+	}
+	if tools := r.Available(chatRoom); len(tools) != 2 {
+		t.Fatalf("Available(chatroom) = %v, want 2 tools", tools)
+	}
+
+	cases := []struct {
+		ctx       *plugin.MessageContext
+		name      string
+		arguments string
+		want      string
+	}{
+		{friend, "echo", `{"a":1}`, `{"a":1}`},
+		{friend, "echo", "", "{}"},
+		{friend, "echo", "{", "不是有效的 JSON"},
+		{friend, "chatroom_only", "{}", "不存在或者当前不可用"},
+		{chatRoom, "chatroom_only", "{}", "执行异常"},
+		{friend, "missing", "{}", "不存在或者当前不可用"},
+	}
+	for _, c := range cases {
+		if got := r.Call(c.ctx, c.name, c.arguments); !strings.Contains(got, c.want) {
+			t.Errorf("Call(%q, %q) = %q, want %q", c.name, c.arguments, got, c.want)
+		}
+	}
+
+	r.Unregister("a")
+	if tools := r.Available(chatRoom); len(tools) != 0 {
+		t.Fatalf("Available after Unregister = %v, want none", tools)
+	}
+}
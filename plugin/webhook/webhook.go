@@ -0,0 +1,336 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/robot"
+	"wechat-robot-client/plugin/pkg"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// HeaderTimestamp 请求时间戳(秒)
+	HeaderTimestamp = "X-Robot-Timestamp"
+	// HeaderSignature 请求签名，格式为 sha256=hex(HMAC-SHA256(secret, timestamp + "\n" + body))
+	HeaderSignature = "X-Robot-Signature"
+	// HeaderPlugin 插件名称
+	HeaderPlugin = "X-Robot-Plugin"
+)
+
+// 请求在处理消息的协程中同步执行，超时时间和重试次数都有上限，避免外部服务不可用时长时间阻塞聊天
+const (
+	// MaxTimeout 单次请求超时时间上限，单位秒
+	MaxTimeout = 30
+	// MaxRetries 最大重试次数上限
+	MaxRetries = 5
+)
+
+type ActionType string
+
+const (
+	ActionText     ActionType = "text"      // 发送文本，可以通过 at 或者 at_sender 艾特群成员
+	ActionImageURL ActionType = "image_url" // 下载图片并发送
+	ActionVoiceURL ActionType = "voice_url" // 下载语音并发送
+	ActionLink     ActionType = "link"      // 发送链接卡片
+)
+
+// SettingsSummary 当前聊天的配置摘要，不包含密钥等敏感信息
+type SettingsSummary struct {
+	AIChatEnabled    bool   `json:"ai_chat_enabled"`
+	AIDrawingEnabled bool   `json:"ai_drawing_enabled"`
+	TTSEnabled       bool   `json:"tts_enabled"`
+	AITriggerWord    string `json:"ai_trigger_word"`
+}
+
+// Request 推送给外部服务的消息
+type Request struct {
+	Plugin         string           `json:"plugin"`
+	Label          string           `json:"label"`
+	Message        *model.Message   `json:"message"`
+	MessageContent string           `json:"message_content"`
+	ReferMessage   *model.Message   `json:"refer_message"`
+	Pat            bool             `json:"pat"`
+	SenderNickname string           `json:"sender_nickname"`
+	Settings       *SettingsSummary `json:"settings"`
+	// 表情、视频、位置、名片消息解析后的内容
+	Emoji     *robot.EmojiXml            `json:"emoji,omitempty"`
+	Video     *robot.VideoSecretXml      `json:"video,omitempty"`
+	Location  *robot.LocationXml         `json:"location,omitempty"`
+	ShareCard *robot.ShareCardMessageXml `json:"share_card,omitempty"`
+}
+
+// Action 外部服务要求执行的动作，消息统一回复到当前聊天
+type Action struct {
+	Type        ActionType `json:"type"`
+	Content     string     `json:"content"`
+	At          []string   `json:"at"`
+	AtSender    bool       `json:"at_sender"`
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	ThumbURL    string     `json:"thumb_url"`
+}
+
+// Response 外部服务的响应，Abort 为 true 时中止插件链
+type Response struct {
+	Actions []Action `json:"actions"`
+	Abort   bool     `json:"abort"`
+}
+
+// WebhookPlugin 把消息推送到外部 HTTP 服务的插件
+type WebhookPlugin struct {
+	config       *model.WebhookPlugin
+	labels       []string
+	keywords     []string
+	regex        *regexp.Regexp
+	messageTypes []model.MessageType
+}
+
+var _ plugin.MessageHandler = (*WebhookPlugin)(nil)
+
+// NewWebhookPlugin 根据配置创建 Webhook 插件，触发条件不合法时返回错误
+func NewWebhookPlugin(config *model.WebhookPlugin) (*WebhookPlugin, error) {
+	p := &WebhookPlugin{config: config}
+	if len(config.Labels) > 0 {
+		if err := json.Unmarshal(config.Labels, &p.labels); err != nil {
+			return nil, fmt.Errorf("解析插件标签失败: %w", err)
+		}
+	}
+	if len(p.labels) == 0 {
+		p.labels = []string{"text"}
+	}
+	// 触发条件为空时匹配所有消息
+	rule := strings.TrimSpace(config.TriggerRule)
+	switch config.TriggerType {
+	case model.WebhookTriggerKeyword:
+		for _, keyword := range strings.Split(rule, ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				p.keywords = append(p.keywords, keyword)
+			}
+		}
+	case model.WebhookTriggerRegex:
+		if rule == "" {
+			break
+		}
+		regex, err := regexp.Compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式不合法: %w", err)
+		}
+		p.regex = regex
+	case model.WebhookTriggerMessageType:
+		if rule == "" {
+			break
+		}
+		for _, item := range strings.Split(rule, ",") {
+			msgType, err := strconv.Atoi(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("消息类型不合法: %s", item)
+			}
+			p.messageTypes = append(p.messageTypes, model.MessageType(msgType))
+		}
+	default:
+		return nil, fmt.Errorf("不支持的触发方式: %s", config.TriggerType)
+	}
+	return p, nil
+}
+
+func (p *WebhookPlugin) GetName() string {
+	return p.config.Name
+}
+
+func (p *WebhookPlugin) GetLabels() []string {
+	return p.labels
+}
+
+// PreAction 判断消息是否命中触发条件
+func (p *WebhookPlugin) PreAction(ctx *plugin.MessageContext) bool {
+	if ctx.Message == nil {
+		return false
+	}
+	if len(p.keywords) > 0 {
+		return slices.ContainsFunc(p.keywords, func(keyword string) bool {
+			return strings.Contains(ctx.MessageContent, keyword)
+		})
+	}
+	if p.regex != nil {
+		return p.regex.MatchString(ctx.MessageContent)
+	}
+	if len(p.messageTypes) > 0 {
+		return slices.Contains(p.messageTypes, ctx.Message.Type)
+	}
+	return true
+}
+
+func (p *WebhookPlugin) PostAction(ctx *plugin.MessageContext) {
+
+}
+
+func (p *WebhookPlugin) Run(ctx *plugin.MessageContext) bool {
+	req := Request{
+		Plugin:         p.config.Name,
+		Message:        ctx.Message,
+		MessageContent: ctx.MessageContent,
+		ReferMessage:   ctx.ReferMessage,
+		Pat:            ctx.Pat,
+		Emoji:          ctx.Emoji,
+		Video:          ctx.Video,
+		Location:       ctx.Location,
+		ShareCard:      ctx.ShareCard,
+	}
+	if ctx.Trace != nil {
+		req.Label = ctx.Trace.Label
+	}
+	if ctx.MessageService != nil {
+		req.SenderNickname = ctx.MessageService.GetSenderNickname(ctx.Message)
+	}
+	if ctx.Settings != nil {
+		req.Settings = &SettingsSummary{
+			AIChatEnabled:    ctx.Settings.IsAIChatEnabled(),
+			AIDrawingEnabled: ctx.Settings.IsAIDrawingEnabled(),
+			TTSEnabled:       ctx.Settings.IsTTSEnabled(),
+			AITriggerWord:    ctx.Settings.GetAITriggerWord(),
+		}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		log.Printf("Webhook插件[%s]序列化消息失败: %v", p.config.Name, err)
+		return false
+	}
+	resp, err := p.call(body)
+	if err != nil {
+		log.Printf("Webhook插件[%s]请求失败: %v", p.config.Name, err)
+		return false
+	}
+	for _, action := range resp.Actions {
+		if err := p.execute(ctx, action); err != nil {
+			log.Printf("Webhook插件[%s]执行动作[%s]失败: %v", p.config.Name, action.Type, err)
+		}
+	}
+	return resp.Abort
+}
+
+// Sign 计算请求签名，外部服务需要用相同的方式校验签名
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// call 发送请求，网络错误和 5xx 响应会按配置重试，4xx 响应不重试
+func (p *WebhookPlugin) call(body []byte) (*Response, error) {
+	timeout := time.Duration(min(p.config.Timeout, MaxTimeout)) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	var lastErr error
+	for attempt := 0; attempt <= min(p.config.MaxRetries, MaxRetries); attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+		}
+		resp, retry, err := p.doRequest(body, timeout)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (p *WebhookPlugin) doRequest(body []byte, timeout time.Duration) (*Response, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(HeaderTimestamp, timestamp)
+	httpReq.Header.Set(HeaderPlugin, p.config.Name)
+	if p.config.Secret != "" {
+		httpReq.Header.Set(HeaderSignature, Sign(p.config.Secret, timestamp, body))
+	}
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, true, err
+	}
+	defer httpResp.Body.Close()
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	if httpResp.StatusCode >= http.StatusInternalServerError {
+		return nil, true, fmt.Errorf("响应状态码: %d", httpResp.StatusCode)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("响应状态码: %d", httpResp.StatusCode)
+	}
+	var resp Response
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		return &resp, false, nil
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, false, fmt.Errorf("解析响应失败: %w", err)
+	}
+	return &resp, false, nil
+}
+
+func (p *WebhookPlugin) execute(ctx *plugin.MessageContext, action Action) error {
+	toWxID := ctx.Message.FromWxID
+	switch action.Type {
+	case ActionText:
+		// 只有群聊消息才能艾特
+		var at []string
+		if ctx.Message.IsChatRoom {
+			at = action.At
+			if action.AtSender {
+				at = append([]string{ctx.Message.SenderWxID}, at...)
+			}
+		}
+		return ctx.MessageService.SendTextMessage(toWxID, action.Content, at...)
+	case ActionImageURL:
+		return pkg.SendImageByURL(ctx.MessageService, toWxID, action.URL)
+	case ActionVoiceURL:
+		resp, err := resty.New().R().SetDoNotParseResponse(true).Get(action.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.RawBody().Close()
+		voiceExt := path.Ext(resp.RawResponse.Request.URL.Path)
+		if voiceExt == "" {
+			voiceExt = ".mp3"
+		}
+		return ctx.MessageService.MsgSendVoice(toWxID, resp.RawBody(), voiceExt)
+	case ActionLink:
+		return ctx.MessageService.ShareLink(toWxID, robot.ShareLinkMessage{
+			Title:    action.Title,
+			Des:      action.Description,
+			Url:      action.URL,
+			ThumbUrl: robot.CDATAString(action.ThumbURL),
+		})
+	default:
+		return fmt.Errorf("不支持的动作类型: %s", action.Type)
+	}
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+)
+
+func TestCallSignsAndRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(HeaderSignature), Sign("secret", r.Header.Get(HeaderTimestamp), body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(Response{Actions: []Action{{Type: ActionText, Content: "pong"}}, Abort: true})
+	}))
+	defer server.Close()
+
+	p, err := NewWebhookPlugin(&model.WebhookPlugin{Name: "test", TriggerType: model.WebhookTriggerKeyword, URL: server.URL, Secret: "secret", MaxRetries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := p.call([]byte(`{"message_content":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", attempts)
+	}
+	if !resp.Abort || len(resp.Actions) != 1 || resp.Actions[0].Content != "pong" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestCallDoesNotRetryClientError(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	p, err := NewWebhookPlugin(&model.WebhookPlugin{Name: "test", TriggerType: model.WebhookTriggerKeyword, URL: server.URL, MaxRetries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.call([]byte(`{}`)); err == nil {
+		t.Fatal("expected error for 4xx response")
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestPreActionTriggers(t *testing.T) {
+	cases := []struct {
+		triggerType model.WebhookTriggerType
+		rule        string
+		message     *model.Message
+		content     string
+		want        bool
+	}{
+		{model.WebhookTriggerKeyword, "天气, 油价", &model.Message{}, "今天油价多少", true},
+		{model.WebhookTriggerKeyword, "天气", &model.Message{}, "你好", false},
+		{model.WebhookTriggerRegex, `^#查询\s+\d+$`, &model.Message{}, "#查询 42", true},
+		{model.WebhookTriggerMessageType, "3,34", &model.Message{Type: model.MsgTypeVoice}, "", true},
+		{model.WebhookTriggerMessageType, "3", &model.Message{Type: model.MsgTypeText}, "", false},
+		{model.WebhookTriggerKeyword, "", &model.Message{}, "任意消息", true},
+	}
+	for _, c := range cases {
+		p, err := NewWebhookPlugin(&model.WebhookPlugin{Name: "test", TriggerType: c.triggerType, TriggerRule: c.rule})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := p.PreAction(&plugin.MessageContext{Message: c.message, MessageContent: c.content})
+		if got != c.want {
+			t.Errorf("%s(%q) on %q = %v, want %v", c.triggerType, c.rule, c.content, got, c.want)
+		}
+	}
+}
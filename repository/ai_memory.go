@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wechat-robot-client/model"
+
+	"gorm.io/gorm"
+)
+
+type AIMemory struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewAIMemoryRepo(ctx context.Context, db *gorm.DB) *AIMemory {
+	return &AIMemory{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+func (respo *AIMemory) GetByUser(contactID, wechatID string) ([]*model.AIMemory, error) {
+	var memories []*model.AIMemory
+	err := respo.DB.WithContext(respo.Ctx).
+		Where("contact_id = ? AND wechat_id = ?", contactID, wechatID).
+		Order("id ASC").
+		Find(&memories).Error
+	return memories, err
+}
+
+// ReplaceByUser 用新的记忆替换用户原来的所有记忆
+func (respo *AIMemory) ReplaceByUser(contactID, wechatID string, contents []string) error {
+	now := time.Now().Unix()
+	return respo.DB.WithContext(respo.Ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []*model.AIMemory
+		if err := tx.Where("contact_id = ? AND wechat_id = ?", contactID, wechatID).Find(&existing).Error; err != nil {
+			return err
+		}
+		// 没有变化的记忆保留原来的创建时间
+		createdAt := make(map[string]int64, len(existing))
+		for _, memory := range existing {
+			createdAt[memory.Content] = memory.CreatedAt
+		}
+		if err := tx.Where("contact_id = ? AND wechat_id = ?", contactID, wechatID).Delete(&model.AIMemory{}).Error; err != nil {
+			return err
+		}
+		if len(contents) == 0 {
+			return nil
+		}
+		memories := make([]*model.AIMemory, 0, len(contents))
+		for _, content := range contents {
+			memory := &model.AIMemory{
+				ContactID: contactID,
+				WechatID:  wechatID,
+				Content:   content,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if created, ok := createdAt[content]; ok {
+				memory.CreatedAt = created
+			}
+			memories = append(memories, memory)
+		}
+		return tx.Create(&memories).Error
+	})
+}
+
+func (respo *AIMemory) DeleteByUser(contactID, wechatID string) error {
+	return respo.DB.WithContext(respo.Ctx).
+		Where("contact_id = ? AND wechat_id = ?", contactID, wechatID).
+		Delete(&model.AIMemory{}).Error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"wechat-robot-client/model"
+
+	"gorm.io/gorm"
+)
+
+type AIPersona struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewAIPersonaRepo(ctx context.Context, db *gorm.DB) *AIPersona {
+	return &AIPersona{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+func (respo *AIPersona) GetByID(id int64) (*model.AIPersona, error) {
+	var persona model.AIPersona
+	err := respo.DB.WithContext(respo.Ctx).Where("id = ?", id).First(&persona).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &persona, nil
+}
+
+func (respo *AIPersona) GetByName(name string) (*model.AIPersona, error) {
+	var persona model.AIPersona
+	err := respo.DB.WithContext(respo.Ctx).Where("name = ?", name).First(&persona).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &persona, nil
+}
+
+func (respo *AIPersona) GetList() ([]*model.AIPersona, error) {
+	var personas []*model.AIPersona
+	err := respo.DB.WithContext(respo.Ctx).Order("id ASC").Find(&personas).Error
+	return personas, err
+}
+
+func (respo *AIPersona) Create(data *model.AIPersona) error {
+	return respo.DB.WithContext(respo.Ctx).Create(data).Error
+}
+
+func (respo *AIPersona) Update(data *model.AIPersona) error {
+	return respo.DB.WithContext(respo.Ctx).Where("id = ?", data.ID).
+		Select("name", "intro", "avatar", "prompt", "model", "temperature", "max_completion_tokens", "tts_voice").
+		Updates(data).Error
+}
+
+// Delete 删除角色，正在使用该角色的群聊、好友恢复为不使用角色
+func (respo *AIPersona) Delete(id int64) error {
+	return respo.DB.WithContext(respo.Ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.ChatRoomSettings{}).Where("ai_persona_id = ?", id).Update("ai_persona_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.FriendSettings{}).Where("ai_persona_id = ?", id).Update("ai_persona_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.AIPersona{}).Error
+	})
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/model"
+
+	"gorm.io/gorm"
+)
+
+type AIUsage struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewAIUsageRepo(ctx context.Context, db *gorm.DB) *AIUsage {
+	return &AIUsage{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+func (respo *AIUsage) Create(data *model.AIUsage) error {
+	return respo.DB.WithContext(respo.Ctx).Create(data).Error
+}
+
+// SumTokens 统计群聊/好友从 since 开始使用的token数量
+func (respo *AIUsage) SumTokens(contactID string, since int64) (int64, error) {
+	var total int64
+	err := respo.DB.WithContext(respo.Ctx).Model(&model.AIUsage{}).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Where("contact_id = ?", contactID).
+		Where("created_at >= ?", since).
+		Scan(&total).Error
+	return total, err
+}
+
+var aiUsageGroupColumns = map[dto.AIUsageGroupBy]string{
+	dto.AIUsageGroupByContact: "contact_id",
+	dto.AIUsageGroupBySender:  "sender_wxid",
+	dto.AIUsageGroupByModel:   "model",
+	dto.AIUsageGroupByFeature: "feature",
+	dto.AIUsageGroupByDay:     "FROM_UNIXTIME(created_at, '%Y-%m-%d')",
+}
+
+func (respo *AIUsage) GetStats(req dto.AIUsageStatsRequest) ([]*dto.AIUsageStat, error) {
+	var stats []*dto.AIUsageStat
+	column, ok := aiUsageGroupColumns[req.GroupBy]
+	if !ok {
+		column = aiUsageGroupColumns[dto.AIUsageGroupByContact]
+	}
+	query := respo.DB.WithContext(respo.Ctx).Model(&model.AIUsage{})
+	query = query.Select(column+" AS `key`",
+		"count( 1 ) AS `calls`",
+		"SUM(prompt_tokens) AS `prompt_tokens`",
+		"SUM(completion_tokens) AS `completion_tokens`",
+		"SUM(total_tokens) AS `total_tokens`",
+		"SUM(cost) AS `cost`")
+	if req.ContactID != "" {
+		query = query.Where("contact_id = ?", req.ContactID)
+	}
+	if req.SenderWxID != "" {
+		query = query.Where("sender_wxid = ?", req.SenderWxID)
+	}
+	if req.Feature != "" {
+		query = query.Where("feature = ?", req.Feature)
+	}
+	if req.StartTime > 0 {
+		query = query.Where("created_at >= ?", req.StartTime)
+	}
+	if req.EndTime > 0 {
+		query = query.Where("created_at < ?", req.EndTime)
+	}
+	query = query.Group("`key`")
+	if req.GroupBy == dto.AIUsageGroupByDay {
+		query = query.Order("`key` ASC")
+	} else {
+		query = query.Order("`total_tokens` DESC")
+	}
+	if err := query.Find(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -67,6 +67,13 @@ func (respo *ChatRoomSettings) GetAllEnableAISummary() ([]*model.ChatRoomSetting
 	return chatRoomSettings, nil
 }
 
+// GetSemanticSearchChatRoomIDs 获取单独开启或者关闭了聊天记录语义搜索的群聊
+func (respo *ChatRoomSettings) GetSemanticSearchChatRoomIDs(enabled bool) ([]string, error) {
+	var chatRoomIDs []string
+	err := respo.DB.WithContext(respo.Ctx).Model(&model.ChatRoomSettings{}).Where("semantic_search_enabled = ?", enabled).Pluck("chat_room_id", &chatRoomIDs).Error
+	return chatRoomIDs, err
+}
+
 func (respo *ChatRoomSettings) Create(data *model.ChatRoomSettings) error {
 	return respo.DB.WithContext(respo.Ctx).Create(data).Error
 }
@@ -74,3 +81,8 @@ func (respo *ChatRoomSettings) Create(data *model.ChatRoomSettings) error {
 func (respo *ChatRoomSettings) Update(data *model.ChatRoomSettings) error {
 	return respo.DB.WithContext(respo.Ctx).Where("id = ?", data.ID).Updates(data).Error
 }
+
+// UpdateAIPersonaID 修改群聊使用的AI角色，personaID 为空表示不使用角色
+func (respo *ChatRoomSettings) UpdateAIPersonaID(chatRoomID string, personaID *int64) error {
+	return respo.DB.WithContext(respo.Ctx).Model(&model.ChatRoomSettings{}).Where("chat_room_id = ?", chatRoomID).Update("ai_persona_id", personaID).Error
+}
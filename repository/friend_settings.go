@@ -31,6 +31,13 @@ func (respo *FriendSettings) GetFriendSettings(contactID string) (*model.FriendS
 	return &friendSettings, nil
 }
 
+// GetSemanticSearchFriendIDs 获取单独开启或者关闭了聊天记录语义搜索的好友
+func (respo *FriendSettings) GetSemanticSearchFriendIDs(enabled bool) ([]string, error) {
+	var wechatIDs []string
+	err := respo.DB.WithContext(respo.Ctx).Model(&model.FriendSettings{}).Where("semantic_search_enabled = ?", enabled).Pluck("wechat_id", &wechatIDs).Error
+	return wechatIDs, err
+}
+
 func (respo *FriendSettings) Create(data *model.FriendSettings) error {
 	return respo.DB.WithContext(respo.Ctx).Create(data).Error
 }
@@ -38,3 +45,8 @@ func (respo *FriendSettings) Create(data *model.FriendSettings) error {
 func (respo *FriendSettings) Update(data *model.FriendSettings) error {
 	return respo.DB.WithContext(respo.Ctx).Updates(data).Error
 }
+
+// UpdateAIPersonaID 修改好友使用的AI角色，personaID 为空表示不使用角色
+func (respo *FriendSettings) UpdateAIPersonaID(wechatID string, personaID *int64) error {
+	return respo.DB.WithContext(respo.Ctx).Model(&model.FriendSettings{}).Where("wechat_id = ?", wechatID).Update("ai_persona_id", personaID).Error
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"wechat-robot-client/model"
+
+	"gorm.io/gorm"
+)
+
+type KnowledgeBase struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewKnowledgeBaseRepo(ctx context.Context, db *gorm.DB) *KnowledgeBase {
+	return &KnowledgeBase{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+func (respo *KnowledgeBase) GetByID(id int64) (*model.KnowledgeBase, error) {
+	var knowledgeBase model.KnowledgeBase
+	err := respo.DB.WithContext(respo.Ctx).Where("id = ?", id).First(&knowledgeBase).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &knowledgeBase, nil
+}
+
+func (respo *KnowledgeBase) GetByName(name string) (*model.KnowledgeBase, error) {
+	var knowledgeBase model.KnowledgeBase
+	err := respo.DB.WithContext(respo.Ctx).Where("name = ?", name).First(&knowledgeBase).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &knowledgeBase, nil
+}
+
+func (respo *KnowledgeBase) GetList() ([]*model.KnowledgeBase, error) {
+	var knowledgeBases []*model.KnowledgeBase
+	err := respo.DB.WithContext(respo.Ctx).Order("id ASC").Find(&knowledgeBases).Error
+	return knowledgeBases, err
+}
+
+func (respo *KnowledgeBase) Create(data *model.KnowledgeBase) error {
+	return respo.DB.WithContext(respo.Ctx).Create(data).Error
+}
+
+func (respo *KnowledgeBase) Update(data *model.KnowledgeBase) error {
+	return respo.DB.WithContext(respo.Ctx).Where("id = ?", data.ID).Select("name", "description").Updates(data).Error
+}
+
+// Delete 删除知识库以及知识库中的文档和片段
+func (respo *KnowledgeBase) Delete(id int64) error {
+	return respo.DB.WithContext(respo.Ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("knowledge_base_id = ?", id).Delete(&model.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("knowledge_base_id = ?", id).Delete(&model.KnowledgeDocument{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.KnowledgeBase{}).Error
+	})
+}
+
+func (respo *KnowledgeBase) GetDocumentByID(id int64) (*model.KnowledgeDocument, error) {
+	var document model.KnowledgeDocument
+	err := respo.DB.WithContext(respo.Ctx).Where("id = ?", id).First(&document).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (respo *KnowledgeBase) GetDocuments(knowledgeBaseID int64) ([]*model.KnowledgeDocument, error) {
+	var documents []*model.KnowledgeDocument
+	err := respo.DB.WithContext(respo.Ctx).Where("knowledge_base_id = ?", knowledgeBaseID).Order("id DESC").Find(&documents).Error
+	return documents, err
+}
+
+// GetDocumentsByIDs 获取文档，用于给检索到的片段标注来源
+func (respo *KnowledgeBase) GetDocumentsByIDs(ids []int64) ([]*model.KnowledgeDocument, error) {
+	var documents []*model.KnowledgeDocument
+	if len(ids) == 0 {
+		return documents, nil
+	}
+	err := respo.DB.WithContext(respo.Ctx).Where("id IN ?", ids).Find(&documents).Error
+	return documents, err
+}
+
+func (respo *KnowledgeBase) CreateDocument(data *model.KnowledgeDocument) error {
+	return respo.DB.WithContext(respo.Ctx).Create(data).Error
+}
+
+// UpdateDocumentStatus 更新文档的处理状态，允许把失败原因更新为空
+func (respo *KnowledgeBase) UpdateDocumentStatus(id int64, status model.KnowledgeDocumentStatus, chunkCount int, errMsg string) error {
+	return respo.DB.WithContext(respo.Ctx).Model(&model.KnowledgeDocument{}).Where("id = ?", id).Updates(map[string]any{
+		"status":      status,
+		"chunk_count": chunkCount,
+		"error":       errMsg,
+	}).Error
+}
+
+// DeleteDocument 删除文档以及文档的片段
+func (respo *KnowledgeBase) DeleteDocument(id int64) error {
+	return respo.DB.WithContext(respo.Ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", id).Delete(&model.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.KnowledgeDocument{}).Error
+	})
+}
+
+func (respo *KnowledgeBase) CreateChunks(chunks []*model.KnowledgeChunk) error {
+	return respo.DB.WithContext(respo.Ctx).CreateInBatches(chunks, 100).Error
+}
+
+// HasChunks 判断知识库中是否有片段
+func (respo *KnowledgeBase) HasChunks(knowledgeBaseIDs []int64) (bool, error) {
+	if len(knowledgeBaseIDs) == 0 {
+		return false, nil
+	}
+	var chunk model.KnowledgeChunk
+	err := respo.DB.WithContext(respo.Ctx).Select("id").Where("knowledge_base_id IN ?", knowledgeBaseIDs).Take(&chunk).Error
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FindChunks 分批读取知识库的片段，用于在内存中计算相似度
+func (respo *KnowledgeBase) FindChunks(knowledgeBaseIDs []int64, handle func(chunks []*model.KnowledgeChunk)) error {
+	if len(knowledgeBaseIDs) == 0 {
+		return nil
+	}
+	var chunks []*model.KnowledgeChunk
+	return respo.DB.WithContext(respo.Ctx).
+		Select("id", "knowledge_base_id", "document_id", "chunk_index", "content", "embedding").
+		Where("knowledge_base_id IN ?", knowledgeBaseIDs).
+		FindInBatches(&chunks, 1000, func(tx *gorm.DB, batch int) error {
+			handle(chunks)
+			return nil
+		}).Error
+}
+
+// FailProcessingDocuments 把处理中的文档标记为失败，并删除这些文档已经保存的片段
+func (respo *KnowledgeBase) FailProcessingDocuments(errMsg string) (int64, error) {
+	var count int64
+	err := respo.DB.WithContext(respo.Ctx).Transaction(func(tx *gorm.DB) error {
+		processing := tx.Model(&model.KnowledgeDocument{}).Select("id").Where("status = ?", model.KnowledgeDocumentStatusProcessing)
+		if err := tx.Where("document_id IN (?)", processing).Delete(&model.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&model.KnowledgeDocument{}).Where("status = ?", model.KnowledgeDocumentStatusProcessing).Updates(map[string]any{
+			"status": model.KnowledgeDocumentStatusFailed,
+			"error":  errMsg,
+		})
+		count = result.RowsAffected
+		return result.Error
+	})
+	return count, err
+}
@@ -47,23 +47,26 @@ func (m *Message) GetByMsgID(msgId int64) (*model.Message, error) {
 	return &message, nil
 }
 
-func (m *Message) GetByContactID(req dto.ChatHistoryRequest, pager appx.Pager) ([]*model.Message, int64, error) {
-	var messages []*model.Message
-	var total int64
+// withSender 查询消息时带上发送者的昵称和头像
+func (m *Message) withSender(contactID string) *gorm.DB {
 	query := m.DB.WithContext(m.Ctx).Model(&model.Message{})
 	// 判断是群聊还是单聊，决定关联哪张表
-	if strings.HasSuffix(req.ContactID, "@chatroom") {
+	if strings.HasSuffix(contactID, "@chatroom") {
 		// 群聊，需要关联 chat_room_members 以获取发送者昵称和头像
-		query = query.
+		return query.
 			Joins("LEFT JOIN chat_room_members ON chat_room_members.wechat_id = messages.sender_wxid AND chat_room_members.chat_room_id = messages.from_wxid").
 			Select("messages.*, IF(chat_room_members.remark != '' AND chat_room_members.remark IS NOT NULL, chat_room_members.remark, chat_room_members.nickname) AS sender_nickname, chat_room_members.avatar AS sender_avatar")
-	} else {
-		// 好友，需要关联 contacts 表
-		query = query.
-			Joins("LEFT JOIN contacts ON contacts.wechat_id = messages.sender_wxid").
-			Select("messages.*, IF(contacts.remark != '' AND contacts.remark IS NOT NULL, contacts.remark, contacts.nickname) AS sender_nickname, contacts.avatar AS sender_avatar")
 	}
-	query = query.Where("from_wxid = ?", req.ContactID)
+	// 好友，需要关联 contacts 表
+	return query.
+		Joins("LEFT JOIN contacts ON contacts.wechat_id = messages.sender_wxid").
+		Select("messages.*, IF(contacts.remark != '' AND contacts.remark IS NOT NULL, contacts.remark, contacts.nickname) AS sender_nickname, contacts.avatar AS sender_avatar")
+}
+
+func (m *Message) GetByContactID(req dto.ChatHistoryRequest, pager appx.Pager) ([]*model.Message, int64, error) {
+	var messages []*model.Message
+	var total int64
+	query := m.withSender(req.ContactID).Where("from_wxid = ?", req.ContactID)
 	if req.Keyword != "" {
 		query = query.Where("content LIKE ?", "%"+req.Keyword+"%")
 	}
@@ -81,6 +84,19 @@ func (m *Message) GetByContactID(req dto.ChatHistoryRequest, pager appx.Pager) (
 	return messages, total, nil
 }
 
+// GetByIDsWithSender 按ID获取聊天中的消息，带上发送者的昵称和头像
+func (m *Message) GetByIDsWithSender(contactID string, ids []int64) ([]*model.Message, error) {
+	var messages []*model.Message
+	if len(ids) == 0 {
+		return messages, nil
+	}
+	err := m.withSender(contactID).
+		Where("from_wxid = ?", contactID).
+		Where("messages.id IN ?", ids).
+		Find(&messages).Error
+	return messages, err
+}
+
 func (m *Message) SetMessageIsInContext(message *model.Message) error {
 	return m.DB.WithContext(m.Ctx).Where("id = ?", message.ID).Updates(&model.Message{IsAIContext: true}).Error
 }
@@ -91,7 +107,7 @@ func (m *Message) GetFriendAIMessageContext(message *model.Message) ([]*model.Me
 	err := m.DB.WithContext(m.Ctx).Where("id < ?", message.ID).
 		Where("from_wxid = ?", message.FromWxID).
 		Where("created_at >= ?", tenMinutesAgo).
-		Where("`type` in (1, 3) OR (`type` = 49 AND `app_msg_type` = 57)").
+		Where("`type` in (1, 3) OR (`type` = 34 AND `voice_text` <> '') OR (`type` = 49 AND `app_msg_type` = 57)").
 		Find(&messages).
 		Order("id ASC").Error
 	if err != nil {
@@ -116,7 +132,7 @@ func (m *Message) GetChatRoomAIMessageContext(message *model.Message) ([]*model.
 		Where("from_wxid = ?", message.FromWxID).
 		Where("(sender_wxid = ? AND is_ai_context = 1) OR reply_wxid = ?", message.SenderWxID, message.SenderWxID).
 		Where("created_at >= ?", tenMinutesAgo).
-		Where("`type` in (1, 3) OR (`type` = 49 AND `app_msg_type` = 57)").
+		Where("`type` in (1, 3) OR (`type` = 34 AND `voice_text` <> '') OR (`type` = 49 AND `app_msg_type` = 57)").
 		Find(&messages).
 		Order("id ASC").Error
 	if err != nil {
@@ -284,3 +300,13 @@ func (m *Message) Update(data *model.Message) error {
 func (c *Message) Delete(data *model.Message) error {
 	return c.DB.WithContext(c.Ctx).Unscoped().Delete(data).Error
 }
+
+// GetRobotReplies 获取机器人 since 之后在群聊中回复给指定成员、还没有撤回的消息
+func (m *Message) GetRobotReplies(chatRoomID, robotWxID, replyWxID string, since int64) ([]*model.Message, error) {
+	var messages []*model.Message
+	err := m.DB.WithContext(m.Ctx).
+		Where("from_wxid = ? AND sender_wxid = ? AND reply_wxid = ? AND created_at >= ? AND is_recalled = ?", chatRoomID, robotWxID, replyWxID, since, false).
+		Order("id ASC").
+		Find(&messages).Error
+	return messages, err
+}
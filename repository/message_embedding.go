@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"wechat-robot-client/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MessageEmbedding struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewMessageEmbeddingRepo(ctx context.Context, db *gorm.DB) *MessageEmbedding {
+	return &MessageEmbedding{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+// GetLatestMessageID 获取最新一条消息的ID，没有消息时返回 0
+func (respo *MessageEmbedding) GetLatestMessageID() (int64, error) {
+	var id int64
+	err := respo.DB.WithContext(respo.Ctx).Model(&model.Message{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	return id, err
+}
+
+// GetPendingMessages 获取ID在 (afterID, maxID] 之间还没有生成向量的文本消息，最新的消息优先
+// exclude 为 false 时只处理 contactIDs 中的聊天，为 true 时处理除了 contactIDs 以外的所有聊天
+func (respo *MessageEmbedding) GetPendingMessages(self string, contactIDs []string, exclude bool, afterID, maxID int64, minRunes, limit int) ([]*model.Message, error) {
+	var messages []*model.Message
+	if !exclude && len(contactIDs) == 0 {
+		return messages, nil
+	}
+	query := respo.DB.WithContext(respo.Ctx).Model(&model.Message{}).
+		Select("messages.id", "messages.from_wxid", "messages.content", "messages.created_at").
+		Joins("LEFT JOIN message_embeddings ON message_embeddings.message_id = messages.id").
+		Where("messages.id > ? AND messages.id <= ?", afterID, maxID).
+		Where("message_embeddings.id IS NULL").
+		Where("messages.type = ?", model.MsgTypeText).
+		Where("messages.is_recalled = ?", false).
+		Where("messages.sender_wxid <> ?", self).
+		Where("CHAR_LENGTH(messages.content) >= ?", minRunes).
+		// 不处理命令消息
+		Where("messages.content NOT LIKE ?", "#%")
+	if exclude {
+		if len(contactIDs) > 0 {
+			query = query.Where("messages.from_wxid NOT IN ?", contactIDs)
+		}
+	} else {
+		query = query.Where("messages.from_wxid IN ?", contactIDs)
+	}
+	err := query.Order("messages.id DESC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// Create 保存消息向量，消息已经有向量时忽略
+func (respo *MessageEmbedding) Create(data []*model.MessageEmbedding) error {
+	return respo.DB.WithContext(respo.Ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&data).Error
+}
+
+// FindByContactID 分批读取聊天从 since 开始的消息向量，用于在内存中计算相似度
+func (respo *MessageEmbedding) FindByContactID(contactID string, since int64, handle func(embeddings []*model.MessageEmbedding)) error {
+	var embeddings []*model.MessageEmbedding
+	return respo.DB.WithContext(respo.Ctx).
+		Select("id", "message_id", "embedding").
+		Where("contact_id = ? AND created_at >= ?", contactID, since).
+		FindInBatches(&embeddings, 1000, func(tx *gorm.DB, batch int) error {
+			handle(embeddings)
+			return nil
+		}).Error
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/appx"
+
+	"gorm.io/gorm"
+)
+
+type MessageSendFailure struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewMessageSendFailureRepo(ctx context.Context, db *gorm.DB) *MessageSendFailure {
+	return &MessageSendFailure{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+func (respo *MessageSendFailure) Create(data *model.MessageSendFailure) error {
+	return respo.DB.WithContext(respo.Ctx).Create(data).Error
+}
+
+func (respo *MessageSendFailure) GetList(req dto.MessageSendFailureRequest, pager appx.Pager) ([]*model.MessageSendFailure, int64, error) {
+	var failures []*model.MessageSendFailure
+	var total int64
+	query := respo.DB.WithContext(respo.Ctx).Model(&model.MessageSendFailure{})
+	if req.ToWxID != "" {
+		query = query.Where("to_wxid = ?", req.ToWxID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id DESC").
+		Offset(pager.OffSet).
+		Limit(pager.PageSize).
+		Find(&failures).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return failures, total, nil
+}
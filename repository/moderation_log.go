@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/appx"
+
+	"gorm.io/gorm"
+)
+
+type ModerationLog struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewModerationLogRepo(ctx context.Context, db *gorm.DB) *ModerationLog {
+	return &ModerationLog{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+func (respo *ModerationLog) Create(data *model.ModerationLog) error {
+	return respo.DB.WithContext(respo.Ctx).Create(data).Error
+}
+
+// CountStrikes 统计成员在群聊中 since 之后的违规次数
+func (respo *ModerationLog) CountStrikes(chatRoomID, memberWxID string, since int64) (int64, error) {
+	var count int64
+	err := respo.DB.WithContext(respo.Ctx).Model(&model.ModerationLog{}).
+		Where("chat_room_id = ? AND member_wxid = ? AND created_at >= ?", chatRoomID, memberWxID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// AddRecalled 累加审核记录撤回的机器人消息数量
+func (respo *ModerationLog) AddRecalled(id int64, recalled int) error {
+	return respo.DB.WithContext(respo.Ctx).Model(&model.ModerationLog{}).Where("id = ?", id).
+		UpdateColumn("recalled", gorm.Expr("recalled + ?", recalled)).Error
+}
+
+func (respo *ModerationLog) GetList(req dto.ModerationLogRequest, pager appx.Pager) ([]*model.ModerationLog, int64, error) {
+	var logs []*model.ModerationLog
+	var total int64
+	query := respo.DB.WithContext(respo.Ctx).Model(&model.ModerationLog{})
+	if req.ChatRoomID != "" {
+		query = query.Where("chat_room_id = ?", req.ChatRoomID)
+	}
+	if req.MemberWxID != "" {
+		query = query.Where("member_wxid = ?", req.MemberWxID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id DESC").Offset(pager.OffSet).Limit(pager.PageSize).Find(&logs).Error
+	return logs, total, err
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"wechat-robot-client/model"
+
+	"gorm.io/gorm"
+)
+
+type PluginSettings struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewPluginSettingsRepo(ctx context.Context, db *gorm.DB) *PluginSettings {
+	return &PluginSettings{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+func (respo *PluginSettings) GetPluginSettings(pluginName, contactID string) (*model.PluginSettings, error) {
+	var pluginSettings model.PluginSettings
+	err := respo.DB.WithContext(respo.Ctx).
+		Where("plugin_name = ? AND contact_id = ?", pluginName, contactID).
+		First(&pluginSettings).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pluginSettings, nil
+}
+
+// GetByContactID 获取全局配置以及指定联系人的插件配置
+func (respo *PluginSettings) GetByContactID(contactID string) ([]*model.PluginSettings, error) {
+	var pluginSettings []*model.PluginSettings
+	err := respo.DB.WithContext(respo.Ctx).
+		Where("contact_id IN ?", []string{"", contactID}).
+		Find(&pluginSettings).Error
+	if err != nil {
+		return nil, err
+	}
+	return pluginSettings, nil
+}
+
+func (respo *PluginSettings) Create(data *model.PluginSettings) error {
+	return respo.DB.WithContext(respo.Ctx).Create(data).Error
+}
+
+func (respo *PluginSettings) Update(data *model.PluginSettings) error {
+	return respo.DB.WithContext(respo.Ctx).Where("id = ?", data.ID).Updates(data).Error
+}
+
+// ResetEnabled 清空插件的启用状态，保留插件配置
+func (respo *PluginSettings) ResetEnabled(pluginName, contactID string) error {
+	return respo.DB.WithContext(respo.Ctx).
+		Model(&model.PluginSettings{}).
+		Where("plugin_name = ? AND contact_id = ?", pluginName, contactID).
+		Update("enabled", nil).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"wechat-robot-client/model"
+
+	"gorm.io/gorm"
+)
+
+type ScriptPlugin struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewScriptPluginRepo(ctx context.Context, db *gorm.DB) *ScriptPlugin {
+	return &ScriptPlugin{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+func (respo *ScriptPlugin) GetByID(id uint64) (*model.ScriptPlugin, error) {
+	var scriptPlugin model.ScriptPlugin
+	err := respo.DB.WithContext(respo.Ctx).Where("id = ?", id).First(&scriptPlugin).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scriptPlugin, nil
+}
+
+func (respo *ScriptPlugin) GetByName(name string) (*model.ScriptPlugin, error) {
+	var scriptPlugin model.ScriptPlugin
+	err := respo.DB.WithContext(respo.Ctx).Where("name = ?", name).First(&scriptPlugin).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scriptPlugin, nil
+}
+
+func (respo *ScriptPlugin) GetList() ([]*model.ScriptPlugin, error) {
+	var scriptPlugins []*model.ScriptPlugin
+	err := respo.DB.WithContext(respo.Ctx).Order("priority ASC, id ASC").Find(&scriptPlugins).Error
+	if err != nil {
+		return nil, err
+	}
+	return scriptPlugins, nil
+}
+
+func (respo *ScriptPlugin) Create(data *model.ScriptPlugin) error {
+	return respo.DB.WithContext(respo.Ctx).Create(data).Error
+}
+
+// Update 更新全部字段，允许把群聊ID、描述等字段更新为零值
+func (respo *ScriptPlugin) Update(data *model.ScriptPlugin) error {
+	return respo.DB.WithContext(respo.Ctx).Where("id = ?", data.ID).Select("*").Omit("created_at").Updates(data).Error
+}
+
+func (respo *ScriptPlugin) Delete(id uint64) error {
+	return respo.DB.WithContext(respo.Ctx).Where("id = ?", id).Delete(&model.ScriptPlugin{}).Error
+}
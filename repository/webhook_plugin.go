@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"wechat-robot-client/model"
+
+	"gorm.io/gorm"
+)
+
+type WebhookPlugin struct {
+	Ctx context.Context
+	DB  *gorm.DB
+}
+
+func NewWebhookPluginRepo(ctx context.Context, db *gorm.DB) *WebhookPlugin {
+	return &WebhookPlugin{
+		Ctx: ctx,
+		DB:  db,
+	}
+}
+
+func (respo *WebhookPlugin) GetByID(id uint64) (*model.WebhookPlugin, error) {
+	var webhookPlugin model.WebhookPlugin
+	err := respo.DB.WithContext(respo.Ctx).Where("id = ?", id).First(&webhookPlugin).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhookPlugin, nil
+}
+
+func (respo *WebhookPlugin) GetByName(name string) (*model.WebhookPlugin, error) {
+	var webhookPlugin model.WebhookPlugin
+	err := respo.DB.WithContext(respo.Ctx).Where("name = ?", name).First(&webhookPlugin).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhookPlugin, nil
+}
+
+func (respo *WebhookPlugin) GetList() ([]*model.WebhookPlugin, error) {
+	var webhookPlugins []*model.WebhookPlugin
+	err := respo.DB.WithContext(respo.Ctx).Order("priority ASC, id ASC").Find(&webhookPlugins).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhookPlugins, nil
+}
+
+func (respo *WebhookPlugin) Create(data *model.WebhookPlugin) error {
+	return respo.DB.WithContext(respo.Ctx).Create(data).Error
+}
+
+// Update 更新全部字段，允许把超时时间、重试次数、密钥等字段更新为零值
+func (respo *WebhookPlugin) Update(data *model.WebhookPlugin) error {
+	return respo.DB.WithContext(respo.Ctx).Where("id = ?", data.ID).Select("*").Omit("created_at").Updates(data).Error
+}
+
+func (respo *WebhookPlugin) Delete(id uint64) error {
+	return respo.DB.WithContext(respo.Ctx).Where("id = ?", id).Delete(&model.WebhookPlugin{}).Error
+}
@@ -23,6 +23,14 @@ var momentsCtl *controller.Moments
 var systemSettingsCtl *controller.SystemSettings
 var ossSettingsCtl *controller.OSSSettings
 var probeCtl *controller.Probe
+var pluginCtl *controller.Plugin
+var webhookPluginCtl *controller.WebhookPlugin
+var scriptPluginCtl *controller.ScriptPlugin
+var rateLimitCtl *controller.RateLimit
+var aiUsageCtl *controller.AIUsage
+var knowledgeBaseCtl *controller.KnowledgeBase
+var aiPersonaCtl *controller.AIPersona
+var moderationCtl *controller.Moderation
 
 func initController() {
 	chatHistoryCtl = controller.NewChatHistoryController()
@@ -41,6 +49,14 @@ func initController() {
 	systemSettingsCtl = controller.NewSystemSettingsController()
 	ossSettingsCtl = controller.NewOSSSettingsController()
 	probeCtl = controller.NewProbeController()
+	pluginCtl = controller.NewPluginController()
+	webhookPluginCtl = controller.NewWebhookPluginController()
+	scriptPluginCtl = controller.NewScriptPluginController()
+	rateLimitCtl = controller.NewRateLimitController()
+	aiUsageCtl = controller.NewAIUsageController()
+	knowledgeBaseCtl = controller.NewKnowledgeBaseController()
+	aiPersonaCtl = controller.NewAIPersonaController()
+	moderationCtl = controller.NewModerationController()
 }
 
 func RegisterRouter(r *gin.Engine) error {
@@ -99,9 +115,12 @@ func RegisterRouter(r *gin.Engine) error {
 	api.DELETE("/robot/chat-room/quit", chatRoomCtl.GroupQuit)
 
 	api.GET("/robot/chat/history", chatHistoryCtl.GetChatHistory)
+	api.GET("/robot/chat/history/semantic-search", chatHistoryCtl.SemanticSearchChatHistory)
 
 	// 消息相关接口
 	api.POST("/robot/message/revoke", messageCtl.MessageRevoke)
+	api.GET("/robot/message/dedup-stats", messageCtl.GetMessageDedupStats)
+	api.GET("/robot/message/send-failures", messageCtl.GetMessageSendFailures)
 	api.POST("/robot/message/send/text", messageCtl.SendTextMessage)
 	api.POST("/robot/message/send/image", messageCtl.SendImageMessage)
 	api.POST("/robot/message/send/video", messageCtl.SendVideoMessage)
@@ -135,6 +154,33 @@ func RegisterRouter(r *gin.Engine) error {
 	api.GET("/robot/chat-room-settings", chatRoomSettingsCtl.GetChatRoomSettings)
 	api.POST("/robot/chat-room-settings", chatRoomSettingsCtl.SaveChatRoomSettings)
 
+	// 插件相关接口
+	api.GET("/robot/plugins", pluginCtl.GetPlugins)
+	api.POST("/robot/plugins", pluginCtl.SavePluginEnabled)
+	api.DELETE("/robot/plugins", pluginCtl.ResetPluginEnabled)
+	api.GET("/robot/plugins/config", pluginCtl.GetPluginConfig)
+	api.POST("/robot/plugins/config", pluginCtl.SavePluginConfig)
+	api.GET("/robot/webhook-plugins", webhookPluginCtl.GetWebhookPlugins)
+	api.POST("/robot/webhook-plugins", webhookPluginCtl.SaveWebhookPlugin)
+	api.DELETE("/robot/webhook-plugins", webhookPluginCtl.DeleteWebhookPlugin)
+	api.GET("/robot/script-plugins", scriptPluginCtl.GetScriptPlugins)
+	api.POST("/robot/script-plugins", scriptPluginCtl.SaveScriptPlugin)
+	api.DELETE("/robot/script-plugins", scriptPluginCtl.DeleteScriptPlugin)
+	api.GET("/robot/rate-limits", rateLimitCtl.GetRateLimitCounters)
+	api.DELETE("/robot/rate-limits", rateLimitCtl.ResetRateLimitCounters)
+	api.GET("/robot/ai-usage/stats", aiUsageCtl.GetAIUsageStats)
+	api.GET("/robot/ai-usage/quota", aiUsageCtl.GetAIQuotaStatus)
+	api.GET("/robot/knowledge-bases", knowledgeBaseCtl.GetKnowledgeBases)
+	api.POST("/robot/knowledge-bases", knowledgeBaseCtl.SaveKnowledgeBase)
+	api.DELETE("/robot/knowledge-bases", knowledgeBaseCtl.DeleteKnowledgeBase)
+	api.GET("/robot/knowledge-bases/documents", knowledgeBaseCtl.GetKnowledgeDocuments)
+	api.POST("/robot/knowledge-bases/documents", knowledgeBaseCtl.UploadKnowledgeDocument)
+	api.DELETE("/robot/knowledge-bases/documents", knowledgeBaseCtl.DeleteKnowledgeDocument)
+	api.GET("/robot/ai-personas", aiPersonaCtl.GetPersonas)
+	api.POST("/robot/ai-personas", aiPersonaCtl.SavePersona)
+	api.DELETE("/robot/ai-personas", aiPersonaCtl.DeletePersona)
+	api.GET("/robot/moderation/logs", moderationCtl.GetModerationLogs)
+
 	// 朋友圈接口
 	api.GET("/robot/moments/list", momentsCtl.FriendCircleGetList)
 	api.GET("/robot/moments/sync", momentsCtl.SyncMoments)
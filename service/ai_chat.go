@@ -4,10 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
 	"wechat-robot-client/interface/ai"
 	"wechat-robot-client/interface/settings"
 	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/aiprovider"
 	"wechat-robot-client/vars"
 
 	"github.com/sashabaranov/go-openai"
@@ -21,12 +21,6 @@ func min(a, b int) int {
 	return b
 }
 
-// isGeminiAPI 检查是否是Gemini API
-func isGeminiAPI(baseURL string) bool {
-	return strings.Contains(strings.ToLower(baseURL), "gemini") ||
-		strings.Contains(strings.ToLower(baseURL), "google")
-}
-
 type AIChatService struct {
 	ctx    context.Context
 	config settings.Settings
@@ -74,45 +68,27 @@ func (s *AIChatService) GetSessionID(message *model.Message) string {
 	return fmt.Sprintf("ai_chat_session_%s:%s", message.FromWxID, message.SenderWxID)
 }
 
-func (s *AIChatService) IsAISessionStart(message *model.Message) bool {
-	if message.Content == "#进入AI会话" {
-		err := s.SetAISession(message)
-		return err == nil
-	}
-	return false
-}
-
 func (s *AIChatService) GetAISessionStartTips() string {
 	return "AI会话已开始，请输入您的问题。10分钟不说话会话将自动结束，您也可以输入 #退出AI会话 来结束会话。"
 }
 
-func (s *AIChatService) IsAISessionEnd(message *model.Message) bool {
-	if message.Content == "#退出AI会话" {
-		err := s.ExpireAISession(message)
-		return err == nil
-	}
-	return false
-}
-
 func (s *AIChatService) GetAISessionEndTips() string {
 	return "AI会话已结束，您可以输入 #进入AI会话 来重新开始。"
 }
 
-func (s *AIChatService) Chat(aiMessages []openai.ChatCompletionMessage) (openai.ChatCompletionMessage, error) {
+// newChatRequest 校验AI配置，创建AI服务和请求，Chat 和 ChatStream 共用
+func (s *AIChatService) newChatRequest(aiMessages []openai.ChatCompletionMessage) (aiprovider.Provider, openai.ChatCompletionRequest, error) {
 	aiConfig := s.config.GetAIConfig()
 
 	// 验证AI配置是否完整
-	if aiConfig.APIKey == "" {
-		return openai.ChatCompletionMessage{}, fmt.Errorf("AI API Key 未配置，请联系管理员")
-	}
-	if aiConfig.BaseURL == "" {
-		return openai.ChatCompletionMessage{}, fmt.Errorf("AI Base URL 未配置，请联系管理员")
+	if err := validateAIConfig(aiConfig); err != nil {
+		return nil, openai.ChatCompletionRequest{}, err
 	}
 	if aiConfig.Model == "" {
-		return openai.ChatCompletionMessage{}, fmt.Errorf("AI Model 未配置，请联系管理员")
+		return nil, openai.ChatCompletionRequest{}, fmt.Errorf("AI Model 未配置，请联系管理员")
 	}
 
-	log.Printf("AI配置验证通过 - BaseURL: %s, Model: %s", aiConfig.BaseURL, aiConfig.Model)
+	log.Printf("AI配置验证通过 - Provider: %s, BaseURL: %s, Model: %s", aiConfig.Provider, aiConfig.BaseURL, aiConfig.Model)
 
 	if aiConfig.Prompt != "" {
 		systemMessage := openai.ChatCompletionMessage{
@@ -124,33 +100,36 @@ func (s *AIChatService) Chat(aiMessages []openai.ChatCompletionMessage) (openai.
 		}
 		aiMessages = append([]openai.ChatCompletionMessage{systemMessage}, aiMessages...)
 	}
-	openaiConfig := openai.DefaultConfig(aiConfig.APIKey)
-
-	// 检查是否是Gemini API，如果是则需要特殊处理
-	if isGeminiAPI(aiConfig.BaseURL) {
-		log.Printf("检测到Gemini API，使用原始配置地址")
-		// 对于Gemini代理服务，直接使用原始配置的URL，不添加任何端点
-		openaiConfig.BaseURL = aiConfig.BaseURL
-	} else {
-		openaiConfig.BaseURL = aiConfig.BaseURL
-	}
-
-	client := openai.NewClientWithConfig(openaiConfig)
 	req := openai.ChatCompletionRequest{
 		Model:    aiConfig.Model,
 		Messages: aiMessages,
 		Stream:   false,
 	}
+	applySamplingParams(&req, aiConfig)
 
 	// 记录详细的请求信息
 	log.Printf("=== AIConfig 完整配置 ===")
+	log.Printf("Provider: %s", aiConfig.Provider)
 	log.Printf("BaseURL: %s", aiConfig.BaseURL)
 	log.Printf("APIKey: %s...", aiConfig.APIKey[:min(8, len(aiConfig.APIKey))])
 	log.Printf("Model: %s", aiConfig.Model)
 	log.Printf("WorkflowModel: %s", aiConfig.WorkflowModel)
 	log.Printf("ImageRecognitionModel: %s", aiConfig.ImageRecognitionModel)
+	log.Printf("FallbackProviders: %d", len(aiConfig.FallbackProviders))
 	log.Printf("Prompt: %s", aiConfig.Prompt)
 	log.Printf("MaxCompletionTokens: %d", aiConfig.MaxCompletionTokens)
+	if aiConfig.Temperature != nil {
+		log.Printf("Temperature: %.2f", *aiConfig.Temperature)
+	}
+	if aiConfig.TopP != nil {
+		log.Printf("TopP: %.2f", *aiConfig.TopP)
+	}
+	if aiConfig.PresencePenalty != nil {
+		log.Printf("PresencePenalty: %.2f", *aiConfig.PresencePenalty)
+	}
+	log.Printf("ReasoningEffort: %s", aiConfig.ReasoningEffort)
+	log.Printf("ResponseFormat: %s", aiConfig.ResponseFormat)
+	log.Printf("ReasoningOutput: %s", aiConfig.ReasoningOutput)
 	log.Printf("ImageModel: %s", aiConfig.ImageModel)
 	log.Printf("ImageAISettings: %s", string(aiConfig.ImageAISettings))
 	log.Printf("TTSSettings: %s", string(aiConfig.TTSSettings))
@@ -158,20 +137,18 @@ func (s *AIChatService) Chat(aiMessages []openai.ChatCompletionMessage) (openai.
 	log.Printf("=== AIConfig 配置结束 ===")
 
 	log.Printf("AI请求详情:")
-	log.Printf("  原始BaseURL: %s", aiConfig.BaseURL)
-	log.Printf("  调整后BaseURL: %s", openaiConfig.BaseURL)
-	log.Printf("  Model: %s", aiConfig.Model)
-	log.Printf("  APIKey: %s...", aiConfig.APIKey[:min(8, len(aiConfig.APIKey))])
 	log.Printf("  消息数量: %d", len(aiMessages))
 	for i, msg := range aiMessages {
 		log.Printf("  消息%d: Role=%s, Content长度=%d", i+1, msg.Role, len(msg.Content))
 	}
 	// 判断一下aiMessages是否包含图片，如果包含，则使用多模态模型
+	usage := newAIUsage(s.config.GetMessage(), model.AIUsageFeatureChat)
 	for _, msg := range aiMessages {
 		if len(msg.MultiContent) > 0 {
 			for _, part := range msg.MultiContent {
 				if part.Type == openai.ChatMessagePartTypeImageURL {
 					req.Model = aiConfig.ImageRecognitionModel
+					usage.Feature = model.AIUsageFeatureImageRecognition
 					break
 				}
 			}
@@ -180,23 +157,55 @@ func (s *AIChatService) Chat(aiMessages []openai.ChatCompletionMessage) (openai.
 	if aiConfig.MaxCompletionTokens > 0 {
 		req.MaxCompletionTokens = aiConfig.MaxCompletionTokens
 	}
-	log.Printf("开始调用AI服务 - 消息数量: %d", len(aiMessages))
-	resp, err := client.CreateChatCompletion(context.Background(), req)
+	provider, err := newAIProvider(s.ctx, aiConfig, usage)
 	if err != nil {
-		log.Printf("AI服务调用失败: %v", err)
+		return nil, openai.ChatCompletionRequest{}, err
+	}
+	return provider, req, nil
+}
 
-		// 如果是Gemini API且返回404，记录详细错误信息
-		if isGeminiAPI(aiConfig.BaseURL) && strings.Contains(err.Error(), "404") {
-			log.Printf("Gemini API 404错误，请检查代理服务配置")
-			log.Printf("当前配置: BaseURL=%s, Model=%s", aiConfig.BaseURL, aiConfig.Model)
-		}
+// applySamplingParams 把采样参数、思考强度和回复格式写入请求，没有配置的参数使用模型默认值
+func applySamplingParams(req *openai.ChatCompletionRequest, aiConfig settings.AIConfig) {
+	if aiConfig.Temperature != nil {
+		req.Temperature = *aiConfig.Temperature
+	}
+	if aiConfig.TopP != nil {
+		req.TopP = *aiConfig.TopP
+	}
+	if aiConfig.PresencePenalty != nil {
+		req.PresencePenalty = *aiConfig.PresencePenalty
+	}
+	if aiConfig.ReasoningEffort != "" {
+		req.ReasoningEffort = aiConfig.ReasoningEffort
+	}
+	if aiConfig.ResponseFormat == model.AIResponseFormatJSON {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+}
+
+// chatError 记录AI服务调用失败的详细信息
+func (s *AIChatService) chatError(err error) error {
+	log.Printf("AI服务调用失败: %v", err)
+	return fmt.Errorf("AI服务调用失败: %v", err)
+}
 
-		return openai.ChatCompletionMessage{}, fmt.Errorf("AI服务调用失败: %v", err)
+func (s *AIChatService) Chat(aiMessages []openai.ChatCompletionMessage) (openai.ChatCompletionMessage, error) {
+	provider, req, err := s.newChatRequest(aiMessages)
+	if err != nil {
+		return openai.ChatCompletionMessage{}, err
+	}
+	log.Printf("开始调用AI服务 - 消息数量: %d", len(req.Messages))
+	resp, err := provider.CreateChatCompletion(context.Background(), req)
+	if err != nil {
+		return openai.ChatCompletionMessage{}, s.chatError(err)
 	}
 	if len(resp.Choices) == 0 {
 		log.Printf("AI返回了空内容")
 		return openai.ChatCompletionMessage{}, fmt.Errorf("AI返回了空内容，请联系管理员")
 	}
-	log.Printf("AI服务调用成功，返回内容长度: %d", len(resp.Choices[0].Message.Content))
-	return resp.Choices[0].Message, nil
+	reply := resp.Choices[0].Message
+	reply.Content = formatReasoning(reply.ReasoningContent, reply.Content, s.config.GetAIConfig().ReasoningOutput)
+	reply.ReasoningContent = ""
+	log.Printf("AI服务调用成功，返回内容长度: %d", len(reply.Content))
+	return reply, nil
 }
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"unicode"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/aiprovider"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// 每条消息至少包含的字数，避免一句话一条消息刷屏
+	streamMinChunkRunes = 40
+	// 每条消息最多包含的字数，超过后强制切分，和 SendLongTextMessage 一样避免单条消息过长被微信折叠
+	streamMaxChunkRunes = 500
+)
+
+// ChatStream 使用流式接口调用AI，按段落/句子切分成多条消息，每切出一条就调用 onChunk 发送
+// tools 不为空时AI可以调用工具，调用工具之前已经生成的内容会先发送出去
+// 返回最后一轮的完整回复，onChunk 返回错误时停止接收
+func (s *AIChatService) ChatStream(aiMessages []openai.ChatCompletionMessage, tools *ChatTools, onChunk func(chunk string) error) (openai.ChatCompletionMessage, error) {
+	provider, req, err := s.newChatRequest(aiMessages)
+	if err != nil {
+		return openai.ChatCompletionMessage{}, err
+	}
+	req.Stream = true
+	reasoningOutput := s.config.GetAIConfig().ReasoningOutput
+	for round := 0; ; round++ {
+		req.Tools = tools.forRound(round)
+		reply, err := s.chatStreamRound(provider, req, reasoningOutput, onChunk)
+		if err != nil {
+			return openai.ChatCompletionMessage{}, err
+		}
+		reply, err = finalToolReply(reply, req.Tools)
+		if err != nil {
+			return openai.ChatCompletionMessage{}, err
+		}
+		if len(reply.ToolCalls) == 0 {
+			if reply.Content == "" && round == 0 {
+				log.Printf("AI返回了空内容")
+				return openai.ChatCompletionMessage{}, errors.New("AI返回了空内容，请联系管理员")
+			}
+			log.Printf("AI服务调用成功，返回内容长度: %d", len(reply.Content))
+			return reply, nil
+		}
+		req.Messages = tools.run(req.Messages, reply)
+	}
+}
+
+// chatStreamRound 接收一次流式返回，文本内容边接收边发送，工具调用合并后返回
+// 思考过程不发送，summary 模式下在回答的第一条消息之前发送思考过程的摘要
+func (s *AIChatService) chatStreamRound(provider aiprovider.Provider, req openai.ChatCompletionRequest, reasoningOutput model.AIReasoningOutput, onChunk func(chunk string) error) (openai.ChatCompletionMessage, error) {
+	log.Printf("开始调用AI服务(流式) - 消息数量: %d, 工具数量: %d", len(req.Messages), len(req.Tools))
+	stream, err := provider.CreateChatCompletionStream(context.Background(), req)
+	if err != nil {
+		return openai.ChatCompletionMessage{}, s.chatError(err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	var toolCalls []openai.ToolCall
+	splitter := &chunkSplitter{minRunes: streamMinChunkRunes, maxRunes: streamMaxChunkRunes}
+	filter := &thinkFilter{}
+	var reasoning strings.Builder
+	summarySent := reasoningOutput != model.AIReasoningOutputSummary
+	// send 发送一条回答，需要时先发送思考过程的摘要
+	send := func(chunk string) error {
+		if !summarySent && strings.TrimSpace(reasoning.String()) != "" {
+			summarySent = true
+			if err := onChunk(reasoningSummary(reasoning.String())); err != nil {
+				return err
+			}
+		}
+		return onChunk(chunk)
+	}
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return openai.ChatCompletionMessage{}, s.chatError(err)
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+		delta := resp.Choices[0].Delta
+		toolCalls = mergeToolCallDeltas(toolCalls, delta.ToolCalls)
+		answer, thought := filter.Write(delta.Content)
+		reasoning.WriteString(delta.ReasoningContent)
+		reasoning.WriteString(thought)
+		content.WriteString(answer)
+		for _, chunk := range splitter.Write(answer) {
+			if err := send(chunk); err != nil {
+				return openai.ChatCompletionMessage{}, err
+			}
+		}
+	}
+	answer, thought := filter.Flush()
+	reasoning.WriteString(thought)
+	content.WriteString(answer)
+	for _, chunk := range append(splitter.Write(answer), splitter.Flush()) {
+		if chunk == "" {
+			continue
+		}
+		if err := send(chunk); err != nil {
+			return openai.ChatCompletionMessage{}, err
+		}
+	}
+	return openai.ChatCompletionMessage{
+		Role:      openai.ChatMessageRoleAssistant,
+		Content:   strings.TrimSpace(content.String()),
+		ToolCalls: toolCalls,
+	}, nil
+}
+
+// chunkSplitter 把流式返回的内容切分成适合单独发送的消息
+// 优先在段落结束处切分，其次是句子结束处，超过最大字数还没有结束时在逗号、空格处强制切分
+type chunkSplitter struct {
+	minRunes int
+	maxRunes int
+	buf      []rune
+}
+
+// Write 追加内容，返回可以发送的消息
+func (c *chunkSplitter) Write(delta string) []string {
+	c.buf = append(c.buf, []rune(delta)...)
+	var chunks []string
+	for {
+		// 切分后剩下的换行不计入下一条消息的字数
+		for len(c.buf) > 0 && unicode.IsSpace(c.buf[0]) {
+			c.buf = c.buf[1:]
+		}
+		pos := c.cutPosition()
+		if pos <= 0 {
+			return chunks
+		}
+		chunk := strings.TrimSpace(string(c.buf[:pos]))
+		c.buf = c.buf[pos:]
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+}
+
+// Flush 返回剩余的内容
+func (c *chunkSplitter) Flush() string {
+	chunk := strings.TrimSpace(string(c.buf))
+	c.buf = nil
+	return chunk
+}
+
+// cutPosition 返回切分位置，不能切分时返回 0
+func (c *chunkSplitter) cutPosition() int {
+	if len(c.buf) < c.minRunes {
+		return 0
+	}
+	end := min(len(c.buf), c.maxRunes)
+	paragraph, sentence, soft := 0, 0, 0
+	// 最后一个字符后面的内容还没有收到，判断不了英文句号是不是句子结束，所以不检查最后一个字符
+	for i := c.minRunes - 1; i < end && i+1 < len(c.buf); i++ {
+		r, next := c.buf[i], c.buf[i+1]
+		switch {
+		case r == '\n' && next == '\n':
+			paragraph = i + 2
+		case r == '\n' || strings.ContainsRune("。！？；…", r) || (strings.ContainsRune(".!?;", r) && unicode.IsSpace(next)):
+			sentence = i + 1
+		case strings.ContainsRune("，、,：:", r) || unicode.IsSpace(r):
+			soft = i + 1
+		}
+	}
+	switch {
+	case paragraph > 0:
+		return paragraph
+	case sentence > 0:
+		return sentence
+	case len(c.buf) >= c.maxRunes && soft > 0:
+		return soft
+	case len(c.buf) >= c.maxRunes:
+		return c.maxRunes
+	}
+	return 0
+}
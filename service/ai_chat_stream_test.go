@@ -0,0 +1,47 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// splitStream 模拟流式返回，每次写入 step 个字符
+func splitStream(c *chunkSplitter, text string, step int) []string {
+	var chunks []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); i += step {
+		chunks = append(chunks, c.Write(string(runes[i:min(i+step, len(runes))]))...)
+	}
+	if chunk := c.Flush(); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func TestChunkSplitter(t *testing.T) {
+	text := "第一段第一句。第一段第二句，比较长一些！\n\n第二段只有一句。第三句 pi is 3.14. Done"
+	chunks := splitStream(&chunkSplitter{minRunes: 10, maxRunes: 100}, text, 3)
+	want := []string{"第一段第一句。第一段第二句，比较长一些！", "第二段只有一句。第三句 pi is 3.14.", "Done"}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Fatalf("chunks = %q, want %q", chunks, want)
+	}
+
+	// 没有句子结束时在逗号处强制切分
+	text = strings.Repeat("一二三四，", 10)
+	chunks = splitStream(&chunkSplitter{minRunes: 5, maxRunes: 12}, text, 1)
+	for _, chunk := range chunks {
+		if utf8.RuneCountInString(chunk) > 12 {
+			t.Fatalf("chunk %q longer than max", chunk)
+		}
+	}
+	if strings.Join(chunks, "") != text {
+		t.Fatalf("chunks = %q, lost content", chunks)
+	}
+
+	// 不足最小字数时不切分
+	chunks = splitStream(&chunkSplitter{minRunes: 40, maxRunes: 100}, "你好。我是机器人。", 1)
+	if len(chunks) != 1 {
+		t.Fatalf("chunks = %q, want one chunk", chunks)
+	}
+}
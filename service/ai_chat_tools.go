@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/aiprovider"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 一次回复中最多调用工具的轮数，超过后不再提供工具，要求AI直接回答
+const maxToolRounds = 5
+
+// ChatTools AI聊天时可以调用的工具，Call 执行工具调用，返回交给AI的结果
+type ChatTools struct {
+	Tools []openai.Tool
+	Call  func(name, arguments string) string
+}
+
+// forRound 返回这一轮提供给AI的工具
+func (t *ChatTools) forRound(round int) []openai.Tool {
+	if t == nil || round >= maxToolRounds {
+		return nil
+	}
+	return t.Tools
+}
+
+// MergeChatTools 合并多组工具，按工具名称把调用分发给对应的一组，名称相同时使用前面的工具，没有工具时返回 nil
+func MergeChatTools(toolSets ...*ChatTools) *ChatTools {
+	merged := &ChatTools{}
+	calls := make(map[string]func(name, arguments string) string)
+	for _, toolSet := range toolSets {
+		if toolSet == nil {
+			continue
+		}
+		for _, tool := range toolSet.Tools {
+			if _, exists := calls[tool.Function.Name]; exists {
+				continue
+			}
+			calls[tool.Function.Name] = toolSet.Call
+			merged.Tools = append(merged.Tools, tool)
+		}
+	}
+	if len(merged.Tools) == 0 {
+		return nil
+	}
+	merged.Call = func(name, arguments string) string {
+		call, ok := calls[name]
+		if !ok {
+			return fmt.Sprintf("工具 %s 不存在或者当前不可用", name)
+		}
+		return call(name, arguments)
+	}
+	return merged
+}
+
+// run 依次执行AI返回的工具调用，把调用和结果追加到对话中
+func (t *ChatTools) run(messages []openai.ChatCompletionMessage, reply openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	reply.Role = openai.ChatMessageRoleAssistant
+	messages = append(messages, reply)
+	for _, call := range reply.ToolCalls {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			ToolCallID: call.ID,
+			Name:       call.Function.Name,
+			Content:    t.Call(call.Function.Name, call.Function.Arguments),
+		})
+	}
+	return messages
+}
+
+// ChatWithTools 把工具提供给AI，AI返回工具调用时执行工具，并把结果交给AI继续回答，直到AI给出最终回复
+func (s *AIChatService) ChatWithTools(aiMessages []openai.ChatCompletionMessage, tools *ChatTools) (openai.ChatCompletionMessage, error) {
+	provider, req, err := s.newChatRequest(aiMessages)
+	if err != nil {
+		return openai.ChatCompletionMessage{}, err
+	}
+	return s.chatWithTools(provider, req, tools, s.config.GetAIConfig().ReasoningOutput)
+}
+
+// chatWithTools 最终回复按 reasoningOutput 处理思考过程，中间几轮的工具调用原样交回给AI
+func (s *AIChatService) chatWithTools(provider aiprovider.Provider, req openai.ChatCompletionRequest, tools *ChatTools, reasoningOutput model.AIReasoningOutput) (openai.ChatCompletionMessage, error) {
+	for round := 0; ; round++ {
+		req.Tools = tools.forRound(round)
+		log.Printf("开始调用AI服务 - 消息数量: %d, 工具数量: %d", len(req.Messages), len(req.Tools))
+		resp, err := provider.CreateChatCompletion(context.Background(), req)
+		if err != nil {
+			return openai.ChatCompletionMessage{}, s.chatError(err)
+		}
+		if len(resp.Choices) == 0 {
+			log.Printf("AI返回了空内容")
+			return openai.ChatCompletionMessage{}, errors.New("AI返回了空内容，请联系管理员")
+		}
+		reply, err := finalToolReply(resp.Choices[0].Message, req.Tools)
+		if err != nil {
+			return openai.ChatCompletionMessage{}, err
+		}
+		if len(reply.ToolCalls) == 0 {
+			reply.Content = formatReasoning(reply.ReasoningContent, reply.Content, reasoningOutput)
+			reply.ReasoningContent = ""
+			log.Printf("AI服务调用成功，返回内容长度: %d", len(reply.Content))
+			return reply, nil
+		}
+		req.Messages = tools.run(req.Messages, reply)
+	}
+}
+
+// finalToolReply 这一轮没有提供工具（没有工具或者超过了调用轮数）时，AI仍然返回的工具调用不再执行，避免无限循环
+func finalToolReply(reply openai.ChatCompletionMessage, tools []openai.Tool) (openai.ChatCompletionMessage, error) {
+	if len(reply.ToolCalls) == 0 || tools != nil {
+		return reply, nil
+	}
+	log.Printf("没有提供工具，忽略AI返回的 %d 个工具调用", len(reply.ToolCalls))
+	if reply.Content == "" {
+		return openai.ChatCompletionMessage{}, errors.New("AI调用工具次数过多，没有给出回复，请稍后再试")
+	}
+	reply.ToolCalls = nil
+	return reply, nil
+}
+
+// mergeToolCallDeltas 合并流式返回的工具调用片段，同一个工具调用的参数分多次返回
+func mergeToolCallDeltas(calls []openai.ToolCall, deltas []openai.ToolCall) []openai.ToolCall {
+	for _, delta := range deltas {
+		var index int
+		switch {
+		case delta.Index != nil:
+			index = *delta.Index
+		case delta.ID != "" || len(calls) == 0:
+			index = len(calls)
+		default:
+			index = len(calls) - 1
+		}
+		for len(calls) <= index {
+			calls = append(calls, openai.ToolCall{Type: openai.ToolTypeFunction})
+		}
+		if delta.ID != "" {
+			calls[index].ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			calls[index].Function.Name = delta.Function.Name
+		}
+		calls[index].Function.Arguments += delta.Function.Arguments
+	}
+	return calls
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/aiprovider"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestMergeToolCallDeltas(t *testing.T) {
+	index := func(i int) *int { return &i }
+	deltas := [][]openai.ToolCall{
+		{{Index: index(0), ID: "call_1", Function: openai.FunctionCall{Name: "draw_image", Arguments: `{"pro`}}},
+		{{Index: index(0), Function: openai.FunctionCall{Arguments: `mpt":"猫"}`}}},
+		{{Index: index(1), ID: "call_2", Function: openai.FunctionCall{Name: "get_weather"}}},
+		{{Index: index(1), Function: openai.FunctionCall{Arguments: `{"city":"广州"}`}}},
+	}
+	var calls []openai.ToolCall
+	for _, delta := range deltas {
+		calls = mergeToolCallDeltas(calls, delta)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("calls = %+v, want 2 calls", calls)
+	}
+	if calls[0].ID != "call_1" || calls[0].Function.Name != "draw_image" || calls[0].Function.Arguments != `{"prompt":"猫"}` {
+		t.Errorf("calls[0] = %+v", calls[0])
+	}
+	if calls[1].ID != "call_2" || calls[1].Function.Name != "get_weather" || calls[1].Function.Arguments != `{"city":"广州"}` {
+		t.Errorf("calls[1] = %+v", calls[1])
+	}
+}
+
+// toolLoopProvider 无论是否提供工具，都返回工具调用
+type toolLoopProvider struct {
+	aiprovider.Provider
+	calls   int
+	content string
+}
+
+func (p *toolLoopProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	p.calls++
+	message := openai.ChatCompletionMessage{
+		Role:      openai.ChatMessageRoleAssistant,
+		ToolCalls: []openai.ToolCall{{ID: "call_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "get_weather"}}},
+	}
+	if req.Tools == nil {
+		message.Content = p.content
+	}
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: message}}}, nil
+}
+
+func TestChatWithToolsStopsAfterMaxRounds(t *testing.T) {
+	tools := &ChatTools{
+		Tools: []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "get_weather"}}},
+		Call:  func(name, arguments string) string { return "晴" },
+	}
+	s := &AIChatService{}
+
+	provider := &toolLoopProvider{content: "今天晴"}
+	reply, err := s.chatWithTools(provider, openai.ChatCompletionRequest{}, tools, model.AIReasoningOutputStrip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Content != "今天晴" || len(reply.ToolCalls) != 0 || provider.calls != maxToolRounds+1 {
+		t.Fatalf("reply = %+v, calls = %d", reply, provider.calls)
+	}
+
+	provider = &toolLoopProvider{}
+	if _, err := s.chatWithTools(provider, openai.ChatCompletionRequest{}, tools, model.AIReasoningOutputStrip); err == nil {
+		t.Fatal("expected error when the final reply is empty")
+	}
+	if provider.calls != maxToolRounds+1 {
+		t.Fatalf("calls = %d, want %d", provider.calls, maxToolRounds+1)
+	}
+}
+
+// scriptedProvider 依次返回预先设定的回复
+type scriptedProvider struct {
+	aiprovider.Provider
+	replies []openai.ChatCompletionMessage
+}
+
+func (p *scriptedProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: reply}}}, nil
+}
+
+func TestChatWithToolsFormatsReasoning(t *testing.T) {
+	tools := &ChatTools{
+		Tools: []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "get_weather"}}},
+		Call:  func(name, arguments string) string { return "晴" },
+	}
+	toolCall := openai.ChatCompletionMessage{
+		Role:      openai.ChatMessageRoleAssistant,
+		ToolCalls: []openai.ToolCall{{ID: "call_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "get_weather"}}},
+	}
+	s := &AIChatService{}
+
+	provider := &scriptedProvider{replies: []openai.ChatCompletionMessage{toolCall, {Content: "<think>查一下天气</think>\n今天晴"}}}
+	reply, err := s.chatWithTools(provider, openai.ChatCompletionRequest{}, tools, model.AIReasoningOutputStrip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Content != "今天晴" {
+		t.Fatalf("strip content = %q", reply.Content)
+	}
+
+	provider = &scriptedProvider{replies: []openai.ChatCompletionMessage{toolCall, {Content: "今天晴", ReasoningContent: "查一下天气"}}}
+	reply, err = s.chatWithTools(provider, openai.ChatCompletionRequest{}, tools, model.AIReasoningOutputSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Content != "【思考过程】查一下天气\n\n今天晴" || reply.ReasoningContent != "" {
+		t.Fatalf("summary reply = %+v", reply)
+	}
+}
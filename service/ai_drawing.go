@@ -56,26 +56,10 @@ func (s *AIDrawingService) GetSessionID(message *model.Message) string {
 	return fmt.Sprintf("ai_drawing_session_%s:%s", message.FromWxID, message.SenderWxID)
 }
 
-func (s *AIDrawingService) IsAISessionStart(message *model.Message) bool {
-	if message.Content == "#进入AI绘图" {
-		err := s.SetAISession(message)
-		return err == nil
-	}
-	return false
-}
-
 func (s *AIDrawingService) GetAISessionStartTips() string {
 	return "AI绘图已开始，请输入您的绘图提示词。10分钟不说话会话将自动结束，您也可以输入 #退出AI绘图 来结束会话。"
 }
 
-func (s *AIDrawingService) IsAISessionEnd(message *model.Message) bool {
-	if message.Content == "#退出AI绘图" {
-		err := s.ExpireAISession(message)
-		return err == nil
-	}
-	return false
-}
-
 func (s *AIDrawingService) GetAISessionEndTips() string {
 	return "AI绘图已结束，您可以输入 #进入AI绘图 来重新开始。"
 }
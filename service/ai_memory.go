@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"wechat-robot-client/interface/settings"
+	"wechat-robot-client/model"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/vars"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+const (
+	// 每个用户最多保存的记忆条数
+	maxAIMemories = 20
+	// 每条记忆最多的字数
+	maxAIMemoryRunes = 100
+)
+
+const aiMemoryExtractPrompt = `你负责维护聊天机器人关于一位用户的长期记忆。根据已有的记忆和最新的一轮对话，返回更新后的完整记忆列表。
+规则：
+1. 只记录长期有效的信息，例如用户的称呼、身份、职业、所在城市、兴趣爱好、偏好、忌讳、正在进行的事情。
+2. 不要记录一次性的问题、闲聊内容、AI的回答，也不要记录密码、身份证号、银行卡号、手机号等敏感信息。
+3. 新信息和已有记忆冲突时以新信息为准，用户要求忘记某些信息时删除对应的记忆。
+4. 合并重复的记忆，每条记忆用一句简短的话描述，不超过50个字。
+5. 最多保留20条，超过时删除最不重要的。
+6. 没有需要记住的新信息时原样返回已有记忆。`
+
+type AIMemoryService struct {
+	ctx         context.Context
+	config      settings.Settings
+	memoryRespo *repository.AIMemory
+}
+
+type AIMemoryExtraction struct {
+	Memories []string `json:"memories"`
+}
+
+func NewAIMemoryService(ctx context.Context, config settings.Settings) *AIMemoryService {
+	return &AIMemoryService{
+		ctx:         ctx,
+		config:      config,
+		memoryRespo: repository.NewAIMemoryRepo(ctx, vars.DB),
+	}
+}
+
+// GetMemories 获取消息发送者在当前聊天中的记忆
+func (s *AIMemoryService) GetMemories(message *model.Message) ([]*model.AIMemory, error) {
+	return s.memoryRespo.GetByUser(message.FromWxID, message.SenderWxID)
+}
+
+// ClearMemories 清除消息发送者在当前聊天中的记忆
+func (s *AIMemoryService) ClearMemories(message *model.Message) error {
+	return s.memoryRespo.DeleteByUser(message.FromWxID, message.SenderWxID)
+}
+
+// MemoryPrompt 返回加入系统提示词的记忆，没有记忆时返回空字符串
+func (s *AIMemoryService) MemoryPrompt(message *model.Message) (string, error) {
+	memories, err := s.GetMemories(message)
+	if err != nil {
+		return "", err
+	}
+	if len(memories) == 0 {
+		return "", nil
+	}
+	var builder strings.Builder
+	builder.WriteString("以下是你之前记住的关于当前用户的信息，回答时可以参考，不需要主动提起：")
+	for _, memory := range memories {
+		builder.WriteString("\n- ")
+		builder.WriteString(memory.Content)
+	}
+	return builder.String(), nil
+}
+
+// Remember 根据最新的一轮对话更新消息发送者的记忆
+func (s *AIMemoryService) Remember(message *model.Message, userText, replyText string) error {
+	if strings.TrimSpace(userText) == "" {
+		return nil
+	}
+	aiConfig := s.config.GetAIConfig()
+	if err := validateAIConfig(aiConfig); err != nil {
+		return err
+	}
+	modelName := aiConfig.WorkflowModel
+	if modelName == "" {
+		modelName = aiConfig.Model
+	}
+	memories, err := s.GetMemories(message)
+	if err != nil {
+		return err
+	}
+	existing := make([]string, 0, len(memories))
+	for _, memory := range memories {
+		existing = append(existing, memory.Content)
+	}
+
+	var conversation strings.Builder
+	conversation.WriteString("已有记忆：")
+	if len(existing) == 0 {
+		conversation.WriteString("无")
+	}
+	for _, memory := range existing {
+		conversation.WriteString("\n- ")
+		conversation.WriteString(memory)
+	}
+	conversation.WriteString(fmt.Sprintf("\n\n最新对话：\n用户：%s\nAI：%s", userText, replyText))
+
+	schema := &jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"memories": {
+				Type:        jsonschema.Array,
+				Items:       &jsonschema.Definition{Type: jsonschema.String},
+				Description: "更新后的完整记忆列表",
+			},
+		},
+		Required:             []string{"memories"},
+		AdditionalProperties: false,
+	}
+	provider, err := newAIProvider(s.ctx, aiConfig, newAIUsage(message, model.AIUsageFeatureWorkflow))
+	if err != nil {
+		return err
+	}
+	resp, err := provider.CreateChatCompletion(s.ctx, openai.ChatCompletionRequest{
+		Model: modelName,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: aiMemoryExtractPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: conversation.String()},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:        "user_memories",
+				Description: "更新后的用户长期记忆",
+				Strict:      true,
+				Schema:      schema,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(resp.Choices) == 0 {
+		return fmt.Errorf("AI返回了空内容")
+	}
+	var result AIMemoryExtraction
+	if err := schema.Unmarshal(resp.Choices[0].Message.Content, &result); err != nil {
+		return fmt.Errorf("解析记忆失败: %w", err)
+	}
+	updated := normalizeMemories(result.Memories)
+	if slices.Equal(updated, existing) {
+		return nil
+	}
+	log.Printf("更新 %s 在 %s 中的记忆，共 %d 条", message.SenderWxID, message.FromWxID, len(updated))
+	return s.memoryRespo.ReplaceByUser(message.FromWxID, message.SenderWxID, updated)
+}
+
+// normalizeMemories 去掉空白和重复的记忆，限制每条的字数和总条数
+func normalizeMemories(memories []string) []string {
+	var result []string
+	for _, memory := range memories {
+		memory = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(memory), "-•*"))
+		if memory == "" {
+			continue
+		}
+		if runes := []rune(memory); len(runes) > maxAIMemoryRunes {
+			memory = string(runes[:maxAIMemoryRunes])
+		}
+		if slices.Contains(result, memory) {
+			continue
+		}
+		result = append(result, memory)
+		if len(result) == maxAIMemories {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeMemories(t *testing.T) {
+	memories := normalizeMemories([]string{" - 喜欢吃辣 ", "", "喜欢吃辣", "住在杭州", strings.Repeat("长", 150)})
+	if len(memories) != 3 || memories[0] != "喜欢吃辣" || memories[1] != "住在杭州" {
+		t.Fatalf("memories = %q", memories)
+	}
+	if len([]rune(memories[2])) != maxAIMemoryRunes {
+		t.Fatalf("memory not truncated: %d runes", len([]rune(memories[2])))
+	}
+
+	many := make([]string, 0, 30)
+	for i := 0; i < 30; i++ {
+		many = append(many, strings.Repeat("记", i+1))
+	}
+	if memories := normalizeMemories(many); len(memories) != maxAIMemories {
+		t.Fatalf("len = %d, want %d", len(memories), maxAIMemories)
+	}
+}
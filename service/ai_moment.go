@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/aiprovider"
 	"wechat-robot-client/utils"
 
 	"github.com/sashabaranov/go-openai"
@@ -25,12 +26,20 @@ func NewAIMomentService(ctx context.Context) *AIMomentService {
 	}
 }
 
-func (s *AIMomentService) GetMomentMood(content string, momentSettings model.MomentSettings) *MomentMood {
-	openaiConfig := openai.DefaultConfig(momentSettings.AIAPIKey)
-	openaiConfig.BaseURL = momentSettings.AIBaseURL
-	openaiConfig.BaseURL = utils.NormalizeAIBaseURL(openaiConfig.BaseURL)
+// newAIProvider 朋友圈使用单独配置的 OpenAI 兼容接口，用量记在发朋友圈的好友名下
+func (s *AIMomentService) newAIProvider(wechatID string, momentSettings model.MomentSettings) (aiprovider.Provider, error) {
+	provider, err := aiprovider.New(model.AIProviderOpenAI, utils.NormalizeAIBaseURL(momentSettings.AIBaseURL), momentSettings.AIAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewAIUsageService(s.ctx).Wrap(provider, model.AIUsage{ContactID: wechatID, Feature: model.AIUsageFeatureMoment}), nil
+}
 
-	client := openai.NewClientWithConfig(openaiConfig)
+func (s *AIMomentService) GetMomentMood(wechatID, content string, momentSettings model.MomentSettings) *MomentMood {
+	provider, err := s.newAIProvider(wechatID, momentSettings)
+	if err != nil {
+		return nil
+	}
 
 	aiMessages := []openai.ChatCompletionMessage{
 		{
@@ -67,7 +76,7 @@ func (s *AIMomentService) GetMomentMood(content string, momentSettings model.Mom
 		AdditionalProperties: false,
 	}
 
-	resp, err := client.CreateChatCompletion(
+	resp, err := provider.CreateChatCompletion(
 		context.Background(),
 		openai.ChatCompletionRequest{
 			Model:    momentSettings.WorkflowModel,
@@ -95,7 +104,7 @@ func (s *AIMomentService) GetMomentMood(content string, momentSettings model.Mom
 	return &result
 }
 
-func (s *AIMomentService) Comment(content string, momentSettings model.MomentSettings) (openai.ChatCompletionMessage, error) {
+func (s *AIMomentService) Comment(wechatID, content string, momentSettings model.MomentSettings) (openai.ChatCompletionMessage, error) {
 	systemPrompt := momentSettings.CommentPrompt
 	if momentSettings.MaxCompletionTokens != nil && *momentSettings.MaxCompletionTokens > 0 {
 		systemPrompt += fmt.Sprintf("\n\n请注意，每次回答不能超过%d个汉字。", *momentSettings.MaxCompletionTokens)
@@ -112,11 +121,10 @@ func (s *AIMomentService) Comment(content string, momentSettings model.MomentSet
 		},
 	}
 
-	openaiConfig := openai.DefaultConfig(momentSettings.AIAPIKey)
-	openaiConfig.BaseURL = momentSettings.AIBaseURL
-	openaiConfig.BaseURL = utils.NormalizeAIBaseURL(openaiConfig.BaseURL)
-
-	client := openai.NewClientWithConfig(openaiConfig)
+	provider, err := s.newAIProvider(wechatID, momentSettings)
+	if err != nil {
+		return openai.ChatCompletionMessage{}, err
+	}
 	req := openai.ChatCompletionRequest{
 		Model:    momentSettings.CommentModel,
 		Messages: aiMessages,
@@ -125,7 +133,7 @@ func (s *AIMomentService) Comment(content string, momentSettings model.MomentSet
 	if momentSettings.MaxCompletionTokens != nil && *momentSettings.MaxCompletionTokens > 0 {
 		req.MaxCompletionTokens = *momentSettings.MaxCompletionTokens
 	}
-	resp, err := client.CreateChatCompletion(context.Background(), req)
+	resp, err := provider.CreateChatCompletion(context.Background(), req)
 	if err != nil {
 		return openai.ChatCompletionMessage{}, err
 	}
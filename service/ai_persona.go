@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/interface/settings"
+	"wechat-robot-client/model"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/vars"
+)
+
+type AIPersonaService struct {
+	ctx      context.Context
+	apRespo  *repository.AIPersona
+	crsRespo *repository.ChatRoomSettings
+	fsRespo  *repository.FriendSettings
+}
+
+func NewAIPersonaService(ctx context.Context) *AIPersonaService {
+	return &AIPersonaService{
+		ctx:      ctx,
+		apRespo:  repository.NewAIPersonaRepo(ctx, vars.DB),
+		crsRespo: repository.NewChatRoomSettingsRepo(ctx, vars.DB),
+		fsRespo:  repository.NewFriendSettingsRepo(ctx, vars.DB),
+	}
+}
+
+// loadPersona 读取群聊、好友选择的AI角色，角色不存在或者读取失败时返回空
+func loadPersona(respo *repository.AIPersona, personaID *int64) *model.AIPersona {
+	if personaID == nil || *personaID == 0 {
+		return nil
+	}
+	persona, err := respo.GetByID(*personaID)
+	if err != nil {
+		log.Printf("获取AI角色失败: %v", err)
+		return nil
+	}
+	return persona
+}
+
+// applyPersona 用角色的配置覆盖聊天配置，角色中为空的配置项保持不变
+func applyPersona(aiConfig *settings.AIConfig, persona *model.AIPersona) {
+	if persona == nil {
+		return
+	}
+	if persona.Prompt != "" {
+		aiConfig.Prompt = persona.Prompt
+	}
+	if persona.Model != "" {
+		aiConfig.Model = persona.Model
+	}
+	if persona.Temperature != nil {
+		aiConfig.Temperature = persona.Temperature
+	}
+	if persona.MaxCompletionTokens > 0 {
+		aiConfig.MaxCompletionTokens = persona.MaxCompletionTokens
+	}
+	if persona.TTSVoice != "" {
+		aiConfig.TTSVoice = persona.TTSVoice
+	}
+}
+
+// applyPersonaPat 拍一拍时用角色的自我介绍和音色回复
+func applyPersonaPat(patConfig *settings.PatConfig, persona *model.AIPersona) {
+	if persona == nil {
+		return
+	}
+	if persona.Intro != "" {
+		patConfig.PatText = persona.Intro
+	}
+	if persona.TTSVoice != "" {
+		patConfig.PatVoiceTimbre = persona.TTSVoice
+	}
+}
+
+func (s *AIPersonaService) GetPersonas() ([]*model.AIPersona, error) {
+	return s.apRespo.GetList()
+}
+
+// SavePersona 新增或者更新角色
+func (s *AIPersonaService) SavePersona(req dto.AIPersonaRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("角色名称不能为空")
+	}
+	if utf8.RuneCountInString(req.Name) > 32 {
+		return errors.New("角色名称不能超过32个字符")
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return errors.New("角色提示词不能为空")
+	}
+	if utf8.RuneCountInString(req.Intro) > 500 {
+		return errors.New("角色介绍不能超过500个字符")
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		return errors.New("采样温度的取值范围是 0~2")
+	}
+	if req.MaxCompletionTokens < 0 {
+		return errors.New("最大回复字数不能小于0")
+	}
+	existing, err := s.apRespo.GetByName(req.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != req.ID {
+		return fmt.Errorf("角色 %s 已存在", req.Name)
+	}
+	data := &model.AIPersona{
+		ID:                  req.ID,
+		Name:                req.Name,
+		Intro:               req.Intro,
+		Avatar:              req.Avatar,
+		Prompt:              req.Prompt,
+		Model:               req.Model,
+		Temperature:         req.Temperature,
+		MaxCompletionTokens: req.MaxCompletionTokens,
+		TTSVoice:            req.TTSVoice,
+	}
+	if req.ID == 0 {
+		return s.apRespo.Create(data)
+	}
+	persona, err := s.apRespo.GetByID(req.ID)
+	if err != nil {
+		return err
+	}
+	if persona == nil {
+		return errors.New("角色不存在")
+	}
+	return s.apRespo.Update(data)
+}
+
+func (s *AIPersonaService) DeletePersona(id int64) error {
+	return s.apRespo.Delete(id)
+}
+
+// GetContactPersona 获取群聊、好友当前使用的角色，没有使用角色时返回空
+func (s *AIPersonaService) GetContactPersona(contactID string) (*model.AIPersona, error) {
+	var personaID *int64
+	if strings.HasSuffix(contactID, "@chatroom") {
+		chatRoomSettings, err := s.crsRespo.GetChatRoomSettings(contactID)
+		if err != nil {
+			return nil, err
+		}
+		if chatRoomSettings != nil {
+			personaID = chatRoomSettings.AIPersonaID
+		}
+	} else {
+		friendSettings, err := s.fsRespo.GetFriendSettings(contactID)
+		if err != nil {
+			return nil, err
+		}
+		if friendSettings != nil {
+			personaID = friendSettings.AIPersonaID
+		}
+	}
+	return loadPersona(s.apRespo, personaID), nil
+}
+
+// SwitchPersona 切换群聊、好友使用的角色，name 为空时恢复为不使用角色
+func (s *AIPersonaService) SwitchPersona(contactID, name string) (*model.AIPersona, error) {
+	var persona *model.AIPersona
+	var personaID *int64
+	if name != "" {
+		var err error
+		persona, err = s.apRespo.GetByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if persona == nil {
+			return nil, fmt.Errorf("角色「%s」不存在", name)
+		}
+		personaID = &persona.ID
+	}
+	if strings.HasSuffix(contactID, "@chatroom") {
+		chatRoomSettings, err := s.crsRespo.GetChatRoomSettings(contactID)
+		if err != nil {
+			return nil, err
+		}
+		if chatRoomSettings == nil {
+			return nil, errors.New("当前群聊还没有单独的配置，请先在管理后台保存群聊配置")
+		}
+		return persona, s.crsRespo.UpdateAIPersonaID(contactID, personaID)
+	}
+	friendSettings, err := s.fsRespo.GetFriendSettings(contactID)
+	if err != nil {
+		return nil, err
+	}
+	if friendSettings == nil {
+		return nil, errors.New("当前好友还没有单独的配置，请先在管理后台保存好友配置")
+	}
+	return persona, s.fsRespo.UpdateAIPersonaID(contactID, personaID)
+}
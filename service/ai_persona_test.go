@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+	"wechat-robot-client/interface/settings"
+	"wechat-robot-client/model"
+)
+
+func TestApplyPersona(t *testing.T) {
+	aiConfig := settings.AIConfig{Model: "gpt-4o", Prompt: "你是一个助手", MaxCompletionTokens: 200}
+	applyPersona(&aiConfig, nil)
+	if aiConfig.Model != "gpt-4o" || aiConfig.Prompt != "你是一个助手" {
+		t.Fatalf("aiConfig changed without persona: %+v", aiConfig)
+	}
+
+	temperature := float32(0.2)
+	applyPersona(&aiConfig, &model.AIPersona{Name: "翻译官", Prompt: "你是一名翻译", Temperature: &temperature, TTSVoice: "zh_male"})
+	if aiConfig.Prompt != "你是一名翻译" || aiConfig.TTSVoice != "zh_male" {
+		t.Fatalf("persona not applied: %+v", aiConfig)
+	}
+	if aiConfig.Temperature == nil || *aiConfig.Temperature != temperature {
+		t.Fatalf("temperature = %v, want %v", aiConfig.Temperature, temperature)
+	}
+	// 角色中为空的配置项使用聊天配置
+	if aiConfig.Model != "gpt-4o" || aiConfig.MaxCompletionTokens != 200 {
+		t.Fatalf("empty persona fields should keep chat config: %+v", aiConfig)
+	}
+
+	patConfig := settings.PatConfig{PatText: "别拍了", PatVoiceTimbre: "zh_female"}
+	applyPersonaPat(&patConfig, &model.AIPersona{Intro: "我是翻译官"})
+	if patConfig.PatText != "我是翻译官" || patConfig.PatVoiceTimbre != "zh_female" {
+		t.Fatalf("pat config = %+v", patConfig)
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"wechat-robot-client/interface/settings"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/aiprovider"
+	"wechat-robot-client/utils"
+
+	"gorm.io/datatypes"
+)
+
+// fallbackModel 返回备用AI服务对应功能的模型，为空时使用请求中的模型
+func fallbackModel(config model.AIProviderConfig, feature model.AIUsageFeature) string {
+	switch feature {
+	case model.AIUsageFeatureWorkflow:
+		return config.WorkflowModel
+	case model.AIUsageFeatureImageRecognition:
+		return config.ImageRecognitionModel
+	}
+	return config.ChatModel
+}
+
+// validateAIConfig 校验主AI服务的配置是否完整
+func validateAIConfig(aiConfig settings.AIConfig) error {
+	// Ollama 本地部署不需要密钥，原生接口不配置地址时使用官方地址
+	if aiConfig.APIKey == "" && aiConfig.Provider != model.AIProviderOllama {
+		return fmt.Errorf("AI API Key 未配置，请联系管理员")
+	}
+	if aiConfig.BaseURL == "" && (aiConfig.Provider == "" || aiConfig.Provider == model.AIProviderOpenAI) {
+		return fmt.Errorf("AI Base URL 未配置，请联系管理员")
+	}
+	return nil
+}
+
+// newAIProvider 根据AI配置创建AI服务，配置了备用AI服务时组成备用链，主AI服务使用请求中的模型
+// usage 为调用AI的聊天、发送者和功能，每次调用消耗的token都会记录下来
+func newAIProvider(ctx context.Context, aiConfig settings.AIConfig, usage model.AIUsage) (aiprovider.Provider, error) {
+	primary, err := aiprovider.New(aiConfig.Provider, aiConfig.BaseURL, aiConfig.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	usageService := NewAIUsageService(ctx)
+	if len(aiConfig.FallbackProviders) == 0 {
+		return usageService.Wrap(primary, usage), nil
+	}
+	targets := []aiprovider.Target{{Provider: primary}}
+	for _, config := range aiConfig.FallbackProviders {
+		provider, err := aiprovider.New(config.Type, config.BaseURL, config.APIKey)
+		if err != nil {
+			log.Printf("创建备用AI服务失败: %v", err)
+			continue
+		}
+		targets = append(targets, aiprovider.Target{Provider: provider, Model: fallbackModel(config, usage.Feature)})
+	}
+	return usageService.Wrap(aiprovider.NewFallback(targets...), usage), nil
+}
+
+// parseAIProviders 解析备用AI服务配置
+func parseAIProviders(data datatypes.JSON) []model.AIProviderConfig {
+	if len(data) == 0 {
+		return nil
+	}
+	var providers []model.AIProviderConfig
+	if err := json.Unmarshal(data, &providers); err != nil {
+		log.Printf("解析备用AI服务配置失败: %v", err)
+		return nil
+	}
+	for i := range providers {
+		// 和主AI服务一样补全 OpenAI 兼容接口的版本号
+		if providers[i].BaseURL != "" {
+			providers[i].BaseURL = utils.NormalizeAIBaseURL(providers[i].BaseURL)
+		}
+	}
+	return providers
+}
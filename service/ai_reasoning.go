@@ -0,0 +1,116 @@
+package service
+
+import (
+	"strings"
+	"wechat-robot-client/model"
+)
+
+const (
+	thinkStartTag = "<think>"
+	thinkEndTag   = "</think>"
+	// 思考过程摘要最多包含的字数
+	reasoningSummaryRunes = 150
+)
+
+// splitThinking 拆分回答中 <think></think> 包裹的思考过程，部分模型只输出结束标签，结束标签之前的内容都是思考过程
+func splitThinking(content string) (reasoning, answer string) {
+	var thoughts []string
+	for {
+		end := strings.Index(content, thinkEndTag)
+		if end < 0 {
+			break
+		}
+		start := strings.Index(content[:end], thinkStartTag)
+		if start < 0 {
+			thoughts = append(thoughts, content[:end])
+			content = content[end+len(thinkEndTag):]
+			continue
+		}
+		thoughts = append(thoughts, content[start+len(thinkStartTag):end])
+		content = content[:start] + content[end+len(thinkEndTag):]
+	}
+	// 没有结束标签说明回答在思考过程中被截断了
+	if start := strings.Index(content, thinkStartTag); start >= 0 {
+		thoughts = append(thoughts, content[start+len(thinkStartTag):])
+		content = content[:start]
+	}
+	return strings.TrimSpace(strings.Join(thoughts, "\n")), strings.TrimSpace(content)
+}
+
+// formatReasoning 去掉回答中的思考过程，summary 模式下在回答前面附上思考过程的摘要
+func formatReasoning(reasoning, content string, output model.AIReasoningOutput) string {
+	inline, answer := splitThinking(content)
+	reasoning = strings.TrimSpace(reasoning + "\n" + inline)
+	if output != model.AIReasoningOutputSummary || reasoning == "" {
+		return answer
+	}
+	return reasoningSummary(reasoning) + "\n\n" + answer
+}
+
+// reasoningSummary 截取思考过程的开头作为摘要，合并多余的空白
+func reasoningSummary(reasoning string) string {
+	runes := []rune(strings.Join(strings.Fields(reasoning), " "))
+	summary := string(runes)
+	if len(runes) > reasoningSummaryRunes {
+		summary = string(runes[:reasoningSummaryRunes]) + "…"
+	}
+	return "【思考过程】" + summary
+}
+
+// thinkFilter 流式返回时过滤 <think></think> 包裹的思考过程，标签可能被拆分在相邻的两次返回中
+type thinkFilter struct {
+	inThink bool
+	pending string
+}
+
+// Write 追加内容，返回可以发送的回答和思考过程
+func (f *thinkFilter) Write(delta string) (answer, reasoning string) {
+	text := f.pending + delta
+	f.pending = ""
+	var answerBuilder, reasoningBuilder strings.Builder
+	write := func(s string) {
+		if f.inThink {
+			reasoningBuilder.WriteString(s)
+		} else {
+			answerBuilder.WriteString(s)
+		}
+	}
+	for text != "" {
+		tag := thinkStartTag
+		if f.inThink {
+			tag = thinkEndTag
+		}
+		if i := strings.Index(text, tag); i >= 0 {
+			write(text[:i])
+			text = text[i+len(tag):]
+			f.inThink = !f.inThink
+			continue
+		}
+		// 末尾可能是不完整的标签，留到下一次返回时再判断
+		keep := partialTagSuffix(text, tag)
+		write(text[:len(text)-keep])
+		f.pending = text[len(text)-keep:]
+		break
+	}
+	return answerBuilder.String(), reasoningBuilder.String()
+}
+
+// Flush 返回剩余的内容
+func (f *thinkFilter) Flush() (answer, reasoning string) {
+	text := f.pending
+	f.pending = ""
+	if f.inThink {
+		return "", text
+	}
+	return text, ""
+}
+
+// partialTagSuffix 返回 text 末尾和 tag 开头相同的最大长度
+func partialTagSuffix(text, tag string) int {
+	for n := min(len(text), len(tag)-1); n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"wechat-robot-client/model"
+)
+
+func TestSplitThinking(t *testing.T) {
+	cases := []struct {
+		content, reasoning, answer string
+	}{
+		{"<think>\n先算一下\n</think>\n\n答案是 2", "先算一下", "答案是 2"},
+		{"想一想</think>答案是 2", "想一想", "答案是 2"},
+		{"<think>想到一半", "想到一半", ""},
+		{"没有思考过程", "", "没有思考过程"},
+	}
+	for _, c := range cases {
+		reasoning, answer := splitThinking(c.content)
+		if reasoning != c.reasoning || answer != c.answer {
+			t.Fatalf("splitThinking(%q) = %q, %q, want %q, %q", c.content, reasoning, answer, c.reasoning, c.answer)
+		}
+	}
+
+	if got := formatReasoning("", "<think>想一想</think>答案", model.AIReasoningOutputStrip); got != "答案" {
+		t.Fatalf("strip = %q", got)
+	}
+	if got := formatReasoning("先  算\n一下", "答案", model.AIReasoningOutputSummary); got != "【思考过程】先 算 一下\n\n答案" {
+		t.Fatalf("summary = %q", got)
+	}
+}
+
+func TestThinkFilter(t *testing.T) {
+	text := "<think>先算一下</think>\n答案是 2 <b>"
+	// 每次写入一个字节，标签一定会被拆开
+	filter := &thinkFilter{}
+	var answer, reasoning strings.Builder
+	for i := 0; i < len(text); i++ {
+		a, r := filter.Write(text[i : i+1])
+		answer.WriteString(a)
+		reasoning.WriteString(r)
+	}
+	a, r := filter.Flush()
+	answer.WriteString(a)
+	reasoning.WriteString(r)
+	if reasoning.String() != "先算一下" || answer.String() != "\n答案是 2 <b>" {
+		t.Fatalf("answer = %q, reasoning = %q", answer.String(), reasoning.String())
+	}
+}
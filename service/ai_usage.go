@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/aiprovider"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/vars"
+
+	"github.com/sashabaranov/go-openai"
+	"gorm.io/datatypes"
+)
+
+const (
+	aiQuotaNotifiedKeyPrefix = "ai_quota_notified"
+	// 已经提示过额度用完的发送者，每个周期只提示一次
+	aiQuotaRepliedKeyPrefix = "ai_quota_replied"
+)
+
+type AIQuotaPeriod string
+
+const (
+	AIQuotaPeriodDaily   AIQuotaPeriod = "daily"
+	AIQuotaPeriodMonthly AIQuotaPeriod = "monthly"
+)
+
+type AIUsageService struct {
+	ctx        context.Context
+	usageRespo *repository.AIUsage
+	gsRespo    *repository.GlobalSettings
+}
+
+func NewAIUsageService(ctx context.Context) *AIUsageService {
+	return &AIUsageService{
+		ctx:        ctx,
+		usageRespo: repository.NewAIUsageRepo(ctx, vars.DB),
+		gsRespo:    repository.NewGlobalSettingsRepo(ctx, vars.DB),
+	}
+}
+
+// newAIUsage 根据消息生成用量记录的聊天和发送者
+func newAIUsage(message *model.Message, feature model.AIUsageFeature) model.AIUsage {
+	usage := model.AIUsage{Feature: feature}
+	if message != nil {
+		usage.ContactID = message.FromWxID
+		usage.SenderWxID = message.SenderWxID
+	}
+	return usage
+}
+
+// mergeAITokenQuota 配置了额度时覆盖之前的额度
+func mergeAITokenQuota(quota model.AITokenQuota, daily, monthly *int64) model.AITokenQuota {
+	if daily != nil {
+		quota.Daily = *daily
+	}
+	if monthly != nil {
+		quota.Monthly = *monthly
+	}
+	return quota
+}
+
+// aiUsageCost 按模型价格计算费用，没有配置价格时为 0
+func aiUsageCost(prices []model.AIModelPrice, usage *model.AIUsage) float64 {
+	for _, price := range prices {
+		if strings.EqualFold(price.Model, usage.Model) {
+			return (float64(usage.PromptTokens)*price.PromptPrice + float64(usage.CompletionTokens)*price.CompletionPrice) / 1e6
+		}
+	}
+	return 0
+}
+
+func parseAIModelPrices(data datatypes.JSON) []model.AIModelPrice {
+	if len(data) == 0 {
+		return nil
+	}
+	var prices []model.AIModelPrice
+	if err := json.Unmarshal(data, &prices); err != nil {
+		log.Printf("解析模型价格失败: %v", err)
+		return nil
+	}
+	return prices
+}
+
+// Record 保存一次AI调用消耗的token，保存失败不影响AI回复
+func (s *AIUsageService) Record(usage *model.AIUsage) {
+	globalSettings, err := s.gsRespo.GetGlobalSettings()
+	if err != nil {
+		log.Printf("获取全局设置失败: %v", err)
+	}
+	if globalSettings != nil {
+		usage.Cost = aiUsageCost(parseAIModelPrices(globalSettings.AIModelPrices), usage)
+	}
+	usage.CreatedAt = time.Now().Unix()
+	if err := s.usageRespo.Create(usage); err != nil {
+		log.Printf("保存AI用量失败: %v", err)
+	}
+}
+
+// Wrap 返回记录用量的AI服务，usage 为每次调用共用的聊天、发送者和功能
+func (s *AIUsageService) Wrap(provider aiprovider.Provider, usage model.AIUsage) aiprovider.Provider {
+	return &usageProvider{Provider: provider, service: s, usage: usage}
+}
+
+// quotaPeriodStart 额度周期的开始时间
+func quotaPeriodStart(now time.Time, period AIQuotaPeriod) time.Time {
+	if period == AIQuotaPeriodMonthly {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// quotaPeriodEnd 额度周期的结束时间
+func quotaPeriodEnd(start time.Time, period AIQuotaPeriod) time.Time {
+	if period == AIQuotaPeriodMonthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// CheckQuota 检查群聊/好友的额度，超过额度时返回超过的周期，没有超过时返回空字符串
+func (s *AIUsageService) CheckQuota(contactID string, quota model.AITokenQuota) (AIQuotaPeriod, error) {
+	now := time.Now()
+	for _, period := range []AIQuotaPeriod{AIQuotaPeriodDaily, AIQuotaPeriodMonthly} {
+		limit := quota.Daily
+		if period == AIQuotaPeriodMonthly {
+			limit = quota.Monthly
+		}
+		if limit <= 0 {
+			continue
+		}
+		used, err := s.usageRespo.SumTokens(contactID, quotaPeriodStart(now, period).Unix())
+		if err != nil {
+			return "", err
+		}
+		if used >= limit {
+			return period, nil
+		}
+	}
+	return "", nil
+}
+
+// NotifyQuotaExceeded 通知超级管理员群聊/好友的额度已用完，没有配置超级管理员时发送到文件传输助手，每个周期只通知一次
+func (s *AIUsageService) NotifyQuotaExceeded(contactID string, period AIQuotaPeriod, quota model.AITokenQuota) {
+	now := time.Now()
+	start := quotaPeriodStart(now, period)
+	key := fmt.Sprintf("%s:%s:%s:%d", aiQuotaNotifiedKeyPrefix, contactID, period, start.Unix())
+	first, err := vars.RedisClient.SetNX(s.ctx, key, 1, quotaPeriodEnd(start, period).Sub(now)).Result()
+	if err != nil {
+		log.Printf("记录AI额度通知失败: %v", err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	name := contactID
+	contact, err := repository.NewContactRepo(s.ctx, vars.DB).GetByWechatID(contactID)
+	if err == nil && contact != nil {
+		if contact.Remark != "" {
+			name = contact.Remark
+		} else if contact.Nickname != nil && *contact.Nickname != "" {
+			name = *contact.Nickname
+		}
+	}
+	content := fmt.Sprintf("%s 今天的AI额度(%d tokens)已经用完，AI功能已暂停，明天自动恢复。", name, quota.Daily)
+	if period == AIQuotaPeriodMonthly {
+		content = fmt.Sprintf("%s 本月的AI额度(%d tokens)已经用完，AI功能已暂停，下个月自动恢复。", name, quota.Monthly)
+	}
+
+	receivers := []string{"filehelper"}
+	systemSettings, err := repository.NewSystemSettingsRepo(s.ctx, vars.DB).GetSystemSettings()
+	if err != nil {
+		log.Printf("获取系统设置失败: %v", err)
+	}
+	if systemSettings != nil && len(systemSettings.SuperAdmins) > 0 {
+		var superAdmins []string
+		if err := json.Unmarshal(systemSettings.SuperAdmins, &superAdmins); err != nil {
+			log.Printf("解析超级管理员失败: %v", err)
+		}
+		superAdmins = slices.DeleteFunc(superAdmins, func(wxID string) bool { return wxID == "" })
+		if len(superAdmins) > 0 {
+			receivers = superAdmins
+		}
+	}
+	msgService := NewMessageService(s.ctx)
+	for _, receiver := range receivers {
+		if err := msgService.SendTextMessage(receiver, content); err != nil {
+			log.Printf("发送AI额度通知失败: %v", err)
+		}
+	}
+}
+
+// ShouldReplyQuotaExceeded 判断是否需要提示发送者额度已用完，同一个发送者每个周期只提示一次，之后的消息直接忽略
+func (s *AIUsageService) ShouldReplyQuotaExceeded(message *model.Message, period AIQuotaPeriod) bool {
+	now := time.Now()
+	start := quotaPeriodStart(now, period)
+	key := fmt.Sprintf("%s:%s:%s:%d:%s", aiQuotaRepliedKeyPrefix, message.FromWxID, period, start.Unix(), message.SenderWxID)
+	first, err := vars.RedisClient.SetNX(s.ctx, key, 1, quotaPeriodEnd(start, period).Sub(now)).Result()
+	if err != nil {
+		log.Printf("记录AI额度提示失败: %v", err)
+		return true
+	}
+	return first
+}
+
+// GetQuotaStatus 获取群聊/好友的额度和已使用的token数量
+func (s *AIUsageService) GetQuotaStatus(contactID string) (*dto.AIQuotaStatus, error) {
+	globalSettings, err := s.gsRespo.GetGlobalSettings()
+	if err != nil {
+		return nil, err
+	}
+	var quota model.AITokenQuota
+	if globalSettings != nil {
+		quota = mergeAITokenQuota(quota, globalSettings.AIDailyTokenQuota, globalSettings.AIMonthlyTokenQuota)
+	}
+	if strings.HasSuffix(contactID, "@chatroom") {
+		chatRoomSettings, err := repository.NewChatRoomSettingsRepo(s.ctx, vars.DB).GetChatRoomSettings(contactID)
+		if err != nil {
+			return nil, err
+		}
+		if chatRoomSettings != nil {
+			quota = mergeAITokenQuota(quota, chatRoomSettings.AIDailyTokenQuota, chatRoomSettings.AIMonthlyTokenQuota)
+		}
+	} else {
+		friendSettings, err := repository.NewFriendSettingsRepo(s.ctx, vars.DB).GetFriendSettings(contactID)
+		if err != nil {
+			return nil, err
+		}
+		if friendSettings != nil {
+			quota = mergeAITokenQuota(quota, friendSettings.AIDailyTokenQuota, friendSettings.AIMonthlyTokenQuota)
+		}
+	}
+	now := time.Now()
+	dailyUsed, err := s.usageRespo.SumTokens(contactID, quotaPeriodStart(now, AIQuotaPeriodDaily).Unix())
+	if err != nil {
+		return nil, err
+	}
+	monthlyUsed, err := s.usageRespo.SumTokens(contactID, quotaPeriodStart(now, AIQuotaPeriodMonthly).Unix())
+	if err != nil {
+		return nil, err
+	}
+	return &dto.AIQuotaStatus{
+		ContactID:    contactID,
+		DailyLimit:   quota.Daily,
+		DailyUsed:    dailyUsed,
+		MonthlyLimit: quota.Monthly,
+		MonthlyUsed:  monthlyUsed,
+	}, nil
+}
+
+func (s *AIUsageService) GetStats(req dto.AIUsageStatsRequest) ([]*dto.AIUsageStat, error) {
+	return s.usageRespo.GetStats(req)
+}
+
+// usageProvider 每次调用AI后记录消耗的token
+type usageProvider struct {
+	aiprovider.Provider
+	service *AIUsageService
+	usage   model.AIUsage
+}
+
+func (p *usageProvider) record(modelName string, usage openai.Usage) {
+	record := p.usage
+	record.Model = modelName
+	record.PromptTokens = usage.PromptTokens
+	record.CompletionTokens = usage.CompletionTokens
+	record.TotalTokens = usage.TotalTokens
+	if record.TotalTokens == 0 {
+		record.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	p.service.Record(&record)
+}
+
+func (p *usageProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	resp, err := p.Provider.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	modelName := req.Model
+	if resp.Model != "" {
+		modelName = resp.Model
+	}
+	p.record(modelName, resp.Usage)
+	return resp, nil
+}
+
+func (p *usageProvider) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (aiprovider.Stream, error) {
+	// OpenAI 兼容接口默认不在流式返回中带用量
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := p.Provider.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &usageStream{Stream: stream, provider: p, model: req.Model}, nil
+}
+
+// usageStream 流式返回结束后记录最后一次返回的用量
+type usageStream struct {
+	aiprovider.Stream
+	provider *usageProvider
+	model    string
+	usage    *openai.Usage
+}
+
+func (s *usageStream) Recv() (openai.ChatCompletionStreamResponse, error) {
+	resp, err := s.Stream.Recv()
+	if err == nil && resp.Usage != nil {
+		s.usage = resp.Usage
+		if resp.Model != "" {
+			s.model = resp.Model
+		}
+	}
+	return resp, err
+}
+
+func (s *usageStream) Close() error {
+	if s.usage != nil {
+		s.provider.record(s.model, *s.usage)
+		s.usage = nil
+	}
+	return s.Stream.Close()
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"wechat-robot-client/model"
+)
+
+func TestMergeAITokenQuota(t *testing.T) {
+	daily, monthly, unlimited := int64(1000), int64(20000), int64(0)
+	quota := mergeAITokenQuota(model.AITokenQuota{}, &daily, &monthly)
+	// 群聊只配置了每天的额度，每月的额度继续使用全局配置
+	quota = mergeAITokenQuota(quota, &unlimited, nil)
+	if quota.Daily != 0 || quota.Monthly != 20000 {
+		t.Fatalf("quota = %+v", quota)
+	}
+}
+
+func TestAIUsageCost(t *testing.T) {
+	prices := []model.AIModelPrice{{Model: "gpt-4o", PromptPrice: 2.5, CompletionPrice: 10}}
+	usage := &model.AIUsage{Model: "GPT-4o", PromptTokens: 1000000, CompletionTokens: 500000}
+	if cost := aiUsageCost(prices, usage); cost != 7.5 {
+		t.Fatalf("cost = %v, want 7.5", cost)
+	}
+	usage.Model = "deepseek-chat"
+	if cost := aiUsageCost(prices, usage); cost != 0 {
+		t.Fatalf("cost = %v, want 0 without price", cost)
+	}
+}
+
+func TestQuotaPeriodStart(t *testing.T) {
+	now := time.Date(2025, 10, 17, 15, 30, 0, 0, time.Local)
+	if start := quotaPeriodStart(now, AIQuotaPeriodDaily); !start.Equal(time.Date(2025, 10, 17, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("daily start = %v", start)
+	}
+	if start := quotaPeriodStart(now, AIQuotaPeriodMonthly); !start.Equal(time.Date(2025, 10, 1, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("monthly start = %v", start)
+	}
+	if end := quotaPeriodEnd(quotaPeriodStart(now, AIQuotaPeriodMonthly), AIQuotaPeriodMonthly); !end.Equal(time.Date(2025, 11, 1, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("monthly end = %v", end)
+	}
+}
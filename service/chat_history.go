@@ -2,6 +2,14 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 	"wechat-robot-client/dto"
 	"wechat-robot-client/model"
 	"wechat-robot-client/pkg/appx"
@@ -9,18 +17,202 @@ import (
 	"wechat-robot-client/vars"
 )
 
+const (
+	// 少于这个字数的消息(例如“好的”、“收到”)不生成向量
+	semanticSearchMinRunes = 4
+	// 每条消息最多提交给向量接口的字数
+	semanticSearchMaxRunes = 1000
+	// 每次定时任务最多生成向量的消息数量
+	semanticSearchEmbedLimit = 512
+	// 没有指定开始时间时搜索最近一年的聊天记录
+	semanticSearchDefaultDays  = 365
+	defaultSemanticSearchLimit = 5
+	maxSemanticSearchLimit     = 20
+	// 已经扫描过的最大消息ID和当时开启语义搜索的聊天，之后只扫描更新的消息，开启语义搜索的聊天变化时重新扫描
+	semanticSearchScannedKey = "semantic_search_scanned"
+)
+
 type ChatHistoryService struct {
 	ctx      context.Context
 	msgRespo *repository.Message
+	embRespo *repository.MessageEmbedding
+	gsRespo  *repository.GlobalSettings
+	crsRespo *repository.ChatRoomSettings
+	fsRespo  *repository.FriendSettings
+}
+
+// scoredMessage 消息和问题的相似度
+type scoredMessage struct {
+	messageID int64
+	score     float64
+}
+
+func (m scoredMessage) similarity() float64 {
+	return m.score
 }
 
 func NewChatHistoryService(ctx context.Context) *ChatHistoryService {
 	return &ChatHistoryService{
 		ctx:      ctx,
 		msgRespo: repository.NewMessageRepo(ctx, vars.DB),
+		embRespo: repository.NewMessageEmbeddingRepo(ctx, vars.DB),
+		gsRespo:  repository.NewGlobalSettingsRepo(ctx, vars.DB),
+		crsRespo: repository.NewChatRoomSettingsRepo(ctx, vars.DB),
+		fsRespo:  repository.NewFriendSettingsRepo(ctx, vars.DB),
 	}
 }
 
 func (s *ChatHistoryService) GetChatHistory(req dto.ChatHistoryRequest, pager appx.Pager) ([]*model.Message, int64, error) {
 	return s.msgRespo.GetByContactID(req, pager)
 }
+
+// EmbedPendingMessages 为开启了语义搜索的聊天中还没有向量的文本消息生成向量，返回处理的消息数量
+func (s *ChatHistoryService) EmbedPendingMessages() (int, error) {
+	globalSettings, err := s.gsRespo.GetGlobalSettings()
+	if err != nil {
+		return 0, err
+	}
+	globalEnabled := globalSettings != nil && globalSettings.SemanticSearchEnabled != nil && *globalSettings.SemanticSearchEnabled
+	// 全局开启时排除单独关闭的聊天，全局关闭时只处理单独开启的聊天
+	chatRoomIDs, err := s.crsRespo.GetSemanticSearchChatRoomIDs(!globalEnabled)
+	if err != nil {
+		return 0, err
+	}
+	friendIDs, err := s.fsRespo.GetSemanticSearchFriendIDs(!globalEnabled)
+	if err != nil {
+		return 0, err
+	}
+	contactIDs := append(chatRoomIDs, friendIDs...)
+	scope := semanticSearchScope(globalEnabled, contactIDs)
+	latestID, err := s.embRespo.GetLatestMessageID()
+	if err != nil {
+		return 0, err
+	}
+	scannedID := s.scannedMessageID(scope)
+	messages, err := s.embRespo.GetPendingMessages(vars.RobotRuntime.WxID, contactIDs, globalEnabled, scannedID, latestID, semanticSearchMinRunes, semanticSearchEmbedLimit)
+	if err != nil {
+		return 0, err
+	}
+	if len(messages) == 0 {
+		s.setScannedMessageID(scope, latestID)
+		return 0, nil
+	}
+	config, err := embeddingSettings(s.gsRespo)
+	if err != nil {
+		return 0, err
+	}
+	for start := 0; start < len(messages); start += knowledgeEmbeddingBatchSize {
+		batch := messages[start:min(start+knowledgeEmbeddingBatchSize, len(messages))]
+		inputs := make([]string, 0, len(batch))
+		for _, message := range batch {
+			content := []rune(message.Content)
+			inputs = append(inputs, string(content[:min(len(content), semanticSearchMaxRunes)]))
+		}
+		embeddings, err := createEmbeddings(s.ctx, config, model.AIUsage{Feature: model.AIUsageFeatureSemanticSearch}, inputs)
+		if err != nil {
+			return start, err
+		}
+		records := make([]*model.MessageEmbedding, 0, len(batch))
+		for i, message := range batch {
+			records = append(records, &model.MessageEmbedding{
+				MessageID: message.ID,
+				ContactID: message.FromWxID,
+				Embedding: encodeEmbedding(embeddings[i]),
+				CreatedAt: message.CreatedAt,
+			})
+		}
+		if err := s.embRespo.Create(records); err != nil {
+			return start, err
+		}
+	}
+	// 没有达到数量上限说明 latestID 之前的消息都已经处理完了
+	if len(messages) < semanticSearchEmbedLimit {
+		s.setScannedMessageID(scope, latestID)
+	}
+	return len(messages), nil
+}
+
+// semanticSearchScope 开启语义搜索的聊天范围，和聊天的顺序无关
+func semanticSearchScope(globalEnabled bool, contactIDs []string) string {
+	ids := slices.Clone(contactIDs)
+	slices.Sort(ids)
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%t:%s", globalEnabled, strings.Join(ids, ","))
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// scannedMessageID 获取已经扫描过的最大消息ID，开启语义搜索的聊天变化或者读取失败时返回 0，从头扫描
+func (s *ChatHistoryService) scannedMessageID(scope string) int64 {
+	values, err := vars.RedisClient.HMGet(s.ctx, semanticSearchScannedKey, "scope", "message_id").Result()
+	if err != nil || values[0] != scope {
+		return 0
+	}
+	id, _ := values[1].(string)
+	messageID, _ := strconv.ParseInt(id, 10, 64)
+	return messageID
+}
+
+func (s *ChatHistoryService) setScannedMessageID(scope string, messageID int64) {
+	if err := vars.RedisClient.HSet(s.ctx, semanticSearchScannedKey, "scope", scope, "message_id", messageID).Err(); err != nil {
+		log.Printf("保存聊天记录向量扫描进度失败: %v", err)
+	}
+}
+
+// SemanticSearch 按语义搜索聊天记录，返回和问题最相似的消息，message 不为空时按消息所在的聊天记录用量
+func (s *ChatHistoryService) SemanticSearch(message *model.Message, req dto.ChatHistorySemanticSearchRequest) ([]*dto.ChatHistorySearchResult, error) {
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, errors.New("搜索内容不能为空")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSemanticSearchLimit
+	}
+	limit = min(limit, maxSemanticSearchLimit)
+	since := req.StartTime
+	if since <= 0 {
+		since = time.Now().AddDate(0, 0, -semanticSearchDefaultDays).Unix()
+	}
+	config, err := embeddingSettings(s.gsRespo)
+	if err != nil {
+		return nil, err
+	}
+	usage := newAIUsage(message, model.AIUsageFeatureSemanticSearch)
+	if message == nil {
+		usage.ContactID = req.ContactID
+	}
+	embeddings, err := createEmbeddings(s.ctx, config, usage, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	var top []scoredMessage
+	err = s.embRespo.FindByContactID(req.ContactID, since, func(batch []*model.MessageEmbedding) {
+		for _, embedding := range batch {
+			score := cosineSimilarity(embeddings[0], decodeEmbedding(embedding.Embedding))
+			top = keepTopScored(top, scoredMessage{messageID: embedding.MessageID, score: score}, limit)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(top))
+	for _, item := range top {
+		ids = append(ids, item.messageID)
+	}
+	messages, err := s.msgRespo.GetByIDsWithSender(req.ContactID, ids)
+	if err != nil {
+		return nil, err
+	}
+	messageMap := make(map[int64]*model.Message, len(messages))
+	for _, message := range messages {
+		messageMap[message.ID] = message
+	}
+	results := make([]*dto.ChatHistorySearchResult, 0, len(top))
+	for _, item := range top {
+		// 消息可能已经被删除
+		if message, ok := messageMap[item.messageID]; ok {
+			results = append(results, &dto.ChatHistorySearchResult{Message: message, Score: item.score})
+		}
+	}
+	return results, nil
+}
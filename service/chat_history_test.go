@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKeepTopScored(t *testing.T) {
+	var top []scoredMessage
+	for i, score := range []float64{0.2, 0.9, 0.5, 0.1, 0.7, 0.9} {
+		top = keepTopScored(top, scoredMessage{messageID: int64(i + 1), score: score}, 3)
+	}
+	want := []int64{2, 6, 5}
+	if len(top) != len(want) {
+		t.Fatalf("top = %v, want message ids %v", top, want)
+	}
+	for i, item := range top {
+		if item.messageID != want[i] {
+			t.Fatalf("top = %v, want message ids %v", top, want)
+		}
+	}
+}
+
+func TestScannedMessageID(t *testing.T) {
+	setupTestRedis(t)
+	s := &ChatHistoryService{ctx: context.Background()}
+	scope := semanticSearchScope(false, []string{"b@chatroom", "a@chatroom"})
+	if scope != semanticSearchScope(false, []string{"a@chatroom", "b@chatroom"}) {
+		t.Fatal("scope should not depend on the order of contacts")
+	}
+	if id := s.scannedMessageID(scope); id != 0 {
+		t.Fatalf("nothing scanned yet: id = %d, want 0", id)
+	}
+	s.setScannedMessageID(scope, 42)
+	if id := s.scannedMessageID(scope); id != 42 {
+		t.Fatalf("id = %d, want 42", id)
+	}
+	// 开启语义搜索的聊天变化后需要重新扫描
+	if id := s.scannedMessageID(semanticSearchScope(true, nil)); id != 0 {
+		t.Fatalf("scope changed: id = %d, want 0", id)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 	"wechat-robot-client/dto"
+	"wechat-robot-client/interface/settings"
 	"wechat-robot-client/model"
 	"wechat-robot-client/pkg/appx"
 	"wechat-robot-client/pkg/robot"
@@ -467,7 +468,13 @@ func (s *ChatRoomService) GetChatRoomSummary(chatRoomID string) (dto.ChatRoomSum
 }
 
 func (s *ChatRoomService) ChatRoomAISummaryByChatRoomID(globalSettings *model.GlobalSettings, setting *model.ChatRoomSettings, startTime, endTime int64) error {
-	msgService := NewMessageService(context.Background())
+	quota := mergeAITokenQuota(model.AITokenQuota{}, globalSettings.AIDailyTokenQuota, globalSettings.AIMonthlyTokenQuota)
+	quota = mergeAITokenQuota(quota, setting.AIDailyTokenQuota, setting.AIMonthlyTokenQuota)
+	if period, err := NewAIUsageService(s.ctx).CheckQuota(setting.ChatRoomID, quota); err == nil && period != "" {
+		log.Printf("群聊 %s 的AI额度已用完，跳过群聊总结", setting.ChatRoomID)
+		return nil
+	}
+	msgService := NewMessageService(s.ctx)
 	chatRoomName := setting.ChatRoomID
 	chatRoom, err := s.ctRespo.GetByWechatID(setting.ChatRoomID)
 	if err != nil {
@@ -533,17 +540,31 @@ func (s *ChatRoomService) ChatRoomAISummaryByChatRoomID(globalSettings *model.Gl
 	if *setting.ChatAPIKey != "" {
 		aiApiKey = *setting.ChatAPIKey
 	}
-	aiConfig := openai.DefaultConfig(aiApiKey)
 	aiApiBaseURL := strings.TrimRight(globalSettings.ChatBaseURL, "/")
 	if setting.ChatBaseURL != nil && *setting.ChatBaseURL != "" {
 		aiApiBaseURL = strings.TrimRight(*setting.ChatBaseURL, "/")
 	}
-	aiConfig.BaseURL = utils.NormalizeAIBaseURL(aiApiBaseURL)
+	aiConfig := settings.AIConfig{
+		Provider:          globalSettings.ChatProvider,
+		BaseURL:           utils.NormalizeAIBaseURL(aiApiBaseURL),
+		APIKey:            aiApiKey,
+		FallbackProviders: parseAIProviders(globalSettings.AIFallbackProviders),
+	}
+	if setting.ChatProvider != nil && *setting.ChatProvider != "" {
+		aiConfig.Provider = *setting.ChatProvider
+	}
+	if providers := parseAIProviders(setting.AIFallbackProviders); len(providers) > 0 {
+		aiConfig.FallbackProviders = providers
+	}
+	ai, err := newAIProvider(s.ctx, aiConfig, model.AIUsage{ContactID: setting.ChatRoomID, Feature: model.AIUsageFeatureSummary})
+	if err != nil {
+		log.Printf("群聊记录总结失败: %v", err.Error())
+		return err
+	}
 	model := globalSettings.ChatRoomSummaryModel
 	if setting.ChatRoomSummaryModel != nil && *setting.ChatRoomSummaryModel != "" {
 		model = *setting.ChatRoomSummaryModel
 	}
-	ai := openai.NewClientWithConfig(aiConfig)
 	var resp openai.ChatCompletionResponse
 	resp, err = ai.CreateChatCompletion(
 		context.Background(),
@@ -620,7 +641,7 @@ func (s *ChatRoomService) ChatRoomRankingDaily() error {
 		return err
 	}
 
-	msgService := NewMessageService(context.Background())
+	msgService := NewMessageService(s.ctx)
 
 	for _, setting := range settings {
 		notifyMsgs := []string{"#昨日水群排行榜"}
@@ -715,7 +736,7 @@ func (s *ChatRoomService) ChatRoomRankingWeekly() error {
 		return err
 	}
 
-	msgService := NewMessageService(context.Background())
+	msgService := NewMessageService(s.ctx)
 
 	for _, setting := range settings {
 		notifyMsgs := []string{"#上周水群排行榜"}
@@ -797,7 +818,7 @@ func (s *ChatRoomService) ChatRoomRankingMonthly() error {
 		return err
 	}
 
-	msgService := NewMessageService(context.Background())
+	msgService := NewMessageService(s.ctx)
 
 	for _, setting := range settings {
 		notifyMsgs := []string{fmt.Sprintf("#%s水群排行榜", monthStr)}
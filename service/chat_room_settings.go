@@ -12,6 +12,8 @@ import (
 	"wechat-robot-client/repository"
 	"wechat-robot-client/utils"
 	"wechat-robot-client/vars"
+
+	"gorm.io/datatypes"
 )
 
 type ChatRoomSettingsService struct {
@@ -19,8 +21,12 @@ type ChatRoomSettingsService struct {
 	Message          *model.Message
 	gsRespo          *repository.GlobalSettings
 	crsRespo         *repository.ChatRoomSettings
+	psRespo          *repository.PluginSettings
+	apRespo          *repository.AIPersona
 	globalSettings   *model.GlobalSettings
 	chatRoomSettings *model.ChatRoomSettings
+	pluginSettings   []*model.PluginSettings
+	persona          *model.AIPersona
 }
 
 var _ settings.Settings = (*ChatRoomSettingsService)(nil)
@@ -30,6 +36,8 @@ func NewChatRoomSettingsService(ctx context.Context) *ChatRoomSettingsService {
 		ctx:      ctx,
 		gsRespo:  repository.NewGlobalSettingsRepo(ctx, vars.DB),
 		crsRespo: repository.NewChatRoomSettingsRepo(ctx, vars.DB),
+		psRespo:  repository.NewPluginSettingsRepo(ctx, vars.DB),
+		apRespo:  repository.NewAIPersonaRepo(ctx, vars.DB),
 	}
 }
 
@@ -59,9 +67,23 @@ func (s *ChatRoomSettingsService) InitByMessage(message *model.Message) error {
 	}
 
 	s.chatRoomSettings = chatRoomSettings
+	if chatRoomSettings != nil {
+		s.persona = loadPersona(s.apRespo, chatRoomSettings.AIPersonaID)
+	}
+
+	pluginSettings, err := s.psRespo.GetByContactID(message.FromWxID)
+	if err != nil {
+		log.Printf("获取插件设置失败: %v", err)
+		return err
+	}
+	s.pluginSettings = pluginSettings
 	return nil
 }
 
+func (s *ChatRoomSettingsService) GetMessage() *model.Message {
+	return s.Message
+}
+
 func (s *ChatRoomSettingsService) GetAIConfig() settings.AIConfig {
 	aiConfig := settings.AIConfig{}
 	if s.globalSettings != nil {
@@ -71,6 +93,10 @@ func (s *ChatRoomSettingsService) GetAIConfig() settings.AIConfig {
 		if s.globalSettings.ChatAPIKey != "" {
 			aiConfig.APIKey = s.globalSettings.ChatAPIKey
 		}
+		if s.globalSettings.ChatProvider != "" {
+			aiConfig.Provider = s.globalSettings.ChatProvider
+		}
+		aiConfig.FallbackProviders = parseAIProviders(s.globalSettings.AIFallbackProviders)
 		if s.globalSettings.ChatModel != "" {
 			aiConfig.Model = s.globalSettings.ChatModel
 		}
@@ -86,6 +112,18 @@ func (s *ChatRoomSettingsService) GetAIConfig() settings.AIConfig {
 		if s.globalSettings.MaxCompletionTokens != nil {
 			aiConfig.MaxCompletionTokens = *s.globalSettings.MaxCompletionTokens
 		}
+		if s.globalSettings.Temperature != nil {
+			aiConfig.Temperature = s.globalSettings.Temperature
+		}
+		if s.globalSettings.TopP != nil {
+			aiConfig.TopP = s.globalSettings.TopP
+		}
+		if s.globalSettings.PresencePenalty != nil {
+			aiConfig.PresencePenalty = s.globalSettings.PresencePenalty
+		}
+		aiConfig.ReasoningEffort = s.globalSettings.ReasoningEffort
+		aiConfig.ResponseFormat = model.AIResponseFormat(s.globalSettings.ResponseFormat)
+		aiConfig.ReasoningOutput = model.AIReasoningOutput(s.globalSettings.ReasoningOutput)
 		if s.globalSettings.ImageModel != "" {
 			aiConfig.ImageModel = s.globalSettings.ImageModel
 		}
@@ -98,6 +136,9 @@ func (s *ChatRoomSettingsService) GetAIConfig() settings.AIConfig {
 		if s.globalSettings.LTTSSettings != nil {
 			aiConfig.LTTSSettings = s.globalSettings.LTTSSettings
 		}
+		if s.globalSettings.ASRSettings != nil {
+			aiConfig.ASRSettings = s.globalSettings.ASRSettings
+		}
 	}
 	if s.chatRoomSettings != nil {
 		if s.chatRoomSettings.ChatBaseURL != nil && *s.chatRoomSettings.ChatBaseURL != "" {
@@ -106,6 +147,12 @@ func (s *ChatRoomSettingsService) GetAIConfig() settings.AIConfig {
 		if s.chatRoomSettings.ChatAPIKey != nil && *s.chatRoomSettings.ChatAPIKey != "" {
 			aiConfig.APIKey = *s.chatRoomSettings.ChatAPIKey
 		}
+		if s.chatRoomSettings.ChatProvider != nil && *s.chatRoomSettings.ChatProvider != "" {
+			aiConfig.Provider = *s.chatRoomSettings.ChatProvider
+		}
+		if providers := parseAIProviders(s.chatRoomSettings.AIFallbackProviders); len(providers) > 0 {
+			aiConfig.FallbackProviders = providers
+		}
 		if s.chatRoomSettings.ChatModel != nil && *s.chatRoomSettings.ChatModel != "" {
 			aiConfig.Model = *s.chatRoomSettings.ChatModel
 		}
@@ -121,6 +168,24 @@ func (s *ChatRoomSettingsService) GetAIConfig() settings.AIConfig {
 		if s.chatRoomSettings.MaxCompletionTokens != nil {
 			aiConfig.MaxCompletionTokens = *s.chatRoomSettings.MaxCompletionTokens
 		}
+		if s.chatRoomSettings.Temperature != nil {
+			aiConfig.Temperature = s.chatRoomSettings.Temperature
+		}
+		if s.chatRoomSettings.TopP != nil {
+			aiConfig.TopP = s.chatRoomSettings.TopP
+		}
+		if s.chatRoomSettings.PresencePenalty != nil {
+			aiConfig.PresencePenalty = s.chatRoomSettings.PresencePenalty
+		}
+		if s.chatRoomSettings.ReasoningEffort != nil && *s.chatRoomSettings.ReasoningEffort != "" {
+			aiConfig.ReasoningEffort = *s.chatRoomSettings.ReasoningEffort
+		}
+		if s.chatRoomSettings.ResponseFormat != nil && *s.chatRoomSettings.ResponseFormat != "" {
+			aiConfig.ResponseFormat = model.AIResponseFormat(*s.chatRoomSettings.ResponseFormat)
+		}
+		if s.chatRoomSettings.ReasoningOutput != nil && *s.chatRoomSettings.ReasoningOutput != "" {
+			aiConfig.ReasoningOutput = model.AIReasoningOutput(*s.chatRoomSettings.ReasoningOutput)
+		}
 		if s.chatRoomSettings.ImageModel != nil && *s.chatRoomSettings.ImageModel != "" {
 			aiConfig.ImageModel = *s.chatRoomSettings.ImageModel
 		}
@@ -133,7 +198,11 @@ func (s *ChatRoomSettingsService) GetAIConfig() settings.AIConfig {
 		if s.chatRoomSettings.LTTSSettings != nil {
 			aiConfig.LTTSSettings = s.chatRoomSettings.LTTSSettings
 		}
+		if s.chatRoomSettings.ASRSettings != nil {
+			aiConfig.ASRSettings = s.chatRoomSettings.ASRSettings
+		}
 	}
+	applyPersona(&aiConfig, s.persona)
 	aiConfig.BaseURL = utils.NormalizeAIBaseURL(aiConfig.BaseURL)
 	return aiConfig
 }
@@ -168,12 +237,66 @@ func (s *ChatRoomSettingsService) IsTTSEnabled() bool {
 	return false
 }
 
+func (s *ChatRoomSettingsService) IsASREnabled() bool {
+	if s.chatRoomSettings != nil && s.chatRoomSettings.ASREnabled != nil {
+		return *s.chatRoomSettings.ASREnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.ASREnabled != nil {
+		return *s.globalSettings.ASREnabled
+	}
+	return false
+}
+
+func (s *ChatRoomSettingsService) IsAIStreamEnabled() bool {
+	if s.chatRoomSettings != nil && s.chatRoomSettings.AIStreamEnabled != nil {
+		return *s.chatRoomSettings.AIStreamEnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.AIStreamEnabled != nil {
+		return *s.globalSettings.AIStreamEnabled
+	}
+	return false
+}
+
+func (s *ChatRoomSettingsService) IsAIMemoryEnabled() bool {
+	if s.chatRoomSettings != nil && s.chatRoomSettings.AIMemoryEnabled != nil {
+		return *s.chatRoomSettings.AIMemoryEnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.AIMemoryEnabled != nil {
+		return *s.globalSettings.AIMemoryEnabled
+	}
+	return false
+}
+
+func (s *ChatRoomSettingsService) IsSemanticSearchEnabled() bool {
+	if s.chatRoomSettings != nil && s.chatRoomSettings.SemanticSearchEnabled != nil {
+		return *s.chatRoomSettings.SemanticSearchEnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.SemanticSearchEnabled != nil {
+		return *s.globalSettings.SemanticSearchEnabled
+	}
+	return false
+}
+
 // 是否属于自动触发AI的指令
 func (s *ChatRoomSettingsService) IsAutoAITrigger(message string) bool {
 	matched, _ := NewAIWorkflowService(s.ctx, s).ChatIntentionSimple(message, nil)
 	return matched
 }
 
+func (s *ChatRoomSettingsService) GetPersona() *model.AIPersona {
+	return s.persona
+}
+
+func (s *ChatRoomSettingsService) IsPersonaSwitchEnabled() bool {
+	if s.chatRoomSettings != nil && s.chatRoomSettings.PersonaSwitchEnabled != nil {
+		return *s.chatRoomSettings.PersonaSwitchEnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.PersonaSwitchEnabled != nil {
+		return *s.globalSettings.PersonaSwitchEnabled
+	}
+	return false
+}
+
 func (s *ChatRoomSettingsService) IsAITrigger() bool {
 	messageContent := s.Message.Content
 	if s.Message.AppMsgType == model.AppMsgTypequote {
@@ -253,27 +376,93 @@ func (s *ChatRoomSettingsService) GetChatRoomWelcomeConfig(chatRoomID string) (*
 }
 
 func (s *ChatRoomSettingsService) GetPatConfig() settings.PatConfig {
-	if s.chatRoomSettings != nil {
-		if s.chatRoomSettings.PatEnabled != nil {
-			return settings.PatConfig{
-				PatEnabled:     *s.chatRoomSettings.PatEnabled,
-				PatType:        s.chatRoomSettings.PatType,
-				PatText:        s.chatRoomSettings.PatText,
-				PatVoiceTimbre: s.chatRoomSettings.PatVoiceTimbre,
-			}
+	patConfig := settings.PatConfig{}
+	if s.chatRoomSettings != nil && s.chatRoomSettings.PatEnabled != nil {
+		patConfig = settings.PatConfig{
+			PatEnabled:     *s.chatRoomSettings.PatEnabled,
+			PatType:        s.chatRoomSettings.PatType,
+			PatText:        s.chatRoomSettings.PatText,
+			PatVoiceTimbre: s.chatRoomSettings.PatVoiceTimbre,
 		}
+	} else if s.globalSettings != nil && s.globalSettings.PatEnabled != nil {
+		patConfig = settings.PatConfig{
+			PatEnabled:     *s.globalSettings.PatEnabled,
+			PatType:        s.globalSettings.PatType,
+			PatText:        s.globalSettings.PatText,
+			PatVoiceTimbre: s.globalSettings.PatVoiceTimbre,
+		}
+	}
+	applyPersonaPat(&patConfig, s.persona)
+	return patConfig
+}
+
+func (s *ChatRoomSettingsService) IsPluginEnabled(pluginName string) bool {
+	return isPluginEnabled(s.pluginSettings, s.Message.FromWxID, pluginName)
+}
+
+func (s *ChatRoomSettingsService) GetPluginConfig(pluginName string) datatypes.JSON {
+	return mergePluginConfig(s.pluginSettings, s.Message.FromWxID, pluginName)
+}
+
+func (s *ChatRoomSettingsService) GetRateLimitRules(feature model.RateLimitFeature) []model.RateLimitRule {
+	var globalRules, chatRoomRules datatypes.JSON
+	if s.globalSettings != nil {
+		globalRules = s.globalSettings.RateLimits
+	}
+	if s.chatRoomSettings != nil {
+		chatRoomRules = s.chatRoomSettings.RateLimits
 	}
+	return mergeRateLimitRules(globalRules, chatRoomRules, feature)
+}
+
+func (s *ChatRoomSettingsService) GetMCPServers() []model.MCPServer {
+	var globalServers, chatRoomServers datatypes.JSON
 	if s.globalSettings != nil {
-		if s.globalSettings.PatEnabled != nil {
-			return settings.PatConfig{
-				PatEnabled:     *s.globalSettings.PatEnabled,
-				PatType:        s.globalSettings.PatType,
-				PatText:        s.globalSettings.PatText,
-				PatVoiceTimbre: s.globalSettings.PatVoiceTimbre,
-			}
-		}
+		globalServers = s.globalSettings.MCPServers
+	}
+	if s.chatRoomSettings != nil {
+		chatRoomServers = s.chatRoomSettings.MCPServers
+	}
+	return mergeMCPServers(globalServers, chatRoomServers)
+}
+
+func (s *ChatRoomSettingsService) GetAITokenQuota() model.AITokenQuota {
+	var quota model.AITokenQuota
+	if s.globalSettings != nil {
+		quota = mergeAITokenQuota(quota, s.globalSettings.AIDailyTokenQuota, s.globalSettings.AIMonthlyTokenQuota)
+	}
+	if s.chatRoomSettings != nil {
+		quota = mergeAITokenQuota(quota, s.chatRoomSettings.AIDailyTokenQuota, s.chatRoomSettings.AIMonthlyTokenQuota)
+	}
+	return quota
+}
+
+func (s *ChatRoomSettingsService) GetKnowledgeBaseIDs() []int64 {
+	if s.chatRoomSettings == nil {
+		return nil
+	}
+	return parseKnowledgeBaseIDs(s.chatRoomSettings.KnowledgeBaseIDs)
+}
+
+func (s *ChatRoomSettingsService) IsModerationEnabled() bool {
+	if s.chatRoomSettings != nil && s.chatRoomSettings.ModerationEnabled != nil {
+		return *s.chatRoomSettings.ModerationEnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.ModerationEnabled != nil {
+		return *s.globalSettings.ModerationEnabled
+	}
+	return false
+}
+
+func (s *ChatRoomSettingsService) GetModerationSettings() model.ModerationSettings {
+	var globalConfig, chatRoomConfig datatypes.JSON
+	if s.globalSettings != nil {
+		globalConfig = s.globalSettings.ModerationSettings
+	}
+	if s.chatRoomSettings != nil {
+		chatRoomConfig = s.chatRoomSettings.ModerationSettings
 	}
-	return settings.PatConfig{}
+	return mergeModerationSettings(globalConfig, chatRoomConfig)
 }
 
 func (s *ChatRoomSettingsService) GetLeaveChatRoomConfig(chatRoomID string) *model.ChatRoomSettings {
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"wechat-robot-client/interface/plugin"
+	pluginpkg "wechat-robot-client/plugin"
+	"wechat-robot-client/vars"
+)
+
+// dynamicPluginStore 保存在数据库中的插件（Webhook 插件、脚本插件）共用的存储操作
+type dynamicPluginStore[T any] interface {
+	GetByID(id uint64) (*T, error)
+	GetByName(name string) (*T, error)
+	Create(data *T) error
+	Update(data *T) error
+}
+
+type dynamicPluginModel[T any] interface {
+	*T
+	GetID() uint64
+}
+
+// dynamicPluginEntry 加载插件时需要读取的插件记录字段
+type dynamicPluginEntry[T any] interface {
+	*T
+	GetName() string
+	GetPriority() int
+	IsEnabled() bool
+}
+
+// saveDynamicPlugin 新增或者更新保存在数据库中的插件，data 的ID为0时新增
+// 插件名称不能和其他类型的插件重名，isSameKind 判断已注册的插件是否和 data 是同一类插件，同类插件的重名通过数据库检查
+func saveDynamicPlugin[T any, P dynamicPluginModel[T]](store dynamicPluginStore[T], data P, name string, isSameKind func(handler plugin.MessageHandler) bool) error {
+	for _, handler := range vars.MessagePlugin.Plugins() {
+		if !isSameKind(handler) && handler.GetName() == name {
+			return fmt.Errorf("插件名称 %s 已被其他插件使用", name)
+		}
+	}
+	existing, err := store.GetByName(name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && P(existing).GetID() != data.GetID() {
+		return fmt.Errorf("插件名称 %s 已存在", name)
+	}
+	if data.GetID() == 0 {
+		return store.Create(data)
+	}
+	current, err := store.GetByID(data.GetID())
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return errors.New("插件不存在")
+	}
+	return store.Update(data)
+}
+
+// loadDynamicPlugins 根据数据库中的插件记录创建插件，并一次性替换掉已注册的同类插件，替换期间分发的消息不会漏掉插件
+// kind 为插件类型名称，只用于日志；isSameKind 判断已注册的插件是否属于这一类插件
+func loadDynamicPlugins[T any, P dynamicPluginEntry[T]](kind string, items []*T, newPlugin func(item *T) (plugin.MessageHandler, error), isSameKind func(handler plugin.MessageHandler) bool) {
+	registrations := make([]pluginpkg.Registration, 0, len(items))
+	for _, item := range items {
+		if !P(item).IsEnabled() {
+			continue
+		}
+		handler, err := newPlugin(item)
+		if err != nil {
+			log.Printf("加载%s[%s]失败: %v", kind, P(item).GetName(), err)
+			continue
+		}
+		registrations = append(registrations, pluginpkg.Registration{Handler: handler, Priority: P(item).GetPriority()})
+	}
+	for _, name := range vars.MessagePlugin.ReplaceAll(isSameKind, registrations) {
+		log.Printf("加载%s[%s]失败: 插件名称已被其他插件使用", kind, name)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"wechat-robot-client/model"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/utils"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// embeddingSettings 读取全局配置中的向量接口配置，知识库和聊天记录语义搜索共用，接口地址和密钥为空时使用AI聊天的配置
+func embeddingSettings(gsRespo *repository.GlobalSettings) (model.KnowledgeSettings, error) {
+	var config model.KnowledgeSettings
+	globalSettings, err := gsRespo.GetGlobalSettings()
+	if err != nil {
+		return config, err
+	}
+	if globalSettings == nil {
+		return config, errors.New("全局配置不存在")
+	}
+	if len(globalSettings.KnowledgeSettings) > 0 {
+		if err := json.Unmarshal(globalSettings.KnowledgeSettings, &config); err != nil {
+			return config, fmt.Errorf("反序列化知识库配置失败: %w", err)
+		}
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = globalSettings.ChatBaseURL
+		if config.APIKey == "" {
+			config.APIKey = globalSettings.ChatAPIKey
+		}
+	}
+	if config.BaseURL == "" {
+		return config, errors.New("向量接口地址未配置")
+	}
+	config.BaseURL = utils.NormalizeAIBaseURL(config.BaseURL)
+	if config.EmbeddingModel == "" {
+		config.EmbeddingModel = defaultKnowledgeEmbeddingModel
+	}
+	if config.TopK <= 0 {
+		config.TopK = defaultKnowledgeTopK
+	}
+	if config.MinScore <= 0 {
+		config.MinScore = defaultKnowledgeMinScore
+	}
+	return config, nil
+}
+
+// createEmbeddings 调用 OpenAI 兼容的向量接口，返回的向量和输入一一对应，并记录用量
+func createEmbeddings(ctx context.Context, config model.KnowledgeSettings, usage model.AIUsage, inputs []string) ([][]float32, error) {
+	openaiConfig := openai.DefaultConfig(config.APIKey)
+	openaiConfig.BaseURL = config.BaseURL
+	client := openai.NewClientWithConfig(openaiConfig)
+	resp, err := client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: inputs,
+		Model: openai.EmbeddingModel(config.EmbeddingModel),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("调用向量接口失败: %w", err)
+	}
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("向量接口返回了 %d 个向量，需要 %d 个", len(resp.Data), len(inputs))
+	}
+	embeddings := make([][]float32, len(inputs))
+	for i, item := range resp.Data {
+		index := item.Index
+		if index < 0 || index >= len(inputs) {
+			index = i
+		}
+		embeddings[index] = item.Embedding
+	}
+	usage.Model = config.EmbeddingModel
+	usage.PromptTokens = resp.Usage.PromptTokens
+	usage.TotalTokens = resp.Usage.TotalTokens
+	NewAIUsageService(ctx).Record(&usage)
+	return embeddings, nil
+}
+
+// encodeEmbedding 把向量按 float32 小端序编码后保存到数据库
+func encodeEmbedding(embedding []float32) []byte {
+	data := make([]byte, len(embedding)*4)
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	return data
+}
+
+func decodeEmbedding(data []byte) []float32 {
+	embedding := make([]float32, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return embedding
+}
+
+// cosineSimilarity 计算两个向量的余弦相似度，维度不同或者有零向量时返回 0
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
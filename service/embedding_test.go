@@ -0,0 +1,28 @@
+package service
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEmbeddingSimilarity(t *testing.T) {
+	embedding := []float32{0.5, -1.25, 3}
+	decoded := decodeEmbedding(encodeEmbedding(embedding))
+	if len(decoded) != len(embedding) {
+		t.Fatalf("decoded = %v, want %v", decoded, embedding)
+	}
+	for i := range embedding {
+		if decoded[i] != embedding[i] {
+			t.Fatalf("decoded = %v, want %v", decoded, embedding)
+		}
+	}
+	if score := cosineSimilarity(embedding, []float32{1, -2.5, 6}); math.Abs(score-1) > 1e-6 {
+		t.Fatalf("score = %v, want 1", score)
+	}
+	if score := cosineSimilarity([]float32{1, 0}, []float32{0, 1}); score != 0 {
+		t.Fatalf("score = %v, want 0", score)
+	}
+	if score := cosineSimilarity([]float32{1, 0}, []float32{1}); score != 0 {
+		t.Fatalf("score = %v, want 0 for different dimensions", score)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"wechat-robot-client/repository"
 	"wechat-robot-client/utils"
 	"wechat-robot-client/vars"
+
+	"gorm.io/datatypes"
 )
 
 type FriendSettingsService struct {
@@ -16,9 +18,13 @@ type FriendSettingsService struct {
 	gsRespo        *repository.GlobalSettings
 	fsRespo        *repository.FriendSettings
 	contactRespo   *repository.Contact
+	psRespo        *repository.PluginSettings
+	apRespo        *repository.AIPersona
 	globalSettings *model.GlobalSettings
 	friendSettings *model.FriendSettings
 	sender         *model.Contact
+	pluginSettings []*model.PluginSettings
+	persona        *model.AIPersona
 }
 
 var _ settings.Settings = (*FriendSettingsService)(nil)
@@ -29,6 +35,8 @@ func NewFriendSettingsService(ctx context.Context) *FriendSettingsService {
 		gsRespo:      repository.NewGlobalSettingsRepo(ctx, vars.DB),
 		fsRespo:      repository.NewFriendSettingsRepo(ctx, vars.DB),
 		contactRespo: repository.NewContactRepo(ctx, vars.DB),
+		psRespo:      repository.NewPluginSettingsRepo(ctx, vars.DB),
+		apRespo:      repository.NewAIPersonaRepo(ctx, vars.DB),
 	}
 }
 
@@ -44,14 +52,26 @@ func (s *FriendSettingsService) InitByMessage(message *model.Message) error {
 		return err
 	}
 	s.friendSettings = friendSettings
+	if friendSettings != nil {
+		s.persona = loadPersona(s.apRespo, friendSettings.AIPersonaID)
+	}
 	contact, err := s.contactRespo.GetContact(message.FromWxID)
 	if err != nil {
 		return err
 	}
 	s.sender = contact
+	pluginSettings, err := s.psRespo.GetByContactID(message.FromWxID)
+	if err != nil {
+		return err
+	}
+	s.pluginSettings = pluginSettings
 	return nil
 }
 
+func (s *FriendSettingsService) GetMessage() *model.Message {
+	return s.Message
+}
+
 func (s *FriendSettingsService) GetAIConfig() settings.AIConfig {
 	aiConfig := settings.AIConfig{}
 	if s.globalSettings != nil {
@@ -61,6 +81,10 @@ func (s *FriendSettingsService) GetAIConfig() settings.AIConfig {
 		if s.globalSettings.ChatAPIKey != "" {
 			aiConfig.APIKey = s.globalSettings.ChatAPIKey
 		}
+		if s.globalSettings.ChatProvider != "" {
+			aiConfig.Provider = s.globalSettings.ChatProvider
+		}
+		aiConfig.FallbackProviders = parseAIProviders(s.globalSettings.AIFallbackProviders)
 		if s.globalSettings.ChatModel != "" {
 			aiConfig.Model = s.globalSettings.ChatModel
 		}
@@ -76,6 +100,18 @@ func (s *FriendSettingsService) GetAIConfig() settings.AIConfig {
 		if s.globalSettings.MaxCompletionTokens != nil {
 			aiConfig.MaxCompletionTokens = *s.globalSettings.MaxCompletionTokens
 		}
+		if s.globalSettings.Temperature != nil {
+			aiConfig.Temperature = s.globalSettings.Temperature
+		}
+		if s.globalSettings.TopP != nil {
+			aiConfig.TopP = s.globalSettings.TopP
+		}
+		if s.globalSettings.PresencePenalty != nil {
+			aiConfig.PresencePenalty = s.globalSettings.PresencePenalty
+		}
+		aiConfig.ReasoningEffort = s.globalSettings.ReasoningEffort
+		aiConfig.ResponseFormat = model.AIResponseFormat(s.globalSettings.ResponseFormat)
+		aiConfig.ReasoningOutput = model.AIReasoningOutput(s.globalSettings.ReasoningOutput)
 		if s.globalSettings.ImageModel != "" {
 			aiConfig.ImageModel = s.globalSettings.ImageModel
 		}
@@ -88,6 +124,9 @@ func (s *FriendSettingsService) GetAIConfig() settings.AIConfig {
 		if s.globalSettings.LTTSSettings != nil {
 			aiConfig.LTTSSettings = s.globalSettings.LTTSSettings
 		}
+		if s.globalSettings.ASRSettings != nil {
+			aiConfig.ASRSettings = s.globalSettings.ASRSettings
+		}
 	}
 	if s.friendSettings != nil {
 		if s.friendSettings.ChatBaseURL != nil && *s.friendSettings.ChatBaseURL != "" {
@@ -96,6 +135,12 @@ func (s *FriendSettingsService) GetAIConfig() settings.AIConfig {
 		if s.friendSettings.ChatAPIKey != nil && *s.friendSettings.ChatAPIKey != "" {
 			aiConfig.APIKey = *s.friendSettings.ChatAPIKey
 		}
+		if s.friendSettings.ChatProvider != nil && *s.friendSettings.ChatProvider != "" {
+			aiConfig.Provider = *s.friendSettings.ChatProvider
+		}
+		if providers := parseAIProviders(s.friendSettings.AIFallbackProviders); len(providers) > 0 {
+			aiConfig.FallbackProviders = providers
+		}
 		if s.friendSettings.ChatModel != nil && *s.friendSettings.ChatModel != "" {
 			aiConfig.Model = *s.friendSettings.ChatModel
 		}
@@ -111,6 +156,24 @@ func (s *FriendSettingsService) GetAIConfig() settings.AIConfig {
 		if s.friendSettings.MaxCompletionTokens != nil {
 			aiConfig.MaxCompletionTokens = *s.friendSettings.MaxCompletionTokens
 		}
+		if s.friendSettings.Temperature != nil {
+			aiConfig.Temperature = s.friendSettings.Temperature
+		}
+		if s.friendSettings.TopP != nil {
+			aiConfig.TopP = s.friendSettings.TopP
+		}
+		if s.friendSettings.PresencePenalty != nil {
+			aiConfig.PresencePenalty = s.friendSettings.PresencePenalty
+		}
+		if s.friendSettings.ReasoningEffort != nil && *s.friendSettings.ReasoningEffort != "" {
+			aiConfig.ReasoningEffort = *s.friendSettings.ReasoningEffort
+		}
+		if s.friendSettings.ResponseFormat != nil && *s.friendSettings.ResponseFormat != "" {
+			aiConfig.ResponseFormat = model.AIResponseFormat(*s.friendSettings.ResponseFormat)
+		}
+		if s.friendSettings.ReasoningOutput != nil && *s.friendSettings.ReasoningOutput != "" {
+			aiConfig.ReasoningOutput = model.AIReasoningOutput(*s.friendSettings.ReasoningOutput)
+		}
 		if s.friendSettings.ImageModel != nil && *s.friendSettings.ImageModel != "" {
 			aiConfig.ImageModel = *s.friendSettings.ImageModel
 		}
@@ -123,7 +186,11 @@ func (s *FriendSettingsService) GetAIConfig() settings.AIConfig {
 		if s.friendSettings.LTTSSettings != nil {
 			aiConfig.LTTSSettings = s.friendSettings.LTTSSettings
 		}
+		if s.friendSettings.ASRSettings != nil {
+			aiConfig.ASRSettings = s.friendSettings.ASRSettings
+		}
 	}
+	applyPersona(&aiConfig, s.persona)
 	aiConfig.BaseURL = utils.NormalizeAIBaseURL(aiConfig.BaseURL)
 	return aiConfig
 }
@@ -174,6 +241,60 @@ func (s *FriendSettingsService) IsTTSEnabled() bool {
 	return false
 }
 
+func (s *FriendSettingsService) IsASREnabled() bool {
+	if s.friendSettings != nil && s.friendSettings.ASREnabled != nil {
+		return *s.friendSettings.ASREnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.ASREnabled != nil {
+		return *s.globalSettings.ASREnabled
+	}
+	return false
+}
+
+func (s *FriendSettingsService) IsAIStreamEnabled() bool {
+	if s.friendSettings != nil && s.friendSettings.AIStreamEnabled != nil {
+		return *s.friendSettings.AIStreamEnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.AIStreamEnabled != nil {
+		return *s.globalSettings.AIStreamEnabled
+	}
+	return false
+}
+
+func (s *FriendSettingsService) IsAIMemoryEnabled() bool {
+	if s.friendSettings != nil && s.friendSettings.AIMemoryEnabled != nil {
+		return *s.friendSettings.AIMemoryEnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.AIMemoryEnabled != nil {
+		return *s.globalSettings.AIMemoryEnabled
+	}
+	return false
+}
+
+func (s *FriendSettingsService) IsSemanticSearchEnabled() bool {
+	if s.friendSettings != nil && s.friendSettings.SemanticSearchEnabled != nil {
+		return *s.friendSettings.SemanticSearchEnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.SemanticSearchEnabled != nil {
+		return *s.globalSettings.SemanticSearchEnabled
+	}
+	return false
+}
+
+func (s *FriendSettingsService) GetPersona() *model.AIPersona {
+	return s.persona
+}
+
+func (s *FriendSettingsService) IsPersonaSwitchEnabled() bool {
+	if s.friendSettings != nil && s.friendSettings.PersonaSwitchEnabled != nil {
+		return *s.friendSettings.PersonaSwitchEnabled
+	}
+	if s.globalSettings != nil && s.globalSettings.PersonaSwitchEnabled != nil {
+		return *s.globalSettings.PersonaSwitchEnabled
+	}
+	return false
+}
+
 func (s *FriendSettingsService) IsAITrigger() bool {
 	return s.IsAIChatEnabled()
 }
@@ -182,6 +303,60 @@ func (s *FriendSettingsService) GetAITriggerWord() string {
 	return ""
 }
 
+func (s *FriendSettingsService) IsPluginEnabled(pluginName string) bool {
+	return isPluginEnabled(s.pluginSettings, s.Message.FromWxID, pluginName)
+}
+
+func (s *FriendSettingsService) GetPluginConfig(pluginName string) datatypes.JSON {
+	return mergePluginConfig(s.pluginSettings, s.Message.FromWxID, pluginName)
+}
+
+func (s *FriendSettingsService) GetRateLimitRules(feature model.RateLimitFeature) []model.RateLimitRule {
+	var globalRules, friendRules datatypes.JSON
+	if s.globalSettings != nil {
+		globalRules = s.globalSettings.RateLimits
+	}
+	if s.friendSettings != nil {
+		friendRules = s.friendSettings.RateLimits
+	}
+	return mergeRateLimitRules(globalRules, friendRules, feature)
+}
+
+// GetMCPServers 好友只使用全局配置的MCP服务器
+func (s *FriendSettingsService) GetMCPServers() []model.MCPServer {
+	if s.globalSettings == nil {
+		return nil
+	}
+	return mergeMCPServers(s.globalSettings.MCPServers, nil)
+}
+
+func (s *FriendSettingsService) GetAITokenQuota() model.AITokenQuota {
+	var quota model.AITokenQuota
+	if s.globalSettings != nil {
+		quota = mergeAITokenQuota(quota, s.globalSettings.AIDailyTokenQuota, s.globalSettings.AIMonthlyTokenQuota)
+	}
+	if s.friendSettings != nil {
+		quota = mergeAITokenQuota(quota, s.friendSettings.AIDailyTokenQuota, s.friendSettings.AIMonthlyTokenQuota)
+	}
+	return quota
+}
+
+func (s *FriendSettingsService) GetKnowledgeBaseIDs() []int64 {
+	if s.friendSettings == nil {
+		return nil
+	}
+	return parseKnowledgeBaseIDs(s.friendSettings.KnowledgeBaseIDs)
+}
+
+// IsModerationEnabled 内容审核只对群聊生效
+func (s *FriendSettingsService) IsModerationEnabled() bool {
+	return false
+}
+
+func (s *FriendSettingsService) GetModerationSettings() model.ModerationSettings {
+	return model.ModerationSettings{}
+}
+
 func (s *FriendSettingsService) GetFriendSettings(contactID string) (*model.FriendSettings, error) {
 	return s.fsRespo.GetFriendSettings(contactID)
 }
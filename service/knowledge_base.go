@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/model"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/vars"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	// 每个片段新增内容的字数
+	knowledgeChunkRunes = 500
+	// 相邻片段重叠的字数
+	knowledgeChunkOverlapRunes = 50
+	// 每个文档最多切分的片段数量
+	maxKnowledgeChunks = 2000
+	// 每次调用向量接口最多提交的片段数量
+	knowledgeEmbeddingBatchSize    = 32
+	defaultKnowledgeEmbeddingModel = "text-embedding-3-small"
+	defaultKnowledgeTopK           = 4
+	defaultKnowledgeMinScore       = 0.3
+)
+
+type KnowledgeBaseService struct {
+	ctx     context.Context
+	kbRespo *repository.KnowledgeBase
+	gsRespo *repository.GlobalSettings
+}
+
+// scoredItem 带有相似度的检索结果
+type scoredItem interface {
+	similarity() float64
+}
+
+// scoredChunk 文档片段和问题的相似度
+type scoredChunk struct {
+	documentID int64
+	content    string
+	score      float64
+}
+
+func (c scoredChunk) similarity() float64 {
+	return c.score
+}
+
+// KnowledgeHit 检索到的文档片段
+type KnowledgeHit struct {
+	DocumentName string
+	Content      string
+	Score        float64
+}
+
+func NewKnowledgeBaseService(ctx context.Context) *KnowledgeBaseService {
+	return &KnowledgeBaseService{
+		ctx:     ctx,
+		kbRespo: repository.NewKnowledgeBaseRepo(ctx, vars.DB),
+		gsRespo: repository.NewGlobalSettingsRepo(ctx, vars.DB),
+	}
+}
+
+// parseKnowledgeBaseIDs 解析群聊、好友关联的知识库ID列表
+func parseKnowledgeBaseIDs(data datatypes.JSON) []int64 {
+	if len(data) == 0 {
+		return nil
+	}
+	var ids []int64
+	if err := json.Unmarshal(data, &ids); err != nil {
+		log.Printf("解析知识库ID列表失败: %v", err)
+		return nil
+	}
+	return ids
+}
+
+func (s *KnowledgeBaseService) GetKnowledgeBases() ([]*model.KnowledgeBase, error) {
+	return s.kbRespo.GetList()
+}
+
+func (s *KnowledgeBaseService) GetKnowledgeBase(id int64) (*model.KnowledgeBase, error) {
+	return s.kbRespo.GetByID(id)
+}
+
+// SaveKnowledgeBase 新增或者更新知识库
+func (s *KnowledgeBaseService) SaveKnowledgeBase(req dto.KnowledgeBaseRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("知识库名称不能为空")
+	}
+	existing, err := s.kbRespo.GetByName(req.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != req.ID {
+		return fmt.Errorf("知识库 %s 已存在", req.Name)
+	}
+	data := &model.KnowledgeBase{
+		ID:          req.ID,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if req.ID == 0 {
+		return s.kbRespo.Create(data)
+	}
+	knowledgeBase, err := s.kbRespo.GetByID(req.ID)
+	if err != nil {
+		return err
+	}
+	if knowledgeBase == nil {
+		return errors.New("知识库不存在")
+	}
+	return s.kbRespo.Update(data)
+}
+
+func (s *KnowledgeBaseService) DeleteKnowledgeBase(id int64) error {
+	return s.kbRespo.Delete(id)
+}
+
+func (s *KnowledgeBaseService) GetDocuments(knowledgeBaseID int64) ([]*model.KnowledgeDocument, error) {
+	return s.kbRespo.GetDocuments(knowledgeBaseID)
+}
+
+func (s *KnowledgeBaseService) DeleteDocument(id int64) error {
+	return s.kbRespo.DeleteDocument(id)
+}
+
+// AddDocument 解析文档并切分成片段，在后台生成向量，生成完成后文档状态变为 ready
+func (s *KnowledgeBaseService) AddDocument(knowledgeBaseID int64, filename string, data []byte) (*model.KnowledgeDocument, error) {
+	knowledgeBase, err := s.kbRespo.GetByID(knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	if knowledgeBase == nil {
+		return nil, errors.New("知识库不存在")
+	}
+	text, err := extractDocumentText(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	chunks := splitKnowledgeText(text, knowledgeChunkRunes, knowledgeChunkOverlapRunes)
+	if len(chunks) > maxKnowledgeChunks {
+		return nil, fmt.Errorf("文档太长，最多支持 %d 个片段，请拆分后再上传", maxKnowledgeChunks)
+	}
+	document := &model.KnowledgeDocument{
+		KnowledgeBaseID: knowledgeBaseID,
+		Name:            filename,
+		Size:            int64(len(data)),
+		ChunkCount:      len(chunks),
+		Status:          model.KnowledgeDocumentStatusProcessing,
+	}
+	if err := s.kbRespo.CreateDocument(document); err != nil {
+		return nil, err
+	}
+	// 在后台协程池中生成向量，服务退出时会等待正在处理的文档完成
+	// 队列满了时不等待，避免上传请求一直卡住，文档直接标记为失败，稍后重新上传即可
+	err = vars.BackgroundWorkerPool.TrySubmit(fmt.Sprintf("knowledge_document:%d", document.ID), func() {
+		NewKnowledgeBaseService(context.Background()).embedDocument(document, chunks)
+	})
+	if err != nil {
+		if err := s.kbRespo.UpdateDocumentStatus(document.ID, model.KnowledgeDocumentStatusFailed, len(chunks), err.Error()); err != nil {
+			log.Printf("更新知识库文档状态失败: %v", err)
+		}
+		return nil, fmt.Errorf("提交文档处理任务失败: %w", err)
+	}
+	return document, nil
+}
+
+// FailInterruptedDocuments 启动时把上次退出前没有处理完的文档标记为失败，上传的原文件没有保存，需要重新上传
+func (s *KnowledgeBaseService) FailInterruptedDocuments() error {
+	count, err := s.kbRespo.FailProcessingDocuments("服务重启，文档处理中断，请重新上传")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("%d 个知识库文档处理中断，已标记为失败", count)
+	}
+	return nil
+}
+
+// embedDocument 生成文档片段的向量并保存
+func (s *KnowledgeBaseService) embedDocument(document *model.KnowledgeDocument, chunks []string) {
+	status, errMsg := model.KnowledgeDocumentStatusReady, ""
+	if err := s.saveChunks(document, chunks); err != nil {
+		log.Printf("知识库文档 %s 生成向量失败: %v", document.Name, err)
+		status, errMsg = model.KnowledgeDocumentStatusFailed, err.Error()
+		if utf8.RuneCountInString(errMsg) > 500 {
+			errMsg = string([]rune(errMsg)[:500])
+		}
+	}
+	if err := s.kbRespo.UpdateDocumentStatus(document.ID, status, len(chunks), errMsg); err != nil {
+		log.Printf("更新知识库文档状态失败: %v", err)
+	}
+}
+
+func (s *KnowledgeBaseService) saveChunks(document *model.KnowledgeDocument, chunks []string) error {
+	var records []*model.KnowledgeChunk
+	for start := 0; start < len(chunks); start += knowledgeEmbeddingBatchSize {
+		batch := chunks[start:min(start+knowledgeEmbeddingBatchSize, len(chunks))]
+		embeddings, err := s.embed(nil, batch)
+		if err != nil {
+			return err
+		}
+		for i, content := range batch {
+			records = append(records, &model.KnowledgeChunk{
+				KnowledgeBaseID: document.KnowledgeBaseID,
+				DocumentID:      document.ID,
+				ChunkIndex:      start + i,
+				Content:         content,
+				Embedding:       encodeEmbedding(embeddings[i]),
+			})
+		}
+	}
+	// 生成向量期间文档可能已经被删除
+	existing, err := s.kbRespo.GetDocumentByID(document.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+	return s.kbRespo.CreateChunks(records)
+}
+
+// embed 生成向量，message 不为空时按消息所在的聊天记录用量
+func (s *KnowledgeBaseService) embed(message *model.Message, inputs []string) ([][]float32, error) {
+	config, err := embeddingSettings(s.gsRespo)
+	if err != nil {
+		return nil, err
+	}
+	return createEmbeddings(s.ctx, config, newAIUsage(message, model.AIUsageFeatureKnowledge), inputs)
+}
+
+// Search 在知识库中检索和问题最相关的片段，按相似度从高到低排序
+func (s *KnowledgeBaseService) Search(message *model.Message, knowledgeBaseIDs []int64, query string) ([]KnowledgeHit, error) {
+	query = strings.TrimSpace(query)
+	if len(knowledgeBaseIDs) == 0 || query == "" {
+		return nil, nil
+	}
+	// 知识库中还没有片段时不需要为问题生成向量
+	hasChunks, err := s.kbRespo.HasChunks(knowledgeBaseIDs)
+	if err != nil {
+		return nil, err
+	}
+	if !hasChunks {
+		return nil, nil
+	}
+	config, err := embeddingSettings(s.gsRespo)
+	if err != nil {
+		return nil, err
+	}
+	embeddings, err := createEmbeddings(s.ctx, config, newAIUsage(message, model.AIUsageFeatureKnowledge), []string{query})
+	if err != nil {
+		return nil, err
+	}
+	// 分批读取片段，只保留相似度最高的 TopK 个，避免每条消息都把所有片段的向量加载到内存中
+	var scored []scoredChunk
+	err = s.kbRespo.FindChunks(knowledgeBaseIDs, func(chunks []*model.KnowledgeChunk) {
+		for _, chunk := range chunks {
+			score := cosineSimilarity(embeddings[0], decodeEmbedding(chunk.Embedding))
+			if score >= config.MinScore {
+				scored = keepTopScored(scored, scoredChunk{documentID: chunk.DocumentID, content: chunk.Content, score: score}, config.TopK)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(scored) == 0 {
+		return nil, nil
+	}
+	var documentIDs []int64
+	for _, item := range scored {
+		if !slices.Contains(documentIDs, item.documentID) {
+			documentIDs = append(documentIDs, item.documentID)
+		}
+	}
+	documents, err := s.kbRespo.GetDocumentsByIDs(documentIDs)
+	if err != nil {
+		return nil, err
+	}
+	documentNames := make(map[int64]string, len(documents))
+	for _, document := range documents {
+		documentNames[document.ID] = document.Name
+	}
+	hits := make([]KnowledgeHit, 0, len(scored))
+	for _, item := range scored {
+		hits = append(hits, KnowledgeHit{
+			DocumentName: documentNames[item.documentID],
+			Content:      item.content,
+			Score:        item.score,
+		})
+	}
+	return hits, nil
+}
+
+// KnowledgePrompt 检索知识库，返回加入上下文的资料，没有相关资料时返回空字符串
+func (s *KnowledgeBaseService) KnowledgePrompt(message *model.Message, knowledgeBaseIDs []int64, query string) (string, error) {
+	hits, err := s.Search(message, knowledgeBaseIDs, query)
+	if err != nil {
+		return "", err
+	}
+	return knowledgePrompt(hits), nil
+}
+
+func knowledgePrompt(hits []KnowledgeHit) string {
+	if len(hits) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString("以下是从知识库中检索到的和用户问题相关的资料。回答时优先依据这些资料，引用资料的内容时在句末用 [编号] 标注来源，并在回答最后列出引用的文档名称；资料中没有答案时如实说明，不要编造。")
+	for i, hit := range hits {
+		builder.WriteString(fmt.Sprintf("\n\n[%d] 《%s》\n%s", i+1, hit.DocumentName, hit.Content))
+	}
+	return builder.String()
+}
+
+// keepTopScored 按相似度从高到低保留最多 limit 条结果，分批读取向量时不需要把所有结果都放在内存中排序
+func keepTopScored[T scoredItem](top []T, item T, limit int) []T {
+	if limit <= 0 || len(top) >= limit && item.similarity() <= top[len(top)-1].similarity() {
+		return top
+	}
+	i := sort.Search(len(top), func(i int) bool {
+		return top[i].similarity() < item.similarity()
+	})
+	top = slices.Insert(top, i, item)
+	return top[:min(len(top), limit)]
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// 知识库支持的文档格式
+var knowledgeDocumentExts = map[string]bool{
+	".txt":      true,
+	".md":       true,
+	".markdown": true,
+	".pdf":      true,
+	".docx":     true,
+}
+
+// IsKnowledgeDocument 判断文件是不是知识库支持的文档格式
+func IsKnowledgeDocument(filename string) bool {
+	return knowledgeDocumentExts[strings.ToLower(filepath.Ext(filename))]
+}
+
+// extractDocumentText 按文件扩展名提取文档的纯文本
+func extractDocumentText(filename string, data []byte) (string, error) {
+	var text string
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", ".md", ".markdown":
+		if !utf8.Valid(data) {
+			return "", errors.New("文档不是 UTF-8 编码")
+		}
+		text = string(data)
+	case ".pdf":
+		text, err = extractPDFText(data)
+	case ".docx":
+		text, err = extractDocxText(data)
+	default:
+		return "", errors.New("不支持的文档格式，仅支持 txt、md、pdf、docx")
+	}
+	if err != nil {
+		return "", err
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("文档中没有可以识别的文字")
+	}
+	return text, nil
+}
+
+func extractPDFText(data []byte) (text string, err error) {
+	// 解析格式不规范的 PDF 时可能会 panic
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("解析 PDF 失败: %v", rec)
+		}
+	}()
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("解析 PDF 失败: %w", err)
+	}
+	plain, err := reader.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("解析 PDF 失败: %w", err)
+	}
+	content, err := io.ReadAll(plain)
+	if err != nil {
+		return "", fmt.Errorf("解析 PDF 失败: %w", err)
+	}
+	return string(content), nil
+}
+
+// extractDocxText 读取 word/document.xml 中的文字，每个段落一行
+func extractDocxText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("解析 docx 失败: %w", err)
+	}
+	var document *zip.File
+	for _, file := range archive.File {
+		if file.Name == "word/document.xml" {
+			document = file
+			break
+		}
+	}
+	if document == nil {
+		return "", errors.New("解析 docx 失败: 没有找到 word/document.xml")
+	}
+	reader, err := document.Open()
+	if err != nil {
+		return "", fmt.Errorf("解析 docx 失败: %w", err)
+	}
+	defer reader.Close()
+
+	var builder strings.Builder
+	decoder := xml.NewDecoder(reader)
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("解析 docx 失败: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				builder.WriteString("\t")
+			case "br":
+				builder.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				builder.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				builder.Write(t)
+			}
+		}
+	}
+	return builder.String(), nil
+}
+
+// splitKnowledgeText 按行把文档切分成片段，每个片段新增的内容不超过 size 个字
+// 片段开头带上前一个片段末尾的 overlap 个字，避免一句话被切开后检索不到
+func splitKnowledgeText(text string, size, overlap int) []string {
+	var pieces [][]rune
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(strings.TrimSpace(line))
+		for len(runes) > size {
+			pieces = append(pieces, runes[:size])
+			runes = runes[size:]
+		}
+		if len(runes) > 0 {
+			pieces = append(pieces, runes)
+		}
+	}
+	var chunks []string
+	var current []rune
+	// current 中不属于重叠部分的字数
+	fresh := 0
+	for _, piece := range pieces {
+		if fresh > 0 && fresh+len(piece) > size {
+			chunks = append(chunks, string(current))
+			current = []rune(strings.TrimSpace(string(current[max(0, len(current)-overlap):])))
+			fresh = 0
+		}
+		if len(current) > 0 {
+			current = append(current, '\n')
+		}
+		current = append(current, piece...)
+		fresh += len(piece)
+	}
+	if fresh > 0 {
+		chunks = append(chunks, string(current))
+	}
+	return chunks
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitKnowledgeText(t *testing.T) {
+	chunks := splitKnowledgeText("第一行\n\n第二行\n", 10, 2)
+	if len(chunks) != 1 || chunks[0] != "第一行\n第二行" {
+		t.Fatalf("chunks = %q, want one chunk", chunks)
+	}
+
+	// 长段落按字数强制切分，相邻片段重叠
+	text := strings.Repeat("一二三四五六七八九十", 5)
+	chunks = splitKnowledgeText(text, 20, 5)
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %q, want 3 chunks", chunks)
+	}
+	for i, chunk := range chunks {
+		if utf8.RuneCountInString(chunk) > 20+5+1 {
+			t.Fatalf("chunk %q longer than size + overlap", chunk)
+		}
+		if i > 0 {
+			prev := []rune(chunks[i-1])
+			if !strings.HasPrefix(chunk, string(prev[len(prev)-5:])) {
+				t.Fatalf("chunk %q does not overlap with %q", chunk, chunks[i-1])
+			}
+		}
+	}
+
+	if chunks := splitKnowledgeText(" \n\n ", 10, 2); len(chunks) != 0 {
+		t.Fatalf("chunks = %q, want none", chunks)
+	}
+}
+
+func TestExtractDocxText(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	file, err := archive.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>报销</w:t></w:r><w:r><w:t>流程</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>第一步</w:t><w:tab/><w:t>提交申请</w:t></w:r></w:p>` +
+		`</w:body></w:document>`))
+	archive.Close()
+
+	text, err := extractDocumentText("制度.docx", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "报销流程\n第一步\t提交申请\n" {
+		t.Fatalf("text = %q", text)
+	}
+	if _, err := extractDocumentText("a.txt", []byte{0xff, 0xfe}); err == nil {
+		t.Fatal("want error for invalid utf-8")
+	}
+}
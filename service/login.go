@@ -294,11 +294,12 @@ func (r *LoginService) Logout() (err error) {
 	return
 }
 
-func (r *LoginService) SyncMessageCallback(wxID string, syncMessage robot.SyncMessage) {
+// SyncMessageCallback 收到的消息只写入消息队列，写入失败时返回错误，由服务端重新推送
+func (r *LoginService) SyncMessageCallback(wxID string, syncMessage robot.SyncMessage) error {
 	if wxID != vars.RobotRuntime.WxID {
-		return
+		return nil
 	}
-	NewMessageService(r.ctx).ProcessMessage(syncMessage)
+	return NewMessageService(r.ctx).EnqueueSyncMessage(syncMessage)
 }
 
 func (r *LoginService) LogoutCallback(req dto.LogoutNotificationRequest) (err error) {
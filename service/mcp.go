@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+	"wechat-robot-client/interface/settings"
+	"wechat-robot-client/model"
+	"wechat-robot-client/vars"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sashabaranov/go-openai"
+	"gorm.io/datatypes"
+)
+
+const (
+	mcpListToolsTimeout = 10 * time.Second
+	mcpCallToolTimeout  = 60 * time.Second
+	// OpenAI 接口要求工具名称不超过 64 个字符
+	mcpToolNameMaxLength = 64
+)
+
+var mcpToolNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// MCPService 把当前聊天配置的MCP服务器提供的工具交给AI调用
+type MCPService struct {
+	ctx    context.Context
+	config settings.Settings
+}
+
+type mcpTool struct {
+	server model.MCPServer
+	name   string
+}
+
+func NewMCPService(ctx context.Context, config settings.Settings) *MCPService {
+	return &MCPService{
+		ctx:    ctx,
+		config: config,
+	}
+}
+
+// ChatTools 返回当前聊天配置的MCP服务器提供的工具，连接失败的服务器会被跳过，没有可用的工具时返回 nil
+func (s *MCPService) ChatTools() *ChatTools {
+	servers := s.config.GetMCPServers()
+	if len(servers) == 0 || vars.MCPManager == nil {
+		return nil
+	}
+	tools := &ChatTools{}
+	toolMap := make(map[string]mcpTool)
+	for _, server := range servers {
+		ctx, cancel := context.WithTimeout(s.ctx, mcpListToolsTimeout)
+		serverTools, err := vars.MCPManager.ListTools(ctx, server)
+		cancel()
+		if err != nil {
+			log.Printf("获取MCP服务器 %s 的工具失败: %v", server.Name, err)
+			continue
+		}
+		for _, tool := range serverTools {
+			name := mcpToolName(server.Name, tool.Name)
+			if _, exists := toolMap[name]; exists {
+				log.Printf("MCP工具 %s 重名，已忽略", name)
+				continue
+			}
+			toolMap[name] = mcpTool{server: server, name: tool.Name}
+			tools.Tools = append(tools.Tools, openai.Tool{
+				Type: openai.ToolTypeFunction,
+				Function: &openai.FunctionDefinition{
+					Name:        name,
+					Description: tool.Description,
+					Parameters:  mcpToolParameters(tool),
+				},
+			})
+		}
+	}
+	if len(tools.Tools) == 0 {
+		return nil
+	}
+	tools.Call = func(name, arguments string) string {
+		tool, ok := toolMap[name]
+		if !ok {
+			return fmt.Sprintf("工具 %s 不存在或者当前不可用", name)
+		}
+		if arguments == "" {
+			arguments = "{}"
+		}
+		if !json.Valid([]byte(arguments)) {
+			return fmt.Sprintf("工具 %s 的参数不是有效的 JSON", name)
+		}
+		log.Printf("[MCP工具] 调用 %s 的 %s，参数: %s", tool.server.Name, tool.name, arguments)
+		ctx, cancel := context.WithTimeout(s.ctx, mcpCallToolTimeout)
+		defer cancel()
+		result, err := vars.MCPManager.CallTool(ctx, tool.server, tool.name, json.RawMessage(arguments))
+		if err != nil {
+			log.Printf("[MCP工具] %s 执行失败: %v", name, err)
+			return fmt.Sprintf("执行失败: %v", err)
+		}
+		return result
+	}
+	return tools
+}
+
+// mcpToolName 交给AI的工具名称，加上服务器名称作为前缀，避免和内置工具、其他服务器的工具重名
+func mcpToolName(serverName, toolName string) string {
+	name := mcpToolNameInvalidChars.ReplaceAllString(fmt.Sprintf("%s__%s", serverName, toolName), "_")
+	if len(name) > mcpToolNameMaxLength {
+		name = name[:mcpToolNameMaxLength]
+	}
+	return name
+}
+
+func mcpToolParameters(tool mcp.Tool) json.RawMessage {
+	if len(tool.RawInputSchema) > 0 {
+		return tool.RawInputSchema
+	}
+	schema := tool.InputSchema
+	if schema.Type == "" {
+		schema.Type = "object"
+	}
+	if schema.Properties == nil {
+		schema.Properties = map[string]any{}
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return data
+}
+
+// mergeMCPServers 合并全局配置和群聊配置的MCP服务器，名称相同时使用群聊的配置，Disabled 的服务器不使用
+func mergeMCPServers(globalServers, contactServers datatypes.JSON) []model.MCPServer {
+	var servers []model.MCPServer
+	index := make(map[string]int)
+	for _, data := range []datatypes.JSON{globalServers, contactServers} {
+		for _, server := range parseMCPServers(data) {
+			if i, exists := index[server.Name]; exists {
+				servers[i] = server
+				continue
+			}
+			index[server.Name] = len(servers)
+			servers = append(servers, server)
+		}
+	}
+	result := servers[:0]
+	for _, server := range servers {
+		if !server.Disabled {
+			result = append(result, server)
+		}
+	}
+	return result
+}
+
+func parseMCPServers(data datatypes.JSON) []model.MCPServer {
+	if len(data) == 0 {
+		return nil
+	}
+	var servers []model.MCPServer
+	if err := json.Unmarshal(data, &servers); err != nil {
+		log.Printf("解析MCP服务器配置失败: %v", err)
+		return nil
+	}
+	var result []model.MCPServer
+	for _, server := range servers {
+		if server.Name != "" {
+			result = append(result, server)
+		}
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/datatypes"
+)
+
+func TestMergeMCPServers(t *testing.T) {
+	global := datatypes.JSON(`[{"name":"kb","transport":"http","url":"http://kb/mcp"},{"name":"ticket","transport":"stdio","command":"ticket-mcp"}]`)
+	chatRoom := datatypes.JSON(`[{"name":"kb","transport":"sse","url":"http://kb2/sse"},{"name":"ticket","disabled":true},{"name":"wiki","transport":"http","url":"http://wiki/mcp"}]`)
+	servers := mergeMCPServers(global, chatRoom)
+	var names []string
+	for _, server := range servers {
+		names = append(names, server.Name)
+	}
+	if strings.Join(names, ",") != "kb,wiki" {
+		t.Fatalf("servers = %v, want kb,wiki", names)
+	}
+	if servers[0].URL != "http://kb2/sse" {
+		t.Errorf("kb url = %q, want chat room config", servers[0].URL)
+	}
+	if servers := mergeMCPServers(global, nil); len(servers) != 2 {
+		t.Errorf("global only = %d servers, want 2", len(servers))
+	}
+}
+
+func TestMCPToolName(t *testing.T) {
+	if name := mcpToolName("知识库", "search.docs"); name != "_____search_docs" {
+		t.Errorf("mcpToolName = %q", name)
+	}
+	if name := mcpToolName("kb", strings.Repeat("a", 100)); len(name) != mcpToolNameMaxLength {
+		t.Errorf("len(mcpToolName) = %d, want %d", len(name), mcpToolNameMaxLength)
+	}
+}
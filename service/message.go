@@ -1,8 +1,10 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -15,12 +17,16 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 	"wechat-robot-client/dto"
 	"wechat-robot-client/interface/plugin"
 	"wechat-robot-client/interface/settings"
 	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/messagequeue"
 	"wechat-robot-client/pkg/robot"
+	"wechat-robot-client/plugin/pkg"
 	"wechat-robot-client/repository"
 	"wechat-robot-client/vars"
 
@@ -36,6 +42,8 @@ type MessageService struct {
 	crmRespo        *repository.ChatRoomMember
 	sysmsgRespo     *repository.SystemMessage
 	robotAdminRespo *repository.RobotAdmin
+	// 正在处理的消息，处理过程中机器人发出的消息都是对它的回复
+	replyTo *model.Message
 }
 
 var _ plugin.MessageServiceIface = (*MessageService)(nil)
@@ -59,15 +67,7 @@ func (s *MessageService) ProcessTextMessage(message *model.Message) {
 		MessageContent: message.Content,
 		MessageService: s,
 	}
-	for _, messagePlugin := range vars.MessagePlugin.Plugins {
-		if !slices.Contains(messagePlugin.GetLabels(), "text") {
-			continue
-		}
-		abort := messagePlugin.Run(msgCtx)
-		if abort {
-			return
-		}
-	}
+	vars.MessagePlugin.Dispatch(msgCtx, "text")
 }
 
 // ProcessImageMessage 处理图片消息
@@ -79,30 +79,99 @@ func (s *MessageService) ProcessImageMessage(message *model.Message) {
 		MessageContent: message.Content,
 		MessageService: s,
 	}
-	for _, messagePlugin := range vars.MessagePlugin.Plugins {
-		if !slices.Contains(messagePlugin.GetLabels(), "image") {
-			continue
-		}
-		abort := messagePlugin.Run(msgCtx)
-		if abort {
-			return
-		}
-	}
+	vars.MessagePlugin.Dispatch(msgCtx, "image")
 }
 
-// ProcessVoiceMessage 处理语音消息
+// ProcessVoiceMessage 处理语音消息，开启了语音转文字时先识别出文字再交给插件处理
 func (s *MessageService) ProcessVoiceMessage(message *model.Message) {
+	msgSettings := s.settings
+	if msgSettings == nil || !msgSettings.IsASREnabled() {
+		return
+	}
+	text, err := s.TranscribeVoice(msgSettings.GetAIConfig(), message)
+	if err != nil {
+		log.Printf("语音转文字失败: %v", err)
+		return
+	}
+	if text == "" {
+		return
+	}
+	message.VoiceText = text
+	err = s.msgRespo.Update(&model.Message{ID: message.ID, VoiceText: text})
+	if err != nil {
+		log.Printf("保存语音识别结果失败: %v", err)
+	}
+	msgCtx := &plugin.MessageContext{
+		Context:        s.ctx,
+		Settings:       msgSettings,
+		Message:        message,
+		MessageContent: text,
+		MessageService: s,
+	}
+	vars.MessagePlugin.Dispatch(msgCtx, "voice")
+}
 
+// TranscribeVoice 下载语音消息并识别成文字，没有单独配置接口地址和密钥时使用AI聊天的配置
+func (s *MessageService) TranscribeVoice(aiConfig settings.AIConfig, message *model.Message) (string, error) {
+	var asrConfig pkg.ASRConfig
+	if aiConfig.ASRSettings != nil {
+		if err := json.Unmarshal(aiConfig.ASRSettings, &asrConfig); err != nil {
+			return "", fmt.Errorf("反序列化语音转文字配置失败: %w", err)
+		}
+	}
+	if asrConfig.BaseURL == "" {
+		asrConfig.BaseURL = aiConfig.BaseURL
+		if asrConfig.APIKey == "" {
+			asrConfig.APIKey = aiConfig.APIKey
+		}
+	}
+	asr, err := pkg.NewASR(asrConfig)
+	if err != nil {
+		return "", err
+	}
+	voice, _, ext, err := vars.RobotRuntime.DownloadVoice(s.ctx, *message)
+	if err != nil {
+		return "", fmt.Errorf("下载语音失败: %w", err)
+	}
+	return asr.Transcribe(s.ctx, bytes.NewReader(voice), fmt.Sprintf("%d%s", message.MsgId, ext))
 }
 
 // ProcessVideoMessage 处理视频消息
 func (s *MessageService) ProcessVideoMessage(message *model.Message) {
-
+	var videoXml robot.VideoMessageXml
+	err := vars.RobotRuntime.XmlDecoder(message.Content, &videoXml)
+	if err != nil {
+		log.Printf("解析视频消息失败: %v", err)
+		return
+	}
+	msgCtx := &plugin.MessageContext{
+		Context:        s.ctx,
+		Settings:       s.settings,
+		Message:        message,
+		MessageContent: message.Content,
+		MessageService: s,
+		Video:          &videoXml.VideoMsg,
+	}
+	vars.MessagePlugin.Dispatch(msgCtx, "video")
 }
 
 // ProcessEmojiMessage 处理表情消息
 func (s *MessageService) ProcessEmojiMessage(message *model.Message) {
-
+	var emojiXml robot.EmojiMessageXml
+	err := vars.RobotRuntime.XmlDecoder(message.Content, &emojiXml)
+	if err != nil {
+		log.Printf("解析表情消息失败: %v", err)
+		return
+	}
+	msgCtx := &plugin.MessageContext{
+		Context:        s.ctx,
+		Settings:       s.settings,
+		Message:        message,
+		MessageContent: message.Content,
+		MessageService: s,
+		Emoji:          &emojiXml.Emoji,
+	}
+	vars.MessagePlugin.Dispatch(msgCtx, "emoji")
 }
 
 // ProcessReferMessage 处理引用消息
@@ -135,15 +204,7 @@ func (s *MessageService) ProcessReferMessage(message *model.Message) {
 		ReferMessage:   referMessage,
 		MessageService: s,
 	}
-	for _, messagePlugin := range vars.MessagePlugin.Plugins {
-		if !slices.Contains(messagePlugin.GetLabels(), "text") {
-			continue
-		}
-		abort := messagePlugin.Run(msgCtx)
-		if abort {
-			return
-		}
-	}
+	vars.MessagePlugin.Dispatch(msgCtx, "text")
 }
 
 // ProcessAppMessage 处理应用消息
@@ -188,13 +249,39 @@ func (s *MessageService) ProcessAppMessage(message *model.Message) {
 			}
 			return
 		}
+		// 链接卡片的标题、描述和链接，每行一个
+		content := strings.Join(slices.DeleteFunc([]string{xmlMessage.AppMsg.Title, xmlMessage.AppMsg.Des, xmlMessage.AppMsg.URL}, func(line string) bool {
+			return line == ""
+		}), "\n")
+		msgCtx := &plugin.MessageContext{
+			Context:        s.ctx,
+			Settings:       s.settings,
+			Message:        message,
+			MessageContent: content,
+			MessageService: s,
+		}
+		vars.MessagePlugin.Dispatch(msgCtx, "link")
 		return
 	}
 }
 
 // ProcessShareCardMessage 处理分享名片消息
 func (s *MessageService) ProcessShareCardMessage(message *model.Message) {
-
+	var cardXml robot.ShareCardMessageXml
+	err := vars.RobotRuntime.XmlDecoder(message.Content, &cardXml)
+	if err != nil {
+		log.Printf("解析名片消息失败: %v", err)
+		return
+	}
+	msgCtx := &plugin.MessageContext{
+		Context:        s.ctx,
+		Settings:       s.settings,
+		Message:        message,
+		MessageContent: cardXml.Nickname,
+		MessageService: s,
+		ShareCard:      &cardXml,
+	}
+	vars.MessagePlugin.Dispatch(msgCtx, "share_card")
 }
 
 // ProcessFriendVerifyMessage 处理好友添加请求通知消息
@@ -281,14 +368,7 @@ func (s *MessageService) ProcessPatMessage(message *model.Message, msgXml robot.
 		Pat:            message.IsChatRoom && msgXml.Pat.PattedUsername == vars.RobotRuntime.WxID,
 		MessageService: s,
 	}
-	for _, messagePlugin := range vars.MessagePlugin.Plugins {
-		if slices.Contains(messagePlugin.GetLabels(), "pat") {
-			abort := messagePlugin.Run(msgCtx)
-			if abort {
-				return
-			}
-		}
-	}
+	vars.MessagePlugin.Dispatch(msgCtx, "pat")
 }
 
 func (s *MessageService) ProcessNewChatRoomMemberMessage(message *model.Message, msgXml robot.SystemMessage) {
@@ -404,12 +484,38 @@ func (s *MessageService) ProcessSystemMessage(message *model.Message) {
 
 // ProcessLocationMessage 处理位置消息
 func (s *MessageService) ProcessLocationMessage(message *model.Message) {
-
+	var locationXml robot.LocationMessageXml
+	err := vars.RobotRuntime.XmlDecoder(message.Content, &locationXml)
+	if err != nil {
+		log.Printf("解析位置消息失败: %v", err)
+		return
+	}
+	// 插件直接拿地点名称去查询天气、周边等
+	content := locationXml.Location.PoiName
+	if content == "" {
+		content = locationXml.Location.Label
+	}
+	msgCtx := &plugin.MessageContext{
+		Context:        s.ctx,
+		Settings:       s.settings,
+		Message:        message,
+		MessageContent: content,
+		MessageService: s,
+		Location:       &locationXml.Location,
+	}
+	vars.MessagePlugin.Dispatch(msgCtx, "location")
 }
 
-// ProcessPromptMessage 处理提示消息
+// ProcessPromptMessage 处理提示消息，例如 "你已添加了xxx，现在可以开始聊天了。"
 func (s *MessageService) ProcessPromptMessage(message *model.Message) {
-
+	msgCtx := &plugin.MessageContext{
+		Context:        s.ctx,
+		Settings:       s.settings,
+		Message:        message,
+		MessageContent: message.Content,
+		MessageService: s,
+	}
+	vars.MessagePlugin.Dispatch(msgCtx, "prompt")
 }
 
 func (s *MessageService) ProcessMessageSender(message *model.Message) {
@@ -497,7 +603,69 @@ func (s *MessageService) InitSettingsByMessage(message *model.Message) (settings
 	return
 }
 
-func (s *MessageService) ProcessMessage(syncResp robot.SyncMessage) {
+// processMessage 排重、入库，然后按消息类型分发给插件，在消息处理协程池中执行
+// 消息还没有入库时返回错误，由消息队列重新投递；入库之后插件的处理结果不影响返回值，避免重复回复
+func (s *MessageService) processMessage(m *model.Message, msgSource string) error {
+	// 同一条消息可能会重复推送，回调和轮询收到的消息都要排重，避免插件重复处理
+	if !s.markMessageReceived(m.MsgId) {
+		s.dropDuplicateMessage(m)
+		return nil
+	}
+	s.ProcessMentionedMeMessage(m, msgSource)
+	settings := s.InitSettingsByMessage(m)
+	if settings == nil {
+		s.unmarkMessageReceived(m.MsgId)
+		return fmt.Errorf("消息 %d 初始化设置失败", m.MsgId)
+	}
+	s.settings = settings
+	s.replyTo = m
+	if err := s.msgRespo.Create(m); err != nil {
+		// 重复的消息已经丢弃时返回 nil，插件不再处理
+		return s.handleCreateError(m, err)
+	}
+	switch m.Type {
+	case model.MsgTypeText:
+		s.ProcessTextMessage(m)
+	case model.MsgTypeImage:
+		s.ProcessImageMessage(m)
+	case model.MsgTypeVoice:
+		s.ProcessVoiceMessage(m)
+	case model.MsgTypeVideo, model.MsgTypeMicroVideo:
+		s.ProcessVideoMessage(m)
+	case model.MsgTypeEmoticon:
+		s.ProcessEmojiMessage(m)
+	case model.MsgTypeApp:
+		s.ProcessAppMessage(m)
+	case model.MsgTypeShareCard:
+		s.ProcessShareCardMessage(m)
+	case model.MsgTypeVerify:
+		// 好友添加请求通知消息
+		s.ProcessFriendVerifyMessage(m)
+	case model.MsgTypeSystem:
+		s.ProcessSystemMessage(m)
+	case model.MsgTypeLocation:
+		s.ProcessLocationMessage(m)
+	case model.MsgTypePrompt:
+		s.ProcessPromptMessage(m)
+	default:
+		// 未知消息类型
+		log.Printf("未知消息类型: %d, 内容: %s", m.Type, m.Content)
+	}
+	// 插入一条联系人记录，获取联系人列表接口获取不到未保存到通讯录的群聊
+	NewContactService(s.ctx).InsertOrUpdateContactActiveTime(m.FromWxID)
+	return nil
+}
+
+// ProcessMessage 把消息提交到消息处理协程池，返回的 wait 等待所有消息处理完成，返回没有处理成功的消息的错误
+func (s *MessageService) ProcessMessage(syncResp robot.SyncMessage) (wait func() error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	addErr := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
 	for _, message := range syncResp.AddMsgs {
 		m := model.Message{
 			MsgId:              message.NewMsgId,
@@ -515,49 +683,20 @@ func (s *MessageService) ProcessMessage(syncResp robot.SyncMessage) {
 		if !s.ProcessMessageShouldInsertToDB(&m) {
 			continue
 		}
-		s.ProcessMentionedMeMessage(&m, message.MsgSource)
-		settings := s.InitSettingsByMessage(&m)
-		if settings == nil {
-			continue
-		}
-		s.settings = settings
-		err := s.msgRespo.Create(&m)
+		msgSource := message.MsgSource
+		// 同一个聊天的消息按顺序处理，不同聊天的消息并行处理；回调请求结束后 gin 的上下文会被复用，所以不能沿用 s.ctx
+		wg.Add(1)
+		err := vars.MessageWorkerPool.Submit(m.FromWxID, func() {
+			defer wg.Done()
+			if err := NewMessageService(context.Background()).processMessage(&m, msgSource); err != nil {
+				addErr(err)
+			}
+		})
 		if err != nil {
-			log.Printf("入库消息失败: %v", err)
-			continue
-		}
-		switch m.Type {
-		case model.MsgTypeText:
-			go s.ProcessTextMessage(&m)
-		case model.MsgTypeImage:
-			go s.ProcessImageMessage(&m)
-		case model.MsgTypeVoice:
-			go s.ProcessVoiceMessage(&m)
-		case model.MsgTypeVideo:
-			go s.ProcessVideoMessage(&m)
-		case model.MsgTypeEmoticon:
-			go s.ProcessEmojiMessage(&m)
-		case model.MsgTypeApp:
-			go s.ProcessAppMessage(&m)
-		case model.MsgTypeShareCard:
-			go s.ProcessShareCardMessage(&m)
-		case model.MsgTypeVerify:
-			// 好友添加请求通知消息
-			go s.ProcessFriendVerifyMessage(&m)
-		case model.MsgTypeSystem:
-			go s.ProcessSystemMessage(&m)
-		case model.MsgTypeLocation:
-			go s.ProcessLocationMessage(&m)
-		case model.MsgTypePrompt:
-			go s.ProcessPromptMessage(&m)
-		default:
-			// 未知消息类型
-			log.Printf("未知消息类型: %d, 内容: %s", m.Type, m.Content)
+			wg.Done()
+			log.Printf("提交消息处理任务失败: %v", err)
+			addErr(err)
 		}
-		go func() {
-			// 插入一条联系人记录，获取联系人列表接口获取不到未保存到通讯录的群聊
-			NewContactService(s.ctx).InsertOrUpdateContactActiveTime(m.FromWxID)
-		}()
 	}
 	for _, contact := range syncResp.ModContacts {
 		if contact.UserName.String != nil {
@@ -578,6 +717,42 @@ func (s *MessageService) ProcessMessage(syncResp robot.SyncMessage) {
 			}
 		}
 	}
+	return func() error {
+		wg.Wait()
+		return errors.Join(errs...)
+	}
+}
+
+// EnqueueSyncMessage 把收到的消息写入消息队列，由消息队列的消费者处理，客户端崩溃或者AI回复太慢都不会丢消息
+func (s *MessageService) EnqueueSyncMessage(syncResp robot.SyncMessage) error {
+	payload, err := json.Marshal(syncResp)
+	if err != nil {
+		return err
+	}
+	return vars.MessageQueue.Publish(s.ctx, payload)
+}
+
+// ConsumeSyncMessage 处理消息队列中的消息，消息全部处理完成后确认，没有入库的消息会重新投递
+func (s *MessageService) ConsumeSyncMessage(msg *messagequeue.Message) {
+	var syncResp robot.SyncMessage
+	if err := json.Unmarshal(msg.Payload, &syncResp); err != nil {
+		msg.Nack(errors.Join(messagequeue.ErrNonRetryable, err))
+		return
+	}
+	// 在当前协程中提交，保证同一个聊天的消息按队列中的顺序处理
+	wait := s.ProcessMessage(syncResp)
+	// 确认协程由消费者跟踪，退出时等待消息确认完成后才关闭队列
+	msg.Go(func() {
+		var err error
+		if processErr := wait(); processErr != nil {
+			err = msg.Nack(processErr)
+		} else {
+			err = msg.Ack()
+		}
+		if err != nil {
+			log.Printf("确认消息 %s 失败: %v", msg.ID, err)
+		}
+	})
 }
 
 func (s *MessageService) SyncMessage() {
@@ -592,7 +767,10 @@ func (s *MessageService) SyncMessage() {
 		// 没有消息，直接返回
 		return
 	}
-	s.ProcessMessage(syncResp)
+	if err := s.EnqueueSyncMessage(syncResp); err != nil {
+		log.Printf("消息写入消息队列失败，直接处理: %v", err)
+		s.ProcessMessage(syncResp)
+	}
 }
 
 func (s *MessageService) XmlDecoder(content string) (robot.XmlMessage, error) {
@@ -667,7 +845,11 @@ func (s *MessageService) SendTextMessage(toWxID, content string, at ...string) e
 		}
 	}
 	content = atContent + content
-	newMessages, err := vars.RobotRuntime.SendTextMessage(toWxID, content, at...)
+	var newMessages robot.SendTextMessageResponse
+	err := s.sendMessage(toWxID, model.MsgTypeText, content, func() (err error) {
+		newMessages, err = vars.RobotRuntime.SendTextMessage(toWxID, content, at...)
+		return
+	})
 	if err != nil {
 		return err
 	}
@@ -693,7 +875,7 @@ func (s *MessageService) SendTextMessage(toWxID, content string, at ...string) e
 				if m.IsChatRoom && len(at) > 0 {
 					m.ReplyWxID = at[0]
 				}
-				err = s.msgRespo.Create(&m)
+				err = s.createSentMessage(&m)
 				if err != nil {
 					log.Printf("入库消息失败: %v", err)
 				}
@@ -706,6 +888,14 @@ func (s *MessageService) SendTextMessage(toWxID, content string, at ...string) e
 	return nil
 }
 
+// createSentMessage 保存机器人发送的消息，处理群聊消息时发出的消息(包括流式回复的后续分段、图片和语音)都记录为回复触发消息的成员
+func (s *MessageService) createSentMessage(m *model.Message) error {
+	if m.ReplyWxID == "" && s.replyTo != nil && s.replyTo.IsChatRoom && m.FromWxID == s.replyTo.FromWxID {
+		m.ReplyWxID = s.replyTo.SenderWxID
+	}
+	return s.msgRespo.Create(m)
+}
+
 // MsgSendGroupMassMsgText 文本消息群发接口
 func (s *MessageService) MsgSendGroupMassMsgText(toWxID []string, content string) error {
 	_, err := vars.RobotRuntime.MsgSendGroupMassMsgText(robot.MsgSendGroupMassMsgTextRequest{
@@ -723,7 +913,11 @@ func (s *MessageService) MsgUploadImg(toWxID string, image io.Reader) (*model.Me
 	if err != nil {
 		return nil, fmt.Errorf("读取文件内容失败: %w", err)
 	}
-	message, err := vars.RobotRuntime.MsgUploadImg(toWxID, imageBytes)
+	var message robot.MsgUploadImgResponse
+	err = s.sendMessage(toWxID, model.MsgTypeImage, "", func() (err error) {
+		message, err = vars.RobotRuntime.MsgUploadImg(toWxID, imageBytes)
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -742,7 +936,7 @@ func (s *MessageService) MsgUploadImg(toWxID string, image io.Reader) (*model.Me
 		CreatedAt:          message.CreateTime,
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -757,7 +951,10 @@ func (s *MessageService) MsgSendVideo(toWxID string, video io.Reader, videoExt s
 	if err != nil {
 		return fmt.Errorf("读取文件内容失败: %w", err)
 	}
-	_, err = vars.RobotRuntime.MsgSendVideo(toWxID, videoBytes, videoExt)
+	err = s.sendMessage(toWxID, model.MsgTypeVideo, "", func() (err error) {
+		_, err = vars.RobotRuntime.MsgSendVideo(toWxID, videoBytes, videoExt)
+		return
+	})
 	if err != nil {
 		return err
 	}
@@ -777,7 +974,7 @@ func (s *MessageService) MsgSendVideo(toWxID string, video io.Reader, videoExt s
 		CreatedAt:          time.Now().Unix(),
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -792,7 +989,11 @@ func (s *MessageService) MsgSendVoice(toWxID string, voice io.Reader, voiceExt s
 	if err != nil {
 		return fmt.Errorf("读取文件内容失败: %w", err)
 	}
-	message, err := vars.RobotRuntime.MsgSendVoice(toWxID, videoBytes, voiceExt)
+	var message robot.MsgSendVoiceResponse
+	err = s.sendMessage(toWxID, model.MsgTypeVoice, "", func() (err error) {
+		message, err = vars.RobotRuntime.MsgSendVoice(toWxID, videoBytes, voiceExt)
+		return
+	})
 	if err != nil {
 		return err
 	}
@@ -812,7 +1013,7 @@ func (s *MessageService) MsgSendVoice(toWxID string, voice io.Reader, voiceExt s
 		CreatedAt:          message.CreateTime,
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -830,14 +1031,30 @@ func (s *MessageService) SendLongTextMessage(toWxID string, longText string) err
 	if currentRobot == nil || currentRobot.Nickname == nil {
 		return fmt.Errorf("未找到机器人信息")
 	}
+	nickname := *currentRobot.Nickname
+	var avatar string
+	if currentRobot.Avatar != nil {
+		avatar = *currentRobot.Avatar
+	}
+	// 当前聊天使用了AI角色时，以角色的名称和头像发送
+	persona, err := NewAIPersonaService(s.ctx).GetContactPersona(toWxID)
+	if err != nil {
+		log.Printf("获取AI角色失败: %v", err)
+	}
+	if persona != nil {
+		nickname = persona.Name
+		if persona.Avatar != "" {
+			avatar = persona.Avatar
+		}
+	}
 
 	dataID := uuid.New().String()
 	fiveMinuteAgo := time.Now().Add(-5 * time.Minute)
 
 	recordInfo := robot.RecordInfo{
-		Info:       fmt.Sprintf("%s: %s", *currentRobot.Nickname, longText),
+		Info:       fmt.Sprintf("%s: %s", nickname, longText),
 		IsChatRoom: 1,
-		Desc:       fmt.Sprintf("%s: %s", *currentRobot.Nickname, longText),
+		Desc:       fmt.Sprintf("%s: %s", nickname, longText),
 		FromScene:  3,
 		DataList: robot.DataList{
 			Count: 1,
@@ -853,8 +1070,8 @@ func (s *MessageService) SendLongTextMessage(toWxID string, longText string) err
 					DataItemSource: &robot.DataItemSource{
 						HashUsername: fmt.Sprintf("%x", sha256.Sum256([]byte(vars.RobotRuntime.WxID))),
 					},
-					SourceName:    *currentRobot.Nickname,
-					SourceHeadURL: *currentRobot.Avatar,
+					SourceName:    nickname,
+					SourceHeadURL: avatar,
 				},
 			},
 		},
@@ -872,13 +1089,17 @@ func (s *MessageService) SendLongTextMessage(toWxID string, longText string) err
 			Title:  "群聊的聊天记录",
 			Type:   19,
 			URL:    "https://support.weixin.qq.com/cgi-bin/mmsupport-bin/readtemplate?t=page/favorite_record__w_unsupport",
-			Des:    fmt.Sprintf("%s: %s", *currentRobot.Nickname, longText),
+			Des:    fmt.Sprintf("%s: %s", nickname, longText),
 			RecordItem: robot.ChatHistoryRecordItem{XML: fmt.Sprintf(`<![CDATA[
 %s
 ]]>`, string(recordInfoBytes))},
 		},
 	}
-	message, err := vars.RobotRuntime.SendChatHistoryMessage(toWxID, newMsg)
+	var message robot.SendAppResponse
+	err = s.sendMessage(toWxID, model.MsgTypeApp, longText, func() (err error) {
+		message, err = vars.RobotRuntime.SendChatHistoryMessage(toWxID, newMsg)
+		return
+	})
 	if err != nil {
 		return err
 	}
@@ -897,7 +1118,7 @@ func (s *MessageService) SendLongTextMessage(toWxID string, longText string) err
 		CreatedAt:          message.CreateTime,
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -939,7 +1160,11 @@ func (s *MessageService) SendMusicMessage(toWxID string, songTitle string) error
 		songInfo.Lyric = *result.Lrc
 	}
 
-	message, err := vars.RobotRuntime.SendMusicMessage(toWxID, songInfo)
+	var message robot.SendAppResponse
+	err = s.sendMessage(toWxID, model.MsgTypeApp, songTitle, func() (err error) {
+		message, err = vars.RobotRuntime.SendMusicMessage(toWxID, songInfo)
+		return
+	})
 	if err != nil {
 		return err
 	}
@@ -958,7 +1183,7 @@ func (s *MessageService) SendMusicMessage(toWxID string, songTitle string) error
 		CreatedAt:          message.CreateTime,
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -1002,7 +1227,7 @@ func (s *MessageService) SendFileMessage(ctx context.Context, req dto.SendFileMe
 		CreatedAt:          message.CreateTime,
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -1013,10 +1238,14 @@ func (s *MessageService) SendFileMessage(ctx context.Context, req dto.SendFileMe
 }
 
 func (s *MessageService) SendEmoji(toWxID string, md5 string, totalLen int32) error {
-	message, err := vars.RobotRuntime.SendEmoji(robot.SendEmojiRequest{
-		ToWxid:   toWxID,
-		Md5:      md5,
-		TotalLen: totalLen,
+	var message robot.SendEmojiResponse
+	err := s.sendMessage(toWxID, model.MsgTypeEmoticon, md5, func() (err error) {
+		message, err = vars.RobotRuntime.SendEmoji(robot.SendEmojiRequest{
+			ToWxid:   toWxID,
+			Md5:      md5,
+			TotalLen: totalLen,
+		})
+		return
 	})
 	if err != nil {
 		return err
@@ -1040,7 +1269,7 @@ func (s *MessageService) SendEmoji(toWxID string, md5 string, totalLen int32) er
 			CreatedAt:          time.Now().Unix(),
 			UpdatedAt:          time.Now().Unix(),
 		}
-		err = s.msgRespo.Create(&m)
+		err = s.createSentMessage(&m)
 		if err != nil {
 			log.Println("入库消息失败: ", err)
 		}
@@ -1052,7 +1281,12 @@ func (s *MessageService) SendEmoji(toWxID string, md5 string, totalLen int32) er
 }
 
 func (s *MessageService) ShareLink(toWxID string, shareLinkInfo robot.ShareLinkMessage) error {
-	message, xmlStr, err := vars.RobotRuntime.ShareLink(toWxID, shareLinkInfo)
+	var message robot.ShareLinkResponse
+	var xmlStr string
+	err := s.sendMessage(toWxID, model.MsgTypeApp, shareLinkInfo.Title, func() (err error) {
+		message, xmlStr, err = vars.RobotRuntime.ShareLink(toWxID, shareLinkInfo)
+		return
+	})
 	if err != nil {
 		return err
 	}
@@ -1070,7 +1304,7 @@ func (s *MessageService) ShareLink(toWxID string, shareLinkInfo robot.ShareLinkM
 		CreatedAt:          message.CreateTime,
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -1080,9 +1314,13 @@ func (s *MessageService) ShareLink(toWxID string, shareLinkInfo robot.ShareLinkM
 }
 
 func (s *MessageService) SendCDNFile(toWxID string, content string) error {
-	message, err := vars.RobotRuntime.SendCDNFile(robot.SendCDNAttachmentRequest{
-		ToWxid:  toWxID,
-		Content: content,
+	var message robot.SendCDNFileResponse
+	err := s.sendMessage(toWxID, model.MsgTypeApp, content, func() (err error) {
+		message, err = vars.RobotRuntime.SendCDNFile(robot.SendCDNAttachmentRequest{
+			ToWxid:  toWxID,
+			Content: content,
+		})
+		return
 	})
 	if err != nil {
 		return err
@@ -1102,7 +1340,7 @@ func (s *MessageService) SendCDNFile(toWxID string, content string) error {
 		CreatedAt:          message.CreateTime,
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -1113,9 +1351,13 @@ func (s *MessageService) SendCDNFile(toWxID string, content string) error {
 }
 
 func (s *MessageService) SendCDNImg(toWxID string, content string) error {
-	message, err := vars.RobotRuntime.SendCDNImg(robot.SendCDNAttachmentRequest{
-		ToWxid:  toWxID,
-		Content: content,
+	var message robot.SendCDNImgResponse
+	err := s.sendMessage(toWxID, model.MsgTypeImage, content, func() (err error) {
+		message, err = vars.RobotRuntime.SendCDNImg(robot.SendCDNAttachmentRequest{
+			ToWxid:  toWxID,
+			Content: content,
+		})
+		return
 	})
 	if err != nil {
 		return err
@@ -1135,7 +1377,7 @@ func (s *MessageService) SendCDNImg(toWxID string, content string) error {
 		CreatedAt:          message.CreateTime,
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -1146,9 +1388,13 @@ func (s *MessageService) SendCDNImg(toWxID string, content string) error {
 }
 
 func (s *MessageService) SendCDNVideo(toWxID string, content string) error {
-	message, err := vars.RobotRuntime.SendCDNVideo(robot.SendCDNAttachmentRequest{
-		ToWxid:  toWxID,
-		Content: content,
+	var message robot.SendCDNVideoResponse
+	err := s.sendMessage(toWxID, model.MsgTypeVideo, content, func() (err error) {
+		message, err = vars.RobotRuntime.SendCDNVideo(robot.SendCDNAttachmentRequest{
+			ToWxid:  toWxID,
+			Content: content,
+		})
+		return
 	})
 	if err != nil {
 		return err
@@ -1168,7 +1414,7 @@ func (s *MessageService) SendCDNVideo(toWxID string, content string) error {
 		CreatedAt:          time.Now().Unix(),
 		UpdatedAt:          time.Now().Unix(),
 	}
-	err = s.msgRespo.Create(&m)
+	err = s.createSentMessage(&m)
 	if err != nil {
 		log.Println("入库消息失败: ", err)
 	}
@@ -1191,6 +1437,9 @@ func (s *MessageService) ProcessAIMessageContext(messages []*model.Message) []op
 		if msg.Type == model.MsgTypeText {
 			aiMessage.Content = re.ReplaceAllString(msg.Content, "")
 		}
+		if msg.Type == model.MsgTypeVoice {
+			aiMessage.Content = msg.VoiceText
+		}
 		if msg.Type == model.MsgTypeImage {
 			aiMessage.MultiContent = []openai.ChatMessagePart{
 				{
@@ -1350,20 +1599,99 @@ func (s *MessageService) GetLastMonthChatRommRank(chatRoomID string) ([]*dto.Cha
 	return s.msgRespo.GetLastMonthChatRommRank(vars.RobotRuntime.WxID, chatRoomID)
 }
 
-func (s *MessageService) ChatRoomAIDisabled(chatRoomID string) error {
+// updateChatRoomSettings 修改群聊配置，群聊还没有单独的配置时返回错误，避免新建的配置覆盖全局配置
+func (s *MessageService) updateChatRoomSettings(chatRoomID string, update func(chatRoomSettings *model.ChatRoomSettings)) error {
 	chatRoomSettingsSvc := NewChatRoomSettingsService(s.ctx)
 	chatRoomSettings, err := chatRoomSettingsSvc.GetChatRoomSettings(chatRoomID)
 	if err != nil {
 		return err
 	}
-	if chatRoomSettings == nil || chatRoomSettings.ChatAIEnabled == nil || !*chatRoomSettings.ChatAIEnabled {
-		return nil
+	if chatRoomSettings == nil {
+		return fmt.Errorf("当前群聊还没有单独的配置，请先在管理后台保存群聊配置")
+	}
+	update(chatRoomSettings)
+	return chatRoomSettingsSvc.SaveChatRoomSettings(chatRoomSettings)
+}
+
+func (s *MessageService) SetChatRoomAIEnabled(chatRoomID string, enabled bool) error {
+	return s.updateChatRoomSettings(chatRoomID, func(chatRoomSettings *model.ChatRoomSettings) {
+		chatRoomSettings.ChatAIEnabled = &enabled
+	})
+}
+
+func (s *MessageService) SetChatRoomAITrigger(chatRoomID, trigger string) error {
+	if utf8.RuneCountInString(trigger) > 20 {
+		return fmt.Errorf("触发词不能超过20个字符")
 	}
-	disabled := false
-	chatRoomSettings.ChatAIEnabled = &disabled
-	err = chatRoomSettingsSvc.SaveChatRoomSettings(chatRoomSettings)
+	return s.updateChatRoomSettings(chatRoomID, func(chatRoomSettings *model.ChatRoomSettings) {
+		chatRoomSettings.ChatAITrigger = &trigger
+	})
+}
+
+func (s *MessageService) SetChatRoomWelcomeEnabled(chatRoomID string, enabled bool) error {
+	return s.updateChatRoomSettings(chatRoomID, func(chatRoomSettings *model.ChatRoomSettings) {
+		chatRoomSettings.WelcomeEnabled = &enabled
+	})
+}
+
+// UpdatePluginConfig 将部分字段合并到插件在指定联系人下的配置中
+func (s *MessageService) UpdatePluginConfig(pluginName, contactID string, fields any) error {
+	return NewPluginService(s.ctx).UpdatePluginConfig(pluginName, contactID, fields)
+}
+
+// GetSenderNickname 获取消息发送者的昵称，群聊优先取群备注
+func (s *MessageService) GetSenderNickname(message *model.Message) string {
+	if message.IsChatRoom {
+		chatRoomMember, err := s.crmRespo.GetChatRoomMember(message.FromWxID, message.SenderWxID)
+		if err != nil || chatRoomMember == nil {
+			return ""
+		}
+		if chatRoomMember.Remark != "" {
+			return chatRoomMember.Remark
+		}
+		return chatRoomMember.Nickname
+	}
+	contact, err := repository.NewContactRepo(s.ctx, vars.DB).GetByWechatID(message.SenderWxID)
+	if err != nil || contact == nil || contact.Nickname == nil {
+		return ""
+	}
+	return *contact.Nickname
+}
+
+// GetSenderRole 获取消息发送者在当前聊天中的角色
+func (s *MessageService) GetSenderRole(message *model.Message) plugin.Role {
+	if message.SenderWxID == vars.RobotRuntime.WxID {
+		return plugin.RoleOwner
+	}
+	systemSettings, err := repository.NewSystemSettingsRepo(s.ctx, vars.DB).GetSystemSettings()
 	if err != nil {
-		return err
+		log.Printf("获取系统设置失败: %v", err)
 	}
-	return nil
+	if systemSettings != nil && len(systemSettings.SuperAdmins) > 0 {
+		var superAdmins []string
+		if err := json.Unmarshal(systemSettings.SuperAdmins, &superAdmins); err != nil {
+			log.Printf("解析超级管理员失败: %v", err)
+		}
+		if slices.Contains(superAdmins, message.SenderWxID) {
+			return plugin.RoleSuperAdmin
+		}
+	}
+	if !message.IsChatRoom {
+		return plugin.RoleMember
+	}
+	chatRoom, err := repository.NewContactRepo(s.ctx, vars.DB).GetByWechatID(message.FromWxID)
+	if err != nil {
+		log.Printf("获取群聊信息失败: %v", err)
+	}
+	if chatRoom != nil && chatRoom.ChatRoomOwner != "" && chatRoom.ChatRoomOwner == message.SenderWxID {
+		return plugin.RoleChatRoomOwner
+	}
+	chatRoomMember, err := s.crmRespo.GetChatRoomMember(message.FromWxID, message.SenderWxID)
+	if err != nil {
+		log.Printf("获取群成员信息失败: %v", err)
+	}
+	if chatRoomMember != nil && chatRoomMember.IsAdmin {
+		return plugin.RoleChatRoomAdmin
+	}
+	return plugin.RoleMember
 }
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/model"
+	"wechat-robot-client/vars"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	messageDedupKeyPrefix  = "message_dedup"
+	messageDedupDroppedKey = "message_dedup:dropped"
+	// 同一条消息重复推送一般发生在服务重启、同步历史消息、失败重试时，一天足够覆盖
+	messageDedupTTL = 24 * time.Hour
+)
+
+// markMessageReceived 记录收到的消息，同一条消息第一次收到时返回 true
+// Redis 出错时放行，由 messages.msg_id 的唯一索引兜底
+func (s *MessageService) markMessageReceived(msgID int64) bool {
+	if msgID == 0 {
+		return true
+	}
+	ok, err := vars.RedisClient.SetNX(s.ctx, fmt.Sprintf("%s:%d", messageDedupKeyPrefix, msgID), 1, messageDedupTTL).Result()
+	if err != nil {
+		log.Printf("消息排重失败: %v", err)
+		return true
+	}
+	return ok
+}
+
+// unmarkMessageReceived 消息入库失败时清除记录，让重试推送的消息可以再次处理
+func (s *MessageService) unmarkMessageReceived(msgID int64) {
+	if msgID == 0 {
+		return
+	}
+	err := vars.RedisClient.Del(s.ctx, fmt.Sprintf("%s:%d", messageDedupKeyPrefix, msgID)).Err()
+	if err != nil {
+		log.Printf("清除消息排重记录失败: %v", err)
+	}
+}
+
+// dropDuplicateMessage 丢弃重复推送的消息并计数
+// 机器人自己发送的消息会在发送时入库，之后同步回来时也会命中唯一索引，这类消息不计入重复消息数量
+func (s *MessageService) dropDuplicateMessage(m *model.Message) {
+	if m.SenderWxID == vars.RobotRuntime.WxID {
+		return
+	}
+	log.Printf("丢弃重复的消息: %d", m.MsgId)
+	err := vars.RedisClient.Incr(s.ctx, messageDedupDroppedKey).Err()
+	if err != nil {
+		log.Printf("记录重复消息数量失败: %v", err)
+	}
+}
+
+// handleCreateError 处理消息入库失败，命中 messages.msg_id 唯一索引的消息按重复消息丢弃并返回 nil，
+// 其他错误清除排重记录后返回，由消息队列重新投递
+func (s *MessageService) handleCreateError(m *model.Message, err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		s.dropDuplicateMessage(m)
+		return nil
+	}
+	s.unmarkMessageReceived(m.MsgId)
+	log.Printf("入库消息失败: %v", err)
+	return fmt.Errorf("消息 %d 入库失败: %w", m.MsgId, err)
+}
+
+// GetMessageDedupStats 获取消息排重统计
+func (s *MessageService) GetMessageDedupStats() (*dto.MessageDedupStats, error) {
+	dropped, err := vars.RedisClient.Get(s.ctx, messageDedupDroppedKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	return &dto.MessageDedupStats{Dropped: dropped}, nil
+}
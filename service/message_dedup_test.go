@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"wechat-robot-client/model"
+	"wechat-robot-client/vars"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// setupTestRedis 使用 miniredis 替换 vars.RedisClient，测试结束后恢复
+func setupTestRedis(t *testing.T) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	previous := vars.RedisClient
+	vars.RedisClient = client
+	t.Cleanup(func() {
+		vars.RedisClient = previous
+		client.Close()
+	})
+}
+
+func TestMarkMessageReceived(t *testing.T) {
+	setupTestRedis(t)
+	s := &MessageService{ctx: context.Background()}
+
+	if !s.markMessageReceived(1001) {
+		t.Fatal("first delivery should be accepted")
+	}
+	if s.markMessageReceived(1001) {
+		t.Fatal("duplicate delivery should be rejected")
+	}
+	// 入库失败后清除记录，重试推送的消息可以再次处理
+	s.unmarkMessageReceived(1001)
+	if !s.markMessageReceived(1001) {
+		t.Fatal("delivery after unmark should be accepted")
+	}
+	// 没有消息ID的消息不排重
+	if !s.markMessageReceived(0) || !s.markMessageReceived(0) {
+		t.Fatal("messages without id should always be accepted")
+	}
+}
+
+func TestHandleCreateError(t *testing.T) {
+	setupTestRedis(t)
+	previousWxID := vars.RobotRuntime.WxID
+	vars.RobotRuntime.WxID = "wxid_robot"
+	t.Cleanup(func() { vars.RobotRuntime.WxID = previousWxID })
+	s := &MessageService{ctx: context.Background()}
+
+	// 命中唯一索引的消息按重复消息丢弃
+	received := &model.Message{MsgId: 2001, SenderWxID: "wxid_a"}
+	s.markMessageReceived(received.MsgId)
+	if err := s.handleCreateError(received, gorm.ErrDuplicatedKey); err != nil {
+		t.Fatalf("duplicate key: err = %v, want nil", err)
+	}
+	// 机器人自己发送的消息同步回来时不计入重复消息数量
+	if err := s.handleCreateError(&model.Message{MsgId: 2002, SenderWxID: "wxid_robot"}, gorm.ErrDuplicatedKey); err != nil {
+		t.Fatalf("self message: err = %v, want nil", err)
+	}
+	stats, err := s.GetMessageDedupStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Dropped != 1 {
+		t.Fatalf("dropped = %d, want 1", stats.Dropped)
+	}
+	if s.markMessageReceived(received.MsgId) {
+		t.Fatal("duplicate message should stay marked as received")
+	}
+
+	// 其他入库错误清除排重记录，由消息队列重新投递
+	failed := &model.Message{MsgId: 2003, SenderWxID: "wxid_a"}
+	s.markMessageReceived(failed.MsgId)
+	dbErr := errors.New("连接断开")
+	if err := s.handleCreateError(failed, dbErr); !errors.Is(err, dbErr) {
+		t.Fatalf("db error: err = %v, want %v", err, dbErr)
+	}
+	if !s.markMessageReceived(failed.MsgId) {
+		t.Fatal("failed message should be accepted again")
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/pkg/sendqueue"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/vars"
+)
+
+// sendMessage 通过发送队列发送消息并等待发送完成，重试之后仍然发送失败的消息记录到数据库
+// 定时任务群发的消息通过 sendqueue.WithPriority 降低优先级，排在回复用户的消息之后
+func (s *MessageService) sendMessage(toWxID string, msgType model.MessageType, content string, send func() error) error {
+	job := &sendqueue.Job{
+		ToWxID:   toWxID,
+		Priority: sendqueue.PriorityFromContext(s.ctx),
+		Send:     send,
+	}
+	err := vars.MessageSendQueue.Send(s.ctx, job)
+	// 调用方取消的消息不需要记录
+	if err == nil || s.ctx.Err() != nil {
+		return err
+	}
+	errMsg := []rune(err.Error())
+	if len(errMsg) > 255 {
+		errMsg = errMsg[:255]
+	}
+	failure := &model.MessageSendFailure{
+		ToWxID:    toWxID,
+		Type:      msgType,
+		Content:   content,
+		Priority:  int(job.Priority),
+		Attempts:  job.Attempts(),
+		Error:     string(errMsg),
+		CreatedAt: time.Now().Unix(),
+	}
+	// 请求结束后 gin 的上下文会被复用，这里使用独立的上下文
+	if recordErr := repository.NewMessageSendFailureRepo(context.Background(), vars.DB).Create(failure); recordErr != nil {
+		log.Printf("记录发送失败的消息失败: %v", recordErr)
+	}
+	return err
+}
+
+func (s *MessageService) GetMessageSendFailures(req dto.MessageSendFailureRequest, pager appx.Pager) ([]*model.MessageSendFailure, int64, error) {
+	return repository.NewMessageSendFailureRepo(s.ctx, vars.DB).GetList(req, pager)
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/interface/settings"
+	"wechat-robot-client/model"
+	"wechat-robot-client/pkg/appx"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/vars"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+	"gorm.io/datatypes"
+)
+
+const (
+	moderationFloodKeyPrefix          = "moderation_flood"
+	defaultModerationStrikeWindowDays = 30
+	// 机器人发送的消息超过两分钟后无法撤回
+	messageRevokeWindow = 2 * time.Minute
+	// AI聊天的回复通常比AI审核慢，审核完成后隔一段时间再撤回一次
+	moderationRecallDelay = 30 * time.Second
+	// 审核记录中保存的消息字数
+	moderationLogContentRunes = 500
+)
+
+// ModerationNoticePrefix 审核提示消息的前缀，撤回机器人回复时跳过这些提示消息
+const ModerationNoticePrefix = "【内容审核】"
+
+const moderationPrompt = `你是微信群的内容审核员，判断群成员发送的一条消息是否违反群规。
+需要审核的类别：
+%s
+规则：
+1. 只根据消息本身判断，正常的聊天、提问、玩笑、转述新闻不算违规。
+2. 不确定时判断为不违规。
+3. 违规时 category 返回对应类别的英文代码，reason 用一句话说明原因，不超过30个字；不违规时 category 和 reason 返回空字符串。`
+
+// 各个类别在提示词中的说明
+var moderationCategoryPrompts = map[model.ModerationCategory]string{
+	model.ModerationCategoryAd:       "ad：招揽生意、推销商品、拉人进群、引流到其他平台等广告",
+	model.ModerationCategoryScam:     "scam：诈骗、钓鱼链接、刷单返利、虚假中奖、索要验证码或者转账",
+	model.ModerationCategoryPorn:     "porn：色情、低俗内容",
+	model.ModerationCategoryViolence: "violence：暴力、血腥、恐怖内容，或者威胁他人",
+	model.ModerationCategoryOffTopic: "off_topic：和群聊主题无关、反复发送的刷屏内容，群聊主题是：%s",
+}
+
+// ModerationResult 一条消息的审核结果
+type ModerationResult struct {
+	Category model.ModerationCategory
+	// 审核方式：rule-关键词和正则，flood-刷屏，ai-AI审核
+	Source string
+	Reason string
+}
+
+type ModerationAIResult struct {
+	Violation bool   `json:"violation"`
+	Category  string `json:"category"`
+	Reason    string `json:"reason"`
+}
+
+type ModerationService struct {
+	ctx      context.Context
+	mlRespo  *repository.ModerationLog
+	msgRespo *repository.Message
+}
+
+func NewModerationService(ctx context.Context) *ModerationService {
+	return &ModerationService{
+		ctx:      ctx,
+		mlRespo:  repository.NewModerationLogRepo(ctx, vars.DB),
+		msgRespo: repository.NewMessageRepo(ctx, vars.DB),
+	}
+}
+
+func parseModerationSettings(data datatypes.JSON) (model.ModerationSettings, bool) {
+	var config model.ModerationSettings
+	if len(data) == 0 {
+		return config, false
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		log.Printf("解析内容审核规则失败: %v", err)
+		return config, false
+	}
+	return config, true
+}
+
+// mergeModerationSettings 群聊配置了审核规则时使用群聊的规则，否则使用全局的规则
+func mergeModerationSettings(global, chatRoom datatypes.JSON) model.ModerationSettings {
+	if config, ok := parseModerationSettings(chatRoom); ok {
+		return config
+	}
+	config, _ := parseModerationSettings(global)
+	return config
+}
+
+// checkModerationRules 按关键词和正则审核消息，关键词不区分大小写
+func checkModerationRules(config model.ModerationSettings, content string) *ModerationResult {
+	lower := strings.ToLower(content)
+	for _, keyword := range config.Keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword != "" && strings.Contains(lower, strings.ToLower(keyword)) {
+			return &ModerationResult{Category: model.ModerationCategoryKeyword, Source: "rule", Reason: fmt.Sprintf("包含违禁词「%s」", keyword)}
+		}
+	}
+	for _, pattern := range config.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("内容审核正则 %s 无法编译: %v", pattern, err)
+			continue
+		}
+		if re.MatchString(content) {
+			return &ModerationResult{Category: model.ModerationCategoryKeyword, Source: "rule", Reason: "命中违禁规则"}
+		}
+	}
+	return nil
+}
+
+// moderationAICategories 返回需要AI审核的类别
+func moderationAICategories(config model.ModerationSettings) []model.ModerationCategory {
+	categories := config.Categories
+	if len(categories) == 0 {
+		categories = []model.ModerationCategory{
+			model.ModerationCategoryAd,
+			model.ModerationCategoryScam,
+			model.ModerationCategoryPorn,
+			model.ModerationCategoryViolence,
+		}
+	}
+	var result []model.ModerationCategory
+	for _, category := range categories {
+		if _, ok := moderationCategoryPrompts[category]; !ok || slices.Contains(result, category) {
+			continue
+		}
+		// 没有配置群聊主题时无法判断是否偏离主题
+		if category == model.ModerationCategoryOffTopic && strings.TrimSpace(config.Topic) == "" {
+			continue
+		}
+		result = append(result, category)
+	}
+	return result
+}
+
+// CheckRules 按关键词、正则和刷屏规则审核消息
+func (s *ModerationService) CheckRules(config model.ModerationSettings, message *model.Message, content string) (*ModerationResult, error) {
+	if result := checkModerationRules(config, content); result != nil {
+		return result, nil
+	}
+	if config.FloodWindow <= 0 || config.FloodMaxMessages <= 0 {
+		return nil, nil
+	}
+	key := fmt.Sprintf("%s:%s:%s", moderationFloodKeyPrefix, message.FromWxID, message.SenderWxID)
+	count, err := vars.RedisClient.Incr(s.ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if count == 1 {
+		if err := vars.RedisClient.Expire(s.ctx, key, time.Duration(config.FloodWindow)*time.Second).Err(); err != nil {
+			return nil, err
+		}
+	}
+	// 同一轮刷屏只处理一次，窗口结束前不再重复警告
+	if count == int64(config.FloodMaxMessages)+1 {
+		return &ModerationResult{
+			Category: model.ModerationCategoryFlood,
+			Source:   "flood",
+			Reason:   fmt.Sprintf("%d秒内发送了超过%d条消息", config.FloodWindow, config.FloodMaxMessages),
+		}, nil
+	}
+	return nil, nil
+}
+
+// CheckAI 使用审核模型审核消息，没有配置审核模型时返回空
+func (s *ModerationService) CheckAI(aiConfig settings.AIConfig, config model.ModerationSettings, message *model.Message, content string) (*ModerationResult, error) {
+	categories := moderationAICategories(config)
+	if config.Model == "" || len(categories) == 0 || strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+	if err := validateAIConfig(aiConfig); err != nil {
+		return nil, err
+	}
+	var lines []string
+	var codes []string
+	for _, category := range categories {
+		line := moderationCategoryPrompts[category]
+		if category == model.ModerationCategoryOffTopic {
+			line = fmt.Sprintf(line, config.Topic)
+		}
+		lines = append(lines, line)
+		codes = append(codes, string(category))
+	}
+	schema := &jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"violation": {Type: jsonschema.Boolean, Description: "是否违规"},
+			"category":  {Type: jsonschema.String, Enum: append(codes, ""), Description: "违规类别"},
+			"reason":    {Type: jsonschema.String, Description: "违规原因"},
+		},
+		Required:             []string{"violation", "category", "reason"},
+		AdditionalProperties: false,
+	}
+	provider, err := newAIProvider(s.ctx, aiConfig, newAIUsage(message, model.AIUsageFeatureModeration))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := provider.CreateChatCompletion(s.ctx, openai.ChatCompletionRequest{
+		Model: config.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: fmt.Sprintf(moderationPrompt, strings.Join(lines, "\n"))},
+			{Role: openai.ChatMessageRoleUser, Content: content},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:        "moderation_result",
+				Description: "内容审核结果",
+				Strict:      true,
+				Schema:      schema,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("AI返回了空内容")
+	}
+	_, answer := splitThinking(resp.Choices[0].Message.Content)
+	var result ModerationAIResult
+	if err := schema.Unmarshal(answer, &result); err != nil {
+		return nil, fmt.Errorf("解析审核结果失败: %w", err)
+	}
+	category := model.ModerationCategory(result.Category)
+	if !result.Violation || !slices.Contains(categories, category) {
+		return nil, nil
+	}
+	return &ModerationResult{Category: category, Source: "ai", Reason: result.Reason}, nil
+}
+
+// Punish 记录违规并处理：违规次数达到上限时踢出群聊，否则警告；开启撤回时撤回机器人回复给违规成员的消息
+func (s *ModerationService) Punish(config model.ModerationSettings, message *model.Message, result *ModerationResult) (*model.ModerationLog, error) {
+	windowDays := config.StrikeWindowDays
+	if windowDays <= 0 {
+		windowDays = defaultModerationStrikeWindowDays
+	}
+	since := time.Now().AddDate(0, 0, -windowDays).Unix()
+	strikes, err := s.mlRespo.CountStrikes(message.FromWxID, message.SenderWxID, since)
+	if err != nil {
+		return nil, err
+	}
+	content := message.Content
+	if utf8.RuneCountInString(content) > moderationLogContentRunes {
+		content = string([]rune(content)[:moderationLogContentRunes])
+	}
+	moderationLog := &model.ModerationLog{
+		ChatRoomID: message.FromWxID,
+		MemberWxID: message.SenderWxID,
+		MessageID:  message.ID,
+		Content:    content,
+		Category:   result.Category,
+		Source:     result.Source,
+		Reason:     result.Reason,
+		Strikes:    int(strikes) + 1,
+		Action:     model.ModerationActionWarn,
+	}
+	if config.RecallReplies {
+		moderationLog.Recalled = s.recallReplies(message)
+	}
+	if config.KickStrikes > 0 && moderationLog.Strikes >= config.KickStrikes {
+		if err := NewChatRoomService(s.ctx).GroupDelChatRoomMember(message.FromWxID, []string{message.SenderWxID}); err != nil {
+			log.Printf("把违规成员 %s 移出群聊 %s 失败: %v", message.SenderWxID, message.FromWxID, err)
+		} else {
+			moderationLog.Action = model.ModerationActionKick
+		}
+	}
+	if err := s.mlRespo.Create(moderationLog); err != nil {
+		return nil, err
+	}
+	if config.RecallReplies {
+		// 到时间后提交到后台任务协程池执行，退出时协程池已经关闭则不再撤回
+		time.AfterFunc(moderationRecallDelay, func() {
+			err := vars.BackgroundWorkerPool.TrySubmit(message.FromWxID, func() {
+				svc := NewModerationService(context.Background())
+				if recalled := svc.recallReplies(message); recalled > 0 {
+					if err := svc.mlRespo.AddRecalled(moderationLog.ID, recalled); err != nil {
+						log.Printf("更新审核记录失败: %v", err)
+					}
+				}
+			})
+			if err != nil {
+				log.Printf("提交撤回任务失败: %v", err)
+			}
+		})
+	}
+	return moderationLog, nil
+}
+
+// recallReplies 撤回机器人在违规消息之后回复给违规成员的消息，返回撤回的数量
+func (s *ModerationService) recallReplies(message *model.Message) int {
+	since := max(message.CreatedAt, time.Now().Add(-messageRevokeWindow).Unix())
+	replies, err := s.msgRespo.GetRobotReplies(message.FromWxID, vars.RobotRuntime.WxID, message.SenderWxID, since)
+	if err != nil {
+		log.Printf("获取机器人回复的消息失败: %v", err)
+		return 0
+	}
+	recalled := 0
+	for _, reply := range replies {
+		if strings.HasPrefix(reply.Content, ModerationNoticePrefix) {
+			continue
+		}
+		if err := vars.RobotRuntime.MessageRevoke(*reply); err != nil {
+			log.Printf("撤回机器人消息失败: %v", err)
+			continue
+		}
+		reply.IsRecalled = true
+		if err := s.msgRespo.Update(reply); err != nil {
+			log.Printf("更新消息撤回状态失败: %v", err)
+		}
+		recalled++
+	}
+	return recalled
+}
+
+func (s *ModerationService) GetModerationLogs(req dto.ModerationLogRequest, pager appx.Pager) ([]*model.ModerationLog, int64, error) {
+	return s.mlRespo.GetList(req, pager)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"wechat-robot-client/model"
+
+	"gorm.io/datatypes"
+)
+
+func TestCheckModerationRules(t *testing.T) {
+	config := model.ModerationSettings{
+		Keywords: []string{" VX ", ""},
+		Patterns: []string{`https?://\S+\.(xyz|top)\b`, `(`},
+	}
+	if result := checkModerationRules(config, "加我vx领红包"); result == nil || result.Category != model.ModerationCategoryKeyword || result.Source != "rule" {
+		t.Fatalf("keyword result = %+v", result)
+	}
+	if result := checkModerationRules(config, "点击 https://gift.xyz 领取"); result == nil {
+		t.Fatal("want pattern match")
+	}
+	// 无法编译的正则被忽略
+	if result := checkModerationRules(config, "今天天气不错 (晴)"); result != nil {
+		t.Fatalf("result = %+v, want nil", result)
+	}
+}
+
+func TestModerationSettings(t *testing.T) {
+	global := datatypes.JSON(`{"model":"gpt-4o-mini","kick_strikes":3}`)
+	if config := mergeModerationSettings(global, nil); config.KickStrikes != 3 {
+		t.Fatalf("config = %+v, want global", config)
+	}
+	if config := mergeModerationSettings(global, datatypes.JSON(`{"kick_strikes":5}`)); config.KickStrikes != 5 || config.Model != "" {
+		t.Fatalf("config = %+v, want chat room", config)
+	}
+
+	categories := moderationAICategories(model.ModerationSettings{})
+	if !slices.Equal(categories, []model.ModerationCategory{"ad", "scam", "porn", "violence"}) {
+		t.Fatalf("default categories = %v", categories)
+	}
+	// 没有群聊主题时不审核偏离主题，不支持AI审核的类别被忽略
+	categories = moderationAICategories(model.ModerationSettings{Categories: []model.ModerationCategory{"off_topic", "flood", "ad", "ad"}})
+	if !slices.Equal(categories, []model.ModerationCategory{"ad"}) {
+		t.Fatalf("categories = %v", categories)
+	}
+	categories = moderationAICategories(model.ModerationSettings{Topic: "摄影", Categories: []model.ModerationCategory{"off_topic"}})
+	if !slices.Equal(categories, []model.ModerationCategory{"off_topic"}) {
+		t.Fatalf("categories = %v", categories)
+	}
+}
@@ -211,7 +211,7 @@ func (s *MomentsService) SyncMoments() {
 				}
 				continue
 			}
-			momentMood = aiMomentService.GetMomentMood(timelineObject.ContentDesc, *momentSettings)
+			momentMood = aiMomentService.GetMomentMood(timelineObject.Username, timelineObject.ContentDesc, *momentSettings)
 			if momentMood == nil {
 				log.Println("获取朋友圈心情失败")
 				continue
@@ -220,7 +220,7 @@ func (s *MomentsService) SyncMoments() {
 				log.Printf("%s: 朋友圈不适合评论，跳过", timelineObject.ContentDesc)
 				continue
 			}
-			commentContent, err := aiMomentService.Comment(timelineObject.ContentDesc, *momentSettings)
+			commentContent, err := aiMomentService.Comment(timelineObject.Username, timelineObject.ContentDesc, *momentSettings)
 			if err != nil {
 				log.Println("获取朋友圈评论内容失败: ", err)
 				continue
@@ -256,7 +256,7 @@ func (s *MomentsService) SyncMoments() {
 		// 自动点赞
 		if momentSettings.AutoLike != nil && *momentSettings.AutoLike {
 			if momentMood == nil {
-				momentMood = aiMomentService.GetMomentMood(timelineObject.ContentDesc, *momentSettings)
+				momentMood = aiMomentService.GetMomentMood(timelineObject.Username, timelineObject.ContentDesc, *momentSettings)
 				if momentMood == nil {
 					log.Println("获取朋友圈心情失败")
 					continue
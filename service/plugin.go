@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/model"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/vars"
+
+	"gorm.io/datatypes"
+)
+
+type PluginService struct {
+	ctx     context.Context
+	psRespo *repository.PluginSettings
+}
+
+func NewPluginService(ctx context.Context) *PluginService {
+	return &PluginService{
+		ctx:     ctx,
+		psRespo: repository.NewPluginSettingsRepo(ctx, vars.DB),
+	}
+}
+
+// isPluginEnabled 判断插件在指定联系人下是否启用，联系人配置优先于全局配置，都没有配置时默认启用
+func isPluginEnabled(pluginSettings []*model.PluginSettings, contactID, pluginName string) bool {
+	var globalEnabled, contactEnabled *bool
+	for _, item := range pluginSettings {
+		if item.PluginName != pluginName {
+			continue
+		}
+		if item.ContactID == "" {
+			globalEnabled = item.Enabled
+		} else if item.ContactID == contactID {
+			contactEnabled = item.Enabled
+		}
+	}
+	if contactEnabled != nil {
+		return *contactEnabled
+	}
+	if globalEnabled != nil {
+		return *globalEnabled
+	}
+	return true
+}
+
+// mergePluginConfig 合并插件配置，群聊/好友配置按顶层字段覆盖全局配置，都没有配置时返回 nil
+func mergePluginConfig(pluginSettings []*model.PluginSettings, contactID, pluginName string) datatypes.JSON {
+	var globalConfig, contactConfig datatypes.JSON
+	for _, item := range pluginSettings {
+		if item.PluginName != pluginName {
+			continue
+		}
+		if item.ContactID == "" {
+			globalConfig = item.Config
+		} else if item.ContactID == contactID {
+			contactConfig = item.Config
+		}
+	}
+	if len(contactConfig) == 0 {
+		return globalConfig
+	}
+	if len(globalConfig) == 0 {
+		return contactConfig
+	}
+	merged := map[string]any{}
+	if err := json.Unmarshal(globalConfig, &merged); err != nil {
+		return contactConfig
+	}
+	overrides := map[string]any{}
+	if err := json.Unmarshal(contactConfig, &overrides); err != nil {
+		return globalConfig
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return globalConfig
+	}
+	return data
+}
+
+func (s *PluginService) GetPlugins(contactID string) ([]*dto.PluginItem, error) {
+	pluginSettings, err := s.psRespo.GetByContactID(contactID)
+	if err != nil {
+		return nil, fmt.Errorf("获取插件配置失败: %w", err)
+	}
+	items := make([]*dto.PluginItem, 0)
+	for _, handler := range vars.MessagePlugin.Plugins() {
+		name := handler.GetName()
+		priority, _ := vars.MessagePlugin.GetPriority(name)
+		item := &dto.PluginItem{
+			Name:     name,
+			Labels:   handler.GetLabels(),
+			Priority: priority,
+			Enabled:  isPluginEnabled(pluginSettings, contactID, name),
+		}
+		for _, ps := range pluginSettings {
+			if ps.PluginName != name {
+				continue
+			}
+			if ps.ContactID == "" {
+				item.GlobalEnabled = ps.Enabled
+			} else if ps.ContactID == contactID {
+				item.ContactEnabled = ps.Enabled
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *PluginService) checkPluginExists(pluginName string) error {
+	if _, ok := vars.MessagePlugin.GetPriority(pluginName); !ok {
+		return fmt.Errorf("插件 %s 不存在", pluginName)
+	}
+	return nil
+}
+
+// SavePluginEnabled 设置插件的启用状态，ContactID 为空时设置全局默认值
+func (s *PluginService) SavePluginEnabled(req dto.PluginEnabledRequest) error {
+	if err := s.checkPluginExists(req.PluginName); err != nil {
+		return err
+	}
+	pluginSettings, err := s.psRespo.GetPluginSettings(req.PluginName, req.ContactID)
+	if err != nil {
+		return err
+	}
+	if pluginSettings == nil {
+		return s.psRespo.Create(&model.PluginSettings{
+			PluginName: req.PluginName,
+			ContactID:  req.ContactID,
+			Enabled:    req.Enabled,
+		})
+	}
+	pluginSettings.Enabled = req.Enabled
+	return s.psRespo.Update(pluginSettings)
+}
+
+// ResetPluginEnabled 清空群聊/好友的插件启用状态，恢复为继承全局配置
+func (s *PluginService) ResetPluginEnabled(req dto.PluginResetRequest) error {
+	if err := s.checkPluginExists(req.PluginName); err != nil {
+		return err
+	}
+	return s.psRespo.ResetEnabled(req.PluginName, req.ContactID)
+}
+
+func (s *PluginService) GetPluginConfig(req dto.PluginConfigRequest) (*dto.PluginConfigResponse, error) {
+	if err := s.checkPluginExists(req.PluginName); err != nil {
+		return nil, err
+	}
+	pluginSettings, err := s.psRespo.GetByContactID(req.ContactID)
+	if err != nil {
+		return nil, fmt.Errorf("获取插件配置失败: %w", err)
+	}
+	resp := &dto.PluginConfigResponse{
+		PluginName: req.PluginName,
+		ContactID:  req.ContactID,
+		Config:     mergePluginConfig(pluginSettings, req.ContactID, req.PluginName),
+	}
+	for _, item := range pluginSettings {
+		if item.PluginName != req.PluginName {
+			continue
+		}
+		if item.ContactID == "" {
+			resp.GlobalConfig = item.Config
+		} else if item.ContactID == req.ContactID {
+			resp.ContactConfig = item.Config
+		}
+	}
+	return resp, nil
+}
+
+// SavePluginConfig 保存插件配置，整体替换已有配置，ContactID 为空时保存全局配置
+func (s *PluginService) SavePluginConfig(req dto.PluginConfigSaveRequest) error {
+	if err := s.checkPluginExists(req.PluginName); err != nil {
+		return err
+	}
+	var config map[string]any
+	if err := json.Unmarshal(req.Config, &config); err != nil {
+		return errors.New("插件配置必须是JSON对象")
+	}
+	return s.savePluginConfig(req.PluginName, req.ContactID, req.Config)
+}
+
+// UpdatePluginConfig 将部分字段合并到已有的插件配置中，供插件在运行时修改自身配置
+func (s *PluginService) UpdatePluginConfig(pluginName, contactID string, fields any) error {
+	pluginSettings, err := s.psRespo.GetPluginSettings(pluginName, contactID)
+	if err != nil {
+		return err
+	}
+	config := map[string]any{}
+	if pluginSettings != nil && len(pluginSettings.Config) > 0 {
+		if err := json.Unmarshal(pluginSettings.Config, &config); err != nil {
+			return fmt.Errorf("解析插件配置失败: %w", err)
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return errors.New("插件配置必须是JSON对象")
+	}
+	data, err = json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return s.savePluginConfig(pluginName, contactID, data)
+}
+
+func (s *PluginService) savePluginConfig(pluginName, contactID string, config datatypes.JSON) error {
+	pluginSettings, err := s.psRespo.GetPluginSettings(pluginName, contactID)
+	if err != nil {
+		return err
+	}
+	if pluginSettings == nil {
+		return s.psRespo.Create(&model.PluginSettings{
+			PluginName: pluginName,
+			ContactID:  contactID,
+			Config:     config,
+		})
+	}
+	pluginSettings.Config = config
+	return s.psRespo.Update(pluginSettings)
+}
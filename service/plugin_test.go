@@ -0,0 +1,151 @@
+package service
+
+import (
+	"testing"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	pluginpkg "wechat-robot-client/plugin"
+	"wechat-robot-client/vars"
+
+	"gorm.io/datatypes"
+)
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestIsPluginEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []*model.PluginSettings
+		want     bool
+	}{
+		{
+			name: "没有配置时默认启用",
+			want: true,
+		},
+		{
+			name: "只有全局配置",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIChat", Enabled: boolPtr(false)},
+			},
+			want: false,
+		},
+		{
+			name: "群聊配置优先于全局配置",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIChat", Enabled: boolPtr(false)},
+				{PluginName: "AIChat", ContactID: "123@chatroom", Enabled: boolPtr(true)},
+			},
+			want: true,
+		},
+		{
+			name: "群聊没有设置启用状态时使用全局配置",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIChat", Enabled: boolPtr(false)},
+				{PluginName: "AIChat", ContactID: "123@chatroom"},
+			},
+			want: false,
+		},
+		{
+			name: "其他群聊的配置不生效",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIChat", ContactID: "456@chatroom", Enabled: boolPtr(false)},
+			},
+			want: true,
+		},
+		{
+			name: "其他插件的配置不生效",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIDrawing", Enabled: boolPtr(false)},
+				{PluginName: "AIDrawing", ContactID: "123@chatroom", Enabled: boolPtr(false)},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPluginEnabled(tt.settings, "123@chatroom", "AIChat"); got != tt.want {
+				t.Fatalf("isPluginEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testPlugin struct{}
+
+func (p *testPlugin) GetName() string                           { return "AIChat" }
+func (p *testPlugin) GetLabels() []string                       { return nil }
+func (p *testPlugin) PreAction(ctx *plugin.MessageContext) bool { return true }
+func (p *testPlugin) PostAction(ctx *plugin.MessageContext)     {}
+func (p *testPlugin) Run(ctx *plugin.MessageContext) bool       { return false }
+
+func TestCheckPluginExists(t *testing.T) {
+	origin := vars.MessagePlugin
+	t.Cleanup(func() { vars.MessagePlugin = origin })
+	vars.MessagePlugin = pluginpkg.NewMessagePlugin()
+	vars.MessagePlugin.Register(&testPlugin{}, 100)
+
+	s := &PluginService{}
+	if err := s.checkPluginExists("AIChat"); err != nil {
+		t.Fatalf("registered plugin: err = %v, want nil", err)
+	}
+	if err := s.checkPluginExists("Unknown"); err == nil {
+		t.Fatal("unknown plugin: err = nil, want error")
+	}
+}
+
+func TestMergePluginConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []*model.PluginSettings
+		want     string
+	}{
+		{
+			name: "没有配置时返回空",
+		},
+		{
+			name: "只有全局配置",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIChat", Config: datatypes.JSON(`{"model":"gpt-4o","max_tokens":1000}`)},
+			},
+			want: `{"model":"gpt-4o","max_tokens":1000}`,
+		},
+		{
+			name: "只有群聊配置",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIChat", ContactID: "123@chatroom", Config: datatypes.JSON(`{"model":"gpt-4o-mini"}`)},
+			},
+			want: `{"model":"gpt-4o-mini"}`,
+		},
+		{
+			name: "群聊配置覆盖全局配置的部分字段",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIChat", Config: datatypes.JSON(`{"model":"gpt-4o","max_tokens":1000}`)},
+				{PluginName: "AIChat", ContactID: "123@chatroom", Config: datatypes.JSON(`{"model":"gpt-4o-mini"}`)},
+			},
+			want: `{"max_tokens":1000,"model":"gpt-4o-mini"}`,
+		},
+		{
+			name: "其他群聊的配置不生效",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIChat", Config: datatypes.JSON(`{"model":"gpt-4o"}`)},
+				{PluginName: "AIChat", ContactID: "456@chatroom", Config: datatypes.JSON(`{"model":"gpt-4o-mini"}`)},
+			},
+			want: `{"model":"gpt-4o"}`,
+		},
+		{
+			name: "其他插件的配置不生效",
+			settings: []*model.PluginSettings{
+				{PluginName: "AIDrawing", Config: datatypes.JSON(`{"model":"jimeng"}`)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePluginConfig(tt.settings, "123@chatroom", "AIChat"); string(got) != tt.want {
+				t.Fatalf("mergePluginConfig() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
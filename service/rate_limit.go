@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/model"
+	"wechat-robot-client/vars"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/datatypes"
+)
+
+const (
+	rateLimitKeyPrefix = "rate_limit"
+	// 已经提示过被限流的发送者，等待时间内不再重复提示
+	rateLimitNoticeKeyPrefix = "rate_limit_notice"
+)
+
+// tokenBucketScript 令牌桶限流，所有规则都有令牌时才会扣减，保证多条规则同时生效时的原子性
+// KEYS: 每条规则对应的令牌桶
+// ARGV: 当前时间(毫秒)，然后依次是每条规则的容量和周期(毫秒)
+// 返回: 是否允许，需要等待的毫秒数
+var tokenBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local tokens = {}
+local wait = 0
+for i, key in ipairs(KEYS) do
+  local capacity = tonumber(ARGV[i * 2])
+  local period = tonumber(ARGV[i * 2 + 1])
+  local data = redis.call('HMGET', key, 'tokens', 'ts')
+  local current = tonumber(data[1])
+  local ts = tonumber(data[2])
+  if current == nil or ts == nil then
+    current = capacity
+    ts = now
+  end
+  local rate = capacity / period
+  current = math.min(capacity, current + math.max(0, now - ts) * rate)
+  tokens[i] = current
+  if current < 1 then
+    wait = math.max(wait, math.ceil((1 - current) / rate))
+  end
+end
+local allowed = 1
+if wait > 0 then
+  allowed = 0
+end
+for i, key in ipairs(KEYS) do
+  local capacity = tonumber(ARGV[i * 2])
+  local period = tonumber(ARGV[i * 2 + 1])
+  local current = tokens[i]
+  if allowed == 1 then
+    current = current - 1
+    redis.call('HINCRBY', key, 'allowed', 1)
+  elseif current < 1 then
+    redis.call('HINCRBY', key, 'rejected', 1)
+  end
+  redis.call('HSET', key, 'tokens', tostring(current), 'ts', now, 'limit', capacity, 'period', period)
+  redis.call('PEXPIRE', key, period * 2)
+end
+return {allowed, wait}
+`)
+
+type RateLimitService struct {
+	ctx context.Context
+}
+
+func NewRateLimitService(ctx context.Context) *RateLimitService {
+	return &RateLimitService{
+		ctx: ctx,
+	}
+}
+
+// mergeRateLimitRules 群聊/好友配置了该功能的规则时使用群聊/好友的规则，否则使用全局规则
+func mergeRateLimitRules(globalRules, contactRules datatypes.JSON, feature model.RateLimitFeature) []model.RateLimitRule {
+	if rules := parseRateLimitRules(contactRules, feature); len(rules) > 0 {
+		return rules
+	}
+	return parseRateLimitRules(globalRules, feature)
+}
+
+func parseRateLimitRules(data datatypes.JSON, feature model.RateLimitFeature) []model.RateLimitRule {
+	if len(data) == 0 {
+		return nil
+	}
+	var rules []model.RateLimitRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.Printf("解析限流规则失败: %v", err)
+		return nil
+	}
+	var result []model.RateLimitRule
+	for _, rule := range rules {
+		if rule.Feature == feature && rule.Limit > 0 && rule.Period > 0 {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+// rateLimitKey 令牌桶的 key，格式为 rate_limit:{聊天ID}:{功能}:{周期}:{范围}[:{发送者}]
+// 同一功能、同一范围可以配置多条不同周期的规则（例如每分钟5次、每天50次），每条规则使用独立的令牌桶
+func rateLimitKey(message *model.Message, rule model.RateLimitRule) string {
+	if rule.Scope == model.RateLimitScopeChat || !message.IsChatRoom {
+		return fmt.Sprintf("%s:%s:%s:%d:%s", rateLimitKeyPrefix, message.FromWxID, rule.Feature, rule.Period, model.RateLimitScopeChat)
+	}
+	return fmt.Sprintf("%s:%s:%s:%d:%s:%s", rateLimitKeyPrefix, message.FromWxID, rule.Feature, rule.Period, model.RateLimitScopeSender, message.SenderWxID)
+}
+
+// Allow 判断这条消息能否使用功能，不能使用时返回需要等待的时间
+func (s *RateLimitService) Allow(message *model.Message, rules []model.RateLimitRule) (bool, time.Duration, error) {
+	if len(rules) == 0 {
+		return true, 0, nil
+	}
+	keys := make([]string, 0, len(rules))
+	args := make([]any, 0, len(rules)*2+1)
+	args = append(args, time.Now().UnixMilli())
+	for _, rule := range rules {
+		keys = append(keys, rateLimitKey(message, rule))
+		args = append(args, rule.Limit, int64(rule.Period)*1000)
+	}
+	result, err := tokenBucketScript.Run(s.ctx, vars.RedisClient, keys, args...).Int64Slice()
+	if err != nil {
+		return true, 0, err
+	}
+	if len(result) != 2 {
+		return true, 0, fmt.Errorf("限流脚本返回值异常: %v", result)
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// ShouldNotify 判断是否需要提示发送者被限流，同一个发送者在等待时间内只提示一次，之后被限流的消息直接丢弃
+func (s *RateLimitService) ShouldNotify(message *model.Message, feature model.RateLimitFeature, wait time.Duration) bool {
+	key := fmt.Sprintf("%s:%s:%s:%s", rateLimitNoticeKeyPrefix, message.FromWxID, feature, message.SenderWxID)
+	first, err := vars.RedisClient.SetNX(s.ctx, key, 1, max(wait, time.Second)).Result()
+	if err != nil {
+		log.Printf("记录限流提示失败: %v", err)
+		return true
+	}
+	return first
+}
+
+// GetRateLimitCounters 获取群聊/好友当前的限流计数
+func (s *RateLimitService) GetRateLimitCounters(contactID string) ([]*dto.RateLimitCounter, error) {
+	keys, err := s.scanKeys(contactID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	counters := make([]*dto.RateLimitCounter, 0, len(keys))
+	for _, key := range keys {
+		data, err := vars.RedisClient.HGetAll(s.ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		// rate_limit:{聊天ID}:{功能}:{周期}:{范围}[:{发送者}]
+		parts := strings.SplitN(key, ":", 6)
+		if len(parts) < 5 {
+			continue
+		}
+		counter := &dto.RateLimitCounter{
+			ContactID: parts[1],
+			Feature:   model.RateLimitFeature(parts[2]),
+			Scope:     model.RateLimitScope(parts[4]),
+		}
+		if len(parts) == 6 {
+			counter.SenderWxID = parts[5]
+		}
+		counter.Limit, _ = strconv.Atoi(data["limit"])
+		period, _ := strconv.ParseInt(data["period"], 10, 64)
+		counter.Period = int(period / 1000)
+		counter.Allowed, _ = strconv.ParseInt(data["allowed"], 10, 64)
+		counter.Rejected, _ = strconv.ParseInt(data["rejected"], 10, 64)
+		// 按经过的时间恢复令牌，和限流脚本的算法保持一致
+		tokens, _ := strconv.ParseFloat(data["tokens"], 64)
+		ts, _ := strconv.ParseInt(data["ts"], 10, 64)
+		if period > 0 && now > ts {
+			tokens += float64(now-ts) * float64(counter.Limit) / float64(period)
+		}
+		counter.Remaining = min(int(tokens), counter.Limit)
+		counters = append(counters, counter)
+	}
+	return counters, nil
+}
+
+// ResetRateLimitCounters 清空群聊/好友的限流计数
+func (s *RateLimitService) ResetRateLimitCounters(contactID string) error {
+	keys, err := s.scanKeys(contactID)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return vars.RedisClient.Del(s.ctx, keys...).Err()
+}
+
+func (s *RateLimitService) scanKeys(contactID string) ([]string, error) {
+	var keys []string
+	iter := vars.RedisClient.Scan(s.ctx, 0, fmt.Sprintf("%s:%s:*", rateLimitKeyPrefix, contactID), 100).Iterator()
+	for iter.Next(s.ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"wechat-robot-client/model"
+
+	"gorm.io/datatypes"
+)
+
+func TestMergeRateLimitRules(t *testing.T) {
+	global := datatypes.JSON(`[{"feature":"chat","scope":"sender","limit":5,"period":60},{"feature":"drawing","scope":"chat","limit":10,"period":86400}]`)
+	contact := datatypes.JSON(`[{"feature":"chat","scope":"sender","limit":2,"period":60},{"feature":"tts","scope":"sender","limit":0,"period":60}]`)
+
+	if rules := mergeRateLimitRules(global, contact, model.RateLimitFeatureChat); len(rules) != 1 || rules[0].Limit != 2 {
+		t.Fatalf("chat rules = %+v, want contact rule", rules)
+	}
+	if rules := mergeRateLimitRules(global, contact, model.RateLimitFeatureDrawing); len(rules) != 1 || rules[0].Limit != 10 {
+		t.Fatalf("drawing rules = %+v, want global rule", rules)
+	}
+	// limit 为 0 的规则无效
+	if rules := mergeRateLimitRules(global, contact, model.RateLimitFeatureTTS); len(rules) != 0 {
+		t.Fatalf("tts rules = %+v, want none", rules)
+	}
+	if rules := mergeRateLimitRules(nil, datatypes.JSON(`{`), model.RateLimitFeatureChat); len(rules) != 0 {
+		t.Fatalf("invalid json rules = %+v, want none", rules)
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	group := &model.Message{FromWxID: "123@chatroom", SenderWxID: "wxid_a", IsChatRoom: true}
+	friend := &model.Message{FromWxID: "wxid_b", SenderWxID: "wxid_b"}
+	senderRule := model.RateLimitRule{Feature: model.RateLimitFeatureChat, Scope: model.RateLimitScopeSender, Period: 60}
+	dailyRule := model.RateLimitRule{Feature: model.RateLimitFeatureChat, Scope: model.RateLimitScopeSender, Period: 86400}
+	chatRule := model.RateLimitRule{Feature: model.RateLimitFeatureDrawing, Scope: model.RateLimitScopeChat, Period: 86400}
+
+	cases := []struct {
+		message *model.Message
+		rule    model.RateLimitRule
+		want    string
+	}{
+		{group, senderRule, "rate_limit:123@chatroom:chat:60:sender:wxid_a"},
+		{group, dailyRule, "rate_limit:123@chatroom:chat:86400:sender:wxid_a"},
+		{group, chatRule, "rate_limit:123@chatroom:drawing:86400:chat"},
+		{friend, senderRule, "rate_limit:wxid_b:chat:60:chat"},
+	}
+	for _, c := range cases {
+		if got := rateLimitKey(c.message, c.rule); got != c.want {
+			t.Errorf("rateLimitKey = %q, want %q", got, c.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/plugin/script"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/vars"
+)
+
+type ScriptPluginService struct {
+	ctx     context.Context
+	spRespo *repository.ScriptPlugin
+}
+
+func NewScriptPluginService(ctx context.Context) *ScriptPluginService {
+	return &ScriptPluginService{
+		ctx:     ctx,
+		spRespo: repository.NewScriptPluginRepo(ctx, vars.DB),
+	}
+}
+
+func (s *ScriptPluginService) GetScriptPlugins() ([]*model.ScriptPlugin, error) {
+	return s.spRespo.GetList()
+}
+
+// SaveScriptPlugin 新增或者更新脚本插件，脚本编译通过后才会保存，保存成功后重新加载插件
+func (s *ScriptPluginService) SaveScriptPlugin(req dto.ScriptPluginRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Timeout < 0 {
+		return errors.New("超时时间不能小于0")
+	}
+	if req.Timeout > script.MaxTimeout {
+		return fmt.Errorf("超时时间不能超过 %d 秒", script.MaxTimeout)
+	}
+	labels, err := json.Marshal(req.Labels)
+	if err != nil {
+		return err
+	}
+	allowedHosts, err := json.Marshal(req.AllowedHosts)
+	if err != nil {
+		return err
+	}
+	data := &model.ScriptPlugin{
+		ID:           req.ID,
+		Name:         req.Name,
+		Description:  req.Description,
+		ContactID:    req.ContactID,
+		Labels:       labels,
+		Script:       req.Script,
+		AllowedHosts: allowedHosts,
+		Priority:     req.Priority,
+		Timeout:      req.Timeout,
+		Enabled:      req.Enabled,
+	}
+	// 先编译一次脚本，语法错误直接返回给管理后台
+	if _, err := script.NewScriptPlugin(data); err != nil {
+		return err
+	}
+	err = saveDynamicPlugin(s.spRespo, data, req.Name, isScriptPlugin)
+	if err != nil {
+		return err
+	}
+	return s.LoadScriptPlugins()
+}
+
+func (s *ScriptPluginService) DeleteScriptPlugin(id uint64) error {
+	if err := s.spRespo.Delete(id); err != nil {
+		return err
+	}
+	return s.LoadScriptPlugins()
+}
+
+// LoadScriptPlugins 从数据库重新加载脚本插件，替换掉已经注册的脚本插件
+func (s *ScriptPluginService) LoadScriptPlugins() error {
+	scriptPlugins, err := s.spRespo.GetList()
+	if err != nil {
+		return fmt.Errorf("获取脚本插件失败: %w", err)
+	}
+	loadDynamicPlugins("脚本插件", scriptPlugins, func(item *model.ScriptPlugin) (plugin.MessageHandler, error) {
+		return script.NewScriptPlugin(item)
+	}, isScriptPlugin)
+	return nil
+}
+
+func isScriptPlugin(handler plugin.MessageHandler) bool {
+	_, ok := handler.(*script.ScriptPlugin)
+	return ok
+}
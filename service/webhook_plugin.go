@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"wechat-robot-client/dto"
+	"wechat-robot-client/interface/plugin"
+	"wechat-robot-client/model"
+	"wechat-robot-client/plugin/webhook"
+	"wechat-robot-client/repository"
+	"wechat-robot-client/vars"
+)
+
+type WebhookPluginService struct {
+	ctx     context.Context
+	wpRespo *repository.WebhookPlugin
+}
+
+func NewWebhookPluginService(ctx context.Context) *WebhookPluginService {
+	return &WebhookPluginService{
+		ctx:     ctx,
+		wpRespo: repository.NewWebhookPluginRepo(ctx, vars.DB),
+	}
+}
+
+func (s *WebhookPluginService) GetWebhookPlugins() ([]*model.WebhookPlugin, error) {
+	return s.wpRespo.GetList()
+}
+
+// SaveWebhookPlugin 新增或者更新 Webhook 插件，保存成功后重新加载插件
+func (s *WebhookPluginService) SaveWebhookPlugin(req dto.WebhookPluginRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if !strings.HasPrefix(req.URL, "http://") && !strings.HasPrefix(req.URL, "https://") {
+		return errors.New("回调地址必须以 http:// 或者 https:// 开头")
+	}
+	if req.Timeout < 0 || req.MaxRetries < 0 {
+		return errors.New("超时时间和重试次数不能小于0")
+	}
+	if req.Timeout > webhook.MaxTimeout {
+		return fmt.Errorf("超时时间不能超过 %d 秒", webhook.MaxTimeout)
+	}
+	if req.MaxRetries > webhook.MaxRetries {
+		return fmt.Errorf("重试次数不能超过 %d 次", webhook.MaxRetries)
+	}
+	labels, err := json.Marshal(req.Labels)
+	if err != nil {
+		return err
+	}
+	data := &model.WebhookPlugin{
+		ID:          req.ID,
+		Name:        req.Name,
+		URL:         req.URL,
+		Secret:      req.Secret,
+		Labels:      labels,
+		TriggerType: req.TriggerType,
+		TriggerRule: req.TriggerRule,
+		Priority:    req.Priority,
+		Timeout:     req.Timeout,
+		MaxRetries:  req.MaxRetries,
+		Enabled:     req.Enabled,
+	}
+	// 先校验触发条件
+	if _, err := webhook.NewWebhookPlugin(data); err != nil {
+		return err
+	}
+	err = saveDynamicPlugin(s.wpRespo, data, req.Name, isWebhookPlugin)
+	if err != nil {
+		return err
+	}
+	return s.LoadWebhookPlugins()
+}
+
+func (s *WebhookPluginService) DeleteWebhookPlugin(id uint64) error {
+	if err := s.wpRespo.Delete(id); err != nil {
+		return err
+	}
+	return s.LoadWebhookPlugins()
+}
+
+// LoadWebhookPlugins 从数据库重新加载 Webhook 插件，替换掉已经注册的 Webhook 插件
+func (s *WebhookPluginService) LoadWebhookPlugins() error {
+	webhookPlugins, err := s.wpRespo.GetList()
+	if err != nil {
+		return fmt.Errorf("获取Webhook插件失败: %w", err)
+	}
+	loadDynamicPlugins("Webhook插件", webhookPlugins, func(item *model.WebhookPlugin) (plugin.MessageHandler, error) {
+		return webhook.NewWebhookPlugin(item)
+	}, isWebhookPlugin)
+	return nil
+}
+
+func isWebhookPlugin(handler plugin.MessageHandler) bool {
+	_, ok := handler.(*webhook.WebhookPlugin)
+	return ok
+}
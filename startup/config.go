@@ -95,6 +95,50 @@ func loadEnvConfig() {
 		vars.RobotStartTimeout = time.Duration(t) * time.Second
 	}
 
+	// 消息处理协程池
+	if workers := os.Getenv("MESSAGE_WORKERS"); workers != "" {
+		n, err := strconv.Atoi(workers)
+		if err != nil {
+			log.Fatalf("MESSAGE_WORKERS 转换失败: %v", err)
+		}
+		vars.MessageWorkerSettings.Workers = n
+	}
+	if queueSize := os.Getenv("MESSAGE_QUEUE_SIZE"); queueSize != "" {
+		n, err := strconv.Atoi(queueSize)
+		if err != nil {
+			log.Fatalf("MESSAGE_QUEUE_SIZE 转换失败: %v", err)
+		}
+		vars.MessageWorkerSettings.QueueSize = n
+	}
+
+	// 后台任务协程池
+	if workers := os.Getenv("BACKGROUND_WORKERS"); workers != "" {
+		n, err := strconv.Atoi(workers)
+		if err != nil {
+			log.Fatalf("BACKGROUND_WORKERS 转换失败: %v", err)
+		}
+		vars.BackgroundWorkerSettings.Workers = n
+	}
+	if queueSize := os.Getenv("BACKGROUND_QUEUE_SIZE"); queueSize != "" {
+		n, err := strconv.Atoi(queueSize)
+		if err != nil {
+			log.Fatalf("BACKGROUND_QUEUE_SIZE 转换失败: %v", err)
+		}
+		vars.BackgroundWorkerSettings.QueueSize = n
+	}
+
+	// 消息队列
+	if backend := os.Getenv("MESSAGE_QUEUE_BACKEND"); backend != "" {
+		vars.MessageQueueSettings.Backend = backend
+	}
+	if maxAttempts := os.Getenv("MESSAGE_MAX_ATTEMPTS"); maxAttempts != "" {
+		n, err := strconv.Atoi(maxAttempts)
+		if err != nil {
+			log.Fatalf("MESSAGE_MAX_ATTEMPTS 转换失败: %v", err)
+		}
+		vars.MessageQueueSettings.MaxAttempts = n
+	}
+
 	vars.ThirdPartyApiKey = os.Getenv("THIRD_PARTY_API_KEY")
 	// 词云
 	vars.WordCloudUrl = os.Getenv("WORD_CLOUD_URL")
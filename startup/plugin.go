@@ -1,42 +1,82 @@
 package startup
 
 import (
+	"context"
+	"log"
 	"wechat-robot-client/plugin"
 	"wechat-robot-client/plugin/plugins"
+	"wechat-robot-client/service"
 	"wechat-robot-client/vars"
 )
 
+// RegisterMessagePlugin 注册消息处理插件
+// 优先级数值越小越先执行：内容审核 > 命令 > 关键词类功能插件 > AI 聊天/绘画等兜底插件
 func RegisterMessagePlugin() {
 	vars.MessagePlugin = plugin.NewMessagePlugin()
+	// 群聊内容审核，在命令和其他插件之前执行，违规消息不再交给后续插件处理
+	vars.MessagePlugin.Register(plugins.NewModerationPlugin(), plugin.PriorityHighest)
+	// 命令路由，插件通过 GetCommands 声明的命令都由命令路由统一分发
+	vars.MessagePlugin.Register(vars.MessagePlugin.Commands(), plugin.PriorityHighest)
 	// 群聊聊天插件
-	vars.MessagePlugin.Register(plugins.NewChatRoomAIChatSessionStartPlugin())
-	vars.MessagePlugin.Register(plugins.NewChatRoomAIChatSessionEndPlugin())
-	vars.MessagePlugin.Register(plugins.NewChatRoomAIChatPlugin())
+	vars.MessagePlugin.Register(plugins.NewChatRoomAIChatCommandPlugin(), plugin.PriorityHigh)
+	vars.MessagePlugin.Register(plugins.NewChatRoomAIChatPlugin(), plugin.PriorityNormal)
 	// 群聊绘画插件
-	vars.MessagePlugin.Register(plugins.NewChatRoomAIDrawingSessionStartPlugin())
-	vars.MessagePlugin.Register(plugins.NewChatRoomAIDrawingSessionEndPlugin())
-	vars.MessagePlugin.Register(plugins.NewChatRoomAIDrawingPlugin())
+	vars.MessagePlugin.Register(plugins.NewChatRoomAIDrawingCommandPlugin(), plugin.PriorityHigh)
+	vars.MessagePlugin.Register(plugins.NewChatRoomAIDrawingPlugin(), plugin.PriorityNormal)
+	// AI记忆命令
+	vars.MessagePlugin.Register(plugins.NewAIMemoryCommandPlugin(), plugin.PriorityHigh)
+	// 知识库命令
+	vars.MessagePlugin.Register(plugins.NewKnowledgeBaseCommandPlugin(), plugin.PriorityHigh)
+	// 聊天记录语义搜索
+	vars.MessagePlugin.Register(plugins.NewChatHistorySearchPlugin(), plugin.PriorityHigh)
+	// AI角色命令
+	vars.MessagePlugin.Register(plugins.NewAIPersonaCommandPlugin(), plugin.PriorityHigh)
+	// 群聊管理命令
+	vars.MessagePlugin.Register(plugins.NewChatRoomAdminCommandPlugin(), plugin.PriorityHigh)
 	// 朋友聊天插件
-	vars.MessagePlugin.Register(plugins.NewFriendAIChatPlugin())
+	vars.MessagePlugin.Register(plugins.NewFriendAIChatPlugin(), plugin.PriorityNormal)
+	// 朋友语音聊天插件，开启语音转文字后才会收到 voice 标签的消息
+	vars.MessagePlugin.Register(plugins.NewFriendAIVoiceChatPlugin(), plugin.PriorityNormal)
 	// 朋友绘画插件
-	vars.MessagePlugin.Register(plugins.NewFriendAIDrawingSessionStartPlugin())
-	vars.MessagePlugin.Register(plugins.NewFriendAIDrawingSessionEndPlugin())
-	vars.MessagePlugin.Register(plugins.NewFriendAIDrawingPlugin())
+	vars.MessagePlugin.Register(plugins.NewFriendAIDrawingCommandPlugin(), plugin.PriorityHigh)
+	vars.MessagePlugin.Register(plugins.NewFriendAIDrawingPlugin(), plugin.PriorityNormal)
+	// 申请进群命令，插件本身只在AI调用工具时执行，所以只注册命令和工具
+	autoJoinGroup := plugins.NewAutoJoinGroupPlugin()
+	vars.MessagePlugin.Commands().Register(autoJoinGroup.GetName(), autoJoinGroup.GetCommands()...)
+	vars.MessagePlugin.RegisterTools(autoJoinGroup)
+	// AI聊天时可以调用的工具，这些插件只由AI聊天插件内部调用，不注册为消息插件
+	vars.MessagePlugin.RegisterTools(plugins.NewAIChatPlugin())
+	vars.MessagePlugin.RegisterTools(plugins.NewAIDrawingPlugin())
+	vars.MessagePlugin.RegisterTools(plugins.NewAImageEditPlugin())
+	vars.MessagePlugin.RegisterTools(plugins.NewAImageRecognizerPlugin())
+	vars.MessagePlugin.RegisterTools(plugins.NewAITTSPlugin())
+	vars.MessagePlugin.RegisterTools(plugins.NewAILTTSPlugin())
+	vars.MessagePlugin.RegisterTools(plugins.NewDouyinVideoParsePlugin())
 	// 群聊拍一拍交互插件
-	vars.MessagePlugin.Register(plugins.NewPatPlugin())
+	vars.MessagePlugin.Register(plugins.NewPatPlugin(), plugin.PriorityNormal)
 	// 图片自动上传插件
-	vars.MessagePlugin.Register(plugins.NewImageAutoUploadPlugin())
+	vars.MessagePlugin.Register(plugins.NewImageAutoUploadPlugin(), plugin.PriorityNormal)
 
 	// === 新增功能插件 ===
+	// 关键词触发的功能插件需要排在 AI 聊天插件之前，否则私聊消息会被 AI 聊天插件提前消费
 	// Apilot多功能插件（星座运势、热榜、天气等）
-	vars.MessagePlugin.Register(plugins.NewApilotPlugin())
+	vars.MessagePlugin.Register(plugins.NewApilotPlugin(), plugin.PriorityKeyword)
 	// 京东积存金价格查询插件
-	vars.MessagePlugin.Register(plugins.NewJdjcjPlugin())
+	vars.MessagePlugin.Register(plugins.NewJdjcjPlugin(), plugin.PriorityKeyword)
 	// KFC相关插件
-	vars.MessagePlugin.Register(plugins.NewKFCWenanPlugin())
+	vars.MessagePlugin.Register(plugins.NewKFCWenanPlugin(), plugin.PriorityKeyword)
 
 	// 网易云音乐插件
-	vars.MessagePlugin.Register(plugins.NewNeteasyPlugin())
+	vars.MessagePlugin.Register(plugins.NewNeteasyPlugin(), plugin.PriorityKeyword)
 	// 图像识别插件（iPad版本）
-	vars.MessagePlugin.Register(plugins.NewImageRecognitionIPadPlugin())
+	vars.MessagePlugin.Register(plugins.NewImageRecognitionIPadPlugin(), plugin.PriorityNormal)
+
+	// 外部 Webhook 插件，配置保存在数据库中，增删改后会自动重新加载
+	if err := service.NewWebhookPluginService(context.Background()).LoadWebhookPlugins(); err != nil {
+		log.Printf("加载Webhook插件失败: %v", err)
+	}
+	// Lua 脚本插件
+	if err := service.NewScriptPluginService(context.Background()).LoadScriptPlugins(); err != nil {
+		log.Printf("加载脚本插件失败: %v", err)
+	}
 }
@@ -5,8 +5,14 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"time"
+	"wechat-robot-client/pkg/mcpclient"
+	"wechat-robot-client/pkg/messagequeue"
+	"wechat-robot-client/pkg/sendqueue"
+	"wechat-robot-client/pkg/workerpool"
 	"wechat-robot-client/vars"
 
 	"github.com/redis/go-redis/v9"
@@ -24,6 +30,54 @@ func SetupVars() error {
 		return fmt.Errorf("redis连接失败: %v", err)
 	}
 	log.Println("Redis连接成功")
+	vars.MessageWorkerPool = workerpool.New("消息处理协程池", vars.MessageWorkerSettings.Workers, vars.MessageWorkerSettings.QueueSize)
+	vars.BackgroundWorkerPool = workerpool.New("后台任务协程池", vars.BackgroundWorkerSettings.Workers, vars.BackgroundWorkerSettings.QueueSize)
+	vars.MessageSendQueue = sendqueue.New("消息发送队列", sendqueue.Config{
+		MaxAttempts:        3,
+		RetryBackoff:       2 * time.Second,
+		MinInterval:        500 * time.Millisecond,
+		MaxInterval:        1500 * time.Millisecond,
+		ContactMinInterval: time.Second,
+		ContactMaxInterval: 3 * time.Second,
+	})
+	vars.MCPManager = mcpclient.New("MCP客户端", mcpclient.Config{
+		ToolsTTL:    5 * time.Minute,
+		IdleTimeout: 30 * time.Minute,
+	})
+	if err := InitMessageQueue(); err != nil {
+		return fmt.Errorf("初始化消息队列失败: %v", err)
+	}
+	log.Printf("消息队列: %s", vars.MessageQueueSettings.Backend)
+	return nil
+}
+
+func InitMessageQueue() error {
+	switch vars.MessageQueueSettings.Backend {
+	case "redis":
+		// 每个机器人实例使用单独的 redis db，不需要区分机器人
+		hostname, _ := os.Hostname()
+		vars.MessageQueue = messagequeue.NewRedisStreamQueue(vars.RedisClient, messagequeue.RedisStreamConfig{
+			Stream:      "sync_message",
+			Consumer:    fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+			MaxAttempts: vars.MessageQueueSettings.MaxAttempts,
+		})
+	case "rabbitmq":
+		amqpURL := url.URL{
+			Scheme: "amqp",
+			User:   url.UserPassword(vars.RabbitmqSettings.User, vars.RabbitmqSettings.Password),
+			Host:   net.JoinHostPort(vars.RabbitmqSettings.Host, vars.RabbitmqSettings.Port),
+			Path:   "/" + vars.RabbitmqSettings.Vhost,
+		}
+		// 多个机器人共用 RabbitMQ，队列名带上机器人编码，数据库名就是环境变量 ROBOT_CODE
+		vars.MessageQueue = messagequeue.NewRabbitMQQueue(messagequeue.RabbitMQConfig{
+			URL:         amqpURL.String(),
+			Queue:       fmt.Sprintf("wechat_robot.sync_message.%s", vars.MysqlSettings.Db),
+			MaxAttempts: vars.MessageQueueSettings.MaxAttempts,
+			Prefetch:    vars.MessageWorkerSettings.Workers * vars.MessageWorkerSettings.QueueSize,
+		})
+	default:
+		return fmt.Errorf("不支持的消息队列: %s", vars.MessageQueueSettings.Backend)
+	}
 	return nil
 }
 
@@ -55,8 +109,8 @@ func InitMySQLClient() (err error) {
 		DontSupportRenameIndex:  true, // 重命名索引时采用删除并新建的方式
 		DontSupportRenameColumn: true, // 用 `change` 重命名列
 	}
-	// gorm 配置
-	gormConfig := gorm.Config{}
+	// gorm 配置，转换数据库错误后可以用 gorm.ErrDuplicatedKey 判断唯一索引冲突
+	gormConfig := gorm.Config{TranslateError: true}
 	// 是否开启调试模式
 	if flag, _ := strconv.ParseBool(os.Getenv("GORM_DEBUG")); flag {
 		gormConfig.Logger = logger.Default.LogMode(logger.Info)
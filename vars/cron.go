@@ -16,6 +16,7 @@ const (
 	NewsCron                  CommonCron = "news_cron"
 	MorningCron               CommonCron = "morning_cron"
 	FriendSyncCron            CommonCron = "friend_sync_cron"
+	MessageEmbeddingCron      CommonCron = "message_embedding_cron"
 )
 
 type TaskHandler func()
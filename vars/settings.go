@@ -26,6 +26,24 @@ type RabbitmqSettingS struct {
 	Vhost    string
 }
 
+type MessageWorkerSettingS struct {
+	Workers   int // 处理消息的 worker 数量
+	QueueSize int // 每个 worker 的队列长度，队列满了之后新消息会等待
+}
+
+type BackgroundWorkerSettingS struct {
+	Workers   int // 执行后台任务(知识库文档向量、AI记忆提取、AI内容审核、延迟撤回等)的 worker 数量
+	QueueSize int // 每个 worker 的队列长度
+}
+
+type MessageQueueSettingS struct {
+	Backend     string // 消息队列，支持 redis(默认)、rabbitmq
+	MaxAttempts int    // 消息最大投递次数，超过后进入死信队列
+}
+
 var MysqlSettings = &MysqlSettingS{}
 var RedisSettings = &RedisSettingS{}
 var RabbitmqSettings = &RabbitmqSettingS{}
+var MessageWorkerSettings = &MessageWorkerSettingS{Workers: 16, QueueSize: 100}
+var BackgroundWorkerSettings = &BackgroundWorkerSettingS{Workers: 4, QueueSize: 100}
+var MessageQueueSettings = &MessageQueueSettingS{Backend: "redis", MaxAttempts: 5}
@@ -2,7 +2,11 @@ package vars
 
 import (
 	"time"
+	"wechat-robot-client/pkg/mcpclient"
+	"wechat-robot-client/pkg/messagequeue"
 	"wechat-robot-client/pkg/robot"
+	"wechat-robot-client/pkg/sendqueue"
+	"wechat-robot-client/pkg/workerpool"
 	"wechat-robot-client/plugin"
 
 	"github.com/redis/go-redis/v9"
@@ -26,6 +30,21 @@ var RedisClient *redis.Client
 
 var MessagePlugin *plugin.MessagePlugin
 
+// 消息处理协程池，同一个聊天的消息按顺序处理
+var MessageWorkerPool *workerpool.Pool
+
+// 后台任务协程池，执行不需要等待结果的AI调用(例如提取记忆)，不占用消息处理协程
+var BackgroundWorkerPool *workerpool.Pool
+
+// 收到的消息先写入消息队列，再由消费者处理
+var MessageQueue messagequeue.Queue
+
+// 发送消息的队列，按优先级依次发送，失败后重试
+var MessageSendQueue *sendqueue.Queue
+
+// MCP服务器连接，AI聊天时使用服务器提供的工具
+var MCPManager *mcpclient.Manager
+
 // 机器人启动超时
 var RobotStartTimeout time.Duration
 